@@ -2,6 +2,7 @@ package ai
 
 import (
     "testing"
+    "image"
 
     playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
     "github.com/kazzmir/master-of-magic/game/magic/setup"
@@ -9,6 +10,8 @@ import (
     "github.com/kazzmir/master-of-magic/game/magic/artifact"
     "github.com/kazzmir/master-of-magic/game/magic/units"
     "github.com/kazzmir/master-of-magic/game/magic/data"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
 )
 
 func TestUnitAttackPowerUsesToHit(test *testing.T) {
@@ -51,3 +54,440 @@ func TestBuyItem(test *testing.T) {
         test.Errorf("expected 700 gold after purchase, got %d", self.Gold)
     }
 }
+
+func TestGoalTypeText(test *testing.T) {
+    for goal := GoalNone; goal <= GoalPlanarTravel; goal++ {
+        text, err := goal.MarshalText()
+        if err != nil {
+            test.Fatalf("unable to marshal %v: %v", goal, err)
+        }
+
+        var out GoalType
+        err = out.UnmarshalText(text)
+        if err != nil {
+            test.Fatalf("unable to unmarshal %v: %v", string(text), err)
+        }
+
+        if out != goal {
+            test.Errorf("goal %v round tripped to %v", goal, out)
+        }
+    }
+
+    var out GoalType
+    if out.UnmarshalText([]byte("not a goal")) == nil {
+        test.Errorf("expected an error for an unknown goal")
+    }
+}
+
+func TestThreatMap(test *testing.T) {
+    threat := MakeThreatMap(data.PlaneArcanus, 20, 10)
+    threat.AddEnemy(0, 5, 100)
+
+    if threat.EnemyAt(0, 5) != 100 {
+        test.Errorf("full strength expected on the enemy tile, got %v", threat.EnemyAt(0, 5))
+    }
+
+    if threat.EnemyAt(2, 5) != 50 {
+        test.Errorf("half strength expected two tiles away, got %v", threat.EnemyAt(2, 5))
+    }
+
+    // the map wraps horizontally
+    if threat.EnemyAt(18, 5) != threat.EnemyAt(2, 5) {
+        test.Errorf("threat should wrap around the map: %v vs %v", threat.EnemyAt(18, 5), threat.EnemyAt(2, 5))
+    }
+
+    if threat.EnemyAt(10, 5) != 0 {
+        test.Errorf("no threat expected beyond the radius, got %v", threat.EnemyAt(10, 5))
+    }
+
+    if threat.DangerAt(1, 5) <= 0 {
+        test.Errorf("tile next to an enemy with no friendly forces should be dangerous")
+    }
+
+    threat.AddFriendly(1, 5, 200)
+    if threat.DangerAt(1, 5) > 0 {
+        test.Errorf("a stronger friendly stack should remove the danger, got %v", threat.DangerAt(1, 5))
+    }
+}
+
+// full knowledge services over a fixed set of players, like the game model
+type allSeeingServices struct {
+    playerlib.AIServices
+    Players []*playerlib.Player
+}
+
+func (services *allSeeingServices) GetTurnNumber() uint64 {
+    return 1
+}
+
+func (services *allSeeingServices) GetEnemies(player *playerlib.Player) []*playerlib.Player {
+    var out []*playerlib.Player
+    for _, other := range services.Players {
+        if other != player {
+            out = append(out, other)
+        }
+    }
+    return out
+}
+
+func (services *allSeeingServices) FindCity(x int, y int, plane data.Plane) (*citylib.City, *playerlib.Player) {
+    for _, player := range services.Players {
+        if city := player.FindCity(x, y, plane); city != nil {
+            return city, player
+        }
+    }
+    return nil, nil
+}
+
+func (services *allSeeingServices) FindStack(x int, y int, plane data.Plane) (*playerlib.UnitStack, *playerlib.Player) {
+    for _, player := range services.Players {
+        if stack := player.FindStack(x, y, plane); stack != nil {
+            return stack, player
+        }
+    }
+    return nil, nil
+}
+
+func (services *allSeeingServices) AllCities() []*citylib.City {
+    var out []*citylib.City
+    for _, player := range services.Players {
+        out = append(out, player.GetCities()...)
+    }
+    return out
+}
+
+func (services *allSeeingServices) ComputeCityStackInfo() playerlib.CityStackInfo {
+    out := playerlib.CityStackInfo{
+        ArcanusStacks: make(map[image.Point]*playerlib.UnitStack),
+        MyrrorStacks: make(map[image.Point]*playerlib.UnitStack),
+        ArcanusCities: make(map[image.Point]*citylib.City),
+        MyrrorCities: make(map[image.Point]*citylib.City),
+    }
+
+    for _, player := range services.Players {
+        for _, stack := range player.Stacks {
+            out.ArcanusStacks[image.Pt(stack.X(), stack.Y())] = stack
+        }
+        for _, city := range player.Cities {
+            out.ArcanusCities[image.Pt(city.X, city.Y)] = city
+        }
+    }
+
+    return out
+}
+
+func makeFogTest() (*playerlib.Player, *playerlib.Player, *citylib.City, *FogServices) {
+    self := playerlib.MakePlayer(setup.WizardCustom{}, false, 10, 10, map[herolib.HeroType]string{}, &playerlib.NoGlobalEnchantments{})
+    enemy := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerRed}, false, 10, 10, map[herolib.HeroType]string{}, &playerlib.NoGlobalEnchantments{})
+
+    city := citylib.MakeCity("Hidden", 5, 5, data.RaceHighMen, nil, nil, nil, enemy)
+    city.Plane = data.PlaneArcanus
+    enemy.AddCity(city)
+
+    return self, enemy, city, MakeFogServices(self, &allSeeingServices{Players: []*playerlib.Player{self, enemy}})
+}
+
+// the given city must not be visible through any of the services
+func checkCityHidden(test *testing.T, services *FogServices, city *citylib.City) {
+    for _, check := range services.AllCities() {
+        if check == city {
+            test.Errorf("AllCities returned hidden city %v", city.Name)
+        }
+    }
+
+    if found, _ := services.FindCity(city.X, city.Y, city.Plane); found != nil {
+        test.Errorf("FindCity returned hidden city %v", city.Name)
+    }
+
+    if services.ComputeCityStackInfo().FindCity(city.X, city.Y, city.Plane) != nil {
+        test.Errorf("ComputeCityStackInfo returned hidden city %v", city.Name)
+    }
+}
+
+func TestFogServicesHiddenCity(test *testing.T) {
+    self, _, city, services := makeFogTest()
+
+    services.Refresh()
+    checkCityHidden(test, services, city)
+
+    if len(services.GetEnemies(self)) != 0 {
+        test.Errorf("an enemy that was never seen should not be returned")
+    }
+
+    // having explored the tile before the city was seen is not enough
+    self.ArcanusFog[city.X][city.Y] = data.FogTypeExplored
+    services.Refresh()
+    checkCityHidden(test, services, city)
+}
+
+func TestFogServicesRemembersCity(test *testing.T) {
+    self, enemy, city, services := makeFogTest()
+
+    self.ArcanusFog[city.X][city.Y] = data.FogTypeVisible
+    services.Refresh()
+
+    if found, owner := services.FindCity(city.X, city.Y, city.Plane); found != city || owner == nil || owner.GetBanner() != enemy.GetBanner() {
+        test.Errorf("expected to find a visible city")
+    }
+
+    if len(services.GetEnemies(self)) != 1 {
+        test.Errorf("expected the enemy to be known after seeing its city")
+    }
+
+    // the city is remembered after the tile falls back under the fog, even if it is gone
+    self.ArcanusFog[city.X][city.Y] = data.FogTypeExplored
+    enemy.RemoveCity(city)
+    services.Refresh()
+
+    if found, _ := services.FindCity(city.X, city.Y, city.Plane); found != city {
+        test.Errorf("expected the last seen city to be remembered")
+    }
+
+    // seeing the empty tile again forgets the city
+    self.ArcanusFog[city.X][city.Y] = data.FogTypeVisible
+    services.Refresh()
+    checkCityHidden(test, services, city)
+}
+
+// a city founded under the fog on a tile explored earlier must not be learned about
+func TestScoutMemoryFog(test *testing.T) {
+    self, enemy, hidden, services := makeFogTest()
+
+    seen := citylib.MakeCity("Seen", 2, 2, data.RaceHighMen, nil, nil, nil, enemy)
+    seen.Plane = data.PlaneArcanus
+    enemy.AddCity(seen)
+
+    self.ArcanusFog[seen.X][seen.Y] = data.FogTypeVisible
+    self.ArcanusFog[hidden.X][hidden.Y] = data.FogTypeExplored
+    services.Refresh()
+
+    ai := MakeEnemy2AI()
+    ai.updateScoutMemory(self, services)
+
+    if _, ok := ai.KnownEnemyCities[CityKey{X: seen.X, Y: seen.Y, Plane: seen.Plane}]; !ok {
+        test.Errorf("a visible enemy city should be remembered")
+    }
+
+    if _, ok := ai.KnownEnemyCities[CityKey{X: hidden.X, Y: hidden.Y, Plane: hidden.Plane}]; ok {
+        test.Errorf("an enemy city that was never seen should not be remembered")
+    }
+}
+
+// a rejection made while the stacks move is part of the trace, but a finished trace does not change anymore
+func TestTraceFinishedAfterMoves(test *testing.T) {
+    self, _, _, services := makeFogTest()
+
+    ai := MakeEnemy2AI()
+    ai.currentTrace = &TurnTrace{Turn: 1, Wizard: self.Wizard.Name}
+
+    ai.reject(GoalNone, 1, 1, data.PlaneArcanus, "too strong", "enter encounter")
+
+    if ai.LastTurnTrace() != nil {
+        test.Errorf("the trace should not be available before the turn is over")
+    }
+
+    ai.PostUpdate(self, services)
+
+    trace := ai.LastTurnTrace()
+    if trace == nil || len(trace.Rejected) != 1 {
+        test.Fatalf("the rejected encounter should be in the finished trace: %v", trace)
+    }
+
+    ai.reject(GoalNone, 2, 2, data.PlaneArcanus, "too strong", "enter encounter")
+    if len(trace.Rejected) != 1 {
+        test.Errorf("a finished trace should not be changed: %v", trace.Rejected)
+    }
+}
+
+func TestFogServicesHiddenStack(test *testing.T) {
+    self, enemy, _, services := makeFogTest()
+
+    enemy.AddUnit(units.MakeOverworldUnit(units.HighMenSwordsmen, 2, 2, data.PlaneArcanus))
+
+    services.Refresh()
+    if stack, _ := services.FindStack(2, 2, data.PlaneArcanus); stack != nil {
+        test.Errorf("FindStack returned a stack under the fog")
+    }
+    if services.ComputeCityStackInfo().FindStack(2, 2, data.PlaneArcanus) != nil {
+        test.Errorf("ComputeCityStackInfo returned a stack under the fog")
+    }
+
+    self.ArcanusFog[2][2] = data.FogTypeVisible
+    services.Refresh()
+    if stack, owner := services.FindStack(2, 2, data.PlaneArcanus); stack == nil || owner == nil || owner.GetBanner() != enemy.GetBanner() {
+        test.Errorf("expected to find a visible stack")
+    }
+
+    self.ArcanusFog[2][2] = data.FogTypeExplored
+    services.Refresh()
+    if stack, _ := services.FindStack(2, 2, data.PlaneArcanus); stack != nil {
+        test.Errorf("FindStack returned a stack under the fog")
+    }
+}
+
+// the enemies handed to the ai only have the cities and stacks the player knows about
+func TestFogServicesEnemyView(test *testing.T) {
+    self, enemy, city, services := makeFogTest()
+
+    hidden := citylib.MakeCity("Far", 8, 8, data.RaceHighMen, nil, nil, nil, enemy)
+    enemy.AddCity(hidden)
+    enemy.AddUnit(units.MakeOverworldUnit(units.HighMenSwordsmen, 2, 2, data.PlaneArcanus))
+    enemy.AddUnit(units.MakeOverworldUnit(units.HighMenSwordsmen, 7, 2, data.PlaneArcanus))
+
+    self.ArcanusFog[city.X][city.Y] = data.FogTypeVisible
+    self.ArcanusFog[2][2] = data.FogTypeVisible
+    services.Refresh()
+
+    enemies := services.GetEnemies(self)
+    if len(enemies) != 1 {
+        test.Fatalf("expected one known enemy but got %v", len(enemies))
+    }
+
+    view := enemies[0]
+    if view == enemy {
+        test.Fatalf("the real enemy should not be handed to the ai")
+    }
+
+    if len(view.Cities) != 1 || view.FindCity(city.X, city.Y, city.Plane) != city {
+        test.Errorf("the enemy should only have the city that was seen: %v", view.Cities)
+    }
+
+    if len(view.Stacks) != 1 || view.FindStack(2, 2, data.PlaneArcanus) == nil {
+        test.Errorf("the enemy should only have the stack that is visible: %v", view.Stacks)
+    }
+
+    // the same copy is returned by every lookup, so the ai can compare owners
+    if _, owner := services.FindCity(city.X, city.Y, city.Plane); owner != view {
+        test.Errorf("FindCity should return the same enemy as GetEnemies")
+    }
+
+    if _, owner := services.FindStack(2, 2, data.PlaneArcanus); owner != view {
+        test.Errorf("FindStack should return the same enemy as GetEnemies")
+    }
+
+    if len(enemy.Cities) != 2 || len(enemy.Stacks) != 2 {
+        test.Errorf("the real enemy should not be changed")
+    }
+}
+
+func TestFogServicesSerialize(test *testing.T) {
+    self, enemy, city, services := makeFogTest()
+    all := services.AIServices
+
+    self.ArcanusFog[city.X][city.Y] = data.FogTypeVisible
+    services.Refresh()
+
+    // the tile falls back under the fog before the game is saved
+    self.ArcanusFog[city.X][city.Y] = data.FogTypeExplored
+    services.Refresh()
+
+    loaded := MakeFogServicesFromSerialized(self, all, services.Serialize())
+    loaded.Refresh()
+
+    if found, owner := loaded.FindCity(city.X, city.Y, city.Plane); found != city || owner == nil || owner.GetBanner() != enemy.GetBanner() {
+        test.Errorf("the remembered city should survive saving and loading")
+    }
+
+    if len(loaded.GetEnemies(self)) != 1 {
+        test.Errorf("the known enemy should survive saving and loading")
+    }
+}
+
+func TestNeutralPopulation(test *testing.T) {
+    // grows at half the normal rate
+    if neutralPopulation(3000, 10, 100) != 3050 {
+        test.Errorf("expected 3050, got %v", neutralPopulation(3000, 10, 100))
+    }
+
+    // never above the maximum size
+    if neutralPopulation(9990, 10, 100) != 10000 {
+        test.Errorf("expected 10000, got %v", neutralPopulation(9990, 10, 100))
+    }
+
+    // neutral towns do not shrink on their own
+    if neutralPopulation(3000, 10, -50) != 3000 {
+        test.Errorf("expected 3000, got %v", neutralPopulation(3000, 10, -50))
+    }
+}
+
+func TestNeutralGarrison(test *testing.T) {
+    if neutralGarrisonTarget(1, 0) != 1 {
+        test.Errorf("a small town should start with one unit, got %v", neutralGarrisonTarget(1, 0))
+    }
+
+    if neutralGarrisonTarget(8, 100) != 4 {
+        test.Errorf("expected a garrison of 4, got %v", neutralGarrisonTarget(8, 100))
+    }
+
+    if neutralGarrisonTarget(25, 1000) != 5 {
+        test.Errorf("the garrison should be capped at 5, got %v", neutralGarrisonTarget(25, 1000))
+    }
+
+    // raids grow with the turn and the difficulty
+    if raidSize(0, data.DifficultyIntro) != 1 {
+        test.Errorf("expected a raid of 1 unit, got %v", raidSize(0, data.DifficultyIntro))
+    }
+
+    if raidSize(150, data.DifficultyHard) != 4 {
+        test.Errorf("expected a raid of 4 units, got %v", raidSize(150, data.DifficultyHard))
+    }
+
+    if raidSize(1000, data.DifficultyImpossible) != 6 {
+        test.Errorf("raids should be capped at 6 units, got %v", raidSize(1000, data.DifficultyImpossible))
+    }
+
+    if raidRate(data.DifficultyIntro) >= raidRate(data.DifficultyImpossible) {
+        test.Errorf("raids should be more frequent on higher difficulties")
+    }
+}
+
+func TestNeutralBuilding(test *testing.T) {
+    costs := map[buildinglib.Building]int{
+        buildinglib.BuildingBarracks: 30,
+        buildinglib.BuildingBuildersHall: 60,
+        buildinglib.BuildingShrine: 30,
+    }
+
+    cost := func(building buildinglib.Building) int {
+        return costs[building]
+    }
+
+    choice, ok := cheapestBuilding([]buildinglib.Building{buildinglib.BuildingBuildersHall, buildinglib.BuildingShrine, buildinglib.BuildingBarracks}, cost)
+    if !ok {
+        test.Fatalf("expected a building to be chosen")
+    }
+
+    // barracks and shrine cost the same, so the lower id wins
+    expected := min(buildinglib.BuildingBarracks, buildinglib.BuildingShrine)
+    if choice != expected {
+        test.Errorf("expected %v, got %v", expected, choice)
+    }
+
+    if _, ok := cheapestBuilding(nil, cost); ok {
+        test.Errorf("nothing should be chosen from an empty list")
+    }
+}
+
+func TestChooseRaidTarget(test *testing.T) {
+    near := &citylib.City{Name: "near"}
+    far := &citylib.City{Name: "far"}
+    strong := &citylib.City{Name: "strong"}
+    distant := &citylib.City{Name: "distant"}
+
+    targets := []raidTarget{
+        raidTarget{City: far, Distance: 8, Defense: 10},
+        raidTarget{City: strong, Distance: 2, Defense: 500},
+        raidTarget{City: near, Distance: 4, Defense: 20},
+        raidTarget{City: distant, Distance: raidRadius + 1, Defense: 0},
+    }
+
+    target, ok := chooseRaidTarget(targets, 100)
+    if !ok || target.City != near {
+        test.Errorf("expected the nearest weak city to be raided, got %v", target.City)
+    }
+
+    // too weak to attack anything
+    if _, ok := chooseRaidTarget(targets, 5); ok {
+        test.Errorf("raiders weaker than every garrison should stay home")
+    }
+}
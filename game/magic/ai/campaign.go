@@ -0,0 +1,269 @@
+package ai
+
+import (
+    "fmt"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    "github.com/kazzmir/master-of-magic/game/magic/units"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* a campaign gathers several stacks at one of our cities (the rally point) until their combined
+ * strength is enough to take an enemy city, and then sends them at the target together. Single
+ * stacks still attack opportunistically in GoalDefeatEnemies, this is for targets that no single
+ * stack can take on its own.
+ *
+ * Stacks merge when they end up on the same tile, so the members of a campaign are not remembered
+ * between turns. Instead every eligible stack on the rally point's continent is a member.
+ */
+
+type CampaignState int
+
+const (
+    CampaignGathering CampaignState = iota
+    CampaignAttacking
+)
+
+func (state CampaignState) String() string {
+    switch state {
+        case CampaignGathering: return "gathering"
+        case CampaignAttacking: return "attacking"
+    }
+
+    return "unknown"
+}
+
+type Campaign struct {
+    Target CityKey
+    // one of our cities on the same continent as the target
+    RallyX int
+    RallyY int
+    State CampaignState
+    // combined strength needed before the attack starts
+    Required int
+    StartTurn uint64
+}
+
+func (campaign *Campaign) String() string {
+    return fmt.Sprintf("%v on (%d,%d) from (%d,%d), need %v", campaign.State, campaign.Target.X, campaign.Target.Y, campaign.RallyX, campaign.RallyY, campaign.Required)
+}
+
+// how much stronger than the defenders the gathered army must be
+const campaignStrengthMargin = 1.5
+
+// assumed garrison strength of a city whose defenders we have never seen
+const unscoutedCityStrength = 150
+
+// never launch a campaign with less than this
+const campaignMinimumStrength = 60
+
+// give up on a campaign that hasn't taken its target after this many turns
+const campaignMaxTurns = 40
+
+// how much closer a target seems for every border tile we share with its owner
+const campaignBorderWeight = 5
+
+// the units a stack can contribute to a campaign. stacks in a city leave behind the city's minimum
+// garrison, or everything if the threat map says the city is in danger
+func (ai *Enemy2AI) campaignUnits(self *playerlib.Player, stack *playerlib.UnitStack) []units.StackUnit {
+    if ai.Attacking[stack] || stackHasSettler(stack) || stackHasMelder(stack) || !stack.HasMoves() {
+        return nil
+    }
+
+    maybeCity := self.FindCity(stack.X(), stack.Y(), stack.Plane())
+    if maybeCity != nil && ai.cityThreatened(maybeCity.X, maybeCity.Y, maybeCity.Plane) {
+        return nil
+    }
+
+    return spareGarrisonUnits(stack, maybeCity)
+}
+
+// stacks that could take part in a campaign rallying at (x, y), with the units each can send
+func (ai *Enemy2AI) campaignMembers(self *playerlib.Player, aiServices playerlib.AIServices, x int, y int, plane data.Plane) map[*playerlib.UnitStack][]units.StackUnit {
+    members := make(map[*playerlib.UnitStack][]units.StackUnit)
+    for _, stack := range aiServices.FindStacksOnContinent(x, y, plane, self) {
+        spare := ai.campaignUnits(self, stack)
+        if unitsCombatStrength(spare) > 0 {
+            members[stack] = spare
+        }
+    }
+
+    return members
+}
+
+func knownCityStrength(info *EnemyCityInfo) int {
+    if !info.Scouted {
+        return unscoutedCityStrength
+    }
+    return info.Strength
+}
+
+// choose the best enemy city to campaign against, if we could muster enough strength to take it
+func (ai *Enemy2AI) chooseCampaign(self *playerlib.Player, aiServices playerlib.AIServices) *Campaign {
+    var best *Campaign
+    bestScore := 0
+
+    // the length of our border with each wizard, per plane
+    borders := make(map[data.Plane]map[data.BannerType]int)
+
+    for key, info := range ai.KnownEnemyCities {
+        useMap := aiServices.GetMap(key.Plane)
+        if useMap == nil {
+            continue
+        }
+
+        // rally at our closest city that can walk to the target
+        var rally *citylib.City
+        for _, city := range aiServices.FindCitiesOnContinent(key.X, key.Y, key.Plane, self) {
+            if rally == nil || useMap.TileDistance(city.X, city.Y, key.X, key.Y) < useMap.TileDistance(rally.X, rally.Y, key.X, key.Y) {
+                rally = city
+            }
+        }
+
+        if rally == nil {
+            continue
+        }
+
+        required := max(campaignMinimumStrength, int(float64(knownCityStrength(info)) * campaignStrengthMargin))
+
+        available := 0
+        for _, spare := range ai.campaignMembers(self, aiServices, rally.X, rally.Y, key.Plane) {
+            available += unitsCombatStrength(spare)
+        }
+
+        if available < required {
+            ai.reject(GoalDefeatEnemies, key.X, key.Y, key.Plane, fmt.Sprintf("available strength %v below required %v", available, required), "start campaign")
+            continue
+        }
+
+        // prefer close and weakly defended targets
+        score := useMap.TileDistance(rally.X, rally.Y, key.X, key.Y) * 10 + required
+
+        // and the wizards whose land presses against ours
+        if _, owner := aiServices.FindCity(key.X, key.Y, key.Plane); owner != nil {
+            if _, ok := borders[key.Plane]; !ok {
+                borders[key.Plane] = aiServices.GetTerritory(key.Plane).Neighbors(self.GetBanner())
+            }
+            score -= borders[key.Plane][owner.GetBanner()] * campaignBorderWeight
+        }
+        if best == nil || score < bestScore {
+            best = &Campaign{
+                Target: key,
+                RallyX: rally.X,
+                RallyY: rally.Y,
+                State: CampaignGathering,
+                Required: required,
+                StartTurn: aiServices.GetTurnNumber(),
+            }
+            bestScore = score
+        }
+    }
+
+    return best
+}
+
+// reason the current campaign should be called off, or "" if it should continue
+func (ai *Enemy2AI) campaignInvalid(self *playerlib.Player, aiServices playerlib.AIServices) string {
+    campaign := ai.Campaign
+
+    if _, ok := ai.KnownEnemyCities[campaign.Target]; !ok {
+        return "target is no longer an enemy city"
+    }
+
+    if aiServices.GetTurnNumber() > campaign.StartTurn + campaignMaxTurns {
+        return "campaign took too long"
+    }
+
+    if campaign.State == CampaignGathering && self.FindCity(campaign.RallyX, campaign.RallyY, campaign.Target.Plane) == nil {
+        return "rally point lost"
+    }
+
+    return ""
+}
+
+// decisions that move the campaign along for this turn. stacks that take part are marked as
+// attacking so the rest of GoalDefeatEnemies leaves them alone
+func (ai *Enemy2AI) campaignDecisions(self *playerlib.Player, aiServices playerlib.AIServices) []playerlib.AIDecision {
+    if ai.Campaign != nil {
+        if reason := ai.campaignInvalid(self, aiServices); reason != "" {
+            ai.reject(GoalDefeatEnemies, ai.Campaign.Target.X, ai.Campaign.Target.Y, ai.Campaign.Target.Plane, reason, "continue campaign")
+            ai.Campaign = nil
+        }
+    }
+
+    if ai.Campaign == nil {
+        ai.Campaign = ai.chooseCampaign(self, aiServices)
+        if ai.Campaign == nil {
+            return nil
+        }
+    }
+
+    campaign := ai.Campaign
+    plane := campaign.Target.Plane
+
+    var decisions []playerlib.AIDecision
+
+    members := ai.campaignMembers(self, aiServices, campaign.RallyX, campaign.RallyY, plane)
+
+    total := 0
+    gathered := 0
+    for stack, spare := range members {
+        strength := unitsCombatStrength(spare)
+        total += strength
+        if stack.X() == campaign.RallyX && stack.Y() == campaign.RallyY {
+            gathered += strength
+        }
+    }
+
+    // lost too much of the army to ever take the target
+    if total < campaign.Required / 2 {
+        ai.reject(GoalDefeatEnemies, campaign.Target.X, campaign.Target.Y, plane, fmt.Sprintf("remaining strength %v too low", total), "continue campaign")
+        ai.Campaign = nil
+        return nil
+    }
+
+    if campaign.State == CampaignGathering && gathered >= campaign.Required {
+        campaign.State = CampaignAttacking
+    }
+
+    useMap := aiServices.GetMap(plane)
+    rallyDistance := useMap.TileDistance(campaign.RallyX, campaign.RallyY, campaign.Target.X, campaign.Target.Y)
+
+    for stack, spare := range members {
+        // once the attack has started, stragglers that never made it to the rally point stay home
+        if campaign.State == CampaignAttacking && useMap.TileDistance(stack.X(), stack.Y(), campaign.Target.X, campaign.Target.Y) > rallyDistance {
+            continue
+        }
+
+        destX, destY := campaign.RallyX, campaign.RallyY
+        activity := fmt.Sprintf("gathering at (%d,%d) for campaign on (%d,%d)", destX, destY, campaign.Target.X, campaign.Target.Y)
+
+        if campaign.State == CampaignAttacking {
+            destX, destY = campaign.Target.X, campaign.Target.Y
+            activity = fmt.Sprintf("campaign attack on (%d,%d)", destX, destY)
+        }
+
+        if stack.X() == destX && stack.Y() == destY {
+            ai.Attacking[stack] = true
+            ai.setActivity(stack, activity)
+            continue
+        }
+
+        path, ok := aiServices.FindPath(stack.X(), stack.Y(), destX, destY, self, stack, self.GetFog(plane))
+        if !ok || len(path) == 0 {
+            continue
+        }
+
+        decisions = append(decisions, &playerlib.AIMoveStackDecision{
+            Stack: stack,
+            Path: path,
+            Units: spare,
+        })
+
+        ai.Attacking[stack] = true
+        ai.setActivity(stack, activity)
+    }
+
+    return decisions
+}
@@ -5,6 +5,7 @@ package ai
 
 import (
     "log"
+    "image"
     "slices"
     "cmp"
     "math/rand/v2"
@@ -255,9 +256,11 @@ func (ai *EnemyAI) Update(self *playerlib.Player, aiServices playerlib.AIService
                             }
                         }
                     } else {
-                        // FIXME: choose a location with a high population maximum and near bonuses. Possibly also near a shore so we can build water units
-                        // choose a random location
-                        location := candidateLocations[rand.N(len(candidateLocations))]
+                        // choose the location the city site advisor likes the most
+                        useMap := aiServices.GetMap(stack.Plane())
+                        location := slices.MaxFunc(candidateLocations, func(a image.Point, b image.Point) int {
+                            return cmp.Compare(useMap.ScoreCitySite(a.X, a.Y).Score(), useMap.ScoreCitySite(b.X, b.Y).Score())
+                        })
                         var ok bool
                         path, ok = aiServices.FindPath(stack.X(), stack.Y(), location.X, location.Y, self, stack, self.GetFog(stack.Plane()))
                         if ok {
@@ -71,6 +71,20 @@ type Enemy2AI struct {
     // reservation a defender build would clobber the just-queued settler and the
     // empire would never actually finish a settler. Rebuilt every Update.
     reservedSettlerCities map[*citylib.City]bool
+
+    // per plane influence map of enemy and friendly strength, rebuilt every Update
+    Threat map[data.Plane]*ThreatMap
+
+    // the multi-stack offensive currently being organized, if any. Persists
+    // across turns; see campaign.go
+    Campaign *Campaign
+
+    // structured record of the turn being played (goals, decisions, rejected
+    // alternatives, stack activity), for the AI debugger. Not gameplay.
+    currentTrace *TurnTrace
+    // the trace of the last finished turn. it is not changed once finished,
+    // since the game may already have recorded it
+    lastTrace *TurnTrace
 }
 
 func MakeEnemy2AI() *Enemy2AI {
@@ -161,6 +175,10 @@ type CityKey struct {
 type EnemyCityInfo struct {
     X, Y int
     Plane data.Plane
+    // combat strength of the garrison the last time the tile was visible,
+    // valid only when Scouted is true
+    Strength int
+    Scouted bool
 }
 
 // margin by which a stack's combat strength must exceed a lair's defenders
@@ -170,6 +188,11 @@ type EnemyCityInfo struct {
 // further 20% because the AI was losing raids too easily.
 const lairAttackMargin = 1.5
 
+// how much the score of a city site changes when it is in the territory of another wizard, or
+// on the free land just outside our own territory
+const settlerForeignTerritoryPenalty = 50
+const settlerFrontierBonus = 20
+
 // requiredLairMargin scales the combat-strength surplus the AI demands before
 // committing to a lair/node, based on how strong the defenders are. Weak lairs
 // can be cleared opportunistically on a modest advantage, but strong lairs are
@@ -770,7 +793,7 @@ func absInt(value int) int {
 }
 
 // countEngineers counts how many road-building (Construction) units the player
-// owns across all stacks.
+// owns across all stacks or has queued up in its cities.
 func countEngineers(self *playerlib.Player) int {
     total := 0
     for _, stack := range self.Stacks {
@@ -780,6 +803,16 @@ func countEngineers(self *playerlib.Player) int {
             }
         }
     }
+
+    // engineers waiting in a production queue will show up soon enough
+    for _, city := range self.Cities {
+        for _, item := range city.Queue {
+            if item.IsUnit() && item.Unit.HasAbility(data.AbilityConstruction) {
+                total += item.Count
+            }
+        }
+    }
+
     return total
 }
 
@@ -1008,13 +1041,20 @@ func nearestReachableOpenTower(self *playerlib.Player, aiServices playerlib.AISe
         return nil
     }
     fog := self.GetFog(stack.Plane())
+    var reachable *pathfinding.ReachableSet
     var best pathfinding.Path
     for _, tower := range useMap.GetOpenTowerLocations() {
         if !self.IsExplored(tower.X, tower.Y, stack.Plane()) {
             continue
         }
-        path, ok := aiServices.FindPath(stack.X(), stack.Y(), tower.X, tower.Y, self, stack, fog)
-        if ok && len(path) > 0 {
+        // one search covers every tower
+        if reachable == nil {
+            reachable = aiServices.FindReachable(stack.X(), stack.Y(), self, stack, fog, pathfinding.Infinity)
+        }
+        path, ok := reachable.PathTo(tower)
+        if ok && len(path) > 1 {
+            // like FindPath, leave out the start point
+            path = path[1:]
             if best == nil || len(path) < len(best) {
                 best = path
             }
@@ -1334,14 +1374,29 @@ func (ai *Enemy2AI) updateScoutMemory(self *playerlib.Player, aiServices playerl
         ai.KnownEnemyCities = make(map[CityKey]*EnemyCityInfo)
     }
 
-    // record enemy cities on tiles we have explored
-    for _, enemy := range aiServices.GetEnemies(self) {
-        for _, city := range enemy.Cities {
-            if !self.IsExplored(city.X, city.Y, city.Plane) {
-                continue
+    // record the enemy cities we know about. the services only return cities we have seen, so a city
+    // founded under the fog on a tile we explored earlier stays unknown
+    enemies := aiServices.GetEnemies(self)
+    for _, city := range aiServices.AllCities() {
+        _, owner := aiServices.FindCity(city.X, city.Y, city.Plane)
+        if owner == nil || !slices.Contains(enemies, owner) {
+            continue
+        }
+
+        key := CityKey{X: city.X, Y: city.Y, Plane: city.Plane}
+        info, ok := ai.KnownEnemyCities[key]
+        if !ok {
+            info = &EnemyCityInfo{X: city.X, Y: city.Y, Plane: city.Plane}
+            ai.KnownEnemyCities[key] = info
+        }
+
+        // the garrison can only be counted while the tile is visible
+        if self.IsVisible(city.X, city.Y, city.Plane) {
+            info.Strength = 0
+            if garrison, _ := aiServices.FindStack(city.X, city.Y, city.Plane); garrison != nil {
+                info.Strength = stackCombatStrength(garrison)
             }
-            key := CityKey{X: city.X, Y: city.Y, Plane: city.Plane}
-            ai.KnownEnemyCities[key] = &EnemyCityInfo{X: city.X, Y: city.Y, Plane: city.Plane}
+            info.Scouted = true
         }
     }
 
@@ -1363,12 +1418,17 @@ func (ai *Enemy2AI) updateScoutMemory(self *playerlib.Player, aiServices playerl
 func (ai *Enemy2AI) findScoutPath(self *playerlib.Player, aiServices playerlib.AIServices, stack *playerlib.UnitStack) pathfinding.Path {
     var shortestPath pathfinding.Path
     fog := self.GetFog(stack.Plane())
+    var reachable *pathfinding.ReachableSet
     for _, lair := range ai.KnownLairs {
         if lair.Scouted || lair.Plane != stack.Plane() {
             continue
         }
-        path, ok := aiServices.FindPath(stack.X(), stack.Y(), lair.X, lair.Y, self, stack, fog)
-        if ok && len(path) > 0 {
+        if reachable == nil {
+            reachable = aiServices.FindReachable(stack.X(), stack.Y(), self, stack, fog, pathfinding.Infinity)
+        }
+        path, ok := reachable.PathTo(image.Pt(lair.X, lair.Y))
+        if ok && len(path) > 1 {
+            path = path[1:]
             if len(shortestPath) == 0 || len(path) < len(shortestPath) {
                 shortestPath = path
             }
@@ -1436,15 +1496,21 @@ func (ai *Enemy2AI) findNavalExplorePath(self *playerlib.Player, aiServices play
         return a.dist - b.dist
     })
 
+    if len(candidates) == 0 {
+        return nil
+    }
+
+    reachable := aiServices.FindReachable(sx, sy, self, stack, fog, pathfinding.Infinity)
+
     tried := 0
     for _, c := range candidates {
         if tried >= 20 {
             break
         }
         tried++
-        path, ok := aiServices.FindPath(sx, sy, c.x, c.y, self, stack, fog)
-        if ok && len(path) > 0 {
-            return path
+        path, ok := reachable.PathTo(image.Pt(c.x, c.y))
+        if ok && len(path) > 1 {
+            return path[1:]
         }
     }
 
@@ -1552,6 +1618,10 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
 
     switch goal.Goal {
         case GoalDefeatEnemies:
+            // organize multi-stack offensives first so the single stack attacks
+            // below leave the campaign's stacks alone
+            decisions = append(decisions, ai.campaignDecisions(self, aiServices)...)
+
             // find possible enemy targets
             var possibleTarget []*playerlib.UnitStack
             var possibleCities []*citylib.City
@@ -1574,6 +1644,10 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
 
             if len(possibleTarget) > 0 {
                 for _, stack := range self.Stacks {
+                    // already part of a campaign
+                    if ai.Attacking[stack] {
+                        continue
+                    }
                     // never march a settler (or its escort) off to attack
                     if stackHasSettler(stack) {
                         continue
@@ -1593,7 +1667,9 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                             if city.Plane == stack.Plane() {
                                 // just assume the city has some power in it
                                 targetPower := 15
-                                if stackPower > targetPower - rand.N(10) {
+                                if stackPower <= targetPower - rand.N(10) {
+                                    ai.reject(goal.Goal, city.X, city.Y, city.Plane, fmt.Sprintf("attack power %v too low against assumed %v", stackPower, targetPower), fmt.Sprintf("attack %v with stack at (%d,%d)", city.Name, stack.X(), stack.Y()))
+                                } else {
                                     pathToCity, ok := aiServices.FindPath(stack.X(), stack.Y(), city.X, city.Y, self, stack, self.GetFog(stack.Plane()))
                                     if ok {
                                         if len(shortestPath) == 0 || len(pathToCity) < len(shortestPath) {
@@ -1610,8 +1686,9 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
 
                                     targetPower := stackAttackPower(target)
 
-                                    if stackPower > targetPower - rand.N(10) {
-
+                                    if stackPower <= targetPower - rand.N(10) {
+                                        ai.reject(goal.Goal, target.X(), target.Y(), target.Plane(), fmt.Sprintf("attack power %v too low against %v", stackPower, targetPower), fmt.Sprintf("attack enemy stack with stack at (%d,%d)", stack.X(), stack.Y()))
+                                    } else {
                                         pathToEnemy, ok := aiServices.FindPath(stack.X(), stack.Y(), target.X(), target.Y(), self, stack, self.GetFog(stack.Plane()))
                                         if ok {
                                             if len(shortestPath) == 0 || len(pathToEnemy) < len(shortestPath) {
@@ -1693,6 +1770,7 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                     // scaled by how dangerous the lair is.
                     margin := requiredLairMargin(lair.Strength)
                     if float32(stackStrength) < float32(lair.Strength) * margin {
+                        ai.reject(goal.Goal, lair.X, lair.Y, lair.Plane, fmt.Sprintf("stack strength %v below %v x %v", stackStrength, lair.Strength, margin), fmt.Sprintf("attack lair with stack at (%d,%d)", stack.X(), stack.Y()))
                         continue
                     }
 
@@ -1768,6 +1846,11 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                             continue
                         }
 
+                        // stacks on the offensive are not pulled back
+                        if ai.Attacking[stack] {
+                            continue
+                        }
+
                         if stackAttackPower(stack) > 0 && stack.HasMoves() {
                             candidates = append(candidates, stack)
                         }
@@ -1884,13 +1967,20 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                             Ok bool
                         }
 
+                        // one search finds the path to every location
+                        reachable := aiServices.FindReachable(stack.X(), stack.Y(), self, stack, fog, pathfinding.Infinity)
                         pathTo := functional.Memoize(func(location image.Point) PathResult {
-                            path, ok := aiServices.FindPath(stack.X(), stack.Y(), location.X, location.Y, self, stack, fog)
-                            return PathResult{Path: path, Ok: ok}
+                            path, ok := reachable.PathTo(location)
+                            if !ok {
+                                return PathResult{}
+                            }
+                            // leave out the start point like FindPath
+                            return PathResult{Path: path[1:], Ok: true}
                         })
 
-                        maximumPopulation := functional.Memoize(func(location image.Point) int {
-                            return aiServices.ComputeMaximumPopulation(location.X, location.Y, stack.Plane())
+                        // the same scoring the city site advisor shows the player
+                        siteScore := functional.Memoize(func(location image.Point) int {
+                            return useMap.ScoreCitySite(location.X, location.Y).Score()
                         })
 
                         // filter out all locations we cannot reach
@@ -1898,12 +1988,25 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                             return pathTo(location).Ok == false
                         })
 
+                        // grow into the free land next to our own territory rather than into the land of other wizards
+                        territory := aiServices.GetTerritory(stack.Plane())
+                        frontier := make(map[image.Point]bool)
+                        for _, point := range territory.Frontier(self.GetBanner()) {
+                            frontier[point] = true
+                        }
+
                         score := func(location image.Point) int {
-                            total := maximumPopulation(location)
+                            total := siteScore(location)
 
                             // prioritize shore locations if we don't have a city on this continent adjacent to a shore
                             if !hasShoreCity && useMap.OnShore(location.X, location.Y) {
-                                total += 10
+                                total += 100
+                            }
+
+                            if owner, ok := territory.Owner(location.X, location.Y); ok && owner != self.GetBanner() {
+                                total -= settlerForeignTerritoryPenalty
+                            } else if frontier[location] {
+                                total += settlerFrontierBonus
                             }
 
                             return total
@@ -2283,29 +2386,6 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                 }
             }
         case GoalIncreasePower:
-            type DependencyFunc func(d data.Race, building buildinglib.Building) bool
-            type BuildingCheckFunc func(building buildinglib.Building) bool
-
-            checkDependency := func(kind BuildingCheckFunc) DependencyFunc { 
-
-                return func(race data.Race, building buildinglib.Building) bool {
-                    infos := aiServices.GetBuildingInfos()
-
-                    dependencies := set.NewSet[buildinglib.Building]()
-                    for _, building := range buildinglib.RacialBuildings(race).Values() {
-                        if kind(building) {
-                            dependencies.InsertMany(infos.Dependencies(building)...)
-                        }
-                    }
-
-                    return dependencies.Contains(building)
-                }
-            }
-
-            isReligiousDependency := functional.Memoize2(checkDependency(buildinglib.Building.IsReligious))
-            isEconomicDependency := functional.Memoize2(checkDependency(buildinglib.Building.IsEconomic))
-            isFoodDependency := functional.Memoize2(checkDependency(buildinglib.Building.ProducesFood))
-
             // feels awkward to build buildings in cities here
             for _, city := range self.Cities {
                 // don't clobber a settler that GoalBuildCities reserved this turn:
@@ -2343,42 +2423,8 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                             possibleBuildings := city.ComputePossibleBuildings(true)
                             values := possibleBuildings.Values()
 
-                            goldSurplus := city.GoldSurplus()
-
-                            needsFood := city.Citizens() < city.MaximumCitySize() / 2 || city.PopulationGrowthRate() < 30
-
-                            weights := make([]int, 0, possibleBuildings.Size())
-                            for _, building := range values {
-                                weight := 1
-
-                                if city.Rebels > 0 {
-                                    if building.IsReligious() {
-                                        weight += 2
-                                    } else if isReligiousDependency(city.Race, building) {
-                                        weight += 1
-                                    }
-                                }
-
-                                if goldSurplus < 0 {
-                                    if building.IsEconomic() {
-                                        weight += 2
-                                    } else if isEconomicDependency(city.Race, building) {
-                                        weight += 1
-                                    }
-                                }
-
-                                if needsFood {
-                                    if building.ProducesFood() {
-                                        weight += 2
-                                    } else if isFoodDependency(city.Race, building) {
-                                        weight += 1
-                                    }
-                                }
-
-                                // FIXME: also consider dependencies of buildings that produce religion/gold/food
-
-                                weights = append(weights, weight)
-                            }
+                            // the same weighting a balanced city governor uses
+                            weights := city.BuildingWeights(values, citylib.GovernorBalanced)
 
                             if possibleBuildings.Size() > 0 {
                                 // choose a random building to create
@@ -2404,6 +2450,7 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
 
             // produce another engineer if we are under budget and can afford it
             if currentEngineers < engineerBudget && aiData.GoldPerTurn() > 0 && self.Gold > 20 {
+                produced := false
                 for _, city := range self.Cities {
                     if isMakingSomething(city) || ai.reservedSettlerCities[city] {
                         continue
@@ -2414,9 +2461,28 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
                             Building: buildinglib.BuildingNone,
                             Unit: engineer,
                         })
+                        produced = true
                         break // only queue one engineer per turn
                     }
                 }
+
+                // every city is busy, so put the engineer after the current project of a city with nothing queued
+                if !produced {
+                    for _, city := range self.Cities {
+                        if len(city.Queue) > 0 || ai.reservedSettlerCities[city] {
+                            continue
+                        }
+                        if engineer, ok := findConstructionUnit(city.ComputePossibleUnits()); ok {
+                            decisions = append(decisions, &playerlib.AIProduceDecision{
+                                City: city,
+                                Building: buildinglib.BuildingNone,
+                                Unit: engineer,
+                                Enqueue: true,
+                            })
+                            break
+                        }
+                    }
+                }
             }
 
             // index our city tiles per plane for the road-connectivity test
@@ -2937,7 +3003,11 @@ func (ai *Enemy2AI) GoalDecisions(self *playerlib.Player, aiServices playerlib.A
     // attribute the decisions this goal produced (after its subgoals) for the debug overlay
     buildingInfo := aiServices.GetBuildingInfos()
     for _, decision := range decisions[ownStart:] {
-        info.Decisions = append(info.Decisions, describeDecision(decision, buildingInfo))
+        description := describeDecision(decision, buildingInfo)
+        info.Decisions = append(info.Decisions, description)
+        if ai.currentTrace != nil {
+            ai.currentTrace.Decisions = append(ai.currentTrace.Decisions, ai.makeTraceDecision(goal.Goal, decision, description))
+        }
     }
 
     return decisions, info
@@ -2949,6 +3019,7 @@ func (ai *Enemy2AI) Update(self *playerlib.Player, aiServices playerlib.AIServic
     // refresh our knowledge of nearby lairs / nodes / enemy cities before planning
     ai.updateLairCatalogue(self, aiServices)
     ai.updateScoutMemory(self, aiServices)
+    ai.updateThreatMaps(self, aiServices)
 
     goals := ai.ComputeGoals(self, aiServices)
 
@@ -2962,6 +3033,12 @@ func (ai *Enemy2AI) Update(self *playerlib.Player, aiServices playerlib.AIServic
         }),
     }
 
+    ai.currentTrace = &TurnTrace{
+        Turn: aiServices.GetTurnNumber(),
+        Wizard: self.Wizard.Name,
+        Banner: self.GetBanner(),
+    }
+
     seenGoals := set.MakeSet[GoalType]()
     ai.LastGoalDebug = nil
     // rebuild per-stack activity descriptions this turn (watch-mode inspector)
@@ -2984,8 +3061,14 @@ func (ai *Enemy2AI) Update(self *playerlib.Player, aiServices playerlib.AIServic
                     choice = spell
                 }
             }
-            decisions = append(decisions, &playerlib.AIResearchSpellDecision{
+            research := &playerlib.AIResearchSpellDecision{
                 Spell: choice,
+            }
+            decisions = append(decisions, research)
+            ai.currentTrace.Decisions = append(ai.currentTrace.Decisions, TraceDecision{
+                Goal: GoalResearchMagic,
+                Description: describeDecision(research, aiServices.GetBuildingInfos()),
+                Reason: "cheapest research candidate",
             })
         }
     }
@@ -3002,7 +3085,11 @@ func (ai *Enemy2AI) ConfirmEncounter(stack *playerlib.UnitStack, encounter *mapl
     // refusing leaves the stack one tile away, which reveals the lair's contents
     // (so a too-weak stack effectively scouts it for a future, stronger attack).
     strength := lairStrength(encounter)
-    return float32(stackCombatStrength(stack)) >= float32(strength) * requiredLairMargin(strength)
+    ok := float32(stackCombatStrength(stack)) >= float32(strength) * requiredLairMargin(strength)
+    if !ok {
+        ai.reject(GoalNone, stack.X(), stack.Y(), stack.Plane(), fmt.Sprintf("stack strength %v below %v x %v", stackCombatStrength(stack), strength, requiredLairMargin(strength)), "enter encounter")
+    }
+    return ok
 }
 
 func (ai *Enemy2AI) InvalidMove(stack *playerlib.UnitStack) {
@@ -3043,6 +3130,9 @@ func (ai *Enemy2AI) PostUpdate(self *playerlib.Player, aiServices playerlib.AISe
 
     // make sure food is balanced at the end
     self.RebalanceFood()
+
+    // the moves of the turn are done, so any encounter that was turned down is in the trace by now
+    ai.finishTrace(self)
 }
 
 func (ai *Enemy2AI) NewTurn(player *playerlib.Player) {
@@ -3052,8 +3142,7 @@ func (ai *Enemy2AI) NewTurn(player *playerlib.Player) {
     // make sure cities have enough farmers
     for _, city := range player.Cities {
         // try to maximize the number of workers
-        city.Workers = city.Citizens()
-        city.ResetCitizens()
+        city.GovernCitizens(citylib.GovernorProduction)
     }
 
     player.RebalanceFood()
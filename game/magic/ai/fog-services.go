@@ -0,0 +1,272 @@
+package ai
+
+import (
+    "image"
+    "slices"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* FogServices wraps the game's AIServices so that an AI only learns about enemy cities and stacks
+ * through its own ArcanusFog/MyrrorFog, the same way a human player does.
+ *  - enemy stacks are only returned while their tile is visible
+ *  - enemy cities are returned once seen, at the position and owner they were last seen with, until
+ *    the tile is seen again without them
+ *  - GetEnemies only returns wizards the player has seen a city or stack of
+ *  - GetTerritory is computed from the cities returned by AllCities
+ *
+ * The enemies returned by GetEnemies, FindCity and FindStack are not the real players but copies of
+ * them whose Stacks and Cities only hold what the player knows about, so the AI can't look through the
+ * fog by reading those fields. The copies are made again on every Refresh.
+ *
+ * What the player remembers is saved with the game, see Serialize.
+ */
+type FogServices struct {
+    playerlib.AIServices
+    Player *playerlib.Player
+
+    cities map[data.PlanePoint]LastSeenCity
+    contacts map[*playerlib.Player]bool
+    // the copy of each real enemy that is handed to the AI
+    views map[*playerlib.Player]*playerlib.Player
+}
+
+type LastSeenCity struct {
+    City *citylib.City
+    // the real player that owned the city when it was seen
+    Owner *playerlib.Player
+    Turn uint64
+}
+
+func MakeFogServices(player *playerlib.Player, services playerlib.AIServices) *FogServices {
+    return &FogServices{
+        AIServices: services,
+        Player: player,
+        cities: make(map[data.PlanePoint]LastSeenCity),
+        contacts: make(map[*playerlib.Player]bool),
+        views: make(map[*playerlib.Player]*playerlib.Player),
+    }
+}
+
+// update the memory of enemy cities from what the player can currently see. should be called before
+// the AI runs each turn
+func (services *FogServices) Refresh() {
+    self := services.Player
+    turn := services.AIServices.GetTurnNumber()
+
+    // use the full list of players here, this is the only place that is allowed to look through the fog
+    for _, enemy := range services.AIServices.GetEnemies(self) {
+        for _, stack := range enemy.Stacks {
+            if self.IsVisible(stack.X(), stack.Y(), stack.Plane()) {
+                services.contacts[enemy] = true
+            }
+        }
+
+        for _, city := range enemy.Cities {
+            if self.IsVisible(city.X, city.Y, city.Plane) {
+                services.cities[city.GetPlanePoint()] = LastSeenCity{City: city, Owner: enemy, Turn: turn}
+                services.contacts[enemy] = true
+            }
+        }
+    }
+
+    // forget cities whose tile we can see but that are no longer an enemy's
+    for point := range services.cities {
+        if !self.IsVisible(point.X, point.Y, point.Plane) {
+            continue
+        }
+
+        city, owner := services.AIServices.FindCity(point.X, point.Y, point.Plane)
+        if city == nil || owner == self {
+            delete(services.cities, point)
+        }
+    }
+
+    services.views = make(map[*playerlib.Player]*playerlib.Player)
+}
+
+// the copy of the enemy that only has the cities and stacks the player knows about
+func (services *FogServices) view(enemy *playerlib.Player) *playerlib.Player {
+    if enemy == nil || enemy == services.Player {
+        return enemy
+    }
+
+    if view, ok := services.views[enemy]; ok {
+        return view
+    }
+
+    view := *enemy
+    view.Stacks = nil
+    view.Cities = make(map[data.PlanePoint]*citylib.City)
+
+    for _, stack := range enemy.Stacks {
+        if services.Player.IsVisible(stack.X(), stack.Y(), stack.Plane()) {
+            view.Stacks = append(view.Stacks, stack)
+        }
+    }
+
+    for point, seen := range services.cities {
+        if seen.Owner == enemy {
+            view.Cities[point] = seen.City
+        }
+    }
+
+    services.views[enemy] = &view
+    services.contacts[enemy] = true
+    return &view
+}
+
+func (services *FogServices) AllCities() []*citylib.City {
+    out := services.Player.GetCities()
+    for _, seen := range services.cities {
+        out = append(out, seen.City)
+    }
+
+    return out
+}
+
+// territory as far as the player knows, from its own cities and the enemy cities it has seen
+func (services *FogServices) GetTerritory(plane data.Plane) *maplib.Territory {
+    return citylib.ComputeTerritory(services.AIServices.GetMap(plane), services.AllCities())
+}
+
+func (services *FogServices) FindCity(x int, y int, plane data.Plane) (*citylib.City, *playerlib.Player) {
+    if city := services.Player.FindCity(x, y, plane); city != nil {
+        return city, services.Player
+    }
+
+    if seen, ok := services.cities[data.PlanePoint{X: x, Y: y, Plane: plane}]; ok {
+        return seen.City, services.view(seen.Owner)
+    }
+
+    return nil, nil
+}
+
+func (services *FogServices) FindStack(x int, y int, plane data.Plane) (*playerlib.UnitStack, *playerlib.Player) {
+    if stack := services.Player.FindStack(x, y, plane); stack != nil {
+        return stack, services.Player
+    }
+
+    if !services.Player.IsVisible(x, y, plane) {
+        return nil, nil
+    }
+
+    stack, owner := services.AIServices.FindStack(x, y, plane)
+    return stack, services.view(owner)
+}
+
+func (services *FogServices) ComputeCityStackInfo() playerlib.CityStackInfo {
+    full := services.AIServices.ComputeCityStackInfo()
+
+    out := playerlib.CityStackInfo{
+        ArcanusStacks: make(map[image.Point]*playerlib.UnitStack),
+        MyrrorStacks: make(map[image.Point]*playerlib.UnitStack),
+        ArcanusCities: make(map[image.Point]*citylib.City),
+        MyrrorCities: make(map[image.Point]*citylib.City),
+    }
+
+    filterStacks := func(plane data.Plane, in map[image.Point]*playerlib.UnitStack, out map[image.Point]*playerlib.UnitStack) {
+        for point, stack := range in {
+            if services.Player.IsVisible(point.X, point.Y, plane) || services.Player.FindStack(point.X, point.Y, plane) == stack {
+                out[point] = stack
+            }
+        }
+    }
+
+    filterStacks(data.PlaneArcanus, full.ArcanusStacks, out.ArcanusStacks)
+    filterStacks(data.PlaneMyrror, full.MyrrorStacks, out.MyrrorStacks)
+
+    for _, city := range services.AllCities() {
+        switch city.Plane {
+            case data.PlaneArcanus: out.ArcanusCities[image.Pt(city.X, city.Y)] = city
+            case data.PlaneMyrror: out.MyrrorCities[image.Pt(city.X, city.Y)] = city
+        }
+    }
+
+    return out
+}
+
+// the known enemies of the player, or of one of those enemies
+func (services *FogServices) GetEnemies(player *playerlib.Player) []*playerlib.Player {
+    var out []*playerlib.Player
+    for _, enemy := range services.AIServices.GetEnemies(player) {
+        if enemy == services.Player {
+            out = append(out, enemy)
+        } else if services.contacts[enemy] {
+            out = append(out, services.view(enemy))
+        }
+    }
+
+    return out
+}
+
+type SerializedLastSeenCity struct {
+    X int `json:"x"`
+    Y int `json:"y"`
+    Plane data.Plane `json:"plane"`
+    Owner data.BannerType `json:"owner"`
+    Turn uint64 `json:"turn"`
+}
+
+type SerializedFogServices struct {
+    Cities []SerializedLastSeenCity `json:"cities,omitempty"`
+    Contacts []data.BannerType `json:"contacts,omitempty"`
+}
+
+func (services *FogServices) Serialize() SerializedFogServices {
+    var out SerializedFogServices
+
+    for point, seen := range services.cities {
+        out.Cities = append(out.Cities, SerializedLastSeenCity{
+            X: point.X,
+            Y: point.Y,
+            Plane: point.Plane,
+            Owner: seen.Owner.GetBanner(),
+            Turn: seen.Turn,
+        })
+    }
+
+    for enemy := range services.contacts {
+        out.Contacts = append(out.Contacts, enemy.GetBanner())
+    }
+
+    slices.Sort(out.Contacts)
+
+    return out
+}
+
+/* the cities are looked up by their position when the game is loaded. a remembered city that has been
+ * destroyed since it was last seen can't be restored, so it is forgotten
+ */
+func MakeFogServicesFromSerialized(player *playerlib.Player, services playerlib.AIServices, serialized SerializedFogServices) *FogServices {
+    out := MakeFogServices(player, services)
+
+    findPlayer := func(banner data.BannerType) *playerlib.Player {
+        for _, enemy := range services.GetEnemies(player) {
+            if enemy.GetBanner() == banner {
+                return enemy
+            }
+        }
+
+        return nil
+    }
+
+    for _, seen := range serialized.Cities {
+        city, _ := services.FindCity(seen.X, seen.Y, seen.Plane)
+        owner := findPlayer(seen.Owner)
+        if city != nil && owner != nil {
+            out.cities[city.GetPlanePoint()] = LastSeenCity{City: city, Owner: owner, Turn: seen.Turn}
+        }
+    }
+
+    for _, banner := range serialized.Contacts {
+        if enemy := findPlayer(banner); enemy != nil {
+            out.contacts[enemy] = true
+        }
+    }
+
+    return out
+}
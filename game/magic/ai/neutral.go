@@ -0,0 +1,248 @@
+package ai
+
+import (
+    "slices"
+    "cmp"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/units"
+)
+
+/* neutral towns, as in the original game:
+ *  - grow at half the normal rate
+ *  - slowly build the cheapest building available to them
+ *  - keep a garrison that grows with the size of the town and the turn number, and is rebuilt one unit per turn after an attack
+ *  - once in a while send the units above their garrison at a weak nearby city
+ * cities that rebel and join the neutral player, such as from the Rebellion random event, are handed to
+ * TakeOverCity and then run the same way.
+ */
+
+// an AI that takes charge of cities given to its player without a fight
+type CityTakeover interface {
+    TakeOverCity(*playerlib.Player, *citylib.City)
+}
+
+// raids only start after this turn
+const raidStartTurn = 30
+
+// how far raiders are willing to walk
+const raidRadius = 10
+
+// the raid accumulator must reach this before a raid is sent
+const raidThreshold = 30
+
+// the number of units a neutral town keeps at home
+func neutralGarrisonTarget(citizens int, turn uint64) int {
+    return min(5, 1 + citizens / 4 + int(turn / 100))
+}
+
+// the number of units sent out in one raid
+func raidSize(turn uint64, difficulty data.DifficultySetting) int {
+    return min(6, 1 + int(difficulty) / 2 + int(turn / 75))
+}
+
+// how fast the raid accumulator fills up
+func raidRate(difficulty data.DifficultySetting) int {
+    return int(difficulty) + 1
+}
+
+// the new population of a neutral town that grows at half the normal rate, never going above the maximum size
+func neutralPopulation(population int, maximumCitizens int, growthRate int) int {
+    if growthRate > 0 {
+        population += growthRate / 2
+    }
+
+    return min(population, maximumCitizens * 1000)
+}
+
+// the building with the lowest production cost, ties are broken by building id so the choice is stable
+func cheapestBuilding(possible []buildinglib.Building, cost func(buildinglib.Building) int) (buildinglib.Building, bool) {
+    if len(possible) == 0 {
+        return buildinglib.BuildingNone, false
+    }
+
+    return slices.MinFunc(possible, func(a, b buildinglib.Building) int {
+        if compare := cmp.Compare(cost(a), cost(b)); compare != 0 {
+            return compare
+        }
+        return cmp.Compare(a, b)
+    }), true
+}
+
+// a city that raiders could attack
+type raidTarget struct {
+    City *citylib.City
+    Distance int
+    // combat strength of the city's garrison
+    Defense int
+}
+
+// the closest target within the raid radius that the raiders are stronger than
+func chooseRaidTarget(targets []raidTarget, strength int) (raidTarget, bool) {
+    var best raidTarget
+    found := false
+    for _, target := range targets {
+        if target.Distance > raidRadius || target.Defense >= strength {
+            continue
+        }
+
+        if !found || target.Distance < best.Distance {
+            best = target
+            found = true
+        }
+    }
+
+    return best, found
+}
+
+// grow the town and advance its building. called once per turn
+func (raider *RaiderAI) updateNeutralCity(self *playerlib.Player, city *citylib.City) {
+    if city.Outpost {
+        return
+    }
+
+    city.Population = neutralPopulation(city.Population, city.MaximumCitySize(), city.PopulationGrowthRate())
+
+    // neutral towns never build units, their garrison is created directly
+    city.ProducingUnit = units.UnitNone
+
+    cost := func(building buildinglib.Building) int {
+        return city.BuildingInfo.ProductionCost(building)
+    }
+
+    possible := city.ComputePossibleBuildings(true)
+    if !possible.Contains(city.ProducingBuilding) {
+        choice, ok := cheapestBuilding(possible.Values(), cost)
+        if !ok {
+            city.ProducingBuilding = buildinglib.BuildingTradeGoods
+            return
+        }
+        city.ProducingBuilding = choice
+        city.Production = 0
+    }
+
+    city.Production += city.WorkProductionRate()
+    if city.Production >= float32(cost(city.ProducingBuilding)) {
+        city.AddBuilding(city.ProducingBuilding)
+        city.Production = 0
+        city.ProducingBuilding = buildinglib.BuildingNone
+    }
+
+    raider.guardCity(self, city)
+}
+
+// units left in the town, such as raiders that came home, guard it
+func (raider *RaiderAI) guardCity(self *playerlib.Player, city *citylib.City) {
+    stack := self.FindStack(city.X, city.Y, city.Plane)
+    if stack != nil && len(stack.CurrentPath) == 0 {
+        for _, unit := range stack.Units() {
+            if unit.GetBusy() == units.BusyStatusNone {
+                unit.SetBusy(units.BusyStatusPatrol)
+            }
+        }
+    }
+}
+
+/* called when a city joins the neutral player. whatever the old owner had queued up is dropped so the town
+ * picks its own building on the next turn, and the garrison that came with the city stays to guard it
+ */
+func (raider *RaiderAI) TakeOverCity(self *playerlib.Player, city *citylib.City) {
+    city.Queue = nil
+    city.ProducingUnit = units.UnitNone
+    city.ProducingBuilding = buildinglib.BuildingNone
+    city.Production = 0
+
+    // whatever the old owner had the units doing, such as building a road, is dropped
+    if stack := self.FindStack(city.X, city.Y, city.Plane); stack != nil {
+        stack.CurrentPath = nil
+        stack.Orders = nil
+        for _, unit := range stack.Units() {
+            unit.SetBusy(units.BusyStatusPatrol)
+        }
+    }
+}
+
+// send the units above a town's garrison at a weak city nearby
+func (raider *RaiderAI) SendRaids(self *playerlib.Player, enemies []*playerlib.Player, aiServices playerlib.AIServices) []playerlib.AIDecision {
+    turn := aiServices.GetTurnNumber()
+    difficulty := aiServices.GetDifficulty()
+
+    if turn < raidStartTurn {
+        return nil
+    }
+
+    raider.RaidAccumulator += raidRate(difficulty)
+    if raider.RaidAccumulator < raidThreshold {
+        return nil
+    }
+
+    for _, city := range self.GetCities() {
+        stack := self.FindStack(city.X, city.Y, city.Plane)
+        if stack == nil {
+            continue
+        }
+
+        spare := stack.Size() - neutralGarrisonTarget(city.Citizens(), turn)
+        if spare <= 0 {
+            continue
+        }
+
+        raiders := stack.Units()[:min(spare, raidSize(turn, difficulty))]
+        strength := unitsCombatStrength(raiders)
+
+        useMap := aiServices.GetMap(city.Plane)
+
+        var targets []raidTarget
+        for _, enemy := range enemies {
+            for _, enemyCity := range enemy.Cities {
+                if enemyCity.Plane != city.Plane {
+                    continue
+                }
+
+                defense := 0
+                if garrison := enemy.FindStack(enemyCity.X, enemyCity.Y, enemyCity.Plane); garrison != nil {
+                    defense = stackCombatStrength(garrison)
+                }
+
+                targets = append(targets, raidTarget{
+                    City: enemyCity,
+                    Distance: useMap.TileDistance(city.X, city.Y, enemyCity.X, enemyCity.Y),
+                    Defense: defense,
+                })
+            }
+        }
+
+        target, ok := chooseRaidTarget(targets, strength)
+        if !ok {
+            continue
+        }
+
+        path, ok := aiServices.FindPath(city.X, city.Y, target.City.X, target.City.Y, self, stack, self.GetFog(city.Plane))
+        if !ok || len(path) == 0 {
+            continue
+        }
+
+        raider.RaidAccumulator = 0
+
+        return []playerlib.AIDecision{&playerlib.AIMoveStackDecision{
+            Stack: stack,
+            Units: raiders,
+            Path: path,
+        }}
+    }
+
+    return nil
+}
+
+// true if the town has fewer units than it should, such as after being attacked
+func needsGarrison(city *citylib.City, stack *playerlib.UnitStack, turn uint64) bool {
+    size := 0
+    if stack != nil {
+        size = stack.Size()
+    }
+
+    return size < neutralGarrisonTarget(city.Citizens(), turn)
+}
@@ -20,6 +20,8 @@ import (
 
 type RaiderAI struct {
     MonsterAccumulator int
+    // grows every turn, a raid from a neutral town is sent once it is high enough
+    RaidAccumulator int
     // MovedStacks map[*playerlib.UnitStack]bool
 }
 
@@ -32,6 +34,10 @@ func MakeRaiderAI() *RaiderAI {
 
 func (raider *RaiderAI) NewTurn(player *playerlib.Player) {
     // raider.MovedStacks = make(map[*playerlib.UnitStack]bool)
+
+    for _, city := range player.Cities {
+        raider.updateNeutralCity(player, city)
+    }
 }
 
 // return a random number between low and high inclusive
@@ -445,14 +451,16 @@ func (raider *RaiderAI) CreateUnits(player *playerlib.Player, aiServices playerl
         decisions = append(decisions, raider.CreateRampagingMonsters(player, aiServices)...)
     }
 
+    turn := aiServices.GetTurnNumber()
     for _, city := range player.Cities {
         stack := player.FindStack(city.X, city.Y, city.Plane)
 
         makeUnit := false
-        // always make a unit if there is no stack in the city
-        if stack == nil || stack.IsEmpty() {
+        // rebuild the garrison one unit per turn, such as after the town was attacked
+        if needsGarrison(city, stack, turn) {
             makeUnit = true
-        } else if rand.N(15) == 0 && (stack != nil && stack.Size() < 6) {
+        } else if rand.N(15) == 0 && stack.Size() < neutralGarrisonTarget(city.Citizens(), turn) + raidSize(turn, aiServices.GetDifficulty()) {
+            // extra units above the garrison are sent out as raiders
             makeUnit = true
         }
 
@@ -493,6 +501,7 @@ func (raider *RaiderAI) Update(player *playerlib.Player, aiServices playerlib.AI
     enemies := aiServices.GetEnemies(player)
 
     decisions = append(decisions, raider.MoveStacks(player, enemies, aiServices)...)
+    decisions = append(decisions, raider.SendRaids(player, enemies, aiServices)...)
     decisions = append(decisions, raider.CreateUnits(player, aiServices)...)
     decisions = append(decisions, raider.UpdateCities(player)...)
 
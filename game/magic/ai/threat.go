@@ -0,0 +1,149 @@
+package ai
+
+import (
+    "math"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* an influence map for one plane. Every known enemy force spreads its combat strength over the
+ * tiles around it, falling off with distance, and our own stacks do the same on the friendly layer.
+ * The map is rebuilt every turn from what the wizard can see (plus remembered enemy cities), so it
+ * never uses information hidden by the fog.
+ */
+type ThreatMap struct {
+    Plane data.Plane
+    Width int
+    Height int
+    Enemy []float64
+    Friendly []float64
+}
+
+// how many tiles away a force still contributes to the threat map
+const threatRadius = 6
+
+func MakeThreatMap(plane data.Plane, width int, height int) *ThreatMap {
+    return &ThreatMap{
+        Plane: plane,
+        Width: width,
+        Height: height,
+        Enemy: make([]float64, width * height),
+        Friendly: make([]float64, width * height),
+    }
+}
+
+func (threat *ThreatMap) index(x int, y int) (int, bool) {
+    if y < 0 || y >= threat.Height || threat.Width <= 0 {
+        return 0, false
+    }
+
+    // the map wraps horizontally
+    x = ((x % threat.Width) + threat.Width) % threat.Width
+    return y * threat.Width + x, true
+}
+
+// spread strength around (x, y). the full value is applied on the tile itself and halves by
+// the time it is two tiles away
+func (threat *ThreatMap) spread(values []float64, x int, y int, strength float64) {
+    if strength <= 0 {
+        return
+    }
+
+    for dy := -threatRadius; dy <= threatRadius; dy++ {
+        for dx := -threatRadius; dx <= threatRadius; dx++ {
+            distance := math.Sqrt(float64(dx * dx + dy * dy))
+            if distance > threatRadius {
+                continue
+            }
+
+            index, ok := threat.index(x + dx, y + dy)
+            if ok {
+                values[index] += strength * 2 / (2 + distance)
+            }
+        }
+    }
+}
+
+func (threat *ThreatMap) AddEnemy(x int, y int, strength int) {
+    threat.spread(threat.Enemy, x, y, float64(strength))
+}
+
+func (threat *ThreatMap) AddFriendly(x int, y int, strength int) {
+    threat.spread(threat.Friendly, x, y, float64(strength))
+}
+
+func (threat *ThreatMap) EnemyAt(x int, y int) float64 {
+    index, ok := threat.index(x, y)
+    if !ok {
+        return 0
+    }
+    return threat.Enemy[index]
+}
+
+func (threat *ThreatMap) FriendlyAt(x int, y int) float64 {
+    index, ok := threat.index(x, y)
+    if !ok {
+        return 0
+    }
+    return threat.Friendly[index]
+}
+
+// positive if the enemy has more strength around this tile than we do
+func (threat *ThreatMap) DangerAt(x int, y int) float64 {
+    return threat.EnemyAt(x, y) - threat.FriendlyAt(x, y)
+}
+
+// the threat map for the given plane, or nil if it hasn't been built yet
+func (ai *Enemy2AI) ThreatFor(plane data.Plane) *ThreatMap {
+    if ai.Threat == nil {
+        return nil
+    }
+    return ai.Threat[plane]
+}
+
+// true if enemy forces around the city outweigh ours, in which case the city keeps its whole garrison
+func (ai *Enemy2AI) cityThreatened(x int, y int, plane data.Plane) bool {
+    threat := ai.ThreatFor(plane)
+    if threat == nil {
+        return false
+    }
+    return threat.DangerAt(x, y) > 0
+}
+
+// rebuild the threat maps from the visible enemy stacks, the remembered enemy cities and our own stacks
+func (ai *Enemy2AI) updateThreatMaps(self *playerlib.Player, aiServices playerlib.AIServices) {
+    ai.Threat = make(map[data.Plane]*ThreatMap)
+
+    for _, plane := range []data.Plane{data.PlaneArcanus, data.PlaneMyrror} {
+        useMap := aiServices.GetMap(plane)
+        if useMap == nil {
+            continue
+        }
+
+        threat := MakeThreatMap(plane, useMap.Width(), useMap.Height())
+
+        for _, enemy := range aiServices.GetEnemies(self) {
+            for _, stack := range enemy.Stacks {
+                if stack.Plane() == plane && self.IsVisible(stack.X(), stack.Y(), plane) {
+                    threat.AddEnemy(stack.X(), stack.Y(), stackCombatStrength(stack))
+                }
+            }
+        }
+
+        // visible garrisons were counted as stacks above, so only the remembered ones are added here
+        for _, known := range ai.KnownEnemyCities {
+            if known.Plane == plane && !self.IsVisible(known.X, known.Y, plane) {
+                threat.AddEnemy(known.X, known.Y, known.Strength)
+            }
+        }
+
+        for _, stack := range self.Stacks {
+            if stack.Plane() == plane {
+                threat.AddFriendly(stack.X(), stack.Y(), stackCombatStrength(stack))
+            }
+        }
+
+        ai.Threat[plane] = threat
+    }
+}
@@ -0,0 +1,208 @@
+package ai
+
+import (
+    "fmt"
+    "slices"
+    "cmp"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* a structured record of everything the Enemy2AI considered during one turn. These are
+ * collected by the game (see game/ai-trace.go), written to a file as json lines, and shown
+ * in the watch-mode AI debugger. Nothing here is used for gameplay.
+ */
+
+// one decision the AI made, attributed to the goal that produced it
+type TraceDecision struct {
+    Goal GoalType
+    Description string
+    // why the decision was made, if known. for stack moves this is the stack's activity
+    Reason string
+    // the tile the decision is about, so the debugger can jump to it
+    X int
+    Y int
+    Plane data.Plane
+    HasLocation bool
+}
+
+// an alternative the AI looked at but decided against
+type TraceRejected struct {
+    Goal GoalType
+    Description string
+    Reason string
+    X int
+    Y int
+    Plane data.Plane
+}
+
+type TraceStack struct {
+    X int
+    Y int
+    Plane data.Plane
+    Units int
+    Strength int
+    Activity string
+}
+
+type TraceCity struct {
+    Name string
+    X int
+    Y int
+    Plane data.Plane
+    Producing string
+}
+
+type TurnTrace struct {
+    Turn uint64
+    Wizard string
+    Banner data.BannerType
+    Goals []GoalDebugInfo
+    Decisions []TraceDecision
+    Rejected []TraceRejected
+    Stacks []TraceStack
+    Cities []TraceCity
+    // the multi-stack campaign in progress at the end of the turn, if any
+    Campaign string
+}
+
+// implemented by AIs that can produce a trace of their last turn
+type TracingAI interface {
+    LastTurnTrace() *TurnTrace
+}
+
+func (goal GoalType) MarshalText() ([]byte, error) {
+    return []byte(goal.String()), nil
+}
+
+func (goal *GoalType) UnmarshalText(text []byte) error {
+    for check := GoalNone; check <= GoalPlanarTravel; check++ {
+        if check.String() == string(text) {
+            *goal = check
+            return nil
+        }
+    }
+
+    return fmt.Errorf("unknown goal %v", string(text))
+}
+
+// the location a decision refers to, if it has one
+func decisionLocation(decision playerlib.AIDecision) (data.PlanePoint, bool) {
+    switch d := decision.(type) {
+        case *playerlib.AIProduceDecision:
+            return d.City.GetPlanePoint(), true
+        case *playerlib.AIUpdateCityDecision:
+            return d.City.GetPlanePoint(), true
+        case *playerlib.AIMoveStackDecision:
+            return data.PlanePoint{X: d.Stack.X(), Y: d.Stack.Y(), Plane: d.Stack.Plane()}, true
+        case *playerlib.AIBuildOutpostDecision:
+            return data.PlanePoint{X: d.Stack.X(), Y: d.Stack.Y(), Plane: d.Stack.Plane()}, true
+        case *playerlib.AIBuildRoadDecision:
+            return data.PlanePoint{X: d.Stack.X(), Y: d.Stack.Y(), Plane: d.Stack.Plane()}, true
+        case *playerlib.AIMeldNodeDecision:
+            return data.PlanePoint{X: d.Stack.X(), Y: d.Stack.Y(), Plane: d.Stack.Plane()}, true
+        case *playerlib.AIPlaneShiftDecision:
+            return data.PlanePoint{X: d.Stack.X(), Y: d.Stack.Y(), Plane: d.Stack.Plane()}, true
+        case *playerlib.AICreateUnitDecision:
+            return data.PlanePoint{X: d.X, Y: d.Y, Plane: d.Plane}, true
+    }
+
+    return data.PlanePoint{}, false
+}
+
+// the stack a decision is about, if any
+func decisionStack(decision playerlib.AIDecision) *playerlib.UnitStack {
+    switch d := decision.(type) {
+        case *playerlib.AIMoveStackDecision: return d.Stack
+        case *playerlib.AIBuildOutpostDecision: return d.Stack
+        case *playerlib.AIBuildRoadDecision: return d.Stack
+        case *playerlib.AIMeldNodeDecision: return d.Stack
+        case *playerlib.AIPlaneShiftDecision: return d.Stack
+    }
+
+    return nil
+}
+
+func (ai *Enemy2AI) makeTraceDecision(goal GoalType, decision playerlib.AIDecision, description string) TraceDecision {
+    trace := TraceDecision{
+        Goal: goal,
+        Description: description,
+    }
+
+    if stack := decisionStack(decision); stack != nil {
+        trace.Reason = ai.StackActivityFor(stack)
+    }
+
+    if location, ok := decisionLocation(decision); ok {
+        trace.X = location.X
+        trace.Y = location.Y
+        trace.Plane = location.Plane
+        trace.HasLocation = true
+    }
+
+    return trace
+}
+
+// record an alternative that was considered but not taken. only kept while a trace is being built
+func (ai *Enemy2AI) reject(goal GoalType, x int, y int, plane data.Plane, reason string, description string) {
+    if ai.currentTrace == nil {
+        return
+    }
+
+    ai.currentTrace.Rejected = append(ai.currentTrace.Rejected, TraceRejected{
+        Goal: goal,
+        Description: description,
+        Reason: reason,
+        X: x,
+        Y: y,
+        Plane: plane,
+    })
+}
+
+// fill in the stack activity map and city state once the turn is over, see PostUpdate
+func (ai *Enemy2AI) finishTrace(self *playerlib.Player) {
+    if ai.currentTrace == nil {
+        return
+    }
+
+    for _, stack := range self.Stacks {
+        ai.currentTrace.Stacks = append(ai.currentTrace.Stacks, TraceStack{
+            X: stack.X(),
+            Y: stack.Y(),
+            Plane: stack.Plane(),
+            Units: len(stack.Units()),
+            Strength: stackCombatStrength(stack),
+            Activity: ai.StackActivityFor(stack),
+        })
+    }
+
+    for _, city := range self.Cities {
+        ai.currentTrace.Cities = append(ai.currentTrace.Cities, TraceCity{
+            Name: city.Name,
+            X: city.X,
+            Y: city.Y,
+            Plane: city.Plane,
+            Producing: city.ProducingString(),
+        })
+    }
+
+    // map iteration order is random, so sort to keep traces stable between runs
+    slices.SortFunc(ai.currentTrace.Cities, func(a, b TraceCity) int {
+        return cmp.Compare(a.Name, b.Name)
+    })
+
+    ai.currentTrace.Goals = ai.LastGoalDebug
+
+    if ai.Campaign != nil {
+        ai.currentTrace.Campaign = ai.Campaign.String()
+    }
+
+    // anything rejected after this, such as an encounter on a later turn, is not added to the finished trace
+    ai.lastTrace = ai.currentTrace
+    ai.currentTrace = nil
+}
+
+func (ai *Enemy2AI) LastTurnTrace() *TurnTrace {
+    return ai.lastTrace
+}
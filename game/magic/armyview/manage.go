@@ -0,0 +1,531 @@
+package armyview
+
+import (
+    "fmt"
+    "log"
+    "cmp"
+    "slices"
+    "strings"
+    "image"
+    "image/color"
+
+    "github.com/kazzmir/master-of-magic/lib/font"
+    "github.com/kazzmir/master-of-magic/game/magic/util"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/* the manage layout lists every stack in small print, one stack per row. the stacks can be sorted and
+ * filtered, and several stacks can be selected to disband them or to send them to a city at once. the
+ * print is half the usual size, so the layout is only offered when the screen is scaled up enough to
+ * read it.
+ */
+
+type StackSort int
+
+const (
+    StackSortLocation StackSort = iota
+    StackSortSize
+    StackSortStrength
+    StackSortUpkeep
+)
+
+type StackFilter int
+
+const (
+    StackFilterAll StackFilter = iota
+    StackFilterIdle
+    StackFilterFortified
+)
+
+func (filter StackFilter) String() string {
+    switch filter {
+        case StackFilterAll: return "All"
+        case StackFilterIdle: return "Idle"
+        case StackFilterFortified: return "Fortified"
+    }
+
+    return ""
+}
+
+func (filter StackFilter) Next() StackFilter {
+    switch filter {
+        case StackFilterAll: return StackFilterIdle
+        case StackFilterIdle: return StackFilterFortified
+    }
+
+    return StackFilterAll
+}
+
+// gold, food and mana spent on the units of the stack each turn, added together
+func stackUpkeep(stack *playerlib.UnitStack) int {
+    total := 0
+    for _, unit := range stack.Units() {
+        total += unit.GetUpkeepGold() + unit.GetUpkeepFood() + unit.GetUpkeepMana()
+    }
+
+    return total
+}
+
+func stackStatus(stack *playerlib.UnitStack) string {
+    switch {
+        case stack.IsFortified(): return "Fortified"
+        case stack.Orders != nil || len(stack.CurrentPath) > 0: return "Moving"
+        case stack.IsIdle(): return "Idle"
+    }
+
+    return "Done"
+}
+
+// the name of the city the stack is in, or its map position
+func (view *ArmyScreen) stackLocation(stack *playerlib.UnitStack) string {
+    city := view.Player.FindCity(stack.X(), stack.Y(), stack.Plane())
+    if city != nil {
+        return city.Name
+    }
+
+    return fmt.Sprintf("%v,%v", stack.X(), stack.Y())
+}
+
+func (view *ArmyScreen) stackStrength(stack *playerlib.UnitStack) int {
+    if view.StackStrength == nil {
+        return 0
+    }
+
+    return view.StackStrength(stack)
+}
+
+// the stacks that pass the filters, in the chosen order. ties are broken by location
+func (view *ArmyScreen) manageStacks() []*playerlib.UnitStack {
+    var out []*playerlib.UnitStack
+    for _, stack := range view.Player.Stacks {
+        if stack.IsEmpty() {
+            continue
+        }
+
+        if stack.Plane() == data.PlaneArcanus && !view.ShowArcanus || stack.Plane() == data.PlaneMyrror && !view.ShowMyrror {
+            continue
+        }
+
+        if view.FilterStatus == StackFilterIdle && !stack.IsIdle() || view.FilterStatus == StackFilterFortified && !stack.IsFortified() {
+            continue
+        }
+
+        out = append(out, stack)
+    }
+
+    compareLocation := func(a *playerlib.UnitStack, b *playerlib.UnitStack) int {
+        return cmp.Or(strings.Compare(view.stackLocation(a), view.stackLocation(b)), cmp.Compare(a.Plane(), b.Plane()))
+    }
+
+    slices.SortStableFunc(out, func(a *playerlib.UnitStack, b *playerlib.UnitStack) int {
+        var result int
+        switch view.Sort {
+            case StackSortSize: result = cmp.Compare(len(a.Units()), len(b.Units()))
+            case StackSortStrength: result = cmp.Compare(view.stackStrength(a), view.stackStrength(b))
+            case StackSortUpkeep: result = cmp.Compare(stackUpkeep(a), stackUpkeep(b))
+            default: result = compareLocation(a, b)
+        }
+
+        if view.SortDescending {
+            result = -result
+        }
+
+        if result == 0 {
+            return compareLocation(a, b)
+        }
+
+        return result
+    })
+
+    return out
+}
+
+func (view *ArmyScreen) selectedStacks(stacks []*playerlib.UnitStack) []*playerlib.UnitStack {
+    var out []*playerlib.UnitStack
+    for _, stack := range stacks {
+        if view.Selected[stack] {
+            out = append(out, stack)
+        }
+    }
+
+    return out
+}
+
+// ask before disbanding every unit in the selected stacks
+func (view *ArmyScreen) disbandSelected(ui *uilib.UI, stacks []*playerlib.UnitStack) {
+    selected := view.selectedStacks(stacks)
+    if len(selected) == 0 {
+        view.Message = "Select stacks first"
+        return
+    }
+
+    count := 0
+    for _, stack := range selected {
+        count += len(stack.Units())
+    }
+
+    yes := func(){
+        for _, stack := range selected {
+            // removing a unit changes the stack, so iterate over a copy
+            for _, unit := range slices.Clone(stack.Units()) {
+                view.Player.RemoveUnit(unit)
+            }
+            delete(view.Selected, stack)
+        }
+
+        view.Message = fmt.Sprintf("Disbanded %v units", count)
+        view.UI = view.MakeUI()
+    }
+
+    no := func(){
+    }
+
+    ui.AddElements(uilib.MakeConfirmDialog(ui, view.Cache, &view.ImageCache, fmt.Sprintf("Do you wish to disband %v units in %v stacks?", count, len(selected)), true, yes, no))
+}
+
+// choose a city on the plane of the selected stacks and send them all there
+func (view *ArmyScreen) sendSelectedToCity(ui *uilib.UI, stacks []*playerlib.UnitStack) {
+    selected := view.selectedStacks(stacks)
+    if len(selected) == 0 {
+        view.Message = "Select stacks first"
+        return
+    }
+
+    plane := selected[0].Plane()
+    selected = slices.DeleteFunc(selected, func(stack *playerlib.UnitStack) bool {
+        return stack.Plane() != plane
+    })
+
+    var cities []*citylib.City
+    for _, city := range view.Player.Cities {
+        if city.Plane == plane {
+            cities = append(cities, city)
+        }
+    }
+
+    if len(cities) == 0 {
+        view.Message = fmt.Sprintf("No cities on %v", plane)
+        return
+    }
+
+    // show the cities closest to the first stack
+    first := selected[0]
+    slices.SortFunc(cities, func(a *citylib.City, b *citylib.City) int {
+        return cmp.Compare(view.CityDistance(first, a), view.CityDistance(first, b))
+    })
+    cities = cities[:min(len(cities), maxCityChoices)]
+
+    var choices []uilib.Selection
+    for _, city := range cities {
+        choices = append(choices, uilib.Selection{
+            Name: city.Name,
+            Action: func(){
+                for _, stack := range selected {
+                    view.SendToCity(stack, city)
+                }
+
+                view.Message = fmt.Sprintf("Sent %v stacks to %v", len(selected), city.Name)
+                view.UI = view.MakeUI()
+            },
+        })
+    }
+
+    ui.AddElements(uilib.MakeSelectionUI(ui, view.Cache, &view.ImageCache, 100, 20, "Send To City", choices, true))
+}
+
+// the most cities to choose from when sending stacks to a city
+const maxCityChoices = 10
+
+func (view *ArmyScreen) makeManageUI() *uilib.UI {
+    loader, err := fontslib.Loader(view.Cache)
+    if err != nil {
+        log.Printf("Error: army list: unable to load font: %v", err)
+        return nil
+    }
+
+    titleFont := loader(fontslib.BigOrangeGradient2)
+    normalFont := loader(fontslib.SmallWhite)
+    yellowFont := loader(fontslib.LightFontSmall)
+
+    if view.Selected == nil {
+        view.Selected = make(map[*playerlib.UnitStack]bool)
+    }
+
+    stacks := view.manageStacks()
+
+    panelColor := util.PremultiplyAlpha(color.RGBA{A: 120})
+    highlightColor := util.PremultiplyAlpha(color.RGBA{R: 255, G: 255, B: 255, A: 60})
+    selectColor := util.PremultiplyAlpha(color.RGBA{R: 0xf9, G: 0xdb, B: 0x4c, A: 0xff})
+
+    // print at half size, see scale.HalfSizeReadable
+    printSmall := func(useFont *font.Font, screen *ebiten.Image, x float64, y float64, justify font.FontJustify, text string) {
+        useFont.PrintOptions(screen, x * 2, y * 2, font.FontOptions{DropShadow: true, Justify: justify, Scale: scale.ScaleAmount / 2}, text)
+    }
+
+    listRect := image.Rect(4, 20, 316, 172)
+    rowHeight := 5
+    firstRowY := listRect.Min.Y + 8
+    visibleRows := (listRect.Max.Y - firstRowY) / rowHeight
+
+    view.FirstRow = max(0, min(view.FirstRow, len(stacks) - visibleRows))
+
+    var highlightedStack *playerlib.UnitStack
+
+    ui := &uilib.UI{
+        Draw: func(ui *uilib.UI, screen *ebiten.Image) {
+            background, _ := view.ImageCache.GetImage("reload.lbx", 0, 0)
+            var options ebiten.DrawImageOptions
+            scale.DrawScaled(screen, background, &options)
+
+            titleFont.PrintOptions(screen, 160, 5, font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount}, fmt.Sprintf("The Armies Of %v", view.Player.Wizard.Name))
+
+            vector.FillRect(screen, scale.Scale(float32(listRect.Min.X)), scale.Scale(float32(listRect.Min.Y)), scale.Scale(float32(listRect.Dx())), scale.Scale(float32(listRect.Dy())), panelColor, false)
+
+            ui.StandardDraw(screen)
+
+            status := fmt.Sprintf("%v of %v stacks", len(stacks), len(view.Player.Stacks))
+            if view.Message != "" {
+                status = view.Message
+            }
+            printSmall(normalFont, screen, 6, 174, font.FontJustifyLeft, status)
+            printSmall(normalFont, screen, 314, 174, font.FontJustifyRight, fmt.Sprintf("Upkeep %vGP %vMP %v Food", view.Player.TotalUnitUpkeepGold(), view.Player.TotalUnitUpkeepMana(), view.Player.TotalUnitUpkeepFood()))
+
+            if highlightedStack != nil && !highlightedStack.IsEmpty() {
+                var names []string
+                for _, unit := range highlightedStack.Units() {
+                    names = append(names, unit.GetName())
+                }
+                printSmall(yellowFont, screen, 6, 178, font.FontJustifyLeft, strings.Join(names, ", "))
+            }
+        },
+    }
+
+    var elements []*uilib.UIElement
+
+    type column struct {
+        Name string
+        X int
+        Justify font.FontJustify
+        Sort StackSort
+        Value func(*playerlib.UnitStack) string
+    }
+
+    noSort := StackSort(-1)
+
+    columns := []column{
+        {Name: "Location", X: 12, Sort: StackSortLocation, Value: view.stackLocation},
+        {Name: "Plane", X: 70, Sort: noSort, Value: func(stack *playerlib.UnitStack) string { return stack.Plane().String() }},
+        {Name: "Units", X: 120, Justify: font.FontJustifyRight, Sort: StackSortSize, Value: func(stack *playerlib.UnitStack) string { return fmt.Sprintf("%v", len(stack.Units())) }},
+        {Name: "Strength", X: 150, Justify: font.FontJustifyRight, Sort: StackSortStrength, Value: func(stack *playerlib.UnitStack) string { return fmt.Sprintf("%v", view.stackStrength(stack)) }},
+        {Name: "Upkeep", X: 176, Justify: font.FontJustifyRight, Sort: StackSortUpkeep, Value: func(stack *playerlib.UnitStack) string { return fmt.Sprintf("%v", stackUpkeep(stack)) }},
+        {Name: "Status", X: 184, Sort: noSort, Value: stackStatus},
+        {Name: "Leader", X: 222, Sort: noSort, Value: func(stack *playerlib.UnitStack) string { return stack.Units()[0].GetName() }},
+    }
+
+    // clicking a header sorts by that column, clicking it again reverses the order
+    for _, column := range columns {
+        width := int(normalFont.MeasureTextWidth(column.Name, 0.5)) + 1
+        rect := image.Rect(column.X, listRect.Min.Y + 1, column.X + width, listRect.Min.Y + 6)
+        if column.Justify == font.FontJustifyRight {
+            rect = rect.Sub(image.Pt(width, 0))
+        }
+
+        elements = append(elements, &uilib.UIElement{
+            Rect: rect,
+            PlaySoundLeftClick: column.Sort != noSort,
+            LeftClick: func(element *uilib.UIElement){
+                if column.Sort == noSort {
+                    return
+                }
+
+                if view.Sort == column.Sort {
+                    view.SortDescending = !view.SortDescending
+                } else {
+                    view.Sort = column.Sort
+                    // bigger numbers are usually more interesting
+                    view.SortDescending = column.Sort != StackSortLocation
+                }
+                view.UI = view.MakeUI()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                useFont := normalFont
+                if column.Sort == view.Sort {
+                    useFont = yellowFont
+                }
+                printSmall(useFont, screen, float64(column.X), float64(listRect.Min.Y + 2), column.Justify, column.Name)
+            },
+        })
+    }
+
+    rows := stacks[view.FirstRow:min(len(stacks), view.FirstRow + visibleRows)]
+    for i, stack := range rows {
+        rowY := firstRowY + i * rowHeight
+
+        // the box on the left selects the stack for bulk actions
+        elements = append(elements, &uilib.UIElement{
+            Rect: image.Rect(listRect.Min.X + 1, rowY, listRect.Min.X + 7, rowY + rowHeight),
+            LeftClick: func(element *uilib.UIElement){
+                view.Selected[stack] = !view.Selected[stack]
+                view.Message = ""
+            },
+            Inside: func(element *uilib.UIElement, x int, y int){
+                highlightedStack = stack
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                x := float32(listRect.Min.X + 2)
+                y := float32(rowY + 1)
+                vector.StrokeRect(screen, scale.Scale(x), scale.Scale(y), scale.Scale(float32(3)), scale.Scale(float32(3)), float32(scale.ScaleAmount / 2), selectColor, false)
+                if view.Selected[stack] {
+                    vector.FillRect(screen, scale.Scale(x + 1), scale.Scale(y + 1), scale.Scale(float32(1)), scale.Scale(float32(1)), selectColor, false)
+                }
+            },
+        })
+
+        elements = append(elements, &uilib.UIElement{
+            Rect: image.Rect(listRect.Min.X + 8, rowY, listRect.Max.X, rowY + rowHeight),
+            LeftClickRelease: func(element *uilib.UIElement){
+                view.Player.SelectedStack = stack
+                view.State = ArmyScreenStateDone
+            },
+            Inside: func(element *uilib.UIElement, x int, y int){
+                highlightedStack = stack
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                if stack.IsEmpty() {
+                    return
+                }
+
+                if highlightedStack == stack {
+                    vector.FillRect(screen, scale.Scale(float32(element.Rect.Min.X)), scale.Scale(float32(element.Rect.Min.Y)), scale.Scale(float32(element.Rect.Dx())), scale.Scale(float32(element.Rect.Dy())), highlightColor, false)
+                }
+
+                for _, column := range columns {
+                    printSmall(normalFont, screen, float64(column.X), float64(rowY + 1), column.Justify, column.Value(stack))
+                }
+            },
+        })
+    }
+
+    // scrolling anywhere moves the list
+    elements = append(elements, &uilib.UIElement{
+        Layer: -1,
+        Rect: image.Rect(0, 0, data.ScreenWidth, data.ScreenHeight),
+        Scroll: func(element *uilib.UIElement, x float64, y float64){
+            if y < 0 && view.FirstRow < len(stacks) - visibleRows {
+                view.FirstRow += 1
+                view.UI = view.MakeUI()
+            } else if y > 0 && view.FirstRow > 0 {
+                view.FirstRow -= 1
+                view.UI = view.MakeUI()
+            }
+        },
+    })
+
+    makeButton := func(rect image.Rectangle, text func() string, action func()) *uilib.UIElement {
+        return &uilib.UIElement{
+            Rect: rect,
+            PlaySoundLeftClick: true,
+            LeftClick: func(element *uilib.UIElement){
+                action()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                vector.FillRect(screen, scale.Scale(float32(rect.Min.X)), scale.Scale(float32(rect.Min.Y)), scale.Scale(float32(rect.Dx())), scale.Scale(float32(rect.Dy())), panelColor, false)
+                printSmall(normalFont, screen, float64(rect.Min.X + rect.Dx() / 2), float64(rect.Min.Y + 2), font.FontJustifyCenter, text())
+            },
+        }
+    }
+
+    type button struct {
+        Text func() string
+        Action func()
+    }
+
+    buttons := []button{
+        {
+            Text: func() string {
+                switch {
+                    case view.ShowArcanus && !view.ShowMyrror: return "Plane: Arcanus"
+                    case view.ShowMyrror && !view.ShowArcanus: return "Plane: Myrror"
+                }
+                return "Plane: All"
+            },
+            Action: func(){
+                // cycle all -> arcanus -> myrror
+                switch {
+                    case view.ShowArcanus && view.ShowMyrror: view.ShowMyrror = false
+                    case view.ShowArcanus: view.ShowArcanus, view.ShowMyrror = false, true
+                    default: view.ShowArcanus = true
+                }
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+        },
+        {
+            Text: func() string { return "Show: " + view.FilterStatus.String() },
+            Action: func(){
+                view.FilterStatus = view.FilterStatus.Next()
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+        },
+        {
+            Text: func() string { return "Select All" },
+            Action: func(){
+                all := !slices.ContainsFunc(stacks, func(stack *playerlib.UnitStack) bool {
+                    return !view.Selected[stack]
+                })
+                for _, stack := range stacks {
+                    view.Selected[stack] = !all
+                }
+                view.Message = ""
+            },
+        },
+        {
+            Text: func() string { return "Disband" },
+            Action: func(){
+                view.disbandSelected(ui, stacks)
+            },
+        },
+    }
+
+    if view.SendToCity != nil && view.CityDistance != nil {
+        buttons = append(buttons, button{
+            Text: func() string { return "Send To City" },
+            Action: func(){
+                view.sendSelectedToCity(ui, stacks)
+            },
+        })
+    }
+
+    buttons = append(buttons, button{
+        Text: func() string { return "Classic" },
+        Action: func(){
+            view.Manage = false
+            view.FirstRow = 0
+            view.UI = view.MakeUI()
+        },
+    }, button{
+        Text: func() string { return "Done" },
+        Action: func(){
+            view.State = ArmyScreenStateDone
+        },
+    })
+
+    buttonWidth := 312 / len(buttons)
+    for i, button := range buttons {
+        x := 4 + i * buttonWidth
+        elements = append(elements, makeButton(image.Rect(x, 184, x + buttonWidth - 2, 192), button.Text, button.Action))
+    }
+
+    ui.SetElementsFromArray(elements)
+
+    return ui
+}
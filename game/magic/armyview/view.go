@@ -15,6 +15,7 @@ import (
     "github.com/kazzmir/master-of-magic/game/magic/scale"
     fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
     playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
     uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
 
     "github.com/hajimehoshi/ebiten/v2"
@@ -42,6 +43,20 @@ type ArmyScreen struct {
     ShowArcanus bool
     ShowMyrror bool
 
+    // the sortable, filterable layout, see manage.go
+    Manage bool
+    Sort StackSort
+    SortDescending bool
+    FilterStatus StackFilter
+    Selected map[*playerlib.UnitStack]bool
+    Message string
+    // how strong the stack is in combat
+    StackStrength func(*playerlib.UnitStack) int
+    // the distance a stack would travel to reach a city
+    CityDistance func(*playerlib.UnitStack, *citylib.City) int
+    // order the stack to go to the city
+    SendToCity func(*playerlib.UnitStack, *citylib.City)
+
     arcanusCounter float32
     myrrorCounter float32
 }
@@ -85,6 +100,10 @@ func MakeArmyViewFonts(cache *lbx.LbxCache) *ArmyViewFonts {
 }
 
 func (view *ArmyScreen) MakeUI() *uilib.UI {
+    if view.Manage && scale.HalfSizeReadable() {
+        return view.makeManageUI()
+    }
+
     var highlightedUnit units.StackUnit
 
     fonts := MakeArmyViewFonts(view.Cache)
@@ -211,6 +230,23 @@ func (view *ArmyScreen) MakeUI() *uilib.UI {
         }
     }
 
+    // switches to the sortable layout, whose small print is only readable when scaled up
+    if scale.HalfSizeReadable() {
+        manageRect := image.Rect(0, 0, int(fonts.SmallerFont.MeasureTextWidth("Manage", 1)), fonts.SmallerFont.Height()).Add(image.Pt(200, 18))
+        ui.AddElement(&uilib.UIElement{
+            Rect: manageRect,
+            PlaySoundLeftClick: true,
+            LeftClick: func (this *uilib.UIElement){
+                view.Manage = true
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+            Draw: func(this *uilib.UIElement, screen *ebiten.Image){
+                fonts.SmallerFont.PrintOptions(screen, float64(manageRect.Min.X), float64(manageRect.Min.Y), font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount}, "Manage")
+            },
+        })
+    }
+
     var resetUnits func()
 
     itemButtons, _ := view.ImageCache.GetImages("armylist.lbx", 3)
@@ -238,6 +238,26 @@ func (building Building) ProducesFood() bool {
     return false
 }
 
+// buildings that increase production
+func (building Building) IsProduction() bool {
+    switch building {
+        case BuildingSawmill, BuildingMinersGuild, BuildingMechaniciansGuild:
+            return true
+    }
+
+    return false
+}
+
+// buildings that produce research
+func (building Building) IsResearch() bool {
+    switch building {
+        case BuildingLibrary, BuildingSagesGuild, BuildingAlchemistsGuild, BuildingUniversity, BuildingWizardsGuild:
+            return true
+    }
+
+    return false
+}
+
 func EnchantmentBuildings() map[data.CityEnchantment]Building {
     buildings := make(map[data.CityEnchantment]Building)
     buildings[data.CityEnchantmentAstralGate] = BuildingAstralGate
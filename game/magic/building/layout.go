@@ -5,6 +5,8 @@ import (
     "slices"
     randomlib "math/rand/v2"
     "cmp"
+
+    "github.com/kazzmir/master-of-magic/lib/set"
 )
 
 // a position within some patch of land where this building is located. the Area field
@@ -255,3 +257,193 @@ func StandardRects() []*Rect {
 
     return append(append(row1, row2...), row3...)
 }
+
+// a building placed in a patch of land of the standard city scape
+type PlacedBuilding struct {
+    Building Building
+    // the id of the rect, see StandardRects
+    Rect int
+    Area image.Rectangle
+}
+
+/* the layout of a city's buildings, kept by the city so the city scape looks the same every time it is
+ * viewed. new buildings are placed in the free space of the existing layout so the rest of the city
+ * doesn't move, and removed buildings leave their space empty. only when a new building doesn't fit
+ * anywhere is the whole city laid out again.
+ */
+type CityLayout struct {
+    // each change to the layout uses random numbers derived from this seed, so the same city
+    // with the same history always looks the same
+    Seed uint64
+    // the seed has been chosen, a seed of 0 is valid
+    Seeded bool
+    Buildings []PlacedBuilding
+}
+
+func (layout *CityLayout) random() *randomlib.Rand {
+    return randomlib.New(randomlib.NewPCG(layout.Seed, uint64(len(layout.Buildings))))
+}
+
+func (layout *CityLayout) Contains(building Building) bool {
+    for _, placed := range layout.Buildings {
+        if placed.Building == building {
+            return true
+        }
+    }
+
+    return false
+}
+
+func (layout *CityLayout) Get(building Building) (PlacedBuilding, bool) {
+    for _, placed := range layout.Buildings {
+        if placed.Building == building {
+            return placed, true
+        }
+    }
+
+    return PlacedBuilding{}, false
+}
+
+func (layout *CityLayout) Remove(building Building) {
+    layout.Buildings = slices.DeleteFunc(layout.Buildings, func(placed PlacedBuilding) bool {
+        return placed.Building == building
+    })
+}
+
+// the standard rects with the placed buildings in them
+func (layout *CityLayout) Rects() []*Rect {
+    rects := StandardRects()
+    for _, placed := range layout.Buildings {
+        for _, rect := range rects {
+            if rect.Id == placed.Rect {
+                rect.Buildings = append(rect.Buildings, BuildingPosition{Building: placed.Building, Area: placed.Area})
+                break
+            }
+        }
+    }
+
+    return rects
+}
+
+func (layout *CityLayout) setRects(rects []*Rect) {
+    layout.Buildings = nil
+    for _, rect := range rects {
+        for _, position := range rect.Buildings {
+            layout.Buildings = append(layout.Buildings, PlacedBuilding{Building: position.Building, Rect: rect.Id, Area: position.Area})
+        }
+    }
+}
+
+// place one building in the free space of some rect, the fortress always goes in the fortress rect
+func addToRects(rects []*Rect, building Building, random *randomlib.Rand) bool {
+    width, height := building.Size()
+
+    for _, i := range random.Perm(len(rects)) {
+        rect := rects[i]
+        if rect.Fortress != (building == BuildingFortress) {
+            continue
+        }
+
+        if rect.Add(building, width, height, random) {
+            return true
+        }
+    }
+
+    return false
+}
+
+/* make the layout hold exactly the given buildings. buildings that are already placed stay where they are.
+ * returns false if the buildings could not be laid out at all
+ */
+func (layout *CityLayout) Update(buildings []Building) bool {
+    wanted := set.NewSet(buildings...)
+    layout.Buildings = slices.DeleteFunc(layout.Buildings, func(placed PlacedBuilding) bool {
+        return !wanted.Contains(placed.Building)
+    })
+
+    var missing []Building
+    for _, building := range buildings {
+        if !layout.Contains(building) {
+            missing = append(missing, building)
+        }
+    }
+
+    if len(missing) == 0 {
+        return true
+    }
+
+    // biggest first, like doLayoutIterative
+    slices.SortFunc(missing, func(a Building, b Building) int {
+        aWidth, aHeight := a.Size()
+        bWidth, bHeight := b.Size()
+        if aWidth * aHeight == bWidth * bHeight {
+            return cmp.Compare(a, b)
+        }
+        return (bWidth * bHeight) - (aWidth * aHeight)
+    })
+
+    random := layout.random()
+
+    rects := layout.Rects()
+    fits := true
+    for _, building := range missing {
+        if !addToRects(rects, building, random) {
+            fits = false
+            break
+        }
+    }
+
+    if fits {
+        layout.setRects(rects)
+        return true
+    }
+
+    // there is no room left, so lay out the whole city again
+    for range 40 {
+        result, ok := LayoutBuildings(buildings, StandardRects(), random)
+        if ok {
+            layout.setRects(result)
+            return true
+        }
+    }
+
+    return false
+}
+
+// move a placed building to the given position in a rect. returns false if the building doesn't fit there
+func (layout *CityLayout) Move(building Building, rectId int, point image.Point) bool {
+    index := slices.IndexFunc(layout.Buildings, func(placed PlacedBuilding) bool {
+        return placed.Building == building
+    })
+
+    if index == -1 {
+        return false
+    }
+
+    var target *Rect
+    for _, rect := range StandardRects() {
+        if rect.Id == rectId {
+            target = rect
+        }
+    }
+
+    if target == nil || target.Fortress != (building == BuildingFortress) {
+        return false
+    }
+
+    area := image.Rectangle{Min: point, Max: point.Add(layout.Buildings[index].Area.Size())}
+    if !area.In(image.Rect(0, 0, target.Width, target.Height)) {
+        return false
+    }
+
+    for i, placed := range layout.Buildings {
+        if i != index && placed.Rect == rectId && placed.Area.Overlaps(area) {
+            return false
+        }
+    }
+
+    layout.Buildings[index].Rect = rectId
+    layout.Buildings[index].Area = area
+
+    return true
+}
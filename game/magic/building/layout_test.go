@@ -2,6 +2,7 @@ package building
 
 import (
     "testing"
+    "image"
     "fmt"
     "time"
     "slices"
@@ -450,3 +451,82 @@ func TestLayoutEquality(test *testing.T){
         }
     }
 }
+
+// buildings that are already placed should not move when other buildings are added or removed
+func TestCityLayoutStable(test *testing.T){
+    layout := CityLayout{Seed: 1234}
+
+    buildings := []Building{BuildingBarracks, BuildingSmithy, BuildingGranary}
+    if !layout.Update(buildings) {
+        test.Fatalf("could not layout %v", buildings)
+    }
+
+    before := make(map[Building]PlacedBuilding)
+    for _, placed := range layout.Buildings {
+        before[placed.Building] = placed
+    }
+
+    buildings = append(buildings, BuildingMarketplace, BuildingLibrary)
+    if !layout.Update(buildings) {
+        test.Fatalf("could not layout %v", buildings)
+    }
+
+    for building, placed := range before {
+        now, ok := layout.Get(building)
+        if !ok || now != placed {
+            test.Errorf("%v moved from %v to %v", building, placed, now)
+        }
+    }
+
+    // selling the smithy leaves everything else alone
+    buildings = slices.DeleteFunc(buildings, func(building Building) bool {
+        return building == BuildingSmithy
+    })
+    layout.Update(buildings)
+
+    if layout.Contains(BuildingSmithy) {
+        test.Errorf("the smithy should have been removed")
+    }
+
+    if now, _ := layout.Get(BuildingBarracks); now != before[BuildingBarracks] {
+        test.Errorf("the barracks moved to %v", now)
+    }
+
+    // the same history gives the same layout
+    other := CityLayout{Seed: 1234}
+    other.Update([]Building{BuildingBarracks, BuildingSmithy, BuildingGranary})
+    other.Update([]Building{BuildingBarracks, BuildingSmithy, BuildingGranary, BuildingMarketplace, BuildingLibrary})
+    other.Update(buildings)
+    if !slices.Equal(layout.Buildings, other.Buildings) {
+        test.Errorf("layouts with the same seed were different")
+    }
+}
+
+func TestCityLayoutMove(test *testing.T){
+    layout := CityLayout{Seed: 1}
+    layout.Update([]Building{BuildingBarracks, BuildingGranary})
+
+    barracks, _ := layout.Get(BuildingBarracks)
+    granary, _ := layout.Get(BuildingGranary)
+
+    if layout.Move(BuildingGranary, barracks.Rect, barracks.Area.Min) {
+        test.Errorf("the granary should not be able to move on top of the barracks")
+    }
+
+    if layout.Move(BuildingGranary, 0, image.Pt(100, 100)) {
+        test.Errorf("the granary should not be able to move outside of the rect")
+    }
+
+    if layout.Move(BuildingBarracks, 6, image.Pt(0, 0)) {
+        test.Errorf("only the fortress can go in the fortress rect")
+    }
+
+    if now, _ := layout.Get(BuildingGranary); now != granary {
+        test.Errorf("a failed move should leave the granary alone")
+    }
+
+    layout.Remove(BuildingBarracks)
+    if !layout.Move(BuildingGranary, barracks.Rect, barracks.Area.Min) {
+        test.Errorf("the granary should fit where the barracks was")
+    }
+}
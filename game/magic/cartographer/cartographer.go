@@ -133,23 +133,27 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
     }
 
     tileImage0, _ := arcanusMap.GetTileImage(0, 0, 0)
+    tileWidth := float64(tileImage0.Bounds().Dx())
+    tileHeight := float64(tileImage0.Bounds().Dy())
 
-    renderMap := func (plane data.Plane) *ebiten.Image {
-        showMap := ebiten.NewImage(20*11, 18*9)
-        showMap.Fill(color.RGBA{A: 0})
-        // showMap.Fill(color.RGBA{R: 32, G: 32, B: 32, A: 255})
-
-        useMap := arcanusMap
-        useFog := arcanusFog
+    getMap := func (plane data.Plane) (*maplib.Map, data.FogMap) {
         if plane == data.PlaneMyrror {
-            useMap = myrrorMap
-            useFog = myrrorFog
+            return myrrorMap, myrrorFog
         }
+        return arcanusMap, arcanusFog
+    }
+
+    // the map is drawn into an area of width x height with the same scale on both axes so that maps of any size keep their shape
+    renderMap := func (plane data.Plane, width int, height int) (*ebiten.Image, float64) {
+        useMap, useFog := getMap(plane)
 
         // log.Printf("tile width: %v height: %v", tileImage0.Bounds().Dx(), tileImage0.Bounds().Dy())
 
-        scaleX := float64(showMap.Bounds().Dx()) / float64(useMap.Width() * tileImage0.Bounds().Dx())
-        scaleY := float64(showMap.Bounds().Dy()) / float64(useMap.Height() * tileImage0.Bounds().Dy())
+        mapScale := min(float64(width) / (float64(useMap.Width()) * tileWidth), float64(height) / (float64(useMap.Height()) * tileHeight))
+
+        showMap := ebiten.NewImage(max(1, int(float64(useMap.Width()) * tileWidth * mapScale)), max(1, int(float64(useMap.Height()) * tileHeight * mapScale)))
+        showMap.Fill(color.RGBA{A: 0})
+        // showMap.Fill(color.RGBA{R: 32, G: 32, B: 32, A: 255})
 
         var options colorm.DrawImageOptions
         var matrix colorm.ColorM
@@ -165,7 +169,7 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
                     if err == nil {
                         options.GeoM.Reset()
                         options.GeoM.Translate(float64(x*tileImage.Bounds().Dx()), float64(y*tileImage.Bounds().Dy()))
-                        options.GeoM.Scale(scaleX, scaleY)
+                        options.GeoM.Scale(mapScale, mapScale)
                         colorm.DrawImage(showMap, tileImage, matrix, &options)
                     }
                 }
@@ -177,8 +181,8 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
             if city.Plane == plane {
                 if useFog[city.X][city.Y] != data.FogTypeUnexplored {
                     options.GeoM.Reset()
-                    options.GeoM.Translate(float64(city.X*tileImage0.Bounds().Dx()), float64(city.Y*tileImage0.Bounds().Dy()))
-                    options.GeoM.Scale(scaleX, scaleY)
+                    options.GeoM.Translate(float64(city.X)*tileWidth, float64(city.Y)*tileHeight)
+                    options.GeoM.Scale(mapScale, mapScale)
 
                     size := 20.0
 
@@ -200,8 +204,8 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
             if stack.Plane() == plane {
                 if useFog[stack.X()][stack.Y()] != data.FogTypeUnexplored {
                     options.GeoM.Reset()
-                    options.GeoM.Translate(float64(stack.X()*tileImage0.Bounds().Dx()), float64(stack.Y()*tileImage0.Bounds().Dy()))
-                    options.GeoM.Scale(scaleX, scaleY)
+                    options.GeoM.Translate(float64(stack.X())*tileWidth, float64(stack.Y())*tileHeight)
+                    options.GeoM.Scale(mapScale, mapScale)
 
                     x1, y1 := options.GeoM.Apply(3, 3)
 
@@ -211,20 +215,83 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
         }
 
 
-        return showMap
+        return showMap, mapScale
+    }
+
+    // the area of the book that the maps are drawn in
+    area := image.Rect(25, 30, 245, 192)
+
+    // one plane drawn somewhere on the screen
+    type planeView struct {
+        Plane data.Plane
+        Render *ebiten.Image
+        X int
+        Y int
+        Scale float64
+    }
+
+    // center the render of the plane in the given area
+    makeView := func (plane data.Plane, where image.Rectangle) planeView {
+        render, mapScale := renderMap(plane, where.Dx(), where.Dy())
+        return planeView{
+            Plane: plane,
+            Render: render,
+            X: where.Min.X + (where.Dx() - render.Bounds().Dx()) / 2,
+            Y: where.Min.Y + (where.Dy() - render.Bounds().Dy()) / 2,
+            Scale: mapScale,
+        }
+    }
+
+    // the tile under the screen position x, y
+    viewTile := func (view planeView, x int, y int) (float64, float64) {
+        return float64(x - view.X) / (tileWidth * view.Scale), float64(y - view.Y) / (tileHeight * view.Scale)
+    }
+
+    singleViews := map[data.Plane]planeView{
+        data.PlaneArcanus: makeView(data.PlaneArcanus, area),
+        data.PlaneMyrror: makeView(data.PlaneMyrror, area),
+    }
+
+    // both planes next to each other, with a small gap in between
+    middle := area.Min.X + area.Dx() / 2
+    bothViews := []planeView{
+        makeView(data.PlaneArcanus, image.Rect(area.Min.X, area.Min.Y, middle - 2, area.Max.Y)),
+        makeView(data.PlaneMyrror, image.Rect(middle + 2, area.Min.Y, area.Max.X, area.Max.Y)),
     }
 
-    arcanusRender := renderMap(data.PlaneArcanus)
-    myrrorRender := renderMap(data.PlaneMyrror)
+    sideBySide := false
+
+    currentViews := func () []planeView {
+        if sideBySide {
+            return bothViews
+        }
+
+        return []planeView{singleViews[currentPlane]}
+    }
 
     mouseX, mouseY := 0, 0
     var drawCityName *citylib.City
+    var drawCityView planeView
 
-    offsetX := 25
-    offsetY := 30
+    // shown at the bottom of the screen after the maps are exported
+    message := ""
 
-    scaleX := float64(arcanusRender.Bounds().Dx()) / float64(arcanusMap.Width() * tileImage0.Bounds().Dx())
-    scaleY := float64(arcanusRender.Bounds().Dy()) / float64(arcanusMap.Height() * tileImage0.Bounds().Dy())
+    // write both planes in the terrain file format so they can be loaded as a scenario by a new game
+    exportMaps := func () {
+        path := "cartographer.png"
+        err := arcanusMap.SaveTerrainFile(path)
+        if err == nil {
+            err = myrrorMap.SaveTerrainFile(maplib.MyrrorTerrainPath(path))
+        }
+
+        if err != nil {
+            log.Printf("Error: could not export maps: %v", err)
+            message = "Could not save the maps"
+        } else {
+            log.Printf("Exported maps to %v and %v", path, maplib.MyrrorTerrainPath(path))
+            message = "Saved " + path
+        }
+    }
 
     ui := &uilib.UI{
         Draw: func (ui *uilib.UI, screen *ebiten.Image) {
@@ -233,34 +300,42 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
             options.ColorScale.ScaleAlpha(getAlpha())
             scale.DrawScaled(screen, background, &options)
 
-            render := arcanusRender
             planeName := "Arcanus Plane"
             if currentPlane == data.PlaneMyrror {
                 planeName = "Myrror Plane"
-                render = myrrorRender
+            }
+            if sideBySide {
+                planeName = "Both Planes"
             }
             fonts.Title.PrintOptions(screen, float64(background.Bounds().Dx() / 2), 10, font.FontOptions{Scale: scale.ScaleAmount, Options: &options, Justify: font.FontJustifyCenter}, planeName)
 
-            options.GeoM.Translate(float64(offsetX), float64(offsetY))
-            scale.DrawScaled(screen, render, &options)
+            for _, view := range currentViews() {
+                options.GeoM.Reset()
+                options.GeoM.Translate(float64(view.X), float64(view.Y))
+                scale.DrawScaled(screen, view.Render, &options)
+
+                if sideBySide {
+                    fonts.Name.PrintOptions(screen, float64(view.X + view.Render.Bounds().Dx() / 2), float64(view.Y + view.Render.Bounds().Dy() + 2), font.FontOptions{Scale: scale.ScaleAmount, Options: &options, Justify: font.FontJustifyCenter}, view.Plane.String())
+                }
+            }
 
             if drawCityName != nil {
                 cityName := drawCityName.Name
 
-                options.GeoM.Reset()
-                options.GeoM.Translate(float64(drawCityName.X*tileImage0.Bounds().Dx()), float64(drawCityName.Y*tileImage0.Bounds().Dy()))
-                options.GeoM.Scale(scaleX, scaleY)
-
-                // why is -60 so large? it seems like we should use -10 or so. A: this is because of the scale in the geom
-                x1, y1 := options.GeoM.Apply(0, -65)
+                x1 := float64(drawCityView.X) + float64(drawCityName.X) * tileWidth * drawCityView.Scale
+                y1 := float64(drawCityView.Y) + float64(drawCityName.Y) * tileHeight * drawCityView.Scale - 12
 
                 fontUse, ok := fonts.BannerFonts[drawCityName.GetBanner()]
 
                 if ok {
-                    fontUse.PrintOptions(screen, x1 + float64(offsetX), y1 + float64(offsetY), font.FontOptions{Scale: scale.ScaleAmount, Options: &options, Justify: font.FontJustifyCenter, DropShadow: true}, cityName)
+                    fontUse.PrintOptions(screen, x1, y1, font.FontOptions{Scale: scale.ScaleAmount, Options: &options, Justify: font.FontJustifyCenter, DropShadow: true}, cityName)
                 }
             }
 
+            if message != "" {
+                fonts.Name.PrintOptions(screen, float64(area.Min.X + area.Dx() / 2), float64(area.Max.Y + 2), font.FontOptions{Scale: scale.ScaleAmount, Options: &options, Justify: font.FontJustifyCenter}, message)
+            }
+
             bannerY := 80
             for _, banner := range bannerList {
                 name := usedBanners[banner]
@@ -277,6 +352,16 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
 
             ui.StandardDraw(screen)
         },
+        HandleKeys: func (keys []ebiten.Key) {
+            for _, key := range keys {
+                switch key {
+                    // show both planes at once
+                    case ebiten.KeyB: sideBySide = !sideBySide
+                    // export the maps as png files
+                    case ebiten.KeyS: exportMaps()
+                }
+            }
+        },
     }
 
     ui.SetElementsFromArray(nil)
@@ -296,35 +381,31 @@ func MakeCartographer(cache *lbx.LbxCache, cities []*citylib.City, stacks []*pla
     })
 
     logic := func (yield coroutine.YieldFunc) error {
-        var geom ebiten.GeoM
-        geom.Scale(float64(tileImage0.Bounds().Dx()), float64(tileImage0.Bounds().Dy()))
-        geom.Scale(scaleX, scaleY)
-        geom.Invert()
-
         for !quit {
             counter += 1
 
             mouseX, mouseY = ebiten.CursorPosition()
             mouseX, mouseY = scale.Unscale2(mouseX, mouseY)
 
-            usePlane := data.PlaneArcanus
-            useFog := arcanusFog
-            if currentPlane == data.PlaneMyrror {
-                usePlane = data.PlaneMyrror
-                useFog = myrrorFog
-            }
-
-            mx, my := geom.Apply(float64(mouseX - offsetX), float64(mouseY - offsetY))
-            // log.Printf("converted mouse coordinates: %v %v", mx, my)
-
             drawCityName = nil
-            // FIXME: use a kd-tree or some spatial datastructure for faster look ups
-            maxDistance := 1.0
-            for _, city := range cities {
-                if city.Plane == usePlane && useFog[city.X][city.Y] != data.FogTypeUnexplored {
-                    if math.Abs(mx - float64(city.X)) < maxDistance && math.Abs(my - float64(city.Y)) < maxDistance {
-                        drawCityName = city
-                        break
+            for _, view := range currentViews() {
+                if !image.Pt(mouseX, mouseY).In(image.Rect(view.X, view.Y, view.X + view.Render.Bounds().Dx(), view.Y + view.Render.Bounds().Dy())) {
+                    continue
+                }
+
+                _, useFog := getMap(view.Plane)
+                mx, my := viewTile(view, mouseX, mouseY)
+                // log.Printf("converted mouse coordinates: %v %v", mx, my)
+
+                // FIXME: use a kd-tree or some spatial datastructure for faster look ups
+                maxDistance := 1.0
+                for _, city := range cities {
+                    if city.Plane == view.Plane && useFog[city.X][city.Y] != data.FogTypeUnexplored {
+                        if math.Abs(mx - float64(city.X)) < maxDistance && math.Abs(my - float64(city.Y)) < maxDistance {
+                            drawCityName = city
+                            drawCityView = view
+                            break
+                        }
                     }
                 }
             }
@@ -34,6 +34,8 @@ type CityEventNewUnit struct {
     Unit units.Unit
     WeaponBonus data.WeaponBonus
     Experience int
+    // the city went on to the next item of its production queue rather than producing the same unit again
+    Queued bool
 }
 
 type CityEventOutpostDestroyed struct {
@@ -46,6 +48,20 @@ type CityEventNewBuilding struct {
     Building buildinglib.Building
 }
 
+// the city doesn't have enough food and will start to lose people next turn
+type CityEventStarvationWarning struct {
+    Deficit int
+}
+
+// most of the city are rebels, and they will rise up if nothing changes, see rebellion.go
+type CityEventRebelsRising struct {
+    TurnsLeft int
+}
+
+// the rebels have risen up, the game decides what happens to the city
+type CityEventRebellion struct {
+}
+
 type CitySize int
 const (
     CitySizeHamlet CitySize = iota
@@ -86,6 +102,8 @@ type CityServicesProvider interface {
     BadMoonActive() bool
     PopulationBoomActive(city *City) bool
     PlagueActive(city *City) bool
+    // the optional food logistics rule is enabled, see food.go
+    FoodLogistics() bool
     GetAllGlobalEnchantments() map[data.BannerType]*set.Set[data.Enchantment]
     GetSpellByName(name string) spellbook.Spell
 }
@@ -101,6 +119,8 @@ type ReignProvider interface {
     GetBanner() data.BannerType
     GetGlobalEnchantments() *set.Set[data.Enchantment]
     GetUnits(x int, y int, plane data.Plane) []units.StackUnit
+    // economic bonuses from the difficulty level, only non-trivial for AI wizards
+    GetDifficultyModifiers() data.DifficultyModifiers
 }
 
 const MAX_CITY_CITIZENS = 25
@@ -138,6 +158,19 @@ type City struct {
     Production float32
     ProducingBuilding buildinglib.Building
     ProducingUnit units.Unit
+    // what to produce after the current project, see queue.go
+    Queue []QueueItem
+    // when set, the citizens and production are managed automatically, see governor.go
+    Governor GovernorFocus
+    // where each building is drawn in the city scape, see layout.go
+    Layout buildinglib.CityLayout
+
+    // food carried in from (positive) or out to (negative) other cities this turn, see food.go
+    FoodImported int
+    // how many turns in a row the city has not had enough food, see food.go
+    StarvingTurns int
+    // how many turns in a row most of the city have been rebels, see rebellion.go
+    RebelTurns int
 
     BuildingInfo buildinglib.BuildingInfos
 }
@@ -186,6 +219,33 @@ func (city *City) GetBanner() data.BannerType {
     return city.ReignProvider.GetBanner()
 }
 
+// how far the territory of the city reaches. see maplib.ComputeTerritory
+func (city *City) InfluenceRadius() int {
+    radius := 2
+    switch city.GetSize() {
+        case CitySizeTown: radius = 3
+        case CitySizeCity, CitySizeCapital: radius = 4
+    }
+
+    if city.HasFortress() {
+        radius += 1
+    }
+
+    return radius
+}
+
+// the territory of the given cities that are on the plane of the map
+func ComputeTerritory(mapObject *maplib.Map, cities []*City) *maplib.Territory {
+    var claims []maplib.TerritoryCity
+    for _, city := range cities {
+        if city.Plane == mapObject.Plane {
+            claims = append(claims, city)
+        }
+    }
+
+    return mapObject.ComputeTerritory(claims)
+}
+
 func (city *City) GetOutpostHouses() int {
     // every 100 population is 1 house
     return city.Population / 100
@@ -193,10 +253,12 @@ func (city *City) GetOutpostHouses() int {
 
 func (city *City) AddBuilding(building buildinglib.Building){
     city.Buildings.Insert(building)
+    city.UpdateLayout()
 }
 
 func (city *City) RemoveBuilding(building buildinglib.Building){
     city.Buildings.Remove(building)
+    city.UpdateLayout()
 }
 
 func (city *City) HasSummoningCircle() bool {
@@ -795,6 +857,11 @@ func oraclePacification(race data.Race) int {
 
 // https://masterofmagic.fandom.com/wiki/Town#Unrest_Reduction
 func (city *City) InteracialUnrest() float64 {
+    return RaceTension(city.Race, city.ReignProvider.GetRulingRace())
+}
+
+// how much the people of one race dislike living under the other, from 0 to 0.4
+func RaceTension(race1 data.Race, race2 data.Race) float64 {
     unrest := make(map[data.Race]map[data.Race]float64)
 
     set := func (race1 data.Race, race2 data.Race, value float64) {
@@ -928,7 +995,7 @@ func (city *City) InteracialUnrest() float64 {
 
     set(data.RaceTroll, data.RaceTroll, 0)
 
-    return unrest[city.Race][city.ReignProvider.GetRulingRace()]
+    return unrest[race1][race2]
 }
 
 func (city *City) GetGarrison() []units.StackUnit {
@@ -1124,6 +1191,18 @@ func (city *City) MaximumCitySize() int {
 }
 
 func (city *City) PopulationGrowthRate() int {
+    base := city.uncappedGrowthRate()
+
+    // can't have positive growth if at max size
+    if base > 0 && city.Citizens() >= city.MaximumCitySize() {
+        return 0
+    }
+
+    return base
+}
+
+// the growth before the size limit of the city, which is how many people want to move away from a full city
+func (city *City) uncappedGrowthRate() int {
     base := 10 * (city.MaximumCitySize() - city.Citizens() + 1) / 2
     switch city.Race {
         case data.RaceBarbarian: base += 20
@@ -1191,16 +1270,16 @@ func (city *City) PopulationGrowthRate() int {
         base -= base % 10
     }
 
-    // how does population boom interact with starving?
-    if city.SurplusFood() < 0 {
+    if city.CityServices.FoodLogistics() {
+        // a city that can't be fed doesn't grow, and loses people in doStarvation
+        if city.NetFood() < 0 {
+            return 0
+        }
+    } else if city.SurplusFood() < 0 {
+        // how does population boom interact with starving?
         base = 50 * city.SurplusFood()
     }
 
-    // can't have positive growth if at max size
-    if base > 0 && city.Citizens() >= city.MaximumCitySize() {
-        return 0
-    }
-
     return base
 }
 
@@ -1262,18 +1341,51 @@ func (city *City) BaseFoodLevel() int {
 }
 
 func (city *City) FoodProductionRate() int {
-    base := city.foodProductionRate(city.Farmers)
+    return city.foodBeforeFamine() + city.FoodFamine()
+}
 
-    // foresters guild doesn't contribute to the food needed to support the town, instead the food is added to the global surplus
-    if city.Buildings.Contains(buildinglib.BuildingForestersGuild) {
-        base += 2
-    }
+func (city *City) foodBeforeFamine() int {
+    return city.foodProductionRate(city.Farmers) + city.FoodForestersGuild()
+}
 
+// the food lost to famine, which halves the food the city produces
+func (city *City) FoodFamine() int {
     if city.HasEnchantment(data.CityEnchantmentFamine) {
-        base /= 2
+        base := city.foodBeforeFamine()
+        return base / 2 - base
     }
 
-    return base
+    return 0
+}
+
+// food grown by the farmers of the city
+func (city *City) FoodFarmers() int {
+    return city.farmerFood(city.Farmers)
+}
+
+func (city *City) FoodGranary() int {
+    if city.Buildings.Contains(buildinglib.BuildingGranary) {
+        return 2
+    }
+
+    return 0
+}
+
+func (city *City) FoodFarmersMarket() int {
+    if city.Buildings.Contains(buildinglib.BuildingFarmersMarket) {
+        return 3
+    }
+
+    return 0
+}
+
+// foresters guild doesn't contribute to the food needed to support the town, instead the food is added to the global surplus
+func (city *City) FoodForestersGuild() int {
+    if city.Buildings.Contains(buildinglib.BuildingForestersGuild) {
+        return 2
+    }
+
+    return 0
 }
 
 func (city *City) FarmerFoodProduction(farmers int) int {
@@ -1291,6 +1403,11 @@ func (city *City) FarmerFoodProduction(farmers int) int {
 }
 
 func (city *City) foodProductionRate(farmers int) int {
+    return city.farmerFood(farmers) + city.FoodGranary() + city.FoodFarmersMarket() + city.ComputeWildGame()
+}
+
+// the food of the given number of farmers, which only counts half once the land can't support it
+func (city *City) farmerFood(farmers int) int {
     baseRate := float32(city.FarmerFoodProduction(farmers))
 
     /*
@@ -1309,19 +1426,7 @@ func (city *City) foodProductionRate(farmers int) int {
         baseRate = baseLevel + (baseRate - baseLevel) / 2
     }
 
-    bonus := 0
-
-    if city.Buildings.Contains(buildinglib.BuildingGranary) {
-        bonus += 2
-    }
-
-    if city.Buildings.Contains(buildinglib.BuildingFarmersMarket) {
-        bonus += 3
-    }
-
-    bonus += city.ComputeWildGame()
-
-    return int(baseRate) + bonus
+    return int(baseRate)
 }
 
 func (city *City) ComputeWildGame() int {
@@ -1345,7 +1450,7 @@ func (city *City) ComputeUpkeep() int {
         costs += city.BuildingInfo.UpkeepCost(building)
     }
 
-    return costs
+    return int(float64(costs) * city.ReignProvider.GetDifficultyModifiers().Upkeep)
 }
 
 func (city *City) GoldTaxation() int {
@@ -1353,8 +1458,9 @@ func (city *City) GoldTaxation() int {
 }
 
 func (city *City) GoldTradeGoods() int {
+    // use the unmodified production so that the difficulty modifiers for production and gold don't stack
     if city.ProducingBuilding == buildinglib.BuildingTradeGoods {
-        return int(city.WorkProductionRate() / 2)
+        return int(city.baseWorkProductionRate() / 2)
     }
 
     return 0
@@ -1451,7 +1557,8 @@ func (city *City) GoldProsperity() int {
     return 0
 }
 
-func (city *City) GoldSurplus() int {
+// gold income before the difficulty modifier and building upkeep
+func (city *City) GoldIncome() int {
     income := city.GoldTaxation()
     income += city.GoldTradeGoods()
     income += city.GoldMinerals()
@@ -1461,11 +1568,17 @@ func (city *City) GoldSurplus() int {
     income += city.GoldMerchantsGuild()
     income += city.GoldProsperity()
 
-    upkeepCosts := city.ComputeUpkeep()
+    return income
+}
 
-    out := income - upkeepCosts
+// the gold gained or lost to the difficulty modifier
+func (city *City) GoldDifficulty() int {
+    income := city.GoldIncome()
+    return int(float64(income) * city.ReignProvider.GetDifficultyModifiers().Gold) - income
+}
 
-    return out
+func (city *City) GoldSurplus() int {
+    return city.GoldIncome() + city.GoldDifficulty() - city.ComputeUpkeep()
 }
 
 func (city *City) ProductionWorkers() float32 {
@@ -1543,7 +1656,8 @@ func (city *City) ProductionInspirations() float32 {
     return 0
 }
 
-func (city *City) WorkProductionRate() float32 {
+// production before the difficulty modifier is applied
+func (city *City) baseWorkProductionRate() float32 {
     result := city.ProductionWorkers() +
               city.ProductionFarmers() +
               city.ProductionMinersGuild() +
@@ -1560,6 +1674,10 @@ func (city *City) WorkProductionRate() float32 {
     return result
 }
 
+func (city *City) WorkProductionRate() float32 {
+    return city.baseWorkProductionRate() * float32(city.ReignProvider.GetDifficultyModifiers().Production)
+}
+
 func (city *City) UnitProductionCost(unit *units.Unit) int {
 
     if !unit.ProductionCostReduction {
@@ -1722,6 +1840,13 @@ func (city *City) DoNextTurn(mapObject *maplib.Map) []CityEvent {
         oldPopulation := city.Population
         city.Population += city.PopulationGrowthRate()
 
+        if city.CityServices.FoodLogistics() {
+            warning := city.doStarvation()
+            if warning != nil {
+                cityEvents = append(cityEvents, warning)
+            }
+        }
+
         if city.HasEnchantment(data.CityEnchantmentPestilence) {
             if city.Citizens() >= 11 || city.Citizens() > (rand.IntN(10) + 1) {
                 city.Population -= 1000
@@ -1739,6 +1864,11 @@ func (city *City) DoNextTurn(mapObject *maplib.Map) []CityEvent {
             cityEvents = append(cityEvents, &CityEventPopulationGrowth{Size: (city.Population - oldPopulation)/1000, Grow: city.Population > oldPopulation})
         }
 
+        // housing and trade goods never finish, so start on the queue right away
+        if city.ProducingFiller() && len(city.Queue) > 0 {
+            city.StartNextQueued()
+        }
+
         buildingCost := city.BuildingInfo.ProductionCost(city.ProducingBuilding)
 
         if buildingCost != 0 || !city.ProducingUnit.Equals(units.UnitNone) {
@@ -1746,11 +1876,13 @@ func (city *City) DoNextTurn(mapObject *maplib.Map) []CityEvent {
 
             if buildingCost != 0 {
                 if city.Production >= float32(buildingCost) {
-                    city.Buildings.Insert(city.ProducingBuilding)
+                    city.AddBuilding(city.ProducingBuilding)
                     cityEvents = append(cityEvents, &CityEventNewBuilding{Building: city.ProducingBuilding})
 
                     city.Production = 0
-                    city.ProducingBuilding = buildinglib.BuildingHousing
+                    if !city.StartNextQueued() {
+                        city.ProducingBuilding = buildinglib.BuildingHousing
+                    }
                 }
             } else if !city.ProducingUnit.Equals(units.UnitNone) && city.Production >= float32(city.UnitProductionCost(&city.ProducingUnit)) {
                 experience := 0
@@ -1760,12 +1892,15 @@ func (city *City) DoNextTurn(mapObject *maplib.Map) []CityEvent {
                     case city.Buildings.Contains(buildinglib.BuildingFightersGuild): experience = 20
                 }
 
-                cityEvents = append(cityEvents, &CityEventNewUnit{Unit: city.ProducingUnit, WeaponBonus: city.GetWeaponBonus(), Experience: experience})
+                newUnit := &CityEventNewUnit{Unit: city.ProducingUnit, WeaponBonus: city.GetWeaponBonus(), Experience: experience}
                 city.Production = 0
 
                 if city.ProducingUnit.IsSettlers() {
                     city.Population -= 1000
                 }
+
+                newUnit.Queued = city.StartNextQueued()
+                cityEvents = append(cityEvents, newUnit)
             }
         }
 
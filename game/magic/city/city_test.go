@@ -77,6 +77,10 @@ func (provider *NoCities) PlagueActive(city *City) bool {
     return false
 }
 
+func (provider *NoCities) FoodLogistics() bool {
+    return false
+}
+
 func (provider *NoCities) GetSpellByName(name string) spellbook.Spell {
     return spellbook.Spell{}
 }
@@ -90,6 +94,8 @@ type NoReign struct {
     NumberOfBooks int
     TaxRate fraction.Fraction
     GlobalEnchantments *set.Set[data.Enchantment]
+    // zero value means no modifiers
+    DifficultyModifiers data.DifficultyModifiers
 }
 
 func (reign *NoReign) HasDivinePower() bool {
@@ -136,6 +142,14 @@ func (reign *NoReign) GetUnits(x int, y int, plane data.Plane) []units.StackUnit
     return nil
 }
 
+func (reign *NoReign) GetDifficultyModifiers() data.DifficultyModifiers {
+    if reign.DifficultyModifiers == (data.DifficultyModifiers{}) {
+        return data.NoDifficultyModifiers()
+    }
+
+    return reign.DifficultyModifiers
+}
+
 
 func TestBasicCity(test *testing.T){
     reign := NoReign{TaxRate: fraction.Make(3, 2)}
@@ -222,6 +236,10 @@ func (provider *AllConnected) PlagueActive(city *City) bool {
     return false
 }
 
+func (provider *AllConnected) FoodLogistics() bool {
+    return false
+}
+
 func (provider *AllConnected) GetSpellByName(name string) spellbook.Spell {
     return spellbook.Spell{}
 }
@@ -985,3 +1003,268 @@ func TestBuildUnits(test *testing.T) {
     }
 
 }
+
+// the difficulty modifiers for production and gold should each be applied once, even for
+// trade goods where production is converted into gold
+func TestDifficultyModifiers(test *testing.T) {
+    makeTestCity := func(modifiers data.DifficultyModifiers) *City {
+        city := MakeCity("Test City", 10, 10, data.RaceHighMen, nil, &Catchment{Map: makeSimpleMap()}, &NoCities{}, &NoReign{TaxRate: fraction.Make(1, 1), DifficultyModifiers: modifiers})
+        city.Population = 8000
+        city.Farmers = 4
+        city.Workers = 4
+        city.ResetCitizens()
+
+        city.BuildingInfo = make([]building.BuildingInfo, 40)
+        city.BuildingInfo[city.BuildingInfo.GetBuildingIndex(building.BuildingBarracks)].UpkeepGold = 3
+        city.BuildingInfo[city.BuildingInfo.GetBuildingIndex(building.BuildingBuildersHall)].UpkeepGold = 3
+        city.BuildingInfo[city.BuildingInfo.GetBuildingIndex(building.BuildingSmithy)].UpkeepGold = 3
+
+        city.AddBuilding(building.BuildingBarracks)
+        city.AddBuilding(building.BuildingBuildersHall)
+        city.AddBuilding(building.BuildingSmithy)
+        return city
+    }
+
+    modifiers := data.DifficultyModifiers{Production: 2, Gold: 3, Research: 1, Mana: 1, Upkeep: 0.5}
+
+    normal := makeTestCity(data.NoDifficultyModifiers())
+    cheating := makeTestCity(modifiers)
+
+    if normal.ComputeUpkeep() != 9 {
+        test.Errorf("upkeep should be 9 but was %v", normal.ComputeUpkeep())
+    }
+
+    // 9 * 0.5 rounded down
+    if cheating.ComputeUpkeep() != 4 {
+        test.Errorf("discounted upkeep should be 4 but was %v", cheating.ComputeUpkeep())
+    }
+
+    if !closeFloat(float64(cheating.WorkProductionRate()), float64(normal.WorkProductionRate()) * modifiers.Production) {
+        test.Errorf("production should be %v but was %v", float64(normal.WorkProductionRate()) * modifiers.Production, cheating.WorkProductionRate())
+    }
+
+    // the gold modifier applies to the income, and the upkeep is discounted separately
+    expectedGold := func() int {
+        income := normal.GoldSurplus() + normal.ComputeUpkeep()
+        return int(float64(income) * modifiers.Gold) - cheating.ComputeUpkeep()
+    }
+
+    if cheating.GoldSurplus() != expectedGold() {
+        test.Errorf("gold should be %v but was %v", expectedGold(), cheating.GoldSurplus())
+    }
+
+    normal.ProducingBuilding = buildinglib.BuildingTradeGoods
+    cheating.ProducingBuilding = buildinglib.BuildingTradeGoods
+
+    if cheating.GoldTradeGoods() != normal.GoldTradeGoods() {
+        test.Errorf("trade goods should not include the production modifier: %v vs %v", cheating.GoldTradeGoods(), normal.GoldTradeGoods())
+    }
+
+    if cheating.GoldSurplus() != expectedGold() {
+        test.Errorf("gold with trade goods should be %v but was %v", expectedGold(), cheating.GoldSurplus())
+    }
+}
+
+type FoodLogisticsServices struct {
+    NoCities
+}
+
+func (provider *FoodLogisticsServices) FoodLogistics() bool {
+    return true
+}
+
+func TestFoodDistribution(test *testing.T) {
+    services := &FoodLogisticsServices{}
+    makeFoodCity := func(name string, farmers int) *City {
+        city := MakeCity(name, 10, 10, data.RaceHighMen, nil, &Catchment{Map: makeSimpleMap()}, services, &NoReign{})
+        city.Population = 8000
+        city.Farmers = farmers
+        city.Workers = 8 - farmers
+        return city
+    }
+
+    donor := makeFoodCity("Donor", 8)
+    hungry := makeFoodCity("Hungry", 0)
+    isolated := makeFoodCity("Isolated", 0)
+
+    if donor.SurplusFood() <= 0 || hungry.SurplusFood() >= 0 {
+        test.Fatalf("donor should have surplus food %v and hungry city should be short %v", donor.SurplusFood(), hungry.SurplusFood())
+    }
+
+    cities := []*City{donor, hungry, isolated}
+    DistributeFood(cities, func(a *City, b *City) bool {
+        return a != isolated && b != isolated
+    })
+
+    transfer := min(donor.SurplusFood(), -hungry.SurplusFood())
+
+    if hungry.FoodImported != transfer {
+        test.Errorf("hungry city should have imported %v food but imported %v", transfer, hungry.FoodImported)
+    }
+
+    if donor.FoodImported != -transfer {
+        test.Errorf("donor should have exported %v food but exported %v", transfer, -donor.FoodImported)
+    }
+
+    if isolated.FoodImported != 0 {
+        test.Errorf("a city without roads should not get any food but got %v", isolated.FoodImported)
+    }
+
+    if donor.NetFood() < 0 {
+        test.Errorf("donor should not give away more than its surplus: %v", donor.NetFood())
+    }
+}
+
+func TestStarvation(test *testing.T) {
+    city := MakeCity("Test City", 10, 10, data.RaceHighMen, nil, &Catchment{Map: makeSimpleMap()}, &FoodLogisticsServices{}, &NoReign{})
+    city.Population = 8000
+    city.Farmers = 0
+    city.Workers = 8
+
+    deficit := -city.NetFood()
+    if deficit <= 0 {
+        test.Fatalf("city should be short of food but had %v", city.NetFood())
+    }
+
+    if city.PopulationGrowthRate() != 0 {
+        test.Errorf("a starving city should not grow or shrink by itself but was %v", city.PopulationGrowthRate())
+    }
+
+    warning, ok := city.doStarvation().(*CityEventStarvationWarning)
+    if !ok || warning.Deficit != deficit {
+        test.Errorf("first turn of starvation should only warn: %v", warning)
+    }
+
+    if city.Population != 8000 {
+        test.Errorf("population should not change on the warning turn but was %v", city.Population)
+    }
+
+    city.doStarvation()
+    firstLoss := 8000 - city.Population
+    if firstLoss != min(MaxStarvationLoss, 50 * deficit) {
+        test.Errorf("population should have dropped by %v but dropped by %v", min(MaxStarvationLoss, 50 * deficit), firstLoss)
+    }
+
+    before := city.Population
+    city.doStarvation()
+    if before - city.Population < firstLoss || before - city.Population > MaxStarvationLoss {
+        test.Errorf("a longer famine should cost more people up to %v but lost %v after losing %v", MaxStarvationLoss, before - city.Population, firstLoss)
+    }
+
+    city.Farmers = city.Citizens()
+    city.Workers = 0
+    if city.NetFood() < 0 {
+        test.Fatalf("city should be fed but had %v", city.NetFood())
+    }
+
+    before = city.Population
+    if city.doStarvation() != nil || city.Population != before || city.StarvingTurns != 0 {
+        test.Errorf("a fed city should stop starving")
+    }
+
+    city.Population = 1000
+    city.Farmers = 0
+    city.Workers = 1
+    city.StarvingTurns = 5
+    city.doStarvation()
+    if city.Population != 1000 {
+        test.Errorf("starvation should not reduce a city below 1000 but was %v", city.Population)
+    }
+}
+
+func TestMigration(test *testing.T) {
+    services := &FoodLogisticsServices{}
+    makeMigrationCity := func(name string, x int, race data.Race) *City {
+        city := MakeCity(name, x, 10, race, nil, &Catchment{Map: makeSimpleMap()}, services, &NoReign{})
+        city.Population = 3000
+        city.Farmers = 3
+        city.Workers = 0
+        return city
+    }
+
+    full := makeMigrationCity("Full", 10, data.RaceHighMen)
+    full.Buildings.Insert(buildinglib.BuildingGranary)
+    full.Population = full.MaximumCitySize() * 1000
+    full.Farmers = full.Citizens()
+
+    // halflings get along with high men but klackons don't
+    friendly := makeMigrationCity("Friendly", 16, data.RaceHalfling)
+    hostile := makeMigrationCity("Hostile", 12, data.RaceKlackon)
+    faraway := makeMigrationCity("Faraway", 30, data.RaceHighMen)
+
+    if full.PopulationGrowthRate() != 0 {
+        test.Errorf("a full city should not grow but was %v", full.PopulationGrowthRate())
+    }
+
+    distance := func(a *City, b *City) int {
+        return max(a.X - b.X, b.X - a.X)
+    }
+
+    migrations := MigratePopulation([]*City{full, friendly, hostile, faraway}, distance)
+    if len(migrations) != 1 {
+        test.Fatalf("expected one migration but got %v", len(migrations))
+    }
+
+    migration := migrations[0]
+    if migration.From != full || migration.To != friendly {
+        test.Errorf("people should have moved from %v to %v but moved from %v to %v", full.Name, friendly.Name, migration.From.Name, migration.To.Name)
+    }
+
+    if migration.Population <= 0 || friendly.Population != 3000 + migration.Population {
+        test.Errorf("friendly city should have gained %v people but has %v", migration.Population, friendly.Population)
+    }
+
+    if hostile.Population != 3000 || faraway.Population != 3000 {
+        test.Errorf("no one should move to an unfriendly or far away city")
+    }
+}
+
+func TestRebellionCheck(test *testing.T) {
+    reign := NoReign{TaxRate: fraction.Make(3, 1)}
+    city := MakeCity("Test City", 10, 10, data.RaceHighMen, nil, &Catchment{Map: makeSimpleMap()}, &NoCities{}, &reign)
+    city.Population = 6000
+    city.Farmers = 6
+    city.Workers = 0
+    city.ResetCitizens()
+
+    if city.RebelFraction() < RebelThreshold {
+        test.Fatalf("city should be above the rebel threshold but was %v", city.RebelFraction())
+    }
+
+    for turn := 1; turn < RebellionTurns; turn++ {
+        rising, ok := city.CheckRebellion().(*CityEventRebelsRising)
+        if !ok || rising.TurnsLeft != RebellionTurns - turn {
+            test.Errorf("turn %v should warn of a rebellion in %v turns: %v", turn, RebellionTurns - turn, rising)
+        }
+    }
+
+    _, ok := city.CheckRebellion().(*CityEventRebellion)
+    if !ok {
+        test.Errorf("rebels should rise up after %v turns", RebellionTurns)
+    }
+
+    if city.RebelTurns != 0 {
+        test.Errorf("rebellion should reset the count but was %v", city.RebelTurns)
+    }
+
+    city.CheckRebellion()
+    reign.TaxRate = fraction.Zero()
+    if city.CheckRebellion() != nil || city.RebelTurns != 0 {
+        test.Errorf("a content city should not rebel and should reset the count: %v", city.RebelTurns)
+    }
+}
+
+func TestLayoutSeed(test *testing.T) {
+    city := MakeCity("Test City", 10, 10, data.RaceHighMen, nil, &Catchment{Map: makeSimpleMap()}, &NoCities{}, &NoReign{TaxRate: fraction.Make(1, 1)})
+    city.UpdateLayout()
+    if !city.Layout.Seeded || city.Layout.Seed != city.layoutSeed() {
+        test.Errorf("a new city should be seeded from its position and race")
+    }
+
+    // 0 is a valid seed, it must not be replaced
+    city.Layout.Seed = 0
+    city.UpdateLayout()
+    if city.Layout.Seed != 0 {
+        test.Errorf("a seed of 0 was replaced by %v", city.Layout.Seed)
+    }
+}
@@ -0,0 +1,137 @@
+package city
+
+import (
+    "slices"
+)
+
+/* food logistics is an optional rule that goes beyond the original game, enabled with
+ * setup.NewGameSettings.FoodLogistics.
+ *  - cities connected by roads carry their surplus food to connected cities that don't grow enough
+ *  - a city that still doesn't have enough food is warned for one turn, and then loses people every
+ *    turn until it is fed, faster the longer the famine goes on
+ *  - a city at its maximum size sends the people that would have been born to a nearby city of a
+ *    race that gets along with it
+ */
+
+// how far people will move away from a full city
+const MigrationDistance = 10
+
+// the most people a city loses to starvation in one turn
+const MaxStarvationLoss = 1000
+
+// the most unrest between two races that still lets people move from one to the other
+const MigrationMaxTension = 0.1
+
+type Migration struct {
+    From *City
+    To *City
+    Population int
+}
+
+// the food left over after feeding the citizens, including food carried in from or out to other cities
+func (city *City) NetFood() int {
+    return city.SurplusFood() + city.FoodImported
+}
+
+/* move surplus food from cities that have some to the connected cities that are short of food. each
+ * city can give away at most its own surplus
+ */
+func DistributeFood(cities []*City, connected func(*City, *City) bool) {
+    remaining := make(map[*City]int)
+    for _, city := range cities {
+        city.FoodImported = 0
+        if !city.Outpost {
+            remaining[city] = max(0, city.SurplusFood())
+        }
+    }
+
+    for _, city := range cities {
+        if city.Outpost || city.SurplusFood() >= 0 {
+            continue
+        }
+
+        for _, donor := range cities {
+            if city.NetFood() >= 0 {
+                break
+            }
+
+            if donor == city || remaining[donor] == 0 || !connected(city, donor) {
+                continue
+            }
+
+            amount := min(remaining[donor], -city.NetFood())
+            remaining[donor] -= amount
+            donor.FoodImported -= amount
+            city.FoodImported += amount
+        }
+    }
+}
+
+/* lose people if the city doesn't have enough food. the first turn without food only gives a warning.
+ * the city is never starved below 1000 people
+ */
+func (city *City) doStarvation() CityEvent {
+    deficit := -city.NetFood()
+    if deficit <= 0 {
+        city.StarvingTurns = 0
+        return nil
+    }
+
+    city.StarvingTurns += 1
+    if city.StarvingTurns == 1 {
+        return &CityEventStarvationWarning{Deficit: deficit}
+    }
+
+    loss := min(MaxStarvationLoss, 50 * deficit * (city.StarvingTurns - 1))
+    city.Population = max(min(city.Population, 1000), city.Population - loss)
+
+    return nil
+}
+
+/* the people that would be born in a city at its maximum size move to the closest city that has room
+ * and whose race gets along with theirs. all the cities should belong to the same wizard
+ */
+func MigratePopulation(cities []*City, distance func(*City, *City) int) []Migration {
+    var out []Migration
+
+    for _, city := range cities {
+        if city.Outpost || city.Citizens() < city.MaximumCitySize() {
+            continue
+        }
+
+        migrants := city.uncappedGrowthRate()
+        if migrants <= 0 {
+            continue
+        }
+
+        var candidates []*City
+        for _, other := range cities {
+            if other == city || other.Outpost || other.Plane != city.Plane {
+                continue
+            }
+
+            if other.Citizens() >= other.MaximumCitySize() || distance(city, other) > MigrationDistance {
+                continue
+            }
+
+            if RaceTension(city.Race, other.Race) > MigrationMaxTension {
+                continue
+            }
+
+            candidates = append(candidates, other)
+        }
+
+        if len(candidates) == 0 {
+            continue
+        }
+
+        target := slices.MinFunc(candidates, func(a *City, b *City) int {
+            return distance(city, a) - distance(city, b)
+        })
+
+        target.Population += migrants
+        out = append(out, Migration{From: city, To: target, Population: migrants})
+    }
+
+    return out
+}
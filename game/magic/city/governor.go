@@ -0,0 +1,233 @@
+package city
+
+import (
+    "slices"
+
+    "github.com/kazzmir/master-of-magic/lib/set"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+)
+
+/* a governor manages a city on behalf of its owner. at the start of each turn it splits the citizens
+ * between farmers and workers according to its focus, and when the city has nothing to produce it picks
+ * the next building. the ai uses the same code to manage its own cities.
+ */
+
+type GovernorFocus int
+
+const (
+    GovernorNone GovernorFocus = iota
+    GovernorGrowth
+    GovernorProduction
+    GovernorGold
+    GovernorResearch
+    GovernorBalanced
+)
+
+// how much extra food a balanced city tries to produce
+const balancedFoodSurplus = 2
+
+func (focus GovernorFocus) String() string {
+    switch focus {
+        case GovernorNone: return "Off"
+        case GovernorGrowth: return "Growth"
+        case GovernorProduction: return "Production"
+        case GovernorGold: return "Gold"
+        case GovernorResearch: return "Research"
+        case GovernorBalanced: return "Balanced"
+    }
+
+    return "?"
+}
+
+// the focus after this one, wrapping around to off
+func (focus GovernorFocus) Next() GovernorFocus {
+    if focus >= GovernorBalanced {
+        return GovernorNone
+    }
+
+    return focus + 1
+}
+
+// true if the building is the kind the focus wants
+func (focus GovernorFocus) Wants(building buildinglib.Building) bool {
+    switch focus {
+        case GovernorGrowth: return building.ProducesFood()
+        case GovernorProduction: return building.IsProduction()
+        case GovernorGold: return building.IsEconomic()
+        // religious buildings produce power
+        case GovernorResearch: return building.IsResearch() || building.IsReligious()
+    }
+
+    return false
+}
+
+func (city *City) citizenScore(focus GovernorFocus) int {
+    food := city.SurplusFood()
+    production := int(city.WorkProductionRate())
+
+    switch focus {
+        case GovernorGrowth:
+            return food * 3 + production
+        case GovernorBalanced:
+            return min(food, balancedFoodSurplus) * 4 + production
+    }
+
+    // the split doesn't change gold or research, so the other focuses go for production to finish
+    // their buildings sooner
+    return production * 3 + food
+}
+
+/* try every number of farmers from the subsistence minimum up to all citizens and keep the split that
+ * scores best for the focus. rebels are taken into account for every split
+ */
+func (city *City) GovernCitizens(focus GovernorFocus) {
+    if focus == GovernorGrowth && city.Citizens() >= city.MaximumCitySize() {
+        // the city can't grow anymore
+        focus = GovernorBalanced
+    }
+
+    citizens := city.Citizens()
+
+    city.Rebels = 0
+    minimumFarmers := min(city.ComputeSubsistenceFarmers(), citizens)
+
+    bestFarmers := minimumFarmers
+    bestScore := 0
+    for farmers := minimumFarmers; farmers <= citizens; farmers++ {
+        city.Farmers = farmers
+        city.Workers = citizens - farmers
+        city.Rebels = 0
+        city.UpdateUnrest()
+
+        score := city.citizenScore(focus)
+        if farmers == minimumFarmers || score > bestScore {
+            bestFarmers = farmers
+            bestScore = score
+        }
+    }
+
+    city.Farmers = bestFarmers
+    city.Workers = citizens - bestFarmers
+    city.Rebels = 0
+    city.UpdateUnrest()
+}
+
+// all the buildings of this race that something of the given kind depends on
+func (city *City) kindDependencies(kind func(buildinglib.Building) bool) *set.Set[buildinglib.Building] {
+    dependencies := set.NewSet[buildinglib.Building]()
+    for _, building := range buildinglib.RacialBuildings(city.Race).Values() {
+        if kind(building) {
+            dependencies.InsertMany(city.BuildingInfo.Dependencies(building)...)
+        }
+    }
+
+    return dependencies
+}
+
+/* how much the city wants each of the given buildings. buildings that fix what the city lacks, such
+ * as religious buildings when there are rebels, are weighted up, as are the buildings the focus wants.
+ * buildings that lead to those get a smaller boost
+ */
+func (city *City) BuildingWeights(buildings []buildinglib.Building, focus GovernorFocus) []int {
+    religiousDependencies := city.kindDependencies(buildinglib.Building.IsReligious)
+    economicDependencies := city.kindDependencies(buildinglib.Building.IsEconomic)
+    foodDependencies := city.kindDependencies(buildinglib.Building.ProducesFood)
+    focusDependencies := city.kindDependencies(focus.Wants)
+
+    goldSurplus := city.GoldSurplus()
+    needsFood := city.Citizens() < city.MaximumCitySize() / 2 || city.PopulationGrowthRate() < 30
+
+    weights := make([]int, 0, len(buildings))
+    for _, building := range buildings {
+        weight := 1
+
+        if city.Rebels > 0 {
+            if building.IsReligious() {
+                weight += 2
+            } else if religiousDependencies.Contains(building) {
+                weight += 1
+            }
+        }
+
+        if goldSurplus < 0 {
+            if building.IsEconomic() {
+                weight += 2
+            } else if economicDependencies.Contains(building) {
+                weight += 1
+            }
+        }
+
+        if needsFood {
+            if building.ProducesFood() {
+                weight += 2
+            } else if foodDependencies.Contains(building) {
+                weight += 1
+            }
+        }
+
+        if focus.Wants(building) {
+            weight += 3
+        } else if focusDependencies.Contains(building) {
+            weight += 1
+        }
+
+        weights = append(weights, weight)
+    }
+
+    return weights
+}
+
+/* the building the governor would build next: the one with the highest weight, and the cheapest of
+ * those. when there is nothing left to build it falls back to housing, or trade goods for gold
+ */
+func (city *City) SuggestBuilding(focus GovernorFocus) buildinglib.Building {
+    possible := city.ComputePossibleBuildings(true).Values()
+    // so that ties are broken the same way every time
+    slices.Sort(possible)
+    weights := city.BuildingWeights(possible, focus)
+
+    best := buildinglib.BuildingNone
+    bestWeight := 0
+    for i, building := range possible {
+        if best == buildinglib.BuildingNone || weights[i] > bestWeight ||
+           (weights[i] == bestWeight && city.BuildingInfo.ProductionCost(building) < city.BuildingInfo.ProductionCost(best)) {
+            best = building
+            bestWeight = weights[i]
+        }
+    }
+
+    if best == buildinglib.BuildingNone {
+        if focus == GovernorGold {
+            return buildinglib.BuildingTradeGoods
+        }
+
+        return buildinglib.BuildingHousing
+    }
+
+    return best
+}
+
+/* run the governor for this turn. the citizens are always rearranged, but production is only chosen
+ * when the city is producing housing or trade goods and has nothing queued, so the owner's choices
+ * are kept. returns true if production was changed
+ */
+func (city *City) RunGovernor() bool {
+    if city.Governor == GovernorNone || city.Outpost {
+        return false
+    }
+
+    city.GovernCitizens(city.Governor)
+
+    if !city.ProducingFiller() || len(city.Queue) > 0 {
+        return false
+    }
+
+    building := city.SuggestBuilding(city.Governor)
+    switch building {
+        // nothing to build, so leave the filler the owner picked
+        case buildinglib.BuildingHousing, buildinglib.BuildingTradeGoods: return false
+    }
+
+    city.ProducingBuilding = building
+    return true
+}
@@ -0,0 +1,74 @@
+package city
+
+import (
+    "testing"
+
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+)
+
+func TestGovernorFocusCycle(test *testing.T) {
+    focus := GovernorNone
+    seen := 0
+    for {
+        focus = focus.Next()
+        seen += 1
+        if focus == GovernorNone {
+            break
+        }
+
+        if seen > 10 {
+            test.Fatalf("the focus should wrap around to none")
+        }
+    }
+
+    if seen != 6 {
+        test.Errorf("expected 6 steps to wrap around but took %v", seen)
+    }
+}
+
+func TestGovernCitizens(test *testing.T) {
+    // 8 citizens, one of them a rebel, that need 4 farmers to feed the city. each farmer past
+    // those grows 2 food and each worker makes 1.5 production
+    expected := []struct {
+        Focus GovernorFocus
+        Farmers int
+        Workers int
+    }{
+        // the 8th farmer can't grow any more food than the 7th
+        {Focus: GovernorGrowth, Farmers: 7, Workers: 0},
+        {Focus: GovernorProduction, Farmers: 4, Workers: 3},
+        {Focus: GovernorGold, Farmers: 4, Workers: 3},
+        {Focus: GovernorResearch, Farmers: 4, Workers: 3},
+        // just enough farmers for a surplus of balancedFoodSurplus
+        {Focus: GovernorBalanced, Farmers: 5, Workers: 2},
+    }
+
+    for _, check := range expected {
+        city := makeQueueCity()
+        city.Population = 8000
+
+        city.GovernCitizens(check.Focus)
+        if city.Farmers != check.Farmers || city.Workers != check.Workers || city.Rebels != 1 {
+            test.Errorf("%v should have %v farmers, %v workers and 1 rebel but had %v %v %v", check.Focus, check.Farmers, check.Workers, city.Farmers, city.Workers, city.Rebels)
+        }
+    }
+}
+
+func TestGovernorBuilding(test *testing.T) {
+    city := makeQueueCity()
+
+    if !city.SuggestBuilding(GovernorGold).IsEconomic() {
+        test.Errorf("a gold governor should pick an economic building")
+    }
+
+    city.Governor = GovernorGold
+    city.ProducingBuilding = buildinglib.BuildingHousing
+    if !city.RunGovernor() || !city.ProducingBuilding.IsEconomic() {
+        test.Errorf("the governor should have replaced housing but is producing %v", city.ProducingBuilding)
+    }
+
+    // something the owner chose is left alone
+    if city.RunGovernor() {
+        test.Errorf("the governor should not change a real project")
+    }
+}
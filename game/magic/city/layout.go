@@ -0,0 +1,69 @@
+package city
+
+import (
+    "log"
+    "slices"
+
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+)
+
+// true if a better building that replaces this one has been built
+func (city *City) WasBuildingReplaced(building buildinglib.Building) bool {
+    if building == buildinglib.BuildingNone {
+        return false
+    }
+
+    replacedBy := building.ReplacedBy()
+    return replacedBy != buildinglib.BuildingNone && (city.Buildings.Contains(replacedBy) || city.WasBuildingReplaced(replacedBy))
+}
+
+/* the buildings that are laid out in the city scape. this includes the buildings of city enchantments,
+ * but not the walls or the water buildings, which are always drawn in the same place
+ */
+func (city *City) ScapeBuildings() []buildinglib.Building {
+    var out []buildinglib.Building
+
+    for _, building := range city.Buildings.Values() {
+        width, height := building.Size()
+        if width == 0 || height == 0 || city.WasBuildingReplaced(building) {
+            continue
+        }
+
+        switch building {
+            case buildinglib.BuildingCityWalls, buildinglib.BuildingShipwrightsGuild,
+                 buildinglib.BuildingShipYard, buildinglib.BuildingMaritimeGuild:
+                continue
+        }
+
+        out = append(out, building)
+    }
+
+    for enchantment, building := range buildinglib.EnchantmentBuildings() {
+        if city.HasEnchantment(enchantment) && !slices.Contains(out, building) {
+            out = append(out, building)
+        }
+    }
+
+    // the set and map above have no order, but the layout must not depend on that
+    slices.Sort(out)
+
+    return out
+}
+
+// made from the position and race of the city, the same values the city scape was drawn from before
+// layouts were stored. cities in the same place with the same race start out with the same layout in every game
+func (city *City) layoutSeed() uint64 {
+    return uint64(city.X) << 32 | uint64(city.Y + 1000 * int(city.Race))
+}
+
+// bring the stored layout of the city scape up to date with the buildings of the city
+func (city *City) UpdateLayout() {
+    if !city.Layout.Seeded {
+        city.Layout.Seed = city.layoutSeed()
+        city.Layout.Seeded = true
+    }
+
+    if !city.Layout.Update(city.ScapeBuildings()) {
+        log.Printf("Warning: could not layout the buildings of %v", city.Name)
+    }
+}
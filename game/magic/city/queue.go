@@ -0,0 +1,258 @@
+package city
+
+import (
+    "fmt"
+    "slices"
+
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/units"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+)
+
+/* the production queue holds the projects that come after the one the city is producing now. when a
+ * building is finished, or a unit is finished and there is more in the queue, the city starts on the
+ * next item instead of falling back to housing. items that can no longer be built, because the building
+ * already exists or something it depends on was lost, are dropped when the queue is looked at.
+ */
+
+// the most items a queue can hold
+const MaxQueueSize = 12
+
+type QueueItem struct {
+    // either a building or a unit
+    Building buildinglib.Building
+    Unit units.Unit
+    // how many units to produce, always 1 for buildings
+    Count int
+}
+
+func MakeQueueBuilding(building buildinglib.Building) QueueItem {
+    return QueueItem{
+        Building: building,
+        Unit: units.UnitNone,
+        Count: 1,
+    }
+}
+
+func MakeQueueUnit(unit units.Unit, count int) QueueItem {
+    return QueueItem{
+        Building: buildinglib.BuildingNone,
+        Unit: unit,
+        Count: count,
+    }
+}
+
+func (item *QueueItem) IsUnit() bool {
+    return !item.Unit.Equals(units.UnitNone)
+}
+
+func (item *QueueItem) Name(buildingInfo buildinglib.BuildingInfos) string {
+    if item.IsUnit() {
+        if item.Count > 1 {
+            return fmt.Sprintf("%v x%v", item.Unit.Name, item.Count)
+        }
+        return item.Unit.Name
+    }
+
+    return buildingInfo.Name(item.Building)
+}
+
+/* add an item to the end of the queue. queueing the same unit as the last item adds to its count.
+ * returns false, leaving the queue as it was, if the queue is full or the item can't be produced after
+ * what is already queued
+ */
+func (city *City) Enqueue(item QueueItem) bool {
+    if item.Count < 1 || len(city.Queue) >= MaxQueueSize {
+        return false
+    }
+
+    queue := append(slices.Clone(city.Queue), item)
+    if !city.queueValidity(queue, city.ProducingBuilding)[len(queue) - 1] {
+        return false
+    }
+
+    if item.IsUnit() && len(city.Queue) > 0 {
+        last := &city.Queue[len(city.Queue) - 1]
+        if last.IsUnit() && last.Unit.Equals(item.Unit) {
+            last.Count += item.Count
+            return true
+        }
+    }
+
+    city.Queue = queue
+    return true
+}
+
+// remove one unit from the item at the given index, or the whole item if it is a building
+func (city *City) DequeueAt(index int) {
+    if index < 0 || index >= len(city.Queue) {
+        return
+    }
+
+    if city.Queue[index].Count > 1 {
+        city.Queue[index].Count -= 1
+        return
+    }
+
+    city.Queue = append(city.Queue[:index], city.Queue[index + 1:]...)
+}
+
+// move the item at the given index one place closer to the front, unless that would put a building
+// before something it depends on
+func (city *City) MoveQueuedUp(index int) bool {
+    if index < 1 || index >= len(city.Queue) {
+        return false
+    }
+
+    queue := slices.Clone(city.Queue)
+    queue[index - 1], queue[index] = queue[index], queue[index - 1]
+    if slices.Contains(city.queueValidity(queue, city.ProducingBuilding), false) {
+        return false
+    }
+
+    city.Queue = queue
+    return true
+}
+
+/* whether each item of the queue could be produced when its turn comes, if the city were producing the
+ * given building. a building is valid if it isn't built or being built already and everything it depends
+ * on exists or comes earlier in the queue. the city is not changed
+ */
+func (city *City) queueValidity(queue []QueueItem, producing buildinglib.Building) []bool {
+    have := city.Buildings.Clone()
+    if producing != buildinglib.BuildingNone {
+        have.Insert(producing)
+    }
+
+    buildable := city.GetBuildableBuildings()
+
+    var out []bool
+
+    for _, item := range queue {
+        ok := item.Count > 0
+
+        if ok && item.IsUnit() {
+            ok = item.Unit.Race == data.RaceAll || item.Unit.Race == city.Race
+            for _, required := range item.Unit.RequiredBuildings {
+                if !have.Contains(required) {
+                    ok = false
+                }
+            }
+        } else if ok {
+            switch item.Building {
+                case buildinglib.BuildingNone, buildinglib.BuildingHousing, buildinglib.BuildingTradeGoods:
+                    ok = false
+                default:
+                    ok = !have.Contains(item.Building) && buildable.Contains(item.Building)
+                    for _, dependency := range city.BuildingInfo.Dependencies(item.Building) {
+                        if !have.Contains(dependency) {
+                            ok = false
+                        }
+                    }
+            }
+
+            if ok {
+                have.Insert(item.Building)
+            }
+        }
+
+        out = append(out, ok)
+    }
+
+    return out
+}
+
+// drop the items that can't be produced when their turn comes, see queueValidity. returns the dropped items
+func (city *City) ValidateQueue() []QueueItem {
+    if len(city.Queue) == 0 {
+        return nil
+    }
+
+    var keep []QueueItem
+    var dropped []QueueItem
+
+    for i, ok := range city.queueValidity(city.Queue, city.ProducingBuilding) {
+        if ok {
+            keep = append(keep, city.Queue[i])
+        } else {
+            dropped = append(dropped, city.Queue[i])
+        }
+    }
+
+    city.Queue = keep
+    return dropped
+}
+
+/* produce the given building or unit right away, keeping the rest of the queue. returns false if the
+ * city can't produce it, such as a building that is already built or a unit of another race
+ */
+func (city *City) ChangeProduction(building buildinglib.Building, unit units.Unit) bool {
+    item := MakeQueueBuilding(building)
+    if !unit.Equals(units.UnitNone) {
+        item = MakeQueueUnit(unit, 1)
+    }
+
+    switch {
+        case item.IsUnit():
+        case building == buildinglib.BuildingNone: return false
+        // housing and trade goods can always be produced but are never queued
+        case building == buildinglib.BuildingHousing, building == buildinglib.BuildingTradeGoods:
+            city.ProducingBuilding = building
+            city.ProducingUnit = units.UnitNone
+            return true
+    }
+
+    // check the item as if nothing else were being produced
+    if !city.queueValidity([]QueueItem{item}, buildinglib.BuildingNone)[0] {
+        return false
+    }
+
+    city.ProducingBuilding = item.Building
+    city.ProducingUnit = item.Unit
+    city.ValidateQueue()
+
+    return true
+}
+
+// start producing the next item of the queue, returns false if the queue is empty
+func (city *City) StartNextQueued() bool {
+    oldBuilding := city.ProducingBuilding
+    oldUnit := city.ProducingUnit
+
+    // the current project no longer counts as being built
+    city.ProducingBuilding = buildinglib.BuildingNone
+    city.ProducingUnit = units.UnitNone
+
+    city.ValidateQueue()
+
+    if len(city.Queue) == 0 {
+        city.ProducingBuilding = oldBuilding
+        city.ProducingUnit = oldUnit
+        return false
+    }
+
+    next := city.Queue[0]
+    if next.Count > 1 {
+        city.Queue[0].Count -= 1
+    } else {
+        city.Queue = city.Queue[1:]
+    }
+
+    city.ProducingBuilding = next.Building
+    city.ProducingUnit = next.Unit
+
+    return true
+}
+
+// true if the city is producing housing or trade goods, which is what it does when there is nothing else
+func (city *City) ProducingFiller() bool {
+    if !city.ProducingUnit.Equals(units.UnitNone) {
+        return false
+    }
+
+    switch city.ProducingBuilding {
+        case buildinglib.BuildingHousing, buildinglib.BuildingTradeGoods, buildinglib.BuildingNone: return true
+    }
+
+    return false
+}
@@ -0,0 +1,194 @@
+package city
+
+import (
+    "testing"
+
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/units"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+    "github.com/kazzmir/master-of-magic/lib/fraction"
+)
+
+func makeQueueCity() *City {
+    reign := NoReign{TaxRate: fraction.Make(1, 1)}
+    city := MakeCity("Test City", 10, 10, data.RaceHighMen, nil, &Catchment{Map: makeSimpleMap()}, &NoCities{}, &reign)
+    city.Population = 6000
+    city.Farmers = 3
+    city.Workers = 3
+    city.BuildingInfo = make([]buildinglib.BuildingInfo, 40)
+
+    // the armory needs barracks
+    armory := city.BuildingInfo.GetBuildingIndex(buildinglib.BuildingArmory)
+    city.BuildingInfo[armory].BuildingDependency1 = city.BuildingInfo.GetBuildingIndex(buildinglib.BuildingBarracks)
+
+    for _, building := range []buildinglib.Building{buildinglib.BuildingBarracks, buildinglib.BuildingArmory, buildinglib.BuildingSmithy} {
+        city.BuildingInfo[city.BuildingInfo.GetBuildingIndex(building)].ConstructionCost = 10
+    }
+
+    return city
+}
+
+func TestQueueDependencies(test *testing.T) {
+    city := makeQueueCity()
+
+    if city.Enqueue(MakeQueueBuilding(buildinglib.BuildingArmory)) {
+        test.Errorf("the armory should not be queued without barracks")
+    }
+
+    if len(city.Queue) != 0 {
+        test.Errorf("queue should be empty but was %v", city.Queue)
+    }
+
+    if !city.Enqueue(MakeQueueBuilding(buildinglib.BuildingBarracks)) || !city.Enqueue(MakeQueueBuilding(buildinglib.BuildingArmory)) {
+        test.Errorf("barracks then armory should be allowed")
+    }
+
+    if city.MoveQueuedUp(1) {
+        test.Errorf("the armory should not move before the barracks")
+    }
+
+    if city.Queue[0].Building != buildinglib.BuildingBarracks {
+        test.Errorf("the queue should not have changed: %v", city.Queue)
+    }
+
+    // building the same thing twice is not allowed
+    if city.Enqueue(MakeQueueBuilding(buildinglib.BuildingBarracks)) {
+        test.Errorf("barracks should not be queued twice")
+    }
+
+    // losing the barracks means the armory can't be built anymore
+    city.Queue = city.Queue[1:]
+    dropped := city.ValidateQueue()
+    if len(dropped) != 1 || len(city.Queue) != 0 {
+        test.Errorf("the armory should have been dropped: %v", dropped)
+    }
+}
+
+func TestQueueUnits(test *testing.T) {
+    city := makeQueueCity()
+
+    city.Enqueue(MakeQueueUnit(units.HighMenSpearmen, 1))
+    city.Enqueue(MakeQueueUnit(units.HighMenSpearmen, 2))
+
+    if len(city.Queue) != 1 || city.Queue[0].Count != 3 {
+        test.Errorf("the same unit should add to the count: %v", city.Queue)
+    }
+
+    city.DequeueAt(0)
+    if city.Queue[0].Count != 2 {
+        test.Errorf("dequeue should remove one unit: %v", city.Queue)
+    }
+
+    for range 2 {
+        if !city.StartNextQueued() || !city.ProducingUnit.Equals(units.HighMenSpearmen) {
+            test.Errorf("should be producing spearmen")
+        }
+    }
+
+    if len(city.Queue) != 0 {
+        test.Errorf("queue should be empty: %v", city.Queue)
+    }
+
+    // nothing left, so keep producing the same thing
+    if city.StartNextQueued() || !city.ProducingUnit.Equals(units.HighMenSpearmen) {
+        test.Errorf("should still be producing spearmen")
+    }
+}
+
+func TestQueueNextTurn(test *testing.T) {
+    city := makeQueueCity()
+    city.ProducingBuilding = buildinglib.BuildingSmithy
+    city.ProducingUnit = units.UnitNone
+    city.Production = 100
+
+    city.Enqueue(MakeQueueBuilding(buildinglib.BuildingBarracks))
+
+    city.DoNextTurn(nil)
+
+    if !city.Buildings.Contains(buildinglib.BuildingSmithy) {
+        test.Errorf("the smithy should have been built")
+    }
+
+    if city.ProducingBuilding != buildinglib.BuildingBarracks {
+        test.Errorf("should be producing barracks from the queue but was %v", city.ProducingBuilding)
+    }
+
+    if len(city.Queue) != 0 {
+        test.Errorf("queue should be empty: %v", city.Queue)
+    }
+}
+
+func TestChangeProduction(test *testing.T) {
+    city := makeQueueCity()
+    city.ProducingBuilding = buildinglib.BuildingHousing
+    city.Enqueue(MakeQueueBuilding(buildinglib.BuildingSmithy))
+    city.Enqueue(MakeQueueBuilding(buildinglib.BuildingBarracks))
+
+    if city.ChangeProduction(buildinglib.BuildingArmory, units.UnitNone) {
+        test.Errorf("the armory should not be produced without barracks")
+    }
+
+    if city.ChangeProduction(buildinglib.BuildingNone, units.OrcSpearmen) {
+        test.Errorf("a unit of another race should not be produced")
+    }
+
+    if city.ProducingBuilding != buildinglib.BuildingHousing {
+        test.Errorf("production should not change when it is not allowed: %v", city.ProducingBuilding)
+    }
+
+    if !city.ChangeProduction(buildinglib.BuildingBarracks, units.UnitNone) || city.ProducingBuilding != buildinglib.BuildingBarracks {
+        test.Errorf("should be producing barracks")
+    }
+
+    // the queued barracks are now being built
+    if len(city.Queue) != 1 || city.Queue[0].Building != buildinglib.BuildingSmithy {
+        test.Errorf("the queue should only have the smithy: %v", city.Queue)
+    }
+
+    if !city.ChangeProduction(buildinglib.BuildingNone, units.HighMenSpearmen) || !city.ProducingUnit.Equals(units.HighMenSpearmen) || city.ProducingBuilding != buildinglib.BuildingNone {
+        test.Errorf("should be producing spearmen")
+    }
+
+    if !city.ChangeProduction(buildinglib.BuildingTradeGoods, units.UnitNone) || !city.ProducingFiller() {
+        test.Errorf("should be producing trade goods")
+    }
+}
+
+func TestQueueFull(test *testing.T) {
+    city := makeQueueCity()
+
+    for range MaxQueueSize / 2 {
+        city.Enqueue(MakeQueueUnit(units.HighMenSpearmen, 1))
+        city.Enqueue(MakeQueueUnit(units.HighMenSettlers, 1))
+    }
+
+    if len(city.Queue) != MaxQueueSize {
+        test.Fatalf("the queue should be full but has %v items", len(city.Queue))
+    }
+
+    // the last item is settlers, but a full queue takes nothing more
+    if city.Enqueue(MakeQueueUnit(units.HighMenSettlers, 1)) || city.Queue[MaxQueueSize - 1].Count != 1 {
+        test.Errorf("a unit should not be added to a full queue")
+    }
+}
+
+func TestEnqueueKeepsOtherItems(test *testing.T) {
+    city := makeQueueCity()
+    city.Enqueue(MakeQueueBuilding(buildinglib.BuildingBarracks))
+    city.Enqueue(MakeQueueBuilding(buildinglib.BuildingArmory))
+
+    // the barracks were built some other way, so the queued barracks are stale
+    city.Buildings.Insert(buildinglib.BuildingBarracks)
+
+    if !city.Enqueue(MakeQueueBuilding(buildinglib.BuildingSmithy)) {
+        test.Errorf("the smithy should be added even though another item is stale")
+    }
+
+    if len(city.Queue) != 3 {
+        test.Errorf("enqueue should only check the new item: %v", city.Queue)
+    }
+
+    if city.Enqueue(MakeQueueUnit(units.OrcSpearmen, 1)) || len(city.Queue) != 3 {
+        test.Errorf("a unit of another race should not be queued: %v", city.Queue)
+    }
+}
@@ -0,0 +1,40 @@
+package city
+
+/* rebellions are an optional rule that goes beyond the original game, enabled with
+ * setup.NewGameSettings.Rebellions. a city where at least half of the citizens are rebels for
+ * several turns in a row rises up at the end of the turn. the game decides what the rebels do: take
+ * up arms, secede to the neutral player, or defect to a famous wizard nearby, see game/rebellion.go
+ */
+
+// the part of the citizens that must be rebels before the city is at risk
+const RebelThreshold = 0.5
+
+// how many turns the city can stay above the threshold before the rebels rise up
+const RebellionTurns = 3
+
+// the part of the citizens that are rebels, from 0 to 1
+func (city *City) RebelFraction() float64 {
+    if city.Outpost || city.Citizens() == 0 {
+        return 0
+    }
+
+    return float64(min(city.ComputeUnrest(), city.Citizens())) / float64(city.Citizens())
+}
+
+/* called at the end of each turn. returns a warning while the rebels are growing restless, and a
+ * rebellion once they have been above the threshold for RebellionTurns turns
+ */
+func (city *City) CheckRebellion() CityEvent {
+    if city.RebelFraction() < RebelThreshold {
+        city.RebelTurns = 0
+        return nil
+    }
+
+    city.RebelTurns += 1
+    if city.RebelTurns >= RebellionTurns {
+        city.RebelTurns = 0
+        return &CityEventRebellion{}
+    }
+
+    return &CityEventRebelsRising{TurnsLeft: RebellionTurns - city.RebelTurns}
+}
@@ -24,6 +24,44 @@ type SerializedCity struct {
     Production float32
     ProducingBuilding buildinglib.Building
     ProducingUnit units.SerializedUnit
+    Queue []SerializedQueueItem
+    Governor GovernorFocus
+    Layout buildinglib.CityLayout
+    FoodImported int
+    StarvingTurns int
+    RebelTurns int
+}
+
+type SerializedQueueItem struct {
+    Building buildinglib.Building
+    Unit units.SerializedUnit
+    Count int
+}
+
+func serializeQueue(queue []QueueItem) []SerializedQueueItem {
+    var out []SerializedQueueItem
+    for _, item := range queue {
+        out = append(out, SerializedQueueItem{
+            Building: item.Building,
+            Unit: units.SerializeUnit(item.Unit),
+            Count: item.Count,
+        })
+    }
+
+    return out
+}
+
+func reconstructQueue(serialized []SerializedQueueItem) []QueueItem {
+    var out []QueueItem
+    for _, item := range serialized {
+        out = append(out, QueueItem{
+            Building: item.Building,
+            Unit: units.DeserializeUnit(item.Unit),
+            Count: item.Count,
+        })
+    }
+
+    return out
 }
 
 func SerializeCity(city *City) SerializedCity {
@@ -44,6 +82,12 @@ func SerializeCity(city *City) SerializedCity {
         Production: city.Production,
         ProducingBuilding: city.ProducingBuilding,
         ProducingUnit: units.SerializeUnit(city.ProducingUnit),
+        Queue: serializeQueue(city.Queue),
+        Governor: city.Governor,
+        Layout: city.Layout,
+        FoodImported: city.FoodImported,
+        StarvingTurns: city.StarvingTurns,
+        RebelTurns: city.RebelTurns,
     }
 }
 
@@ -63,6 +107,12 @@ func ReconstructCity(serialized *SerializedCity, catchmentProvider CatchmentProv
         Production: serialized.Production,
         ProducingBuilding: serialized.ProducingBuilding,
         ProducingUnit: units.DeserializeUnit(serialized.ProducingUnit),
+        Queue: reconstructQueue(serialized.Queue),
+        Governor: serialized.Governor,
+        Layout: serialized.Layout,
+        FoodImported: serialized.FoodImported,
+        StarvingTurns: serialized.StarvingTurns,
+        RebelTurns: serialized.RebelTurns,
 
         CatchmentProvider: catchmentProvider,
         CityServices: cityServices,
@@ -0,0 +1,436 @@
+package citylistview
+
+import (
+    "fmt"
+    "log"
+    "cmp"
+    "slices"
+    "strings"
+    "maps"
+    "image"
+    "image/color"
+
+    "github.com/kazzmir/master-of-magic/lib/font"
+    "github.com/kazzmir/master-of-magic/game/magic/util"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
+    uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
+    "github.com/kazzmir/master-of-magic/game/magic/cityview"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/* the manage layout lists every city in small print so that a large empire fits on one screen. the
+ * cities can be sorted by any column and filtered, and several cities can be selected to change their
+ * production at once. the print is half the usual size, so the layout is only offered when the screen
+ * is scaled up enough to read it.
+ */
+
+type CitySort int
+
+const (
+    CitySortName CitySort = iota
+    CitySortPopulation
+    CitySortProduction
+    CitySortGold
+    CitySortUnrest
+    CitySortUpkeep
+)
+
+func compareCities(sortBy CitySort, a *citylib.City, b *citylib.City) int {
+    switch sortBy {
+        case CitySortPopulation: return cmp.Compare(a.Population, b.Population)
+        case CitySortProduction: return cmp.Compare(a.WorkProductionRate(), b.WorkProductionRate())
+        case CitySortGold: return cmp.Compare(a.GoldSurplus(), b.GoldSurplus())
+        case CitySortUnrest: return cmp.Compare(a.ComputeUnrest(), b.ComputeUnrest())
+        case CitySortUpkeep: return cmp.Compare(a.ComputeUpkeep(), b.ComputeUpkeep())
+    }
+
+    return strings.Compare(a.Name, b.Name)
+}
+
+// the cities that pass the filters, in the chosen order. ties are broken by name
+func (view *CityListScreen) manageCities() []*citylib.City {
+    var out []*citylib.City
+    for _, city := range view.Player.Cities {
+        if city.Plane == data.PlaneArcanus && !view.ShowArcanus || city.Plane == data.PlaneMyrror && !view.ShowMyrror {
+            continue
+        }
+
+        if view.FilterRace != data.RaceNone && city.Race != view.FilterRace {
+            continue
+        }
+
+        // cities that will go on producing housing or trade goods forever
+        if view.FilterNothingQueued && !(city.ProducingFiller() && len(city.Queue) == 0) {
+            continue
+        }
+
+        out = append(out, city)
+    }
+
+    slices.SortFunc(out, func(a *citylib.City, b *citylib.City) int {
+        result := compareCities(view.Sort, a, b)
+        if view.SortDescending {
+            result = -result
+        }
+
+        if result == 0 {
+            return strings.Compare(a.Name, b.Name)
+        }
+
+        return result
+    })
+
+    return out
+}
+
+// the races of the player's cities, for the race filter
+func (view *CityListScreen) cityRaces() []data.Race {
+    races := make(map[data.Race]bool)
+    for _, city := range view.Player.Cities {
+        races[city.Race] = true
+    }
+
+    out := slices.Collect(maps.Keys(races))
+    slices.SortFunc(out, func(a data.Race, b data.Race) int {
+        return strings.Compare(a.String(), b.String())
+    })
+
+    return out
+}
+
+// change the production of every selected city to what is chosen for the first one
+func (view *CityListScreen) setSelectedProduction(cities []*citylib.City) {
+    var selected []*citylib.City
+    for _, city := range cities {
+        if view.Selected[city] {
+            selected = append(selected, city)
+        }
+    }
+
+    if len(selected) == 0 {
+        view.Message = "Select cities first"
+        return
+    }
+
+    buildScreen := cityview.MakeBuildScreen(view.Cache, selected[0])
+    view.CurrentBuildScreen = buildScreen
+    view.BuildScreenUpdate = func(){
+        buildScreen.Apply()
+
+        changed := 1
+        for _, city := range selected[1:] {
+            if city.ChangeProduction(buildScreen.ProducingBuilding, buildScreen.ProducingUnit) {
+                changed += 1
+            }
+        }
+
+        view.Message = fmt.Sprintf("Production changed in %v of %v cities", changed, len(selected))
+        view.UI = view.MakeUI()
+    }
+}
+
+func (view *CityListScreen) makeManageUI() *uilib.UI {
+    loader, err := fontslib.Loader(view.Cache)
+    if err != nil {
+        log.Printf("Error: city list: unable to load font: %v", err)
+        return nil
+    }
+
+    titleFont := loader(fontslib.BigOrangeGradient2)
+    normalFont := loader(fontslib.SmallWhite)
+    yellowFont := loader(fontslib.LightFontSmall)
+
+    if view.Selected == nil {
+        view.Selected = make(map[*citylib.City]bool)
+    }
+
+    cities := view.manageCities()
+
+    panelColor := util.PremultiplyAlpha(color.RGBA{A: 120})
+    highlightColor := util.PremultiplyAlpha(color.RGBA{R: 255, G: 255, B: 255, A: 60})
+    selectColor := util.PremultiplyAlpha(color.RGBA{R: 0xf9, G: 0xdb, B: 0x4c, A: 0xff})
+
+    // print at half size, see scale.HalfSizeReadable
+    printSmall := func(useFont *font.Font, screen *ebiten.Image, x float64, y float64, justify font.FontJustify, text string) {
+        useFont.PrintOptions(screen, x * 2, y * 2, font.FontOptions{DropShadow: true, Justify: justify, Scale: scale.ScaleAmount / 2}, text)
+    }
+
+    listRect := image.Rect(4, 20, 316, 172)
+    rowHeight := 5
+    firstRowY := listRect.Min.Y + 8
+    visibleRows := (listRect.Max.Y - firstRowY) / rowHeight
+
+    view.FirstRow = max(0, min(view.FirstRow, len(cities) - visibleRows))
+
+    var highlightedCity *citylib.City
+
+    ui := &uilib.UI{
+        Draw: func(ui *uilib.UI, screen *ebiten.Image) {
+            background, _ := view.ImageCache.GetImage("reload.lbx", 0, 0)
+            var options ebiten.DrawImageOptions
+            scale.DrawScaled(screen, background, &options)
+
+            titleFont.PrintOptions(screen, 160, 5, font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount}, fmt.Sprintf("The Cities Of %v", view.Player.Wizard.Name))
+
+            vector.FillRect(screen, scale.Scale(float32(listRect.Min.X)), scale.Scale(float32(listRect.Min.Y)), scale.Scale(float32(listRect.Dx())), scale.Scale(float32(listRect.Dy())), panelColor, false)
+
+            ui.StandardDraw(screen)
+
+            status := fmt.Sprintf("%v of %v cities", len(cities), len(view.Player.Cities))
+            if view.Message != "" {
+                status = view.Message
+            }
+            printSmall(normalFont, screen, 6, 174, font.FontJustifyLeft, status)
+            printSmall(normalFont, screen, 314, 174, font.FontJustifyRight, fmt.Sprintf("%vGP %vMP", view.Player.Gold, view.Player.Mana))
+
+            if highlightedCity != nil {
+                printSmall(yellowFont, screen, 160, 174, font.FontJustifyCenter, fmt.Sprintf("%v: %v", highlightedCity.Name, highlightedCity.ProducingString()))
+            }
+        },
+    }
+
+    var elements []*uilib.UIElement
+
+    type column struct {
+        Name string
+        X int
+        Justify font.FontJustify
+        Sort CitySort
+        Value func(*citylib.City) string
+    }
+
+    noSort := CitySort(-1)
+
+    columns := []column{
+        {Name: "Name", X: 12, Sort: CitySortName, Value: func(city *citylib.City) string { return city.Name }},
+        {Name: "Race", X: 62, Sort: noSort, Value: func(city *citylib.City) string { return city.Race.String() }},
+        {Name: "Pop", X: 112, Justify: font.FontJustifyRight, Sort: CitySortPopulation, Value: func(city *citylib.City) string { return fmt.Sprintf("%v", city.Citizens()) }},
+        {Name: "Prd", X: 128, Justify: font.FontJustifyRight, Sort: CitySortProduction, Value: func(city *citylib.City) string { return fmt.Sprintf("%v", int(city.WorkProductionRate())) }},
+        {Name: "Gold", X: 144, Justify: font.FontJustifyRight, Sort: CitySortGold, Value: func(city *citylib.City) string { return fmt.Sprintf("%v", city.GoldSurplus()) }},
+        {Name: "Unrest", X: 164, Justify: font.FontJustifyRight, Sort: CitySortUnrest, Value: func(city *citylib.City) string { return fmt.Sprintf("%v", city.ComputeUnrest()) }},
+        {Name: "Upkeep", X: 186, Justify: font.FontJustifyRight, Sort: CitySortUpkeep, Value: func(city *citylib.City) string { return fmt.Sprintf("%v", city.ComputeUpkeep()) }},
+        {Name: "Producing", X: 192, Sort: noSort, Value: func(city *citylib.City) string { return city.ProducingString() }},
+        {Name: "Time", X: 312, Justify: font.FontJustifyRight, Sort: noSort, Value: func(city *citylib.City) string { return fmt.Sprintf("%v", city.ProducingTurnsLeft()) }},
+    }
+
+    // clicking a header sorts by that column, clicking it again reverses the order
+    for _, column := range columns {
+        width := int(normalFont.MeasureTextWidth(column.Name, 0.5)) + 1
+        rect := image.Rect(column.X, listRect.Min.Y + 1, column.X + width, listRect.Min.Y + 6)
+        if column.Justify == font.FontJustifyRight {
+            rect = rect.Sub(image.Pt(width, 0))
+        }
+
+        elements = append(elements, &uilib.UIElement{
+            Rect: rect,
+            PlaySoundLeftClick: column.Sort != noSort,
+            LeftClick: func(element *uilib.UIElement){
+                if column.Sort == noSort {
+                    return
+                }
+
+                if view.Sort == column.Sort {
+                    view.SortDescending = !view.SortDescending
+                } else {
+                    view.Sort = column.Sort
+                    // bigger numbers are usually more interesting
+                    view.SortDescending = column.Sort != CitySortName
+                }
+                view.UI = view.MakeUI()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                useFont := normalFont
+                if column.Sort == view.Sort {
+                    useFont = yellowFont
+                }
+                printSmall(useFont, screen, float64(column.X), float64(listRect.Min.Y + 2), column.Justify, column.Name)
+            },
+        })
+    }
+
+    rows := cities[view.FirstRow:min(len(cities), view.FirstRow + visibleRows)]
+    for i, city := range rows {
+        rowY := firstRowY + i * rowHeight
+
+        // the box on the left selects the city for bulk actions
+        elements = append(elements, &uilib.UIElement{
+            Rect: image.Rect(listRect.Min.X + 1, rowY, listRect.Min.X + 7, rowY + rowHeight),
+            LeftClick: func(element *uilib.UIElement){
+                view.Selected[city] = !view.Selected[city]
+                view.Message = ""
+            },
+            Inside: func(element *uilib.UIElement, x int, y int){
+                highlightedCity = city
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                x := float32(listRect.Min.X + 2)
+                y := float32(rowY + 1)
+                vector.StrokeRect(screen, scale.Scale(x), scale.Scale(y), scale.Scale(float32(3)), scale.Scale(float32(3)), float32(scale.ScaleAmount / 2), selectColor, false)
+                if view.Selected[city] {
+                    vector.FillRect(screen, scale.Scale(x + 1), scale.Scale(y + 1), scale.Scale(float32(1)), scale.Scale(float32(1)), selectColor, false)
+                }
+            },
+        })
+
+        elements = append(elements, &uilib.UIElement{
+            Rect: image.Rect(listRect.Min.X + 8, rowY, listRect.Max.X, rowY + rowHeight),
+            LeftClickRelease: func(element *uilib.UIElement){
+                view.DoSelectCity(city)
+                view.State = CityListScreenStateDone
+            },
+            RightClick: func(element *uilib.UIElement){
+                buildScreen := cityview.MakeBuildScreen(view.Cache, city)
+                view.CurrentBuildScreen = buildScreen
+                view.BuildScreenUpdate = func(){
+                    buildScreen.Apply()
+                }
+            },
+            Inside: func(element *uilib.UIElement, x int, y int){
+                highlightedCity = city
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                if highlightedCity == city {
+                    vector.FillRect(screen, scale.Scale(float32(element.Rect.Min.X)), scale.Scale(float32(element.Rect.Min.Y)), scale.Scale(float32(element.Rect.Dx())), scale.Scale(float32(element.Rect.Dy())), highlightColor, false)
+                }
+
+                for _, column := range columns {
+                    printSmall(normalFont, screen, float64(column.X), float64(rowY + 1), column.Justify, column.Value(city))
+                }
+            },
+        })
+    }
+
+    // scrolling anywhere moves the list
+    elements = append(elements, &uilib.UIElement{
+        Layer: -1,
+        Rect: image.Rect(0, 0, data.ScreenWidth, data.ScreenHeight),
+        Scroll: func(element *uilib.UIElement, x float64, y float64){
+            if y < 0 && view.FirstRow < len(cities) - visibleRows {
+                view.FirstRow += 1
+                view.UI = view.MakeUI()
+            } else if y > 0 && view.FirstRow > 0 {
+                view.FirstRow -= 1
+                view.UI = view.MakeUI()
+            }
+        },
+    })
+
+    makeButton := func(rect image.Rectangle, text func() string, action func()) *uilib.UIElement {
+        return &uilib.UIElement{
+            Rect: rect,
+            PlaySoundLeftClick: true,
+            LeftClick: func(element *uilib.UIElement){
+                action()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                vector.FillRect(screen, scale.Scale(float32(rect.Min.X)), scale.Scale(float32(rect.Min.Y)), scale.Scale(float32(rect.Dx())), scale.Scale(float32(rect.Dy())), panelColor, false)
+                printSmall(normalFont, screen, float64(rect.Min.X + rect.Dx() / 2), float64(rect.Min.Y + 2), font.FontJustifyCenter, text())
+            },
+        }
+    }
+
+    races := view.cityRaces()
+
+    buttons := []struct {
+        Text func() string
+        Action func()
+    }{
+        {
+            Text: func() string {
+                switch {
+                    case view.ShowArcanus && !view.ShowMyrror: return "Plane: Arcanus"
+                    case view.ShowMyrror && !view.ShowArcanus: return "Plane: Myrror"
+                }
+                return "Plane: All"
+            },
+            Action: func(){
+                // cycle all -> arcanus -> myrror
+                switch {
+                    case view.ShowArcanus && view.ShowMyrror: view.ShowMyrror = false
+                    case view.ShowArcanus: view.ShowArcanus, view.ShowMyrror = false, true
+                    default: view.ShowArcanus = true
+                }
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+        },
+        {
+            Text: func() string {
+                if view.FilterRace == data.RaceNone {
+                    return "Race: All"
+                }
+                return "Race: " + view.FilterRace.String()
+            },
+            Action: func(){
+                index := slices.Index(races, view.FilterRace)
+                if index + 1 < len(races) {
+                    view.FilterRace = races[index + 1]
+                } else {
+                    view.FilterRace = data.RaceNone
+                }
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+        },
+        {
+            Text: func() string {
+                if view.FilterNothingQueued {
+                    return "Nothing Queued"
+                }
+                return "Any Production"
+            },
+            Action: func(){
+                view.FilterNothingQueued = !view.FilterNothingQueued
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+        },
+        {
+            Text: func() string { return "Select All" },
+            Action: func(){
+                all := !slices.ContainsFunc(cities, func(city *citylib.City) bool {
+                    return !view.Selected[city]
+                })
+                for _, city := range cities {
+                    view.Selected[city] = !all
+                }
+                view.Message = ""
+            },
+        },
+        {
+            Text: func() string { return "Set Production" },
+            Action: func(){
+                view.setSelectedProduction(cities)
+            },
+        },
+        {
+            Text: func() string { return "Classic" },
+            Action: func(){
+                view.Manage = false
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+        },
+        {
+            Text: func() string { return "Done" },
+            Action: func(){
+                view.State = CityListScreenStateDone
+            },
+        },
+    }
+
+    buttonWidth := 312 / len(buttons)
+    for i, button := range buttons {
+        x := 4 + i * buttonWidth
+        elements = append(elements, makeButton(image.Rect(x, 182, x + buttonWidth - 2, 190), button.Text, button.Action))
+    }
+
+    ui.SetElementsFromArray(elements)
+
+    return ui
+}
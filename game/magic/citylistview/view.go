@@ -40,6 +40,17 @@ type CityListScreen struct {
     DrawMinimap func(*ebiten.Image, int, int, data.Plane, uint64)
     DoSelectCity func(*citylib.City)
     FirstRow int
+
+    // the sortable, filterable layout, see manage.go
+    Manage bool
+    Sort CitySort
+    SortDescending bool
+    ShowArcanus bool
+    ShowMyrror bool
+    FilterRace data.Race
+    FilterNothingQueued bool
+    Selected map[*citylib.City]bool
+    Message string
 }
 
 func MakeCityListScreen(cache *lbx.LbxCache, player *playerlib.Player, drawMinimap func(*ebiten.Image, int, int, data.Plane, uint64), selectCity func(*citylib.City)) *CityListScreen {
@@ -51,6 +62,9 @@ func MakeCityListScreen(cache *lbx.LbxCache, player *playerlib.Player, drawMinim
         DrawMinimap: drawMinimap,
         DoSelectCity: selectCity,
         FirstRow: 0,
+        ShowArcanus: true,
+        ShowMyrror: true,
+        FilterRace: data.RaceNone,
     }
 
     view.UI = view.MakeUI()
@@ -59,6 +73,10 @@ func MakeCityListScreen(cache *lbx.LbxCache, player *playerlib.Player, drawMinim
 }
 
 func (view *CityListScreen) MakeUI() *uilib.UI {
+    if view.Manage && scale.HalfSizeReadable() {
+        return view.makeManageUI()
+    }
+
     fontLbx, err := view.Cache.GetLbxFile("fonts.lbx")
     if err != nil {
         log.Printf("Unable to read fonts.lbx: %v", err)
@@ -128,6 +146,38 @@ func (view *CityListScreen) MakeUI() *uilib.UI {
 
     var elements []*uilib.UIElement
 
+    // cycles the governor of the highlighted city
+    elements = append(elements, &uilib.UIElement{
+        Rect: image.Rect(99, 167, 190, 175),
+        LeftClick: func(element *uilib.UIElement){
+            if highlightedCity != nil {
+                highlightedCity.Governor = highlightedCity.Governor.Next()
+                highlightedCity.RunGovernor()
+            }
+        },
+        Draw: func(element *uilib.UIElement, screen *ebiten.Image) {
+            if highlightedCity != nil {
+                normalFont.Print(screen, float64(element.Rect.Min.X), float64(element.Rect.Min.Y), scale.ScaleAmount, ebiten.ColorScale{}, fmt.Sprintf("Governor: %v", highlightedCity.Governor))
+            }
+        },
+    })
+
+    // switches to the sortable layout, whose small print is only readable when scaled up
+    if scale.HalfSizeReadable() {
+        elements = append(elements, &uilib.UIElement{
+            Rect: image.Rect(99, 177, 150, 185),
+            PlaySoundLeftClick: true,
+            LeftClick: func(element *uilib.UIElement){
+                view.Manage = true
+                view.FirstRow = 0
+                view.UI = view.MakeUI()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image) {
+                normalFont.Print(screen, float64(element.Rect.Min.X), float64(element.Rect.Min.Y), scale.ScaleAmount, ebiten.ColorScale{}, "Manage...")
+            },
+        })
+    }
+
     cities := slices.Collect(maps.Values(view.Player.Cities))
     slices.SortFunc(cities, func(a *citylib.City, b *citylib.City) int {
         return strings.Compare(a.Name, b.Name)
@@ -161,8 +211,7 @@ func (view *CityListScreen) MakeUI() *uilib.UI {
                 buildScreen := cityview.MakeBuildScreen(view.Cache, city)
                 view.CurrentBuildScreen = buildScreen
                 view.BuildScreenUpdate = func(){
-                    city.ProducingBuilding = buildScreen.ProducingBuilding
-                    city.ProducingUnit = buildScreen.ProducingUnit
+                    buildScreen.Apply()
                 }
             },
             Inside: func(element *uilib.UIElement, x int, y int){
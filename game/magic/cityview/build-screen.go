@@ -3,6 +3,7 @@ package cityview
 import (
     "fmt"
     "image"
+    "image/color"
     "slices"
     "cmp"
     "log"
@@ -21,7 +22,7 @@ import (
     helplib "github.com/kazzmir/master-of-magic/game/magic/help"
     fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
     "github.com/hajimehoshi/ebiten/v2"
-    // "github.com/hajimehoshi/ebiten/v2/vector"
+    "github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 type BuildScreenState int
@@ -39,6 +40,8 @@ type BuildScreen struct {
     State BuildScreenState
     ProducingBuilding buildinglib.Building
     ProducingUnit units.Unit
+    // what the city produces afterwards
+    Queue []citylib.QueueItem
 }
 
 func MakeBuildScreen(cache *lbx.LbxCache, city *citylib.City) *BuildScreen {
@@ -61,6 +64,7 @@ func MakeBuildScreen(cache *lbx.LbxCache, city *citylib.City) *BuildScreen {
         State: BuildScreenRunning,
         ProducingBuilding: city.ProducingBuilding,
         ProducingUnit: city.ProducingUnit,
+        Queue: slices.Clone(city.Queue),
     }
 
     ui := makeBuildUI(cache, &imageCache, city, buildScreen, doCancel, doOk)
@@ -78,6 +82,33 @@ func (buildScreen *BuildScreen) Ok() {
     buildScreen.State = BuildScreenOk
 }
 
+// a copy of the city producing what is chosen on the build screen, to edit the queue against
+func (buildScreen *BuildScreen) queueCity() *citylib.City {
+    city := *buildScreen.City
+    city.ProducingBuilding = buildScreen.ProducingBuilding
+    city.ProducingUnit = buildScreen.ProducingUnit
+    city.Queue = slices.Clone(buildScreen.Queue)
+    return &city
+}
+
+func (buildScreen *BuildScreen) editQueue(edit func(*citylib.City)) {
+    city := buildScreen.queueCity()
+    edit(city)
+    buildScreen.Queue = city.Queue
+}
+
+// set the production of the city to what was chosen
+func (buildScreen *BuildScreen) Apply() {
+    buildScreen.City.ProducingBuilding = buildScreen.ProducingBuilding
+    buildScreen.City.ProducingUnit = buildScreen.ProducingUnit
+    buildScreen.City.Queue = buildScreen.Queue
+    buildScreen.City.ValidateQueue()
+}
+
+func shiftPressed() bool {
+    return ebiten.IsKeyPressed(ebiten.KeyShiftLeft) || ebiten.IsKeyPressed(ebiten.KeyShiftRight)
+}
+
 func combineStrings(all []string) string {
     if len(all) == 0 {
         return ""
@@ -164,6 +195,9 @@ func makeBuildUI(cache *lbx.LbxCache, imageCache *util.ImageCache, city *citylib
 
     var selectedElement *uilib.UIElement
 
+    // the queue is drawn on top of the description, so it is redone whenever the main element is
+    var updateQueue func()
+
     updateMainElementBuilding := func(building buildinglib.Building){
         descriptionWrapped := fonts.DescriptionFont.CreateWrappedText(float64(155), 1, buildDescriptions.Get(building))
 
@@ -248,6 +282,8 @@ func makeBuildUI(cache *lbx.LbxCache, imageCache *util.ImageCache, city *citylib
                 */
             },
         })
+
+        updateQueue()
     }
 
     updateMainElementUnit := func(unit units.Unit){
@@ -291,8 +327,89 @@ func makeBuildUI(cache *lbx.LbxCache, imageCache *util.ImageCache, city *citylib
 
         mainGroup.AddElements(unitview.MakeUnitAbilitiesElements(mainGroup, cache, imageCache, bannerUnit, fonts.MediumFont, 85, 108, &ui.Counter, 0, &getAlpha, true, 0, false))
         // ui.AddElements(mainElements)
+
+        updateQueue()
     }
 
+    /* the queue is shown under the description. clicking an item removes it, or one unit of it,
+     * and shift clicking moves it earlier
+     */
+    queueGroup := uilib.MakeGroup()
+    updateQueue = func(){
+        ui.RemoveGroup(queueGroup)
+        queueGroup = uilib.MakeGroup()
+        ui.AddGroup(queueGroup)
+
+        queueX := 85
+        queueY := 163
+        queueWidth := 155
+
+        queueGroup.AddElement(&uilib.UIElement{
+            Draw: func(this *uilib.UIElement, screen *ebiten.Image) {
+                // keep out of the way of the description when there is nothing queued
+                if len(buildScreen.Queue) == 0 {
+                    vector.FillRect(screen, scale.Scale(float32(queueX - 1)), scale.Scale(float32(queueY + 7)), scale.Scale(float32(queueWidth + 2)), scale.Scale(float32(9)), color.RGBA{A: 200}, false)
+                    fonts.SmallFont.PrintOptions(screen, float64(queueX), float64(queueY + 8), font.FontOptions{Scale: scale.ScaleAmount}, "Shift click to add to the queue")
+                } else {
+                    vector.FillRect(screen, scale.Scale(float32(queueX - 1)), scale.Scale(float32(queueY - 1)), scale.Scale(float32(queueWidth + 2)), scale.Scale(float32(17)), color.RGBA{A: 200}, false)
+                }
+            },
+        })
+
+        x := queueX
+        y := queueY
+        for i, item := range buildScreen.Queue {
+            text := fmt.Sprintf("%v.%v", i + 1, item.Name(city.BuildingInfo))
+            width := int(fonts.SmallFont.MeasureTextWidth(text, 1)) + 4
+
+            if x + width > queueX + queueWidth {
+                x = queueX
+                y += 8
+                // only two rows fit
+                if y > queueY + 8 {
+                    break
+                }
+            }
+
+            itemX := x
+            itemY := y
+            var hover bool
+
+            queueGroup.AddElement(&uilib.UIElement{
+                Rect: image.Rect(itemX, itemY, itemX + width, itemY + 7),
+                PlaySoundLeftClick: true,
+                LeftClick: func(this *uilib.UIElement) {
+                    if shiftPressed() {
+                        buildScreen.editQueue(func(city *citylib.City){
+                            city.MoveQueuedUp(i)
+                        })
+                    } else {
+                        buildScreen.editQueue(func(city *citylib.City){
+                            city.DequeueAt(i)
+                        })
+                    }
+                    updateQueue()
+                },
+                Inside: func(this *uilib.UIElement, x int, y int){
+                    hover = true
+                },
+                NotInside: func(this *uilib.UIElement){
+                    hover = false
+                },
+                Draw: func(this *uilib.UIElement, screen *ebiten.Image) {
+                    if hover {
+                        vector.FillRect(screen, scale.Scale(float32(itemX - 1)), scale.Scale(float32(itemY - 1)), scale.Scale(float32(width - 2)), scale.Scale(float32(8)), color.RGBA{R: 0x60, G: 0x50, B: 0x20, A: 0xff}, false)
+                    }
+                    fonts.SmallFont.PrintOptions(screen, float64(itemX), float64(itemY), font.FontOptions{Scale: scale.ScaleAmount}, text)
+                },
+            })
+
+            x += width
+        }
+    }
+
+    defer updateQueue()
+
     if err == nil {
         possibleBuildings := city.ComputePossibleBuildings(false)
         for i, building := range slices.SortedFunc(slices.Values(possibleBuildings.Values()), func (a, b buildinglib.Building) int {
@@ -306,10 +423,21 @@ func makeBuildUI(cache *lbx.LbxCache, imageCache *util.ImageCache, city *citylib
             element := &uilib.UIElement{
                 Rect: image.Rect(x1, y1, x2, y2),
                 DoubleLeftClick: func(this *uilib.UIElement) {
-                    doOk()
+                    if !shiftPressed() {
+                        doOk()
+                    }
                 },
                 PlaySoundLeftClick: true,
                 LeftClick: func(this *uilib.UIElement) {
+                    // shift click adds to the queue instead
+                    if shiftPressed() {
+                        buildScreen.editQueue(func(city *citylib.City){
+                            city.Enqueue(citylib.MakeQueueBuilding(building))
+                        })
+                        updateQueue()
+                        return
+                    }
+
                     selectedElement = this
                     buildScreen.ProducingBuilding = building
                     buildScreen.ProducingUnit = units.UnitNone
@@ -363,10 +491,20 @@ func makeBuildUI(cache *lbx.LbxCache, imageCache *util.ImageCache, city *citylib
             element := &uilib.UIElement{
                 Rect: image.Rect(x1, y1, x2, y2),
                 DoubleLeftClick: func(this *uilib.UIElement) {
-                    doOk()
+                    if !shiftPressed() {
+                        doOk()
+                    }
                 },
                 PlaySoundLeftClick: true,
                 LeftClick: func(this *uilib.UIElement) {
+                    if shiftPressed() {
+                        buildScreen.editQueue(func(city *citylib.City){
+                            city.Enqueue(citylib.MakeQueueUnit(unit, 1))
+                        })
+                        updateQueue()
+                        return
+                    }
+
                     selectedElement = this
                     buildScreen.ProducingBuilding = buildinglib.BuildingNone
                     buildScreen.ProducingUnit = unit
@@ -133,6 +133,9 @@ type CityScreen struct {
     Buildings []BuildingSlot
     BuildScreen *BuildScreen
 
+    // show the layout of the city scape and let buildings be dragged around, see layout-debug.go
+    LayoutDebug bool
+
     Actions chan CityScreenActions
 
     // the building that was just built
@@ -272,47 +275,12 @@ func makeBuildingSlots2(city *citylib.City) []BuildingSlot {
 }
 
 func makeBuildingSlots(city *citylib.City) []BuildingSlot {
-    // use a random seed based on the position and race of the city so that each game gets
-    // a different city view, but within the same game the city view is consistent
-    random := rand.New(rand.NewPCG(uint64(city.X), uint64(city.Y) + uint64(1000 * city.Race)))
-
-    toLayout := city.Buildings.Clone()
-
-    for _, building := range toLayout.Values() {
-        width, height := building.Size()
-        if width == 0 || height == 0 || wasBuildingReplaced(building, city) {
-            toLayout.Remove(building)
-        }
-    }
-
-    enchantmentBuildings := buildinglib.EnchantmentBuildings()
-
-    for enchantment, building := range enchantmentBuildings {
-        if city.HasEnchantment(enchantment) {
-            toLayout.Insert(building)
-        }
-    }
-
-    toLayout.RemoveMany(buildinglib.BuildingCityWalls, buildinglib.BuildingShipwrightsGuild, buildinglib.BuildingShipYard, buildinglib.BuildingMaritimeGuild)
-
-    var result []*buildinglib.Rect
-    ok := false
-    // start := time.Now()
-    for range 40 {
-        result, ok = buildinglib.LayoutBuildings(toLayout.Values(), buildinglib.StandardRects(), random)
-        if ok {
-            break
-        }
-    }
-    // end := time.Now()
-
-    if !ok {
-        log.Printf("Warning: could not layout buildings")
-        // FIXME: use some random layout?
-        return nil
-    }
+    // the buildings keep the positions they were given when they were built
+    city.UpdateLayout()
+    result := city.Layout.Rects()
 
-    // log.Printf("Building layout took %v", end.Sub(start))
+    // the trees and houses between the buildings come from the same seed, so they don't change either
+    random := rand.New(rand.NewPCG(city.Layout.Seed, 0))
 
     getHouseTypes := func() []buildinglib.Building {
         trees := []buildinglib.Building{BuildingTreeHouse1, BuildingTreeHouse2, BuildingTreeHouse3, BuildingTreeHouse4, BuildingTreeHouse5}
@@ -372,7 +340,7 @@ func makeBuildingSlots(city *citylib.City) []BuildingSlot {
 
     // water buildings always go in the same place
     for _, waterBuilding := range []buildinglib.Building{buildinglib.BuildingShipwrightsGuild, buildinglib.BuildingShipYard, buildinglib.BuildingMaritimeGuild} {
-        if city.Buildings.Contains(waterBuilding) && !wasBuildingReplaced(waterBuilding, city) {
+        if city.Buildings.Contains(waterBuilding) && !city.WasBuildingReplaced(waterBuilding) {
             slots = append(slots, BuildingSlot{Building: waterBuilding, Point: image.Pt(12, 45)})
         }
     }
@@ -441,15 +409,6 @@ func sellAmount(city *citylib.City, building buildinglib.Building) int {
     return cost
 }
 
-func wasBuildingReplaced(building buildinglib.Building, city *citylib.City) bool {
-    if building == buildinglib.BuildingNone {
-        return false
-    }
-
-    replacedBy := building.ReplacedBy()
-    return replacedBy != buildinglib.BuildingNone && (city.Buildings.Contains(replacedBy) || wasBuildingReplaced(replacedBy, city))
-}
-
 func (cityScreen *CityScreen) SellBuilding(building buildinglib.Building) {
     // convert the building pic to one of the rubble ones
     // give player back the gold for the building
@@ -459,7 +418,7 @@ func (cityScreen *CityScreen) SellBuilding(building buildinglib.Building) {
 
     cityScreen.Player.Gold += sellAmount(cityScreen.City, building)
 
-    cityScreen.City.Buildings.Remove(building)
+    cityScreen.City.RemoveBuilding(building)
 
     for i, _ := range cityScreen.Buildings {
         if cityScreen.Buildings[i].Building == building {
@@ -470,7 +429,7 @@ func (cityScreen *CityScreen) SellBuilding(building buildinglib.Building) {
     }
 }
 
-func makeCityScapeElement(cache *lbx.LbxCache, group *uilib.UIElementGroup, city *citylib.City, help *helplib.Help, imageCache *util.ImageCache, doSell func(buildinglib.Building), buildings []BuildingSlot, newBuilding buildinglib.Building, x1 int, y1 int, fonts *fontslib.CityViewFonts, player *playerlib.Player, getAlpha *util.AlphaFadeFunc) *uilib.UIElement {
+func makeCityScapeElement(cache *lbx.LbxCache, group *uilib.UIElementGroup, city *citylib.City, help *helplib.Help, imageCache *util.ImageCache, doSell func(buildinglib.Building), buildings []BuildingSlot, newBuilding buildinglib.Building, x1 int, y1 int, fonts *fontslib.CityViewFonts, player *playerlib.Player, getAlpha *util.AlphaFadeFunc, moveBuilding func(buildinglib.Building, int, image.Point)) *uilib.UIElement {
     // this stores the in-memory image because we don't need the gpu ebiten.Image to do pixel perfect detection
     rawImageCache := make(map[int]image.Image)
 
@@ -499,6 +458,11 @@ func makeCityScapeElement(cache *lbx.LbxCache, group *uilib.UIElementGroup, city
     buildingLook := buildinglib.BuildingNone
     buildingLookTime := uint64(0)
     buildingView := image.Rect(x1, y1, x1 + 206, y1 + 96)
+
+    // the layout can only be rearranged in debug mode, when moveBuilding is given
+    dragging := buildinglib.BuildingNone
+    var mouse image.Point
+
     element := &uilib.UIElement{
         Rect: buildingView,
         Draw: func(element *uilib.UIElement, screen *ebiten.Image) {
@@ -506,6 +470,9 @@ func makeCityScapeElement(cache *lbx.LbxCache, group *uilib.UIElementGroup, city
             geom.Translate(float64(x1), float64(y1))
             cityScapeScreen := screen.SubImage(scale.ScaleRect(buildingView)).(*ebiten.Image)
             drawCityScape(cityScapeScreen, city, buildings, buildingLook, buildingLookTime, newBuilding, group.Counter / 8, imageCache, fonts, player, geom, (*getAlpha)())
+            if moveBuilding != nil {
+                drawLayoutDebug(cityScapeScreen, city, fonts, geom, dragging, mouse)
+            }
             // vector.StrokeRect(screen, float32(buildingView.Min.X), float32(buildingView.Min.Y), float32(buildingView.Dx()), float32(buildingView.Dy()), 1, color.RGBA{R: 0xff, G: 0x0, B: 0x0, A: 0xff}, true)
         },
         RightClick: func(element *uilib.UIElement) {
@@ -517,12 +484,29 @@ func makeCityScapeElement(cache *lbx.LbxCache, group *uilib.UIElementGroup, city
             }
         },
         LeftClick: func(element *uilib.UIElement) {
+            if moveBuilding != nil {
+                if city.Layout.Contains(buildingLook) {
+                    dragging = buildingLook
+                }
+                return
+            }
+
             if buildingLook != buildinglib.BuildingNone && canSellBuilding(city, buildingLook) {
                 doSell(buildingLook)
             }
         },
+        LeftClickRelease: func(element *uilib.UIElement) {
+            if dragging != buildinglib.BuildingNone {
+                tile, ok := layoutTileAt(mouse.X, mouse.Y)
+                if ok {
+                    moveBuilding(dragging, tile.Rect, tile.Point)
+                }
+                dragging = buildinglib.BuildingNone
+            }
+        },
         // if the user hovers over a building then show the name of the building
         Inside: func(element *uilib.UIElement, x int, y int){
+            mouse = image.Pt(x, y)
             oldBuildingLook := buildingLook
 
             buildingLook = buildinglib.BuildingNone
@@ -583,6 +567,11 @@ func (cityScreen *CityScreen) ResetUI() {
     cityScreen.UI = cityScreen.MakeUI(buildinglib.BuildingNone)
 }
 
+func (cityScreen *CityScreen) EnableLayoutDebug() {
+    cityScreen.LayoutDebug = true
+    cityScreen.ResetUI()
+}
+
 // returns the elements and the right most pixel used
 func (cityScreen *CityScreen) CreateCitizenIcons(offset1 int, offset2 int, setupWorkers func()) ([]*uilib.UIElement, int) {
     farmer, err := cityScreen.ImageCache.GetImage("backgrnd.lbx", getRaceFarmerIndex(cityScreen.City.Race), 0)
@@ -719,6 +708,10 @@ func (cityScreen *CityScreen) CreateCitizenIcons(offset1 int, offset2 int, setup
     return workerElements, citizenX
 }
 
+// the governor is shown at the right of the header of the resource panel, away from the name of the city
+const governorRight = 136
+const governorTop = 43
+
 func (cityScreen *CityScreen) MakeUI(newBuilding buildinglib.Building) *uilib.UI {
     ui := &uilib.UI{
         Cache: cityScreen.LbxCache,
@@ -755,6 +748,21 @@ func (cityScreen *CityScreen) MakeUI(newBuilding buildinglib.Building) *uilib.UI
         },
     })
 
+    // clicking the governor cycles through its focuses
+    governorText := fmt.Sprintf("Governor: %v", cityScreen.City.Governor)
+    governorWidth := int(cityScreen.Fonts.DescriptionFont.MeasureTextWidth(governorText, 1))
+    group.AddElement(&uilib.UIElement{
+        Rect: image.Rect(governorRight - governorWidth, governorTop, governorRight, governorTop + cityScreen.Fonts.DescriptionFont.Height()),
+        Tooltip: func(element *uilib.UIElement) (string, *font.Font) {
+            return "Click to change what the governor manages this city for", cityScreen.Fonts.SmallFont
+        },
+        LeftClick: func(element *uilib.UIElement) {
+            cityScreen.City.Governor = cityScreen.City.Governor.Next()
+            cityScreen.City.RunGovernor()
+            cityScreen.UI = cityScreen.MakeUI(buildinglib.BuildingNone)
+        },
+    })
+
     sellBuilding := func (toSell buildinglib.Building) {
         // FIXME: Check if building is needed for other building
         if cityScreen.City.SoldBuilding {
@@ -782,7 +790,19 @@ func (cityScreen *CityScreen) MakeUI(newBuilding buildinglib.Building) *uilib.UI
 
     var getAlpha util.AlphaFadeFunc = func() float32 { return 1 }
 
-    group.AddElement(makeCityScapeElement(cityScreen.LbxCache, group, cityScreen.City, &help, &cityScreen.ImageCache, sellBuilding, cityScreen.Buildings, newBuilding, 4, 101, cityScreen.Fonts, cityScreen.Player, &getAlpha))
+    var moveBuilding func(buildinglib.Building, int, image.Point)
+    if cityScreen.LayoutDebug {
+        moveBuilding = func(building buildinglib.Building, rect int, point image.Point){
+            if cityScreen.City.Layout.Move(building, rect, point) {
+                cityScreen.Buildings = makeBuildingSlots(cityScreen.City)
+                cityScreen.UI = cityScreen.MakeUI(buildinglib.BuildingNone)
+            } else {
+                log.Printf("Building %v does not fit at %v in rect %v", building, point, rect)
+            }
+        }
+    }
+
+    group.AddElement(makeCityScapeElement(cityScreen.LbxCache, group, cityScreen.City, &help, &cityScreen.ImageCache, sellBuilding, cityScreen.Buildings, newBuilding, 4, 101, cityScreen.Fonts, cityScreen.Player, &getAlpha, moveBuilding))
 
     // returns the amount of gold and the amount of production that will be used to buy a building
     computeBuyAmount := func(cost int) (int, float32) {
@@ -986,7 +1006,7 @@ func (cityScreen *CityScreen) MakeUI(newBuilding buildinglib.Building) *uilib.UI
                         enchantmentBuildings := buildinglib.EnchantmentBuildings()
                         building, ok := enchantmentBuildings[enchantment.Enchantment]
                         if ok {
-                            cityScreen.City.Buildings.Remove(building)
+                            cityScreen.City.RemoveBuilding(building)
 
                             cityScreen.Buildings = slices.DeleteFunc(cityScreen.Buildings, func(slot BuildingSlot) bool {
                                 return slot.Building == building
@@ -1291,8 +1311,7 @@ func (cityScreen *CityScreen) Update() CityScreenState {
                 cityScreen.BuildScreen = nil
                 cityScreen.UI = cityScreen.MakeUI(buildinglib.BuildingNone)
             case BuildScreenOk:
-                cityScreen.City.ProducingBuilding = cityScreen.BuildScreen.ProducingBuilding
-                cityScreen.City.ProducingUnit = cityScreen.BuildScreen.ProducingUnit
+                cityScreen.BuildScreen.Apply()
                 cityScreen.BuildScreen = nil
                 cityScreen.UI = cityScreen.MakeUI(buildinglib.BuildingNone)
         }
@@ -1710,7 +1729,7 @@ func (cityScreen *CityScreen) FoodProducers() []ResourceUsage {
             usages = append(usages, ResourceUsage{
                 Count: value,
                 Name: cityScreen.City.BuildingInfo.Name(building),
-                Replaced: wasBuildingReplaced(building, cityScreen.City),
+                Replaced: cityScreen.City.WasBuildingReplaced(building),
             })
         }
     }
@@ -1974,7 +1993,7 @@ func (cityScreen *CityScreen) WorkProducers() []ResourceUsage {
             usage = append(usage, ResourceUsage{
                 Count: count,
                 Name: name,
-                Replaced: wasBuildingReplaced(building, cityScreen.City),
+                Replaced: cityScreen.City.WasBuildingReplaced(building),
             })
         }
     }
@@ -2004,7 +2023,7 @@ func (cityScreen *CityScreen) BuildingMaintenanceResources() []ResourceUsage {
         usage = append(usage, ResourceUsage{
             Count: maintenance,
             Name: cityScreen.City.BuildingInfo.Name(building),
-            Replaced: wasBuildingReplaced(building, cityScreen.City),
+            Replaced: cityScreen.City.WasBuildingReplaced(building),
         })
     }
 
@@ -2019,7 +2038,7 @@ func (cityScreen *CityScreen) GoldProducers() []ResourceUsage {
             usage = append(usage, ResourceUsage{
                 Count: count,
                 Name: name,
-                Replaced: wasBuildingReplaced(building, cityScreen.City),
+                Replaced: cityScreen.City.WasBuildingReplaced(building),
             })
         }
     }
@@ -2044,7 +2063,7 @@ func (cityScreen *CityScreen) PowerProducers() []ResourceUsage {
             usage = append(usage, ResourceUsage{
                 Count: count,
                 Name: name,
-                Replaced: wasBuildingReplaced(building, cityScreen.City),
+                Replaced: cityScreen.City.WasBuildingReplaced(building),
             })
         }
     }
@@ -2075,7 +2094,7 @@ func (cityScreen *CityScreen) ResearchProducers() []ResourceUsage {
             usage = append(usage, ResourceUsage{
                 Count: research,
                 Name: cityScreen.City.BuildingInfo.Name(building),
-                Replaced: wasBuildingReplaced(building, cityScreen.City),
+                Replaced: cityScreen.City.WasBuildingReplaced(building),
             })
         }
     }
@@ -2356,6 +2375,7 @@ func (cityScreen *CityScreen) Draw(screen *ebiten.Image, mapView func (screen *e
     }
 
     cityScreen.Fonts.DescriptionFont.PrintOptions(screen, 210, 19, font.FontOptions{Justify: font.FontJustifyRight, Scale: scale.ScaleAmount, DropShadow: true}, fmt.Sprintf("Population: %v (%v)", numberWithComma(cityScreen.City.Population), deltaNumber(cityScreen.City.PopulationGrowthRate())))
+    cityScreen.Fonts.DescriptionFont.PrintOptions(screen, governorRight, governorTop, font.FontOptions{Justify: font.FontJustifyRight, Scale: scale.ScaleAmount, DropShadow: true}, fmt.Sprintf("Governor: %v", cityScreen.City.Governor))
 
     showWork := false
     workRequired := 0
@@ -2598,7 +2618,7 @@ func SimplifiedView(cache *lbx.LbxCache, city *citylib.City, player *playerlib.P
         ui.AddGroup(group)
         x1, y1 := options.GeoM.Apply(5, 102)
 
-        cityScapeElement := makeCityScapeElement(cache, group, city, &help, &imageCache, func(buildinglib.Building){}, buildings, buildinglib.BuildingNone, int(x1), int(y1), fonts, otherPlayer, &getAlpha)
+        cityScapeElement := makeCityScapeElement(cache, group, city, &help, &imageCache, func(buildinglib.Building){}, buildings, buildinglib.BuildingNone, int(x1), int(y1), fonts, otherPlayer, &getAlpha, nil)
 
         group.AddElement(cityScapeElement)
 
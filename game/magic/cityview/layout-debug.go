@@ -0,0 +1,114 @@
+package cityview
+
+import (
+    "fmt"
+    "image"
+    "image/color"
+
+    "github.com/kazzmir/master-of-magic/lib/font"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/* in debug mode the city scape shows which patch of land (rect) each tile belongs to, and buildings can be
+ * dragged to another tile to rearrange the stored layout of the city, see buildinglib.CityLayout
+ */
+
+// the same offset drawCityScape uses for the buildings
+const layoutRoadX = 0
+const layoutRoadY = 18
+
+var layoutDebugColors = []color.RGBA{
+    {R: 0xff, G: 0x40, B: 0x40, A: 0xff},
+    {R: 0x40, G: 0xff, B: 0x40, A: 0xff},
+    {R: 0x40, G: 0x80, B: 0xff, A: 0xff},
+    {R: 0xff, G: 0xff, B: 0x40, A: 0xff},
+    {R: 0xff, G: 0x40, B: 0xff, A: 0xff},
+    {R: 0x40, G: 0xff, B: 0xff, A: 0xff},
+}
+
+type layoutTile struct {
+    Rect int
+    Point image.Point
+    // where the tile is in the city scape
+    Position image.Point
+}
+
+func layoutTiles() []layoutTile {
+    var out []layoutTile
+    for _, rect := range buildinglib.StandardRects() {
+        computePoint := makeRectComputePoint(rect)
+        for x := range rect.Width {
+            for y := range rect.Height {
+                out = append(out, layoutTile{Rect: rect.Id, Point: image.Pt(x, y), Position: computePoint(x, y).Add(image.Pt(layoutRoadX, layoutRoadY))})
+            }
+        }
+    }
+
+    return out
+}
+
+// the tile closest to the given position in the city scape
+func layoutTileAt(x int, y int) (layoutTile, bool) {
+    var best layoutTile
+    bestDistance := -1
+    for _, tile := range layoutTiles() {
+        dx := tile.Position.X - x
+        dy := tile.Position.Y - y
+        distance := dx * dx + dy * dy
+        if bestDistance == -1 || distance < bestDistance {
+            best = tile
+            bestDistance = distance
+        }
+    }
+
+    // too far away from any tile
+    return best, bestDistance != -1 && bestDistance < 100
+}
+
+func drawLayoutDebug(screen *ebiten.Image, city *citylib.City, fonts *fontslib.CityViewFonts, baseGeoM ebiten.GeoM, dragging buildinglib.Building, mouse image.Point) {
+    occupied := make(map[layoutTile]buildinglib.Building)
+    for _, placed := range city.Layout.Buildings {
+        for x := placed.Area.Min.X; x < placed.Area.Max.X; x++ {
+            for y := placed.Area.Min.Y; y < placed.Area.Max.Y; y++ {
+                occupied[layoutTile{Rect: placed.Rect, Point: image.Pt(x, y)}] = placed.Building
+            }
+        }
+    }
+
+    labeled := make(map[int]bool)
+
+    for _, tile := range layoutTiles() {
+        tileColor := layoutDebugColors[tile.Rect % len(layoutDebugColors)]
+        size := float32(1)
+
+        building, ok := occupied[layoutTile{Rect: tile.Rect, Point: tile.Point}]
+        if ok {
+            size = 2
+            if building == dragging {
+                tileColor = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+            }
+        }
+
+        x, y := baseGeoM.Apply(float64(tile.Position.X), float64(tile.Position.Y))
+        vector.FillCircle(screen, scale.Scale(float32(x)), scale.Scale(float32(y)), size * float32(scale.ScaleAmount), tileColor, false)
+
+        if !labeled[tile.Rect] {
+            labeled[tile.Rect] = true
+            fonts.SmallFont.PrintOptions(screen, x, y - 6, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount}, fmt.Sprintf("%v", tile.Rect))
+        }
+    }
+
+    if dragging != buildinglib.BuildingNone {
+        tile, ok := layoutTileAt(mouse.X, mouse.Y)
+        if ok {
+            x, y := baseGeoM.Apply(float64(tile.Position.X), float64(tile.Position.Y))
+            vector.StrokeCircle(screen, scale.Scale(float32(x)), scale.Scale(float32(y)), 4 * float32(scale.ScaleAmount), float32(scale.ScaleAmount), color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, false)
+        }
+    }
+}
@@ -10,6 +10,7 @@ import (
     "github.com/kazzmir/master-of-magic/game/magic/util"
     "github.com/kazzmir/master-of-magic/game/magic/scale"
     "github.com/kazzmir/master-of-magic/game/magic/spellbook"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
     gamelib "github.com/kazzmir/master-of-magic/game/magic/game"
 
     "github.com/hajimehoshi/ebiten/v2/text/v2"
@@ -78,6 +79,22 @@ func (console *Console) Run(command string) {
             console.Lines = append(console.Lines, "  help - This help")
             console.Lines = append(console.Lines, "  cast <term> ... - Cast a spell. Search for spells given the terms,")
             console.Lines = append(console.Lines, "    such as 'cast gua wi' will cast Guardian Wind.")
+            console.Lines = append(console.Lines, "  starts - Show how fair the start locations of the wizards are")
+        case "starts":
+            placements := console.Game.Model.StartPlacements
+            if len(placements) == 0 {
+                console.Lines = append(console.Lines, "No start locations were chosen in this game")
+            }
+
+            for _, plane := range []data.Plane{data.PlaneArcanus, data.PlaneMyrror} {
+                placement, ok := placements[plane]
+                if ok {
+                    console.Lines = append(console.Lines, plane.String() + ":")
+                    for _, line := range placement.Report() {
+                        console.Lines = append(console.Lines, "  " + line)
+                    }
+                }
+            }
         case "cast":
             if len(parts) == 1 {
                 console.Lines = append(console.Lines, "Cast a spell. Give the name of the spell to cast. Partial names are ok.")
@@ -109,3 +109,38 @@ func TestItemAbilityName(test *testing.T) {
         test.Errorf("ItemAbilityNone and ItemAbilityStoning should not share a name")
     }
 }
+
+func TestDifficultyAIModifiers(test *testing.T) {
+    difficulties := []DifficultySetting{DifficultyIntro, DifficultyEasy, DifficultyAverage, DifficultyHard, DifficultyExtreme, DifficultyImpossible}
+
+    // normal is where the computer wizards play on equal terms with the player
+    if !DifficultyAverage.AIModifiers().IsNone() {
+        test.Errorf("the AI should not get bonuses on average")
+    }
+
+    for _, difficulty := range []DifficultySetting{DifficultyIntro, DifficultyEasy} {
+        if difficulty.AIModifiers().Production >= 1 {
+            test.Errorf("the AI should be behind the player on %v", difficulty)
+        }
+    }
+
+    for _, difficulty := range []DifficultySetting{DifficultyHard, DifficultyExtreme, DifficultyImpossible} {
+        if difficulty.AIModifiers().Production <= 1 {
+            test.Errorf("the AI should be ahead of the player on %v", difficulty)
+        }
+    }
+
+    // bonuses should never get smaller as the difficulty increases
+    for i := 1; i < len(difficulties); i++ {
+        previous := difficulties[i-1].AIModifiers()
+        current := difficulties[i].AIModifiers()
+
+        if current.Production < previous.Production || current.Gold < previous.Gold || current.Research < previous.Research || current.Mana < previous.Mana {
+            test.Errorf("modifiers for %v are smaller than for %v", difficulties[i], difficulties[i-1])
+        }
+
+        if current.Upkeep > previous.Upkeep {
+            test.Errorf("upkeep for %v is larger than for %v", difficulties[i], difficulties[i-1])
+        }
+    }
+}
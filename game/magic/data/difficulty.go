@@ -0,0 +1,73 @@
+package data
+
+func (difficulty DifficultySetting) String() string {
+    switch difficulty {
+        case DifficultyIntro: return "Intro"
+        case DifficultyEasy: return "Easy"
+        case DifficultyAverage: return "Average"
+        case DifficultyHard: return "Hard"
+        case DifficultyExtreme: return "Extreme"
+        case DifficultyImpossible: return "Impossible"
+    }
+
+    return "Unknown"
+}
+
+/* economic multipliers given to AI wizards depending on the difficulty level. A value of 1 means
+ * no change. Production, Gold, Research and Mana multiply income, while Upkeep multiplies the
+ * gold/mana cost of buildings and units, so values below 1 are a discount.
+ */
+type DifficultyModifiers struct {
+    Production float64
+    Gold float64
+    Research float64
+    Mana float64
+    Upkeep float64
+}
+
+// the modifiers used by human players, the neutral player, and anything else that doesn't cheat
+func NoDifficultyModifiers() DifficultyModifiers {
+    return DifficultyModifiers{
+        Production: 1,
+        Gold: 1,
+        Research: 1,
+        Mana: 1,
+        Upkeep: 1,
+    }
+}
+
+/* the central table of AI bonuses per difficulty. the manual of the original game (docs/Master of Magic -
+ * Manual.pdf, page 4) says that the player's wizard is advantaged on Intro and Easy, equivalent on Normal
+ * and disadvantaged on Hard and Impossible relative to the computer wizards, and that the difficulty
+ * changes production, population growth and research speed. it doesn't give the amounts, so the size of
+ * each step here is our own. Average is the original's Normal, and Extreme does not exist in the original
+ * so it sits between Hard and Impossible.
+ */
+func (difficulty DifficultySetting) AIModifiers() DifficultyModifiers {
+    switch difficulty {
+        case DifficultyIntro:
+            return DifficultyModifiers{Production: 0.5, Gold: 0.5, Research: 0.5, Mana: 0.5, Upkeep: 1}
+        case DifficultyEasy:
+            return DifficultyModifiers{Production: 0.75, Gold: 0.75, Research: 0.75, Mana: 0.75, Upkeep: 1}
+        case DifficultyAverage:
+            return NoDifficultyModifiers()
+        case DifficultyHard:
+            return DifficultyModifiers{Production: 1.5, Gold: 1.5, Research: 1.5, Mana: 1.5, Upkeep: 0.75}
+        case DifficultyExtreme:
+            return DifficultyModifiers{Production: 1.75, Gold: 1.75, Research: 1.75, Mana: 1.75, Upkeep: 0.5}
+        case DifficultyImpossible:
+            return DifficultyModifiers{Production: 2, Gold: 2, Research: 2, Mana: 2, Upkeep: 0.5}
+    }
+
+    return NoDifficultyModifiers()
+}
+
+// on the higher levels the AI wizards can see through the fog of war, on the lower levels they
+// only know what they have seen themselves (unless the AI Sees Through Fog setting is on)
+func (difficulty DifficultySetting) AICheatsInformation() bool {
+    return difficulty >= DifficultyHard
+}
+
+func (modifiers DifficultyModifiers) IsNone() bool {
+    return modifiers == NoDifficultyModifiers()
+}
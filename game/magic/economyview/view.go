@@ -0,0 +1,336 @@
+package economyview
+
+import (
+    "fmt"
+    "log"
+    "cmp"
+    "slices"
+    "image"
+    "image/color"
+
+    "github.com/kazzmir/master-of-magic/lib/lbx"
+    "github.com/kazzmir/master-of-magic/lib/font"
+    "github.com/kazzmir/master-of-magic/game/magic/util"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
+    uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/* a report of where the empire's gold, food, production, power, mana and research come from and where
+ * they go. the left side lists the empire and each city with its total for the chosen category, and
+ * clicking a row shows the individual sources and sinks on the right.
+ */
+
+type EconomyScreenState int
+
+const (
+    EconomyScreenStateRunning EconomyScreenState = iota
+    EconomyScreenStateDone
+)
+
+type SortOrder int
+
+const (
+    SortByName SortOrder = iota
+    SortByAmount
+)
+
+type EconomyScreen struct {
+    Cache *lbx.LbxCache
+    ImageCache util.ImageCache
+    Report *playerlib.EconomyReport
+    Date string
+    UI *uilib.UI
+    State EconomyScreenState
+
+    Category playerlib.EconomyCategory
+    Sort SortOrder
+    Descending bool
+    // nil when the empire is selected
+    Selected *citylib.City
+    FirstRow int
+}
+
+const maxRows = 13
+
+func MakeEconomyScreen(cache *lbx.LbxCache, report *playerlib.EconomyReport, date string) *EconomyScreen {
+    view := &EconomyScreen{
+        Cache: cache,
+        ImageCache: util.MakeImageCache(cache),
+        Report: report,
+        Date: date,
+        State: EconomyScreenStateRunning,
+        Category: playerlib.EconomyGold,
+    }
+
+    view.UI = view.MakeUI()
+
+    return view
+}
+
+func signed(amount int) string {
+    if amount > 0 {
+        return fmt.Sprintf("+%v", amount)
+    }
+
+    return fmt.Sprintf("%v", amount)
+}
+
+// the cities in the order chosen by the player
+func (view *EconomyScreen) sortedCities() []playerlib.CityEconomy {
+    cities := slices.Clone(view.Report.Cities)
+    slices.SortStableFunc(cities, func(a playerlib.CityEconomy, b playerlib.CityEconomy) int {
+        var out int
+        switch view.Sort {
+            case SortByName: out = cmp.Compare(a.City.Name, b.City.Name)
+            case SortByAmount: out = cmp.Compare(a.Lines.Total(view.Category), b.Lines.Total(view.Category))
+        }
+
+        if view.Descending {
+            return -out
+        }
+
+        return out
+    })
+
+    return cities
+}
+
+// the lines shown on the right for the selected row
+func (view *EconomyScreen) selectedLines() (string, []playerlib.EconomyLine) {
+    if view.Selected == nil {
+        cityTotal := 0
+        for _, city := range view.Report.Cities {
+            cityTotal += city.Lines.Total(view.Category)
+        }
+
+        var lines []playerlib.EconomyLine
+        if cityTotal != 0 {
+            lines = append(lines, playerlib.EconomyLine{Name: "Cities", Amount: cityTotal})
+        }
+
+        return "Empire", append(lines, view.Report.Empire[view.Category]...)
+    }
+
+    for _, city := range view.Report.Cities {
+        if city.City == view.Selected {
+            return city.City.Name, city.Lines[view.Category]
+        }
+    }
+
+    return "", nil
+}
+
+// what will happen next turn because of the selected category
+func (view *EconomyScreen) projection() string {
+    total := view.Report.Total(view.Category)
+
+    if view.Selected != nil {
+        switch view.Category {
+            case playerlib.EconomyFood:
+                return fmt.Sprintf("Population %v", signed(view.Selected.PopulationGrowthRate()))
+            case playerlib.EconomyProduction:
+                return fmt.Sprintf("%v in %v turns", view.Selected.ProducingString(), view.Selected.ProducingTurnsLeft())
+        }
+
+        return ""
+    }
+
+    switch view.Category {
+        case playerlib.EconomyGold:
+            return fmt.Sprintf("Next turn: %v GP", view.Report.Gold + total)
+        case playerlib.EconomyMana:
+            return fmt.Sprintf("Next turn: %v MP", view.Report.Mana + total)
+        case playerlib.EconomyFood:
+            if total > 0 {
+                return fmt.Sprintf("Surplus sold for %v GP", total / 2)
+            }
+            return ""
+    }
+
+    return ""
+}
+
+func (view *EconomyScreen) MakeUI() *uilib.UI {
+    loader, err := fontslib.Loader(view.Cache)
+    if err != nil {
+        log.Printf("Error: economy: unable to load fonts: %v", err)
+        return nil
+    }
+
+    titleFont := loader(fontslib.BigOrangeGradient2)
+    dateFont := loader(fontslib.LightFontSmall)
+    normalFont := loader(fontslib.SmallWhite)
+
+    highlightColor := util.PremultiplyAlpha(color.RGBA{R: 255, G: 255, B: 255, A: 70})
+    panelColor := util.PremultiplyAlpha(color.RGBA{A: 120})
+
+    ui := &uilib.UI{
+        Draw: func(ui *uilib.UI, screen *ebiten.Image) {
+            background, _ := view.ImageCache.GetImage("reload.lbx", 0, 0)
+            var options ebiten.DrawImageOptions
+            scale.DrawScaled(screen, background, &options)
+
+            titleFont.PrintOptions(screen, 160, 10, font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount}, "Empire Economy")
+            dateFont.PrintOptions(screen, 312, 11, font.FontOptions{DropShadow: true, Justify: font.FontJustifyRight, Scale: scale.ScaleAmount}, view.Date)
+
+            vector.FillRect(screen, scale.Scale(float32(8)), scale.Scale(float32(40)), scale.Scale(float32(146)), scale.Scale(float32(140)), panelColor, false)
+            vector.FillRect(screen, scale.Scale(float32(162)), scale.Scale(float32(40)), scale.Scale(float32(150)), scale.Scale(float32(140)), panelColor, false)
+
+            ui.StandardDraw(screen)
+
+            name, lines := view.selectedLines()
+            normalFont.PrintOptions(screen, 166, 43, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount}, fmt.Sprintf("%v %v", name, view.Category))
+
+            y := 54.0
+            total := 0
+            for _, line := range lines {
+                normalFont.PrintOptions(screen, 166, y, font.FontOptions{Scale: scale.ScaleAmount}, line.Name)
+                normalFont.PrintOptions(screen, 308, y, font.FontOptions{Justify: font.FontJustifyRight, Scale: scale.ScaleAmount}, signed(line.Amount))
+                total += line.Amount
+                y += 8
+            }
+
+            vector.StrokeLine(screen, scale.Scale(float32(166)), scale.Scale(float32(y)), scale.Scale(float32(308)), scale.Scale(float32(y)), float32(scale.ScaleAmount), color.RGBA{R: 0xc0, G: 0xc0, B: 0xc0, A: 0xff}, false)
+            y += 2
+            normalFont.PrintOptions(screen, 166, y, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount}, "Total")
+            normalFont.PrintOptions(screen, 308, y, font.FontOptions{DropShadow: true, Justify: font.FontJustifyRight, Scale: scale.ScaleAmount}, signed(total))
+
+            projection := view.projection()
+            if projection != "" {
+                normalFont.PrintOptions(screen, 166, 170, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount}, projection)
+            }
+        },
+    }
+
+    var elements []*uilib.UIElement
+
+    // one tab per category
+    tabWidth := 50
+    for i, category := range playerlib.EconomyCategories {
+        x := 10 + i * tabWidth
+        elements = append(elements, &uilib.UIElement{
+            Rect: image.Rect(x, 26, x + tabWidth - 2, 36),
+            PlaySoundLeftClick: true,
+            LeftClick: func(element *uilib.UIElement){
+                view.Category = category
+                view.UI = view.MakeUI()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                rect := element.Rect
+                if view.Category == category {
+                    vector.FillRect(screen, scale.Scale(float32(rect.Min.X)), scale.Scale(float32(rect.Min.Y)), scale.Scale(float32(rect.Dx())), scale.Scale(float32(rect.Dy())), highlightColor, false)
+                }
+                normalFont.PrintOptions(screen, float64(rect.Min.X + rect.Dx() / 2), float64(rect.Min.Y + 2), font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount}, category.String())
+            },
+        })
+    }
+
+    // clicking a header sorts by it, clicking it again reverses the order
+    makeHeader := func(rect image.Rectangle, text string, justify font.FontJustify, order SortOrder) *uilib.UIElement {
+        return &uilib.UIElement{
+            Rect: rect,
+            LeftClick: func(element *uilib.UIElement){
+                if view.Sort == order {
+                    view.Descending = !view.Descending
+                } else {
+                    view.Sort = order
+                    view.Descending = order == SortByAmount
+                }
+                view.UI = view.MakeUI()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                x := rect.Min.X
+                if justify == font.FontJustifyRight {
+                    x = rect.Max.X
+                }
+
+                label := text
+                if view.Sort == order {
+                    if view.Descending {
+                        label += " v"
+                    } else {
+                        label += " ^"
+                    }
+                }
+
+                normalFont.PrintOptions(screen, float64(x), float64(rect.Min.Y), font.FontOptions{DropShadow: true, Justify: justify, Scale: scale.ScaleAmount}, label)
+            },
+        }
+    }
+
+    elements = append(elements, makeHeader(image.Rect(12, 43, 80, 51), "City", font.FontJustifyLeft, SortByName))
+    elements = append(elements, makeHeader(image.Rect(100, 43, 150, 51), "Total", font.FontJustifyRight, SortByAmount))
+
+    makeRow := func(y int, name string, city *citylib.City, total func() int) *uilib.UIElement {
+        return &uilib.UIElement{
+            Rect: image.Rect(10, y, 152, y + 8),
+            LeftClick: func(element *uilib.UIElement){
+                view.Selected = city
+            },
+            Scroll: func(element *uilib.UIElement, x float64, y float64){
+                if y > 0 && view.FirstRow > 0 {
+                    view.FirstRow -= 1
+                    view.UI = view.MakeUI()
+                } else if y < 0 && view.FirstRow < len(view.Report.Cities) - maxRows {
+                    view.FirstRow += 1
+                    view.UI = view.MakeUI()
+                }
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                rect := element.Rect
+                if view.Selected == city {
+                    vector.FillRect(screen, scale.Scale(float32(rect.Min.X)), scale.Scale(float32(rect.Min.Y - 1)), scale.Scale(float32(rect.Dx())), scale.Scale(float32(rect.Dy())), highlightColor, false)
+                }
+                normalFont.PrintOptions(screen, 12, float64(rect.Min.Y), font.FontOptions{Scale: scale.ScaleAmount}, name)
+                normalFont.PrintOptions(screen, 150, float64(rect.Min.Y), font.FontOptions{Justify: font.FontJustifyRight, Scale: scale.ScaleAmount}, signed(total()))
+            },
+        }
+    }
+
+    elements = append(elements, makeRow(54, "Empire", nil, func() int {
+        return view.Report.Total(view.Category)
+    }))
+
+    y := 64
+    for i, city := range view.sortedCities() {
+        if i < view.FirstRow || i >= view.FirstRow + maxRows {
+            continue
+        }
+
+        elements = append(elements, makeRow(y, city.City.Name, city.City, func() int {
+            return city.Lines.Total(view.Category)
+        }))
+        y += 8
+    }
+
+    doneRect := image.Rect(270, 184, 312, 196)
+    elements = append(elements, &uilib.UIElement{
+        Rect: doneRect,
+        PlaySoundLeftClick: true,
+        LeftClick: func(element *uilib.UIElement){
+            view.State = EconomyScreenStateDone
+        },
+        Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+            vector.FillRect(screen, scale.Scale(float32(doneRect.Min.X)), scale.Scale(float32(doneRect.Min.Y)), scale.Scale(float32(doneRect.Dx())), scale.Scale(float32(doneRect.Dy())), panelColor, false)
+            normalFont.PrintOptions(screen, float64(doneRect.Min.X + doneRect.Dx() / 2), float64(doneRect.Min.Y + 3), font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount}, "Done")
+        },
+    })
+
+    ui.SetElementsFromArray(elements)
+
+    return ui
+}
+
+func (view *EconomyScreen) Update() EconomyScreenState {
+    view.UI.StandardUpdate()
+    return view.State
+}
+
+func (view *EconomyScreen) Draw(screen *ebiten.Image) {
+    view.UI.Draw(view.UI, screen)
+}
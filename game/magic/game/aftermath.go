@@ -0,0 +1,135 @@
+package game
+
+import (
+    "fmt"
+    "strings"
+    "math/rand/v2"
+
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* when a city falls, the winner loots a share of the defender's treasury, and the fighting kills some
+ * of the people and destroys some of the buildings. the more collateral damage was done during the
+ * battle, the more is lost. the enchantments the defender cast on the city end with the defender's rule
+ */
+
+// what the defender lost when a city was captured or razed
+type CityAftermath struct {
+    City *citylib.City
+    Attacker *playerlib.Player
+    Defender *playerlib.Player
+    Razed bool
+    Gold int
+    // citizens killed
+    Population int
+    Buildings []buildinglib.Building
+    Enchantments []data.CityEnchantment
+}
+
+/* roll the citizens and buildings lost in the battle for a city. at least one citizen always survives,
+ * and the fortress and summoning circle are never destroyed
+ */
+func rollCityDamage(city *citylib.City, collateralDamage int, neutralAttacker bool) (int, []buildinglib.Building) {
+    population := 0
+
+    // maximum chance is 50%, minimum is 10%
+    chance := min(50, 10 + collateralDamage * 2)
+    for range city.Citizens() - 1 {
+        if rand.N(100) < chance {
+            population += 1
+        }
+    }
+
+    minBuildingChance := 10
+    if neutralAttacker {
+        minBuildingChance = 50
+    }
+
+    var buildings []buildinglib.Building
+
+    chance = min(75, minBuildingChance + collateralDamage)
+    for _, building := range city.Buildings.Values() {
+        if building == buildinglib.BuildingFortress || building == buildinglib.BuildingSummoningCircle {
+            continue
+        }
+
+        if rand.N(100) < chance {
+            buildings = append(buildings, building)
+        }
+    }
+
+    return population, buildings
+}
+
+// remove the people and buildings that were lost in the battle
+func (aftermath *CityAftermath) applyDamage() {
+    city := aftermath.City
+
+    city.Population -= aftermath.Population * 1000
+    for _, building := range aftermath.Buildings {
+        city.RemoveBuilding(building)
+    }
+    city.ResetCitizens()
+}
+
+// the defender's enchantments on the city, which end when the city changes hands
+func defenderEnchantments(city *citylib.City, defender *playerlib.Player) []data.CityEnchantment {
+    var out []data.CityEnchantment
+    for _, enchantment := range city.Enchantments.Values() {
+        if enchantment.Owner == defender.GetBanner() {
+            out = append(out, enchantment.Enchantment)
+        }
+    }
+
+    return out
+}
+
+// a description of everything that was lost, from the point of view of the given player
+func (aftermath *CityAftermath) Summary(buildingInfo buildinglib.BuildingInfos, player *playerlib.Player) string {
+    var lines []string
+
+    attacker := player == aftermath.Attacker
+
+    switch {
+        case aftermath.Razed && attacker: lines = append(lines, fmt.Sprintf("Your forces have razed %v to the ground.", aftermath.City.Name))
+        case aftermath.Razed: lines = append(lines, fmt.Sprintf("%v has been razed to the ground by %v.", aftermath.City.Name, aftermath.Attacker.Wizard.Name))
+        case attacker: lines = append(lines, fmt.Sprintf("Your forces have captured %v.", aftermath.City.Name))
+        default: lines = append(lines, fmt.Sprintf("%v has fallen to %v.", aftermath.City.Name, aftermath.Attacker.Wizard.Name))
+    }
+
+    if aftermath.Gold > 0 {
+        if attacker {
+            lines = append(lines, fmt.Sprintf("Gold looted: %v", aftermath.Gold))
+        } else {
+            lines = append(lines, fmt.Sprintf("Gold lost: %v", aftermath.Gold))
+        }
+    }
+
+    if aftermath.Population > 0 {
+        lines = append(lines, fmt.Sprintf("Citizens killed: %v", aftermath.Population))
+    }
+
+    // a razed city loses everything anyway
+    if !aftermath.Razed {
+        if len(aftermath.Buildings) > 0 {
+            var names []string
+            for _, building := range aftermath.Buildings {
+                names = append(names, buildingInfo.Name(building))
+            }
+            lines = append(lines, "Buildings destroyed: " + strings.Join(names, ", "))
+        }
+
+        if len(aftermath.Enchantments) > 0 {
+            var names []string
+            for _, enchantment := range aftermath.Enchantments {
+                names = append(names, enchantment.Name())
+            }
+            lines = append(lines, "Enchantments lost: " + strings.Join(names, ", "))
+        }
+    }
+
+    return strings.Join(lines, "\n")
+}
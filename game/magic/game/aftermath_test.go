@@ -0,0 +1,156 @@
+package game
+
+import (
+    "testing"
+    "slices"
+    "strings"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+    herolib "github.com/kazzmir/master-of-magic/game/magic/hero"
+    "github.com/kazzmir/master-of-magic/game/magic/setup"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/lib/coroutine"
+)
+
+func TestRollCityDamage(test *testing.T) {
+    player := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerRed, Race: data.RaceHighMen}, true, 1, 1, make(map[herolib.HeroType]string), nil)
+
+    city := citylib.MakeCity("xyz", 1, 1, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, player)
+    city.Population = 8000
+    city.Buildings.Insert(buildinglib.BuildingFortress)
+    city.Buildings.Insert(buildinglib.BuildingSummoningCircle)
+    city.Buildings.Insert(buildinglib.BuildingGranary)
+    city.Buildings.Insert(buildinglib.BuildingBarracks)
+
+    for range 100 {
+        population, buildings := rollCityDamage(city, 1000, true)
+        if population < 0 || population > city.Citizens() - 1 {
+            test.Fatalf("at least one citizen should survive but lost %v of %v", population, city.Citizens())
+        }
+
+        if slices.Contains(buildings, buildinglib.BuildingFortress) || slices.Contains(buildings, buildinglib.BuildingSummoningCircle) {
+            test.Fatalf("the fortress and summoning circle should never be destroyed: %v", buildings)
+        }
+    }
+}
+
+func TestCityAftermathSummary(test *testing.T) {
+    attacker := playerlib.MakePlayer(setup.WizardCustom{Name: "Merlin", Banner: data.BannerRed}, true, 1, 1, make(map[herolib.HeroType]string), nil)
+    defender := playerlib.MakePlayer(setup.WizardCustom{Name: "Raven", Banner: data.BannerBlue}, false, 1, 1, make(map[herolib.HeroType]string), nil)
+
+    city := citylib.MakeCity("xyz", 1, 1, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, defender)
+    city.AddEnchantment(data.CityEnchantmentWallOfFire, defender.GetBanner())
+    city.AddEnchantment(data.CityEnchantmentCursedLands, attacker.GetBanner())
+
+    aftermath := &CityAftermath{
+        City: city,
+        Attacker: attacker,
+        Defender: defender,
+        Gold: 50,
+        Population: 2,
+        Enchantments: defenderEnchantments(city, defender),
+    }
+
+    if len(aftermath.Enchantments) != 1 || aftermath.Enchantments[0] != data.CityEnchantmentWallOfFire {
+        test.Errorf("only the defender's enchantments should be lost: %v", aftermath.Enchantments)
+    }
+
+    summary := aftermath.Summary(nil, attacker)
+    for _, part := range []string{"captured xyz", "Gold looted: 50", "Citizens killed: 2", "Wall of Fire"} {
+        if !strings.Contains(summary, part) {
+            test.Errorf("summary should contain %q: %v", part, summary)
+        }
+    }
+
+    summary = aftermath.Summary(nil, defender)
+    if !strings.Contains(summary, "Gold lost: 50") || !strings.Contains(summary, "fallen to Merlin") {
+        test.Errorf("defender summary is wrong: %v", summary)
+    }
+}
+
+// an ai that always makes the same choice about razing a captured city
+type razeAI struct {
+    playerlib.AIBehavior
+    Raze bool
+}
+
+func (ai *razeAI) ConfirmRazeTown(city *citylib.City) bool {
+    return ai.Raze
+}
+
+func TestDefeatCity(test *testing.T) {
+    makeCity := func(raze bool) (*Game, *playerlib.Player, *playerlib.Player, *citylib.City) {
+        attacker := playerlib.MakePlayer(setup.WizardCustom{Name: "Merlin", Banner: data.BannerRed}, false, 1, 1, make(map[herolib.HeroType]string), nil)
+        attacker.AIBehavior = &razeAI{Raze: raze}
+        defender := playerlib.MakePlayer(setup.WizardCustom{Name: "Raven", Banner: data.BannerBlue}, false, 1, 1, make(map[herolib.HeroType]string), nil)
+        defender.Gold = 1000
+
+        city := citylib.MakeCity("xyz", 1, 1, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, defender)
+        city.Population = 8000
+        city.Buildings.Insert(buildinglib.BuildingGranary)
+        city.Buildings.Insert(buildinglib.BuildingBarracks)
+        city.Buildings.Insert(buildinglib.BuildingSmithy)
+        city.AddEnchantment(data.CityEnchantmentWallOfFire, defender.GetBanner())
+        defender.AddCity(city)
+
+        game := &Game{Model: &GameModel{}, Events: make(chan GameEvent, 10)}
+        return game, attacker, defender, city
+    }
+
+    var yield coroutine.YieldFunc = func() error {
+        return nil
+    }
+
+    // a city taken without a battle is not damaged
+    game, attacker, defender, city := makeCity(false)
+    plunder := defender.ComputePlunderedGold(city)
+    aftermath := game.defeatCity(yield, attacker, nil, defender, city, false, 1000)
+
+    if aftermath.Population != 0 || len(aftermath.Buildings) != 0 {
+        test.Errorf("an unguarded city should not be damaged: %v citizens, buildings %v", aftermath.Population, aftermath.Buildings)
+    }
+
+    if city.Population != 8000 || city.Buildings.Size() != 3 {
+        test.Errorf("the city should keep its people and buildings: %v, %v", city.Population, city.Buildings.Values())
+    }
+
+    if aftermath.Gold != plunder || plunder == 0 {
+        test.Errorf("the attacker should plunder %v gold but got %v", plunder, aftermath.Gold)
+    }
+
+    if attacker.FindCity(1, 1, data.PlaneArcanus) != city || defender.FindCity(1, 1, data.PlaneArcanus) != nil {
+        test.Errorf("the city should now belong to the attacker")
+    }
+
+    if len(aftermath.Enchantments) != 1 || city.HasEnchantment(data.CityEnchantmentWallOfFire) {
+        test.Errorf("the defender's wall of fire should be lost: %v", aftermath.Enchantments)
+    }
+
+    // the damage rolled after a battle is taken from the city
+    for range 20 {
+        game, attacker, defender, city = makeCity(false)
+        aftermath = game.defeatCity(yield, attacker, nil, defender, city, true, 1000)
+
+        if city.Population != 8000 - aftermath.Population * 1000 {
+            test.Fatalf("the city should have lost %v citizens but has %v people", aftermath.Population, city.Population)
+        }
+
+        if city.Buildings.Size() != 3 - len(aftermath.Buildings) {
+            test.Fatalf("the city should have lost %v but has %v", aftermath.Buildings, city.Buildings.Values())
+        }
+
+        for _, building := range aftermath.Buildings {
+            if city.Buildings.Contains(building) {
+                test.Fatalf("%v should have been destroyed", building)
+            }
+        }
+    }
+
+    game, attacker, defender, city = makeCity(true)
+    aftermath = game.defeatCity(yield, attacker, nil, defender, city, true, 0)
+    if !aftermath.Razed || attacker.FindCity(1, 1, data.PlaneArcanus) != nil || defender.FindCity(1, 1, data.PlaneArcanus) != nil {
+        test.Errorf("a razed city should belong to nobody")
+    }
+}
@@ -0,0 +1,437 @@
+package game
+
+import (
+    "fmt"
+    "io"
+    "image"
+    "image/color"
+    "encoding/json"
+    "slices"
+    "strings"
+    "log"
+
+    "github.com/kazzmir/master-of-magic/game/magic/ai"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    "github.com/kazzmir/master-of-magic/game/magic/inputmanager"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/lib/coroutine"
+    "github.com/kazzmir/master-of-magic/lib/font"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/inpututil"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/* keeps the per-turn traces produced by the AI wizards for the last AITraceTurns turns, and
+ * optionally writes every trace out as json lines (one TurnTrace per line) so a whole game can be
+ * inspected afterwards.
+ */
+
+// how many turns of traces are kept in memory for the debugger
+const AITraceTurns = 20
+
+type AITraceLog struct {
+    Traces []*ai.TurnTrace
+    out io.WriteCloser
+    encoder *json.Encoder
+}
+
+// out can be nil to only keep traces in memory
+func MakeAITraceLog(out io.WriteCloser) *AITraceLog {
+    traceLog := &AITraceLog{
+        out: out,
+    }
+
+    if out != nil {
+        traceLog.encoder = json.NewEncoder(out)
+    }
+
+    return traceLog
+}
+
+func (traceLog *AITraceLog) Record(trace *ai.TurnTrace) {
+    if trace == nil {
+        return
+    }
+
+    traceLog.Traces = append(traceLog.Traces, trace)
+
+    // forget traces that are too old. the oldest traces are at the front
+    drop := 0
+    for drop < len(traceLog.Traces) && traceLog.Traces[drop].Turn + AITraceTurns <= trace.Turn {
+        drop += 1
+    }
+
+    if drop > 0 {
+        traceLog.Traces = slices.Delete(traceLog.Traces, 0, drop)
+    }
+
+    if traceLog.encoder != nil {
+        err := traceLog.encoder.Encode(trace)
+        if err != nil {
+            log.Printf("Unable to write ai trace: %v", err)
+        }
+    }
+}
+
+func (traceLog *AITraceLog) Close() {
+    if traceLog.out != nil {
+        traceLog.out.Close()
+        traceLog.out = nil
+        traceLog.encoder = nil
+    }
+}
+
+// the distinct turns that have at least one trace, in order
+func (traceLog *AITraceLog) Turns() []uint64 {
+    var turns []uint64
+    for _, trace := range traceLog.Traces {
+        if !slices.Contains(turns, trace.Turn) {
+            turns = append(turns, trace.Turn)
+        }
+    }
+
+    slices.Sort(turns)
+    return turns
+}
+
+// the names of all wizards that have been traced
+func (traceLog *AITraceLog) Wizards() []string {
+    var wizards []string
+    for _, trace := range traceLog.Traces {
+        if !slices.Contains(wizards, trace.Wizard) {
+            wizards = append(wizards, trace.Wizard)
+        }
+    }
+
+    return wizards
+}
+
+// traces for the given turn. if wizard is empty then traces for all wizards are returned
+func (traceLog *AITraceLog) Find(turn uint64, wizard string) []*ai.TurnTrace {
+    var out []*ai.TurnTrace
+    for _, trace := range traceLog.Traces {
+        if trace.Turn == turn && (wizard == "" || trace.Wizard == wizard) {
+            out = append(out, trace)
+        }
+    }
+
+    return out
+}
+
+// record the trace of the given player's most recent ai update, if the ai supports tracing
+func (game *Game) recordAITrace(player *playerlib.Player) {
+    if game.AITrace == nil {
+        return
+    }
+
+    tracing, ok := player.AIBehavior.(ai.TracingAI)
+    if ok {
+        game.AITrace.Record(tracing.LastTurnTrace())
+    }
+}
+
+type aiDebuggerLine struct {
+    Text string
+    Color color.Color
+    Location data.PlanePoint
+    HasLocation bool
+}
+
+func goalDebugLines(goals []ai.GoalDebugInfo, indent int) []aiDebuggerLine {
+    var lines []aiDebuggerLine
+
+    for _, goal := range goals {
+        text := fmt.Sprintf("%v%v %.2f", strings.Repeat(" ", indent * 2), goal.Goal, goal.Weight)
+        if goal.Skipped {
+            text += " (skipped)"
+        }
+        lines = append(lines, aiDebuggerLine{Text: text, Color: color.RGBA{R: 0xff, G: 0xff, B: 0x80, A: 0xff}})
+        lines = append(lines, goalDebugLines(goal.SubGoals, indent + 1)...)
+    }
+
+    return lines
+}
+
+// the text shown in the debugger panel for the given traces. if selected is set then only
+// entries that refer to that tile are shown
+func aiDebuggerLines(traces []*ai.TurnTrace, selected *data.PlanePoint) []aiDebuggerLine {
+    var lines []aiDebuggerLine
+
+    white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+    header := color.RGBA{R: 0x80, G: 0xc0, B: 0xff, A: 0xff}
+    red := color.RGBA{R: 0xff, G: 0x80, B: 0x80, A: 0xff}
+
+    at := func(x int, y int, plane data.Plane) bool {
+        return selected == nil || (selected.X == x && selected.Y == y && selected.Plane == plane)
+    }
+
+    for _, trace := range traces {
+        lines = append(lines, aiDebuggerLine{Text: fmt.Sprintf("%v (%v)", trace.Wizard, trace.Banner), Color: trace.Banner.Color()})
+
+        if selected == nil {
+            lines = append(lines, aiDebuggerLine{Text: "Goals", Color: header})
+            lines = append(lines, goalDebugLines(trace.Goals, 1)...)
+        }
+
+        if trace.Campaign != "" && selected == nil {
+            lines = append(lines, aiDebuggerLine{Text: fmt.Sprintf("Campaign: %v", trace.Campaign), Color: header})
+        }
+
+        lines = append(lines, aiDebuggerLine{Text: "Decisions", Color: header})
+        for _, decision := range trace.Decisions {
+            if decision.HasLocation && !at(decision.X, decision.Y, decision.Plane) {
+                continue
+            }
+
+            if !decision.HasLocation && selected != nil {
+                continue
+            }
+
+            text := fmt.Sprintf(" %v: %v", decision.Goal, decision.Description)
+            if decision.Reason != "" {
+                text += fmt.Sprintf(" - %v", decision.Reason)
+            }
+
+            lines = append(lines, aiDebuggerLine{
+                Text: text,
+                Color: white,
+                Location: data.PlanePoint{X: decision.X, Y: decision.Y, Plane: decision.Plane},
+                HasLocation: decision.HasLocation,
+            })
+        }
+
+        lines = append(lines, aiDebuggerLine{Text: "Rejected", Color: header})
+        for _, rejected := range trace.Rejected {
+            if !at(rejected.X, rejected.Y, rejected.Plane) {
+                continue
+            }
+
+            lines = append(lines, aiDebuggerLine{
+                Text: fmt.Sprintf(" %v: %v - %v", rejected.Goal, rejected.Description, rejected.Reason),
+                Color: red,
+                Location: data.PlanePoint{X: rejected.X, Y: rejected.Y, Plane: rejected.Plane},
+                HasLocation: true,
+            })
+        }
+
+        lines = append(lines, aiDebuggerLine{Text: "Stacks", Color: header})
+        for _, stack := range trace.Stacks {
+            if !at(stack.X, stack.Y, stack.Plane) {
+                continue
+            }
+
+            activity := stack.Activity
+            if activity == "" {
+                activity = "idle"
+            }
+
+            lines = append(lines, aiDebuggerLine{
+                Text: fmt.Sprintf(" (%v,%v) %v units str %v: %v", stack.X, stack.Y, stack.Units, stack.Strength, activity),
+                Color: white,
+                Location: data.PlanePoint{X: stack.X, Y: stack.Y, Plane: stack.Plane},
+                HasLocation: true,
+            })
+        }
+
+        lines = append(lines, aiDebuggerLine{Text: "Cities", Color: header})
+        for _, city := range trace.Cities {
+            if !at(city.X, city.Y, city.Plane) {
+                continue
+            }
+
+            lines = append(lines, aiDebuggerLine{
+                Text: fmt.Sprintf(" %v: %v", city.Name, city.Producing),
+                Color: white,
+                Location: data.PlanePoint{X: city.X, Y: city.Y, Plane: city.Plane},
+                HasLocation: true,
+            })
+        }
+    }
+
+    return lines
+}
+
+/* the in-game AI debugger, available in watch mode by pressing D. The right side of the screen
+ * shows the trace of the selected turn, the bottom of the screen is a timeline of all traced turns.
+ *   left/right or click the timeline: change turn
+ *   w: cycle the wizard filter
+ *   up/down or mouse wheel: scroll
+ *   click on the map: only show entries for that tile
+ *   click on an entry: move the camera to it
+ *   escape or d: close
+ */
+func (game *Game) showAIDebugger(yield coroutine.YieldFunc) {
+    if game.AITrace == nil || len(game.AITrace.Traces) == 0 {
+        return
+    }
+
+    const panelX = 180
+    const lineHeight = 7
+    const timelineHeight = 12
+
+    panelRect := image.Rect(panelX, 0, data.ScreenWidth, data.ScreenHeight - timelineHeight)
+    timelineRect := image.Rect(0, data.ScreenHeight - timelineHeight, data.ScreenWidth, data.ScreenHeight)
+
+    turns := game.AITrace.Turns()
+    turnIndex := len(turns) - 1
+    // -1 means all wizards
+    wizardIndex := -1
+    scroll := 0
+    var selected *data.PlanePoint
+
+    currentWizard := func() string {
+        wizards := game.AITrace.Wizards()
+        if wizardIndex < 0 || wizardIndex >= len(wizards) {
+            return ""
+        }
+        return wizards[wizardIndex]
+    }
+
+    computeLines := func() []aiDebuggerLine {
+        return aiDebuggerLines(game.AITrace.Find(turns[turnIndex], currentWizard()), selected)
+    }
+
+    lines := computeLines()
+
+    visibleLines := (panelRect.Dy() - 20) / lineHeight
+
+    moveTo := func(location data.PlanePoint) {
+        if location.Plane != game.Model.Plane {
+            game.Model.SwitchPlane()
+        }
+        game.doMoveCamera(yield, location.X, location.Y)
+    }
+
+    oldDrawer := game.LastDrawer()
+    game.PushDrawer(func(screen *ebiten.Image){
+        oldDrawer(screen)
+
+        if selected != nil && selected.Plane == game.Model.Plane {
+            x, y := game.TileToScreen(selected.X, selected.Y)
+            tileWidth := float64(game.Model.CurrentMap().TileWidth()) * game.Camera.GetAnimatedZoom()
+            tileHeight := float64(game.Model.CurrentMap().TileHeight()) * game.Camera.GetAnimatedZoom()
+            vector.StrokeRect(screen, float32(scale.Scale(float64(x) - tileWidth / 2)), float32(scale.Scale(float64(y) - tileHeight / 2)), float32(scale.Scale(tileWidth)), float32(scale.Scale(tileHeight)), float32(scale.Scale(1)), color.RGBA{R: 0xff, G: 0xff, A: 0xff}, false)
+        }
+
+        vector.FillRect(screen, float32(scale.Scale(panelRect.Min.X)), float32(scale.Scale(panelRect.Min.Y)), float32(scale.Scale(panelRect.Dx())), float32(scale.Scale(panelRect.Dy())), color.RGBA{A: 200}, false)
+        vector.FillRect(screen, float32(scale.Scale(timelineRect.Min.X)), float32(scale.Scale(timelineRect.Min.Y)), float32(scale.Scale(timelineRect.Dx())), float32(scale.Scale(timelineRect.Dy())), color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 220}, false)
+
+        fontOptions := font.FontOptions{Scale: scale.ScaleAmount, DropShadow: true}
+
+        wizard := currentWizard()
+        if wizard == "" {
+            wizard = "All"
+        }
+        game.Fonts.WhiteFont.PrintOptions(screen, float64(panelRect.Min.X + 2), float64(panelRect.Min.Y + 2), fontOptions, fmt.Sprintf("Year %v  Wizard: %v", turns[turnIndex], wizard))
+        if selected != nil {
+            game.Fonts.WhiteFont.PrintOptions(screen, float64(panelRect.Min.X + 2), float64(panelRect.Min.Y + 2 + lineHeight), fontOptions, fmt.Sprintf("Tile (%v,%v)", selected.X, selected.Y))
+        }
+
+        y := panelRect.Min.Y + 20
+        for i := scroll; i < len(lines) && i < scroll + visibleLines; i++ {
+            var options ebiten.DrawImageOptions
+            options.ColorScale.ScaleWithColor(lines[i].Color)
+            useOptions := fontOptions
+            useOptions.Options = &options
+            game.Fonts.WhiteFont.PrintOptions(screen, float64(panelRect.Min.X + 2), float64(y), useOptions, lines[i].Text)
+            y += lineHeight
+        }
+
+        // one tick per traced turn, with the current turn highlighted
+        for i := range turns {
+            x := timelineRect.Min.X + 2 + i * (timelineRect.Dx() - 4) / max(1, len(turns))
+            tickColor := color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+            if i == turnIndex {
+                tickColor = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+            }
+            vector.FillRect(screen, float32(scale.Scale(x)), float32(scale.Scale(timelineRect.Min.Y + 2)), float32(scale.Scale(1)), float32(scale.Scale(timelineRect.Dy() - 4)), tickColor, false)
+        }
+    })
+    defer game.PopDrawer()
+
+    // absorb the key press that opened the debugger
+    yield()
+
+    quit := false
+    for !quit {
+        game.Counter += 1
+
+        changed := false
+
+        for _, key := range inpututil.AppendJustPressedKeys(nil) {
+            switch key {
+                case ebiten.KeyEscape, ebiten.KeyD:
+                    quit = true
+                case ebiten.KeyLeft:
+                    if turnIndex > 0 {
+                        turnIndex -= 1
+                        changed = true
+                    }
+                case ebiten.KeyRight:
+                    if turnIndex < len(turns) - 1 {
+                        turnIndex += 1
+                        changed = true
+                    }
+                case ebiten.KeyW:
+                    wizardIndex += 1
+                    if wizardIndex >= len(game.AITrace.Wizards()) {
+                        wizardIndex = -1
+                    }
+                    changed = true
+                case ebiten.KeyUp:
+                    scroll = max(0, scroll - 1)
+                case ebiten.KeyDown:
+                    scroll = max(0, min(len(lines) - visibleLines, scroll + 1))
+            }
+        }
+
+        _, wheelY := inputmanager.Wheel()
+        if wheelY < 0 {
+            scroll = max(0, min(len(lines) - visibleLines, scroll + 1))
+        } else if wheelY > 0 {
+            scroll = max(0, scroll - 1)
+        }
+
+        if inputmanager.LeftClick() {
+            mouseX, mouseY := inputmanager.MousePosition()
+            point := image.Pt(scale.Unscale(mouseX), scale.Unscale(mouseY))
+
+            switch {
+                case point.In(timelineRect):
+                    turnIndex = min(len(turns) - 1, max(0, (point.X - timelineRect.Min.X - 2) * len(turns) / max(1, timelineRect.Dx() - 4)))
+                    changed = true
+                case point.In(panelRect):
+                    index := scroll + (point.Y - panelRect.Min.Y - 20) / lineHeight
+                    if point.Y >= panelRect.Min.Y + 20 && index < len(lines) && lines[index].HasLocation {
+                        location := lines[index].Location
+                        selected = &location
+                        moveTo(location)
+                        changed = true
+                    }
+                default:
+                    tileX, tileY := game.ScreenToTile(float64(mouseX), float64(mouseY))
+                    tileX = game.Model.CurrentMap().WrapX(tileX)
+                    if selected != nil && selected.X == tileX && selected.Y == tileY && selected.Plane == game.Model.Plane {
+                        selected = nil
+                    } else {
+                        selected = &data.PlanePoint{X: tileX, Y: tileY, Plane: game.Model.Plane}
+                    }
+                    changed = true
+            }
+        }
+
+        if changed {
+            lines = computeLines()
+            scroll = max(0, min(scroll, len(lines) - visibleLines))
+        }
+
+        if yield() != nil {
+            return
+        }
+    }
+
+    // absorb the key press that closed the debugger
+    yield()
+}
@@ -0,0 +1,29 @@
+package game
+
+import (
+    "testing"
+
+    "github.com/kazzmir/master-of-magic/game/magic/ai"
+)
+
+func TestAITraceLogBounded(test *testing.T) {
+    traceLog := MakeAITraceLog(nil)
+
+    for turn := range uint64(100) {
+        traceLog.Record(&ai.TurnTrace{Turn: turn, Wizard: "Merlin"})
+        traceLog.Record(&ai.TurnTrace{Turn: turn, Wizard: "Raven"})
+    }
+
+    turns := traceLog.Turns()
+    if len(turns) != AITraceTurns || turns[0] != 100 - AITraceTurns || turns[len(turns) - 1] != 99 {
+        test.Errorf("only the last %v turns should be kept but have %v", AITraceTurns, turns)
+    }
+
+    if len(traceLog.Traces) != AITraceTurns * 2 {
+        test.Errorf("expected %v traces but have %v", AITraceTurns * 2, len(traceLog.Traces))
+    }
+
+    if len(traceLog.Find(99, "Raven")) != 1 || len(traceLog.Find(10, "")) != 0 {
+        test.Errorf("recent traces should be found and old ones forgotten")
+    }
+}
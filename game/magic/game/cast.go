@@ -448,8 +448,8 @@ func (game *Game) doCastSpell(player *playerlib.Player, spell spellbook.Spell) {
                         check.RemoveBuilding(building.BuildingFortress)
                     }
 
-                    city.Buildings.Insert(buildinglib.BuildingFortress)
-                    city.Buildings.Insert(buildinglib.BuildingSummoningCircle)
+                    city.AddBuilding(buildinglib.BuildingFortress)
+                    city.AddBuilding(buildinglib.BuildingSummoningCircle)
                     player.Wizard.Race = city.Race
                 }
             }
@@ -819,7 +819,7 @@ func (game *Game) doCastSpell(player *playerlib.Player, spell spellbook.Spell) {
             after := func(chosenCity *citylib.City) bool {
                 for _, city := range player.Cities {
                     if city != chosenCity && city.Buildings.Contains(building.BuildingSummoningCircle) {
-                        city.Buildings.Remove(building.BuildingSummoningCircle)
+                        city.RemoveBuilding(building.BuildingSummoningCircle)
                         break
                     }
                 }
@@ -832,11 +832,11 @@ func (game *Game) doCastSpell(player *playerlib.Player, spell spellbook.Spell) {
 
                 for _, city := range player.Cities {
                     if city != chosenCity && city.Buildings.Contains(building.BuildingFortress) {
-                        city.Buildings.Remove(building.BuildingFortress)
+                        city.RemoveBuilding(building.BuildingFortress)
 
                         if city.Buildings.Contains(building.BuildingSummoningCircle) {
-                            chosenCity.Buildings.Insert(building.BuildingSummoningCircle)
-                            city.Buildings.Remove(building.BuildingSummoningCircle)
+                            chosenCity.AddBuilding(building.BuildingSummoningCircle)
+                            city.RemoveBuilding(building.BuildingSummoningCircle)
                         }
                         break
                     }
@@ -2393,6 +2393,8 @@ func (game *Game) doCastEnchantRoad(yield coroutine.YieldFunc, tileX int, tileY
             }
         }
     }
+
+    game.Model.InvalidateMovementCosts()
 }
 
 func (game *Game) doCastEarthLore(yield coroutine.YieldFunc, tileX int, tileY int, player *playerlib.Player, soundIndex int) {
@@ -2407,15 +2409,22 @@ func (game *Game) doCastChangeTerrain(yield coroutine.YieldFunc, tileX int, tile
     update := func (x int, y int, frame int) {
         if frame == 7 {
             mapObject := game.Model.CurrentMap()
+            change := func (terrainType terrain.TerrainType) {
+                game.Model.TransformNow(mapObject.Plane, maplib.TerrainTransform{X: x, Y: y, Kind: maplib.TransformTerrain, Terrain: terrainType})
+            }
+
             switch mapObject.GetTile(x, y).Tile.TerrainType() {
                 case terrain.Desert, terrain.Forest, terrain.Hill, terrain.Swamp:
-                    mapObject.Map.SetTerrainAt(x, y, terrain.Grass, mapObject.Data, mapObject.Plane)
+                    change(terrain.Grass)
                 case terrain.Grass:
-                    mapObject.Map.SetTerrainAt(x, y, terrain.Forest, mapObject.Data, mapObject.Plane)
+                    change(terrain.Forest)
                 case terrain.Volcano:
-                    mapObject.RemoveVolcano(x, y)
+                    // volcanoes that were not raised by a spell have no extra to remove
+                    if !game.Model.TransformNow(mapObject.Plane, maplib.TerrainTransform{X: x, Y: y, Kind: maplib.TransformRevertVolcano}) {
+                        change(terrain.Mountain)
+                    }
                 case terrain.Mountain:
-                    mapObject.Map.SetTerrainAt(x, y, terrain.Hill, mapObject.Data, mapObject.Plane)
+                    change(terrain.Hill)
             }
         }
     }
@@ -2436,6 +2445,8 @@ func (game *Game) doCastTransmute(yield coroutine.YieldFunc, tileX int, tileY in
                 case data.BonusSilverOre: mapObject.SetBonus(x, y, data.BonusMithrilOre)
                 case data.BonusMithrilOre: mapObject.SetBonus(x, y, data.BonusSilverOre)
             }
+
+            game.Model.TerrainChanged(mapObject.Plane, []image.Point{image.Pt(x, y)})
         }
     }
 
@@ -2455,7 +2466,7 @@ func (game *Game) doCastRaiseVolcano(yield coroutine.YieldFunc, tileX int, tileY
     game.doCastOnMap(yield, tileX, tileY, 11, 98, update)
 
     mapObject := game.Model.CurrentMap()
-    mapObject.SetVolcano(tileX, tileY, player)
+    game.Model.TransformNow(mapObject.Plane, maplib.TerrainTransform{X: tileX, Y: tileY, Kind: maplib.TransformRaiseVolcano, Caster: player})
 
     // volcanoes may destroy buildings if cast in a city
     for _, player := range game.Model.Players {
@@ -2463,7 +2474,7 @@ func (game *Game) doCastRaiseVolcano(yield coroutine.YieldFunc, tileX int, tileY
         if city != nil {
             for _, building := range city.Buildings.Values() {
                 if rand.N(100) < 15 {
-                    city.Buildings.Remove(building)
+                    city.RemoveBuilding(building)
                 }
             }
         }
@@ -2477,8 +2488,7 @@ func (game *Game) doCastCorruption(yield coroutine.YieldFunc, tileX int, tileY i
         if frame == 6 {
             mapObject := game.Model.CurrentMap()
             if y >= 0 || y < mapObject.Map.Rows() {
-                x = mapObject.WrapX(x)
-                mapObject.SetCorruption(x, y)
+                game.Model.TransformNow(mapObject.Plane, maplib.TerrainTransform{X: x, Y: y, Kind: maplib.TransformCorrupt})
             }
         }
     }
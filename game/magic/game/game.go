@@ -12,6 +12,7 @@ import (
     "context"
     "strings"
     "slices"
+    "cmp"
     "errors"
     "time"
 
@@ -30,6 +31,7 @@ import (
     "github.com/kazzmir/master-of-magic/game/magic/camera"
     "github.com/kazzmir/master-of-magic/game/magic/banish"
     playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/ai"
     "github.com/kazzmir/master-of-magic/game/magic/combat"
     "github.com/kazzmir/master-of-magic/game/magic/unitview"
     citylib "github.com/kazzmir/master-of-magic/game/magic/city"
@@ -39,6 +41,7 @@ import (
     "github.com/kazzmir/master-of-magic/game/magic/cityview"
     "github.com/kazzmir/master-of-magic/game/magic/armyview"
     "github.com/kazzmir/master-of-magic/game/magic/citylistview"
+    "github.com/kazzmir/master-of-magic/game/magic/economyview"
     "github.com/kazzmir/master-of-magic/game/magic/magicview"
     "github.com/kazzmir/master-of-magic/game/magic/diplomacy"
     "github.com/kazzmir/master-of-magic/game/magic/data"
@@ -135,6 +138,15 @@ type GameEventAstrologer struct {
 type GameEventHistorian struct {
 }
 
+type GameEventEconomy struct {
+}
+
+type GameEventStatistics struct {
+}
+
+type GameEventTradeRoutes struct {
+}
+
 type GameEventCastSpellBook struct {
 }
 
@@ -145,6 +157,9 @@ type GameEventBuildRoad struct {
 type GameEventPauseWatchMode struct {
 }
 
+type GameEventAIDebugger struct {
+}
+
 type GameEventNotice struct {
     Message string
 }
@@ -395,6 +410,13 @@ type Game struct {
     WatchMode bool
     WatchModePaused bool
 
+    // traces of the ai players' turns, shown by the ai debugger. nil if tracing is off
+    AITrace *AITraceLog
+
+    // if set, the statistics are written to this csv file at the end of every turn
+    StatisticsPath string
+
+
     // press tab 5 times to enable
     DebugMode bool
 
@@ -404,7 +426,23 @@ type Game struct {
     Help helplib.Help
 
     Camera camera.Camera
-    
+
+    // show the whole map in the minimap rather than the area around the camera
+    MinimapFit bool
+    MinimapLayer maplib.MinimapLayer
+    // draw the borders of each wizard's territory on the overworld and minimap
+    ShowBorders bool
+    // draw the trade routes of the human player on the overworld, when trade routes are enabled
+    ShowTradeRoutes bool
+    // show the city and army lists in their sortable layout, when the screen is scaled up enough
+    ManageLists bool
+
+    // scores for the city site advisor while a settler is selected
+    citySiteAdvice *CitySiteAdvice
+
+    // the territory drawn for the human player, see territory.go
+    territoryCache *territoryCache
+
     Drawers []func(screen *ebiten.Image)
 }
 
@@ -553,8 +591,18 @@ func MakeGame(lbxCache *lbx.LbxCache, music *musiclib.Music, gameSettings *setti
         return nil
     }
 
+    var scenario *maplib.Scenario
+    if settings.Scenario != "" {
+        loaded, err := maplib.LoadScenario(settings.Scenario, terrainData, settings.Magic, settings.Difficulty)
+        if err != nil {
+            log.Printf("Unable to load scenario %v: %v", settings.Scenario, err)
+            return nil
+        }
+        scenario = &loaded
+    }
+
     return MakeGameWithModel(lbxCache, music, gameSettings, func (lbxCache *lbx.LbxCache, events chan GameEvent) *GameModel {
-        return MakeGameModel(terrainData, settings, data.PlaneArcanus, events, heroNames, allSpells, createArtifactPool(lbxCache), buildingInfo)
+        return MakeGameModel(terrainData, settings, scenario, data.PlaneArcanus, events, heroNames, allSpells, createArtifactPool(lbxCache), buildingInfo)
     })
 }
 
@@ -620,6 +668,7 @@ func MakeGameWithModel(lbxCache *lbx.LbxCache, music *musiclib.Music, gameSettin
         Camera: camera.MakeCamera(),
         GameLoader: &DummyGameLoader{},
         AnimationSpeed: fraction.FromInt(1),
+        ShowTradeRoutes: true,
     }
 
     // game.Model = MakeGameModel(terrainData, settings, data.PlaneArcanus, game.Events, heroNames, game.AllSpells(), createArtifactPool(lbxCache), buildingInfo)
@@ -686,18 +735,9 @@ func (area CityValidArea) FindLocation() (int, int, bool) {
 func (game *Game) MakeCityValidArea(plane data.Plane) CityValidArea {
     out := make(CityValidArea)
 
-    mapUse := game.GetMap(plane)
-    continents := mapUse.Map.FindContinents()
-
-    for _, continent := range continents {
-        if continent.Size() > 100 {
-            for _, point := range continent.Values() {
-                tile := terrain.GetTile(mapUse.Map.Terrain[point.X][point.Y])
-                if point.Y > 3 && point.Y < mapUse.Map.Rows() - 3 && tile.IsLand() && !tile.IsMagic() && mapUse.GetEncounter(point.X, point.Y) == nil {
-                    out[point] = true
-                }
-            }
-        }
+    // the same tiles that a capital could be placed on
+    for _, point := range game.GetMap(plane).StartCandidates(nil) {
+        out[point] = true
     }
 
     return out
@@ -916,6 +956,10 @@ func (game *Game) doCityListView(yield coroutine.YieldFunc) {
         }
 
         view := citylistview.MakeCityListScreen(game.Cache, game.Model.GetHumanPlayer(), drawMinimap, selectCity)
+        if game.ManageLists {
+            view.Manage = true
+            view.UI = view.MakeUI()
+        }
 
         setDrawer(func (screen *ebiten.Image){
             view.Draw(screen)
@@ -927,6 +971,8 @@ func (game *Game) doCityListView(yield coroutine.YieldFunc) {
             }
         }
 
+        game.ManageLists = view.Manage
+
         // absorb most recent left click
         yield()
 
@@ -943,6 +989,24 @@ func (game *Game) doCityListView(yield coroutine.YieldFunc) {
     game.RefreshUI()
 }
 
+func (game *Game) doEconomyView(yield coroutine.YieldFunc) {
+    player := game.Model.GetHumanPlayer()
+    report := player.MakeEconomyReport(game.Model.ComputePowerSources(player), game.Model)
+
+    view := economyview.MakeEconomyScreen(game.Cache, report, game.TurnDate())
+
+    game.PushDrawer(func (screen *ebiten.Image){
+        view.Draw(screen)
+    })
+    defer game.PopDrawer()
+
+    for view.Update() == economyview.EconomyScreenStateRunning {
+        if yield() != nil {
+            return
+        }
+    }
+}
+
 func (game *Game) doArmyView(yield coroutine.YieldFunc) {
     cities := game.Model.AllCities()
 
@@ -959,7 +1023,22 @@ func (game *Game) doArmyView(yield coroutine.YieldFunc) {
         game.doVault(yield, nil)
     }
 
-    army := armyview.MakeArmyScreen(game.Cache, game.Model.GetHumanPlayer(), drawMinimap, showVault)
+    player := game.Model.GetHumanPlayer()
+
+    army := armyview.MakeArmyScreen(game.Cache, player, drawMinimap, showVault)
+    army.StackStrength = ai.StackCombatStrength
+    army.CityDistance = func(stack *playerlib.UnitStack, city *citylib.City) int {
+        return game.GetMap(city.Plane).TileDistance(stack.X(), stack.Y(), city.X, city.Y)
+    }
+    army.SendToCity = func(stack *playerlib.UnitStack, city *citylib.City) {
+        stack.CurrentPath = nil
+        stack.Orders = playerlib.MakeGotoCityOrders(image.Pt(city.X, city.Y))
+        game.startOrders(player, stack)
+    }
+    if game.ManageLists {
+        army.Manage = true
+        army.UI = army.MakeUI()
+    }
 
     game.PushDrawer(func (screen *ebiten.Image){
         army.Draw(screen)
@@ -970,6 +1049,8 @@ func (game *Game) doArmyView(yield coroutine.YieldFunc) {
         yield()
     }
 
+    game.ManageLists = army.Manage
+
     if army.Player.SelectedStack != nil {
         stack := army.Player.SelectedStack
         select {
@@ -1019,6 +1100,9 @@ func (game *Game) doDiplomacy(yield coroutine.YieldFunc, player *playerlib.Playe
 
     logic(yield)
 
+    // a new treaty can open or close trade routes
+    game.Model.InvalidateTradeRoutes()
+
     yield()
     game.RefreshUI()
 }
@@ -1667,7 +1751,7 @@ type SettingsUI struct {
 }
 
 func (settings *SettingsUI) RunSettingsUI() {
-    group, quit := settingslib.MakeSettingsUI(settings.Yield, settings.Game.HudUI, settings.Game.Cache, &settings.Game.ImageCache, settings.Game.Settings, settings.Game.Music)
+    group, quit := settingslib.MakeSettingsUI(settings.Yield, settings.Game.HudUI, settings.Game.Cache, &settings.Game.ImageCache, settings.Game.Settings, settings.Game.Music, settings.Game.Model)
     settings.Game.doRunUI(settings.Yield, group, quit)
 }
 
@@ -2379,6 +2463,8 @@ func (game *Game) ProcessEvents(yield coroutine.YieldFunc) {
                         if game.WatchModePaused {
                             game.DoPause(yield)
                         }
+                    case *GameEventAIDebugger:
+                        game.showAIDebugger(yield)
 
                     case *GameEventMerchant:
                         merchant := event.(*GameEventMerchant)
@@ -2440,6 +2526,12 @@ func (game *Game) ProcessEvents(yield coroutine.YieldFunc) {
                         game.ShowAstrologer(yield)
                     case *GameEventHistorian:
                         game.ShowHistorian(yield)
+                    case *GameEventEconomy:
+                        game.doEconomyView(yield)
+                    case *GameEventStatistics:
+                        game.ShowStatistics(yield)
+                    case *GameEventTradeRoutes:
+                        game.ShowTrade(yield)
                     case *GameEventApprenticeUI:
                         game.ShowApprenticeUI(yield, game.Model.GetHumanPlayer())
                     case *GameEventArmyView:
@@ -3325,25 +3417,53 @@ func (game *Game) ResolveStackAt(x int, y int, plane data.Plane) {
     }
 }
 
-// returns true if the city was razed, and the amount of gold plundered from the city
-func (game *Game) defeatCity(yield coroutine.YieldFunc, attacker *playerlib.Player, attackerStack *playerlib.UnitStack, defender *playerlib.Player, city *citylib.City) (bool, int) {
-    raze := false
-    gold := defender.ComputePlunderedGold(city)
+/* the attacker takes the city. the city only suffers damage if the attacker won a battle for it, not if
+ * it was unguarded or its defenders fled. the caller is responsible for moving the plundered gold
+ */
+func (game *Game) defeatCity(yield coroutine.YieldFunc, attacker *playerlib.Player, attackerStack *playerlib.UnitStack, defender *playerlib.Player, city *citylib.City, battleWon bool, collateralDamage int) *CityAftermath {
+    aftermath := &CityAftermath{
+        City: city,
+        Attacker: attacker,
+        Defender: defender,
+        Gold: defender.ComputePlunderedGold(city),
+    }
+
+    if battleWon {
+        aftermath.Population, aftermath.Buildings = rollCityDamage(city, collateralDamage, attacker.GetBanner() == data.BannerBrown)
+        aftermath.applyDamage()
+    }
 
     if attacker.IsHuman() {
-        raze = game.confirmRazeTown(yield, city)
+        aftermath.Razed = game.confirmRazeTown(yield, city)
     } else {
-        raze = attacker.AIBehavior.ConfirmRazeTown(city)
+        aftermath.Razed = attacker.AIBehavior.ConfirmRazeTown(city)
     }
 
     containedFortress := city.Buildings.Contains(buildinglib.BuildingFortress)
 
-    if raze {
+    if aftermath.Razed {
         defender.RemoveCity(city)
     } else {
+        aftermath.Enchantments = defenderEnchantments(city, defender)
         ChangeCityOwner(city, defender, attacker, ChangeCityRemoveOwnerEnchantments)
     }
 
+    game.Model.InvalidateMovementCosts()
+
+    title := "CITY CAPTURED"
+    if aftermath.Razed {
+        title = "CITY RAZED"
+    }
+
+    for _, player := range []*playerlib.Player{attacker, defender} {
+        if player.IsHuman() {
+            select {
+                case game.Events <- &GameEventScroll{Title: title, Text: aftermath.Summary(game.Model.BuildingInfo, player)}:
+                default:
+            }
+        }
+    }
+
     if containedFortress {
         defender.Banished = true
 
@@ -3354,7 +3474,7 @@ func (game *Game) defeatCity(yield coroutine.YieldFunc, attacker *playerlib.Play
         // FIXME: automatically start casting spell of return if possible
     }
 
-    return raze, gold
+    return aftermath
 }
 
 func (game *Game) doBanish(yield coroutine.YieldFunc, attacker *playerlib.Player, defender *playerlib.Player) {
@@ -3402,6 +3522,8 @@ func (game *Game) doMoveSelectedUnit(yield coroutine.YieldFunc, player *playerli
 
     stepsTaken := 0
     stopMoving := false
+    // something happened along the way that the player should look at before the stack goes on with its orders
+    cancelOrders := false
     var mergeStack *playerlib.UnitStack
     // kind of a hack, in case the stack couldn't move due to spell ward or something we attempt to merge
     // the stack with whatever stack it is standing on
@@ -3439,6 +3561,7 @@ func (game *Game) doMoveSelectedUnit(yield coroutine.YieldFunc, player *playerli
             for _, unit := range stack.ActiveUnits() {
                 if !city.CanEnter(unit) {
                     stopMoving = true
+                    cancelOrders = true
                     game.Events <- &GameEventNotice{Message: fmt.Sprintf("%v can not enter the city of %v", unit.GetRawUnit().Name, city.Name)}
                     break quitMoving
                 }
@@ -3469,6 +3592,7 @@ func (game *Game) doMoveSelectedUnit(yield coroutine.YieldFunc, player *playerli
                 }
 
                 stopMoving = true
+                cancelOrders = true
                 break quitMoving
             }
 
@@ -3503,6 +3627,7 @@ func (game *Game) doMoveSelectedUnit(yield coroutine.YieldFunc, player *playerli
                     game.RefreshUI()
 
                     stopMoving = true
+                    cancelOrders = true
                     break quitMoving
                 }
 
@@ -3510,18 +3635,20 @@ func (game *Game) doMoveSelectedUnit(yield coroutine.YieldFunc, player *playerli
                 otherCity := entityInfo.FindCity(stack.X(), stack.Y(), stack.Plane())
                 if otherCity != nil {
                     defenderPlayer := game.GetCityOwner(otherCity)
-                    raze, gold := game.defeatCity(yield, player, stack, defenderPlayer, otherCity)
+                    // no battle was fought, so the city is not damaged
+                    aftermath := game.defeatCity(yield, player, stack, defenderPlayer, otherCity, false, 0)
 
                     // FIXME: show a notice about any fame won
-                    player.Fame = max(0, player.Fame + otherCity.FameForCaptureOrRaze(!raze))
+                    player.Fame = max(0, player.Fame + otherCity.FameForCaptureOrRaze(!aftermath.Razed))
                     defenderPlayer.Fame = max(0, defenderPlayer.Fame + otherCity.FameForCaptureOrRaze(false))
-                    player.Gold += gold
-                    defenderPlayer.Gold -= gold
+                    player.Gold += aftermath.Gold
+                    defenderPlayer.Gold -= aftermath.Gold
 
                     stack.ExhaustMoves()
                     game.RefreshUI()
 
                     stopMoving = true
+                    cancelOrders = true
                     break quitMoving
 
                 }
@@ -3555,6 +3682,20 @@ func (game *Game) doMoveSelectedUnit(yield coroutine.YieldFunc, player *playerli
         stack.CurrentPath = stack.CurrentPath[stepsTaken:]
     }
 
+    if cancelOrders {
+        stack.Orders = nil
+    } else if stack.Orders != nil && len(stack.CurrentPath) == 0 {
+        // reached a waypoint or the path was blocked, so find the way to the next waypoint
+        ok, err := game.Model.FollowOrders(player, stack)
+        game.ordersNotice(player, err)
+        if ok && stack.HasMoves() {
+            select {
+                case game.Events <- &GameEventMoveUnit{Player: player}:
+                default:
+            }
+        }
+    }
+
     // there might be some units in the stack that can still move, but if they move as a group
     // then all units should be out of moves once the unit with least amount of movement points is done
     if stack.GetRemainingMoves().IsZero() {
@@ -3564,7 +3705,7 @@ func (game *Game) doMoveSelectedUnit(yield coroutine.YieldFunc, player *playerli
     }
 
     // only merge stacks if both stacks are stopped, otherwise they can move through each other
-    if len(stack.CurrentPath) == 0 && mergeStack != nil && mergeStack != stack && mergeStack.X() == stack.X() && mergeStack.Y() == stack.Y() {
+    if len(stack.CurrentPath) == 0 && stack.Orders == nil && mergeStack != nil && mergeStack != stack && mergeStack.X() == stack.X() && mergeStack.Y() == stack.Y() {
         stack = player.MergeStacks(mergeStack, stack)
         player.SelectedStack = stack
         game.RefreshUI()
@@ -3641,10 +3782,20 @@ func (game *Game) doPlayerUpdate(yield coroutine.YieldFunc, player *playerlib.Pl
                     newX, newY = game.ScreenToTile(float64(mouseX), float64(mouseY))
                     // log.Printf("Click at %v, %v -> %v, %v", mouseX, mouseY, newX, newY)
                     newX = game.Model.CurrentMap().WrapX(newX)
+
+                    // shift click adds a waypoint to the route of the stack
+                    if (ebiten.IsKeyPressed(ebiten.KeyShiftLeft) || ebiten.IsKeyPressed(ebiten.KeyShiftRight)) && newY >= 0 && newY < mapUse.Height() {
+                        game.AddWaypoint(player, stack, newX, newY)
+                        newX = oldX
+                        newY = oldY
+                    }
                 }
             }
 
             if newX != oldX || newY != oldY {
+                // the player gave a new destination, so any orders are forgotten
+                stack.Orders = nil
+
                 activeUnits := stack.ActiveUnits()
                 if len(activeUnits) > 0 {
                     if newY >= 0 && newY < mapUse.Height() {
@@ -3721,8 +3872,9 @@ func (game *Game) doPlayerUpdate(yield coroutine.YieldFunc, player *playerlib.Pl
                         stack.SetBuildRoadPath(nil)
                     }
                 }
-            } else if leftClick && game.InOverworldArea(mouseX, mouseY) {
+            } else if leftClick && game.InOverworldArea(mouseX, mouseY) && !ebiten.IsKeyPressed(ebiten.KeyShiftLeft) && !ebiten.IsKeyPressed(ebiten.KeyShiftRight) {
                 stack.CurrentPath = nil
+                stack.Orders = nil
             }
         }
     }
@@ -3838,6 +3990,16 @@ func (game *Game) DoViewInput(yield coroutine.YieldFunc) {
                     }
                 case ebiten.KeyP:
                     game.Model.SwitchPlane()
+                case ebiten.KeyD:
+                    select {
+                        case game.Events <- &GameEventAIDebugger{}:
+                        default:
+                    }
+                case ebiten.KeyS:
+                    select {
+                        case game.Events <- &GameEventStatistics{}:
+                        default:
+                    }
             }
         }
     }
@@ -3902,7 +4064,8 @@ func (handlers *GameMoveHandlers) DoCombat(player *playerlib.Player, stack *play
 }
 
 func (handlers *GameMoveHandlers) DefeatCity(player *playerlib.Player, stack *playerlib.UnitStack, enemy *playerlib.Player, city *citylib.City) (bool, int) {
-    return handlers.Game.defeatCity(handlers.Yield, player, stack, enemy, city)
+    aftermath := handlers.Game.defeatCity(handlers.Yield, player, stack, enemy, city, false, 0)
+    return aftermath.Razed, aftermath.Gold
 }
 
 func MakeMoveHandlers(game *Game, yield coroutine.YieldFunc) MovementHandler {
@@ -3912,14 +4075,27 @@ func MakeMoveHandlers(game *Game, yield coroutine.YieldFunc) MovementHandler {
     }
 }
 
+// the view of the game given to an ai player. on the lower difficulties the ai only knows
+// about enemy cities and stacks it has seen itself
+func (game *Game) aiServices(player *playerlib.Player) playerlib.AIServices {
+    if game.Model.GetDifficulty().AICheatsInformation() || (game.Settings != nil && game.Settings.AISeesThroughFog) {
+        return game.Model
+    }
+
+    view := game.Model.FogView(player)
+    view.Refresh()
+    return view
+}
+
 func (game *Game) doAiUpdate(yield coroutine.YieldFunc, player *playerlib.Player) {
     // log.Printf("AI %v year %v: make decisions", player.Wizard.Name, game.Model.TurnNumber)
 
     if player.AIBehavior != nil {
+        aiServices := game.aiServices(player)
         decisionResult := make(chan []playerlib.AIDecision)
         go func() {
             // run AI in background so the UI doesn't totally freeze
-            out := player.AIBehavior.Update(player, game.Model)
+            out := player.AIBehavior.Update(player, aiServices)
             decisionResult <- out
             close(decisionResult)
         }()
@@ -4033,8 +4209,16 @@ func (game *Game) doAiUpdate(yield coroutine.YieldFunc, player *playerlib.Player
                 case *playerlib.AIProduceDecision:
                     produce := decision.(*playerlib.AIProduceDecision)
                     log.Printf("Year=%v AI %v(%v) city %v producing %v %v", game.Model.TurnNumber, player.Wizard.Name, player.GetBanner(), produce.City.Name, game.Model.BuildingInfo.Name(produce.Building), produce.Unit.Name)
-                    produce.City.ProducingBuilding = produce.Building
-                    produce.City.ProducingUnit = produce.Unit
+                    if produce.Enqueue {
+                        if produce.Unit.Equals(units.UnitNone) {
+                            produce.City.Enqueue(citylib.MakeQueueBuilding(produce.Building))
+                        } else {
+                            produce.City.Enqueue(citylib.MakeQueueUnit(produce.Unit, 1))
+                        }
+                    } else {
+                        produce.City.ProducingBuilding = produce.Building
+                        produce.City.ProducingUnit = produce.Unit
+                    }
                 case *playerlib.AIResearchSpellDecision:
                     research := decision.(*playerlib.AIResearchSpellDecision)
                     if player.ResearchingSpell.Invalid() {
@@ -4079,7 +4263,8 @@ func (game *Game) doAiUpdate(yield coroutine.YieldFunc, player *playerlib.Player
             game.tryMeldNode(stack, player)
         }
 
-        player.AIBehavior.PostUpdate(player, game.Model)
+        player.AIBehavior.PostUpdate(player, aiServices)
+        game.recordAITrace(player)
     }
 
     // if len(decisions) == 0 {
@@ -4108,6 +4293,9 @@ func (game *Game) doEnemyCityView(yield coroutine.YieldFunc, city *citylib.City,
  */
 func (game *Game) doCityScreen(yield coroutine.YieldFunc, city *citylib.City, player *playerlib.Player, newBuilding buildinglib.Building){
     cityScreen := cityview.MakeCityScreen(game.Cache, city, player, newBuilding)
+    if game.DebugMode {
+        cityScreen.EnableLayoutDebug()
+    }
 
     var cities []*citylib.City
     var stacks []*playerlib.UnitStack
@@ -4806,15 +4994,22 @@ func (game *Game) doCombat(yield coroutine.YieldFunc, attacker *playerlib.Player
     // fame
     var attackerFame, defenderFame int
 
+    cityPopulationLoss := 0
+    cityBuildingLoss := 0
+
     if state == combat.CombatStateAttackerWin || state == combat.CombatStateDefenderFlee {
         if zone.City != nil {
-            razeCity, gold := game.defeatCity(yield, attacker, attackerStack, defender, zone.City)
-            // if razeCity is true then we pass in false to get the fame for capturing the city
-            attackerFame += zone.City.FameForCaptureOrRaze(!razeCity)
+            // a city whose defenders fled is taken without a fight
+            aftermath := game.defeatCity(yield, attacker, attackerStack, defender, zone.City, state == combat.CombatStateAttackerWin, combatModel.CollateralDamage)
+            // if the city was razed then we pass in false to get the fame for capturing the city
+            attackerFame += zone.City.FameForCaptureOrRaze(!aftermath.Razed)
             defenderFame += zone.City.FameForCaptureOrRaze(false)
 
-            attacker.Gold += gold
-            defender.Gold -= gold
+            attacker.Gold += aftermath.Gold
+            defender.Gold -= aftermath.Gold
+
+            cityPopulationLoss = aftermath.Population
+            cityBuildingLoss = len(aftermath.Buildings)
         }
 
         winner, loser := distributeFame(attacker, defender, defenderStack, defeatedDefenders)
@@ -4853,39 +5048,11 @@ func (game *Game) doCombat(yield coroutine.YieldFunc, attacker *playerlib.Player
     attacker.Fame = max(0, attacker.Fame + attackerFame)
     defender.Fame = max(0, defender.Fame + defenderFame)
 
-    cityPopulationLoss := 0
-    var cityBuildingLoss []buildinglib.Building
-
-    if zone.City != nil && state == combat.CombatStateAttackerWin {
-        // maximum chance is 50%, minimum is 10%
-        chance := min(50, 10 + combatModel.CollateralDamage * 2)
-        for range zone.City.Citizens() - 1 {
-            if rand.N(100) < chance {
-                cityPopulationLoss += 1
-            }
-        }
-
-        minBuildingChance := 10
-        if attacker.GetBanner() == data.BannerBrown {
-            minBuildingChance = 50
-        }
-
-        chance = min(75, minBuildingChance + combatModel.CollateralDamage)
-        for _, building := range zone.City.Buildings.Values() {
-            if building == buildinglib.BuildingFortress || building == buildinglib.BuildingSummoningCircle {
-                continue
-            }
-
-            if rand.N(100) < chance {
-                cityBuildingLoss = append(cityBuildingLoss, building)
-            }
-        }
-
-        zone.City.Population -= cityPopulationLoss * 1000
-        for _, building := range cityBuildingLoss {
-            zone.City.Buildings.Remove(building)
-        }
-        zone.City.ResetCitizens()
+    switch state {
+        case combat.CombatStateAttackerWin, combat.CombatStateDefenderFlee:
+            game.Model.RecordBattle(attacker, defender)
+        case combat.CombatStateDefenderWin, combat.CombatStateAttackerFlee:
+            game.Model.RecordBattle(defender, attacker)
     }
 
     // Show end screen
@@ -4908,7 +5075,7 @@ func (game *Game) doCombat(yield coroutine.YieldFunc, attacker *playerlib.Player
         }
 
         // FIXME: show how much gold was plundered (or lost)
-        endScreen := combat.MakeCombatEndScreen(game.Cache, result, combatModel.DiedWhileFleeing, fame, cityPopulationLoss, len(cityBuildingLoss))
+        endScreen := combat.MakeCombatEndScreen(game.Cache, result, combatModel.DiedWhileFleeing, fame, cityPopulationLoss, cityBuildingLoss)
 
         lastDrawer := game.LastDrawer()
         game.PushDrawer(func (screen *ebiten.Image){
@@ -5430,6 +5597,38 @@ func (game *Game) MakeInfoUI(cornerX int, cornerY int) []*uilib.UIElement {
             },
             Hotkey: "(F9)",
         },
+        uilib.Selection{
+            Name: "Economy",
+            Action: func(){
+                select {
+                    case game.Events<- &GameEventEconomy{}:
+                    default:
+                }
+            },
+            Hotkey: "(F10)",
+        },
+        uilib.Selection{
+            Name: "Statistics",
+            Action: func(){
+                select {
+                    case game.Events<- &GameEventStatistics{}:
+                    default:
+                }
+            },
+            Hotkey: "(F11)",
+        },
+    }
+
+    if game.Model.Settings.TradeRoutes {
+        advisors = append(advisors, uilib.Selection{
+            Name: "Trade Routes",
+            Action: func(){
+                select {
+                    case game.Events<- &GameEventTradeRoutes{}:
+                    default:
+                }
+            },
+        })
     }
 
     return uilib.MakeSelectionUI(game.HudUI, game.Cache, &game.ImageCache, cornerX, cornerY, "Select An Advisor", advisors, true)
@@ -5586,6 +5785,7 @@ func (game *Game) CreateOutpost(settlers units.StackUnit, player *playerlib.Play
     player.SelectedStack = nil
     game.RefreshUI()
     player.AddCity(newCity)
+    game.Model.InvalidateMovementCosts()
 
     stack := player.FindStack(newCity.X, newCity.Y, newCity.Plane)
 
@@ -5933,6 +6133,16 @@ func (game *Game) MakeHudUI() *uilib.UI {
                                 game.ShowGrandVizierUI()
                             case keybindings.Get(keybinds.ActionMirror):
                                 game.ShowMirror()
+                            case keybindings.Get(keybinds.ActionEconomy):
+                                select {
+                                    case game.Events<- &GameEventEconomy{}:
+                                    default:
+                                }
+                            case keybindings.Get(keybinds.ActionStatistics):
+                                select {
+                                    case game.Events<- &GameEventStatistics{}:
+                                    default:
+                                }
                             case keybindings.Get(keybinds.ActionGameScreen):
                                 select {
                                     case game.Events <- &GameEventGameMenu{}:
@@ -5969,6 +6179,24 @@ func (game *Game) MakeHudUI() *uilib.UI {
                                     case game.Events <- &GameEventDefaultItemEditor{}:
                                     default:
                                 }
+                            case keybindings.Get(keybinds.ActionMinimapZoom):
+                                game.MinimapFit = !game.MinimapFit
+                            case keybindings.Get(keybinds.ActionMinimapLayer):
+                                game.MinimapLayer = game.MinimapLayer.Next()
+                            case keybindings.Get(keybinds.ActionTerritoryBorders):
+                                game.ShowBorders = !game.ShowBorders
+                            case keybindings.Get(keybinds.ActionPatrol):
+                                if player.SelectedStack != nil {
+                                    game.PatrolStack(player, player.SelectedStack)
+                                }
+                            case keybindings.Get(keybinds.ActionGotoCity):
+                                if player.SelectedStack != nil {
+                                    game.ShowGotoCity(player, player.SelectedStack)
+                                }
+                            case keybindings.Get(keybinds.ActionSentry):
+                                if player.SelectedStack != nil {
+                                    game.SentryStack(player, player.SelectedStack)
+                                }
 
                             case ebiten.KeyTab:
                                 if !game.DebugMode {
@@ -6121,6 +6349,11 @@ func (game *Game) MakeHudUI() *uilib.UI {
         x := game.Camera.GetX() + (minimapPoint.X - middleMapX)
         y := game.Camera.GetY() + (minimapPoint.Y - middleMapY)
 
+        if game.MinimapFit {
+            // the minimap is drawn in scaled pixels
+            x, y = game.Model.CurrentMap().FitMinimapTile(scale.Scale(minimapRect.Dx()), scale.Scale(minimapRect.Dy()), game.Camera.GetX(), scale.Scale(minimapPoint.X), scale.Scale(minimapPoint.Y))
+        }
+
         select {
             case game.Events <- &GameEventMoveCamera{Plane: game.Model.Plane, X: x, Y: y, Instant: false}:
             default:
@@ -6302,14 +6535,17 @@ func (game *Game) MakeHudUI() *uilib.UI {
 
                         useGeom := options.GeoM
 
-                        // draw a G on the unit if they are moving, P if purify, and B if building road
+                        // draw a G on the unit if they are moving, P if purify, B if building road and S if on sentry
                         if unit.GetBusy() == units.BusyStatusBuildRoad {
                             x, y := useGeom.Apply(float64(1), float64(1))
                             game.Fonts.WhiteFont.Print(screen, x, y, scale.ScaleAmount, options.ColorScale, "B")
                         } else if unit.GetBusy() == units.BusyStatusPurify {
                             x, y := useGeom.Apply(float64(1), float64(1))
                             game.Fonts.WhiteFont.Print(screen, x, y, scale.ScaleAmount, options.ColorScale, "P")
-                        } else if len(stack.CurrentPath) != 0 {
+                        } else if stack.Orders != nil && stack.Orders.Kind == playerlib.OrderSentry {
+                            x, y := useGeom.Apply(float64(1), float64(1))
+                            game.Fonts.WhiteFont.Print(screen, x, y, scale.ScaleAmount, options.ColorScale, "S")
+                        } else if len(stack.CurrentPath) != 0 || stack.Orders != nil {
                             x, y := useGeom.Apply(float64(1), float64(1))
                             game.Fonts.WhiteFont.Print(screen, x, y, scale.ScaleAmount, options.ColorScale, "G")
                         }
@@ -6795,6 +7031,10 @@ func (game *Game) DoNextUnit(player *playerlib.Player){
 
     if player.IsHuman() {
         if player.SelectedStack != nil {
+            // continue with the next leg of any standing orders
+            _, err := game.Model.FollowOrders(player, player.SelectedStack)
+            game.ordersNotice(player, err)
+
             if len(player.SelectedStack.CurrentPath) > 0 {
                 select {
                     case game.Events<- &GameEventMoveUnit{Player: player}:
@@ -7099,6 +7339,30 @@ func handleStasis(stack *playerlib.UnitStack) {
     }
 }
 
+// share food between the player's road-connected cities and move people out of full cities, see city/food.go
+func (game *Game) doFoodLogistics(player *playerlib.Player) {
+    // the cities are kept in a map, so give them a fixed order to decide who is fed first
+    cities := player.GetCities()
+    slices.SortFunc(cities, func(a *citylib.City, b *citylib.City) int {
+        return cmp.Compare(a.Name, b.Name)
+    })
+
+    citylib.DistributeFood(cities, game.Model.IsCityRoadConnected)
+
+    migrations := citylib.MigratePopulation(cities, func(from *citylib.City, to *citylib.City) int {
+        return game.GetMap(from.Plane).TileDistance(from.X, from.Y, to.X, to.Y)
+    })
+
+    for _, migration := range migrations {
+        if player.IsHuman() && migration.To.Citizens() > (migration.To.Population - migration.Population) / 1000 {
+            select {
+                case game.Events<- &GameEventNotice{Message: fmt.Sprintf("%v is overcrowded. Settlers have moved to %v, which has grown to a population of %v.", migration.From.Name, migration.To.Name, migration.To.Citizens())}:
+                default:
+            }
+        }
+    }
+}
+
 func (game *Game) StartPlayerTurn(player *playerlib.Player) {
     if player.IsHuman() {
         game.Model.ScrollEvents = nil
@@ -7127,6 +7391,8 @@ func (game *Game) StartPlayerTurn(player *playerlib.Player) {
     // timestop may have dissipated by now
     timeStop := player.HasEnchantment(data.EnchantmentTimeStop)
 
+    game.Model.UpdateTradeRoutes()
+
     player.Gold += player.GoldPerTurn()
     if player.Gold < 0 {
         player.Gold = 0
@@ -7205,10 +7471,24 @@ func (game *Game) StartPlayerTurn(player *playerlib.Player) {
     player.RemainingCastingSkill = player.ComputeOverworldCastingSkill()
 
     var removeCities []*citylib.City
+    // cities whose rebels rose up this turn, handled once all the cities are done
+    var rebellions []*citylib.City
 
     if !timeStop {
+        if game.Model.Settings.FoodLogistics {
+            game.doFoodLogistics(player)
+        }
+
         for _, city := range player.Cities {
             cityEvents := city.DoNextTurn(game.GetMap(city.Plane))
+
+            if game.Model.Settings.Rebellions && player.GetBanner() != data.BannerBrown {
+                rebellion := city.CheckRebellion()
+                if rebellion != nil {
+                    cityEvents = append(cityEvents, rebellion)
+                }
+            }
+
             for _, event := range cityEvents {
                 switch event.(type) {
                 case *citylib.CityEventPopulationGrowth:
@@ -7230,6 +7510,27 @@ func (game *Game) StartPlayerTurn(player *playerlib.Player) {
                             default:
                         }
                     }
+                case *citylib.CityEventStarvationWarning:
+                    if player.IsHuman() {
+                        select {
+                            case game.Events<- &GameEventNotice{Message: fmt.Sprintf("The people of %v are starving. Without %v more food the city will begin to lose its population.", city.Name, event.(*citylib.CityEventStarvationWarning).Deficit)}:
+                            default:
+                        }
+                    }
+                case *citylib.CityEventRebelsRising:
+                    if player.IsHuman() {
+                        when := "next turn"
+                        if turns := event.(*citylib.CityEventRebelsRising).TurnsLeft; turns > 1 {
+                            when = fmt.Sprintf("in %v turns", turns)
+                        }
+
+                        select {
+                            case game.Events<- &GameEventNotice{Message: fmt.Sprintf("The rebels of %v are growing restless. Unless order is restored they will rise up %v.", city.Name, when)}:
+                            default:
+                        }
+                    }
+                case *citylib.CityEventRebellion:
+                    rebellions = append(rebellions, city)
                 case *citylib.CityEventCityAbandoned:
                     removeCities = append(removeCities, city)
                     if player.IsHuman() {
@@ -7283,7 +7584,10 @@ func (game *Game) StartPlayerTurn(player *playerlib.Player) {
                     game.ResolveStackAt(city.X, city.Y, city.Plane)
 
                     if player.AIBehavior != nil {
-                        player.AIBehavior.ProducedUnit(city, player)
+                        // the ai only wants one unit at a time unless it queued up more
+                        if !newUnit.Queued {
+                            player.AIBehavior.ProducedUnit(city, player)
+                        }
                         log.Printf("Year=%v AI %v(%v) city %v created unit %v", game.Model.TurnNumber, player.Wizard.Name, player.GetBanner(), city.Name, overworldUnit.GetName())
                     }
                 }
@@ -7347,6 +7651,23 @@ func (game *Game) StartPlayerTurn(player *playerlib.Player) {
         player.RemoveCity(city)
     }
 
+    if len(removeCities) > 0 {
+        game.Model.InvalidateMovementCosts()
+    }
+
+    for _, city := range rebellions {
+        if !slices.Contains(removeCities, city) {
+            game.doRebellion(city, player)
+        }
+    }
+
+    // cities run by a governor rearrange their citizens now that they may have grown
+    for _, city := range player.Cities {
+        if city.RunGovernor() {
+            log.Printf("Year=%v %v(%v) governor of %v started %v", game.Model.TurnNumber, player.Wizard.Name, player.GetBanner(), city.Name, game.Model.BuildingInfo.Name(city.ProducingBuilding))
+        }
+    }
+
     game.maybeHireHero(player)
     game.maybeHireMercenaries(player)
     game.maybeBuyFromMerchant(player)
@@ -7357,6 +7678,15 @@ func (game *Game) StartPlayerTurn(player *playerlib.Player) {
         game.doExploreFogForAwareness(player)
     }
 
+    for _, stack := range game.Model.WakeSentries(player) {
+        if player.IsHuman() {
+            select {
+                case game.Events <- &GameEventNotice{Message: fmt.Sprintf("Units on sentry at %v, %v have spotted an enemy.", stack.X(), stack.Y())}:
+                default:
+            }
+        }
+    }
+
     // game.CenterCamera(player.Cities[0].X, player.Cities[0].Y)
     game.DoNextUnit(player)
     if player.IsHuman() {
@@ -7370,7 +7700,7 @@ func (game *Game) revertVolcanos() {
         for location, _ := range mapObject.ExtraMap {
             if mapObject.HasVolcano(location.X, location.Y) {
                 if rand.N(100) < 2 {
-                    mapObject.RemoveVolcano(location.X, location.Y)
+                    mapObject.ScheduleTransform(maplib.TerrainTransform{X: location.X, Y: location.Y, Kind: maplib.TransformRevertVolcano, Turn: game.Model.TurnNumber})
                 }
             }
         }
@@ -7419,7 +7749,8 @@ func (game *Game) doArmageddon() {
             // create 4 to 6 volcanoes
             for _, index := range rand.Perm(len(points))[:min(len(points), 4 + rand.IntN(2))] {
                 point := points[index]
-                game.GetMap(point.Plane).SetVolcano(point.X, point.Y, player)
+                turn := game.Model.TurnNumber + ArmageddonMinimumDelay + uint64(rand.IntN(ArmageddonRandomDelay + 1))
+                game.GetMap(point.Plane).ScheduleTransform(maplib.TerrainTransform{X: point.X, Y: point.Y, Kind: maplib.TransformRaiseVolcano, Turn: turn, Caster: player, Source: "Armageddon"})
             }
         }
     }
@@ -7456,7 +7787,8 @@ func (game *Game) doGreatWasting() {
             // corrupt 3 to 6 tiles
             for _, index := range rand.Perm(len(points))[:min(len(points), 3 + rand.IntN(3))] {
                 point := points[index]
-                game.GetMap(point.Plane).SetCorruption(point.X, point.Y)
+                turn := game.Model.TurnNumber + GreatWastingMinimumDelay + uint64(rand.IntN(GreatWastingRandomDelay + 1))
+                game.GetMap(point.Plane).ScheduleTransform(maplib.TerrainTransform{X: point.X, Y: point.Y, Kind: maplib.TransformCorrupt, Turn: turn, Caster: player, Source: "Great Wasting"})
             }
         }
     }
@@ -7468,8 +7800,11 @@ func ChangeCityOwner(city *citylib.City, owner *playerlib.Player, newOwner *play
     newOwner.AddCity(city)
     city.ReignProvider = newOwner
 
-    city.Buildings.Remove(buildinglib.BuildingFortress)
-    city.Buildings.Remove(buildinglib.BuildingSummoningCircle)
+    city.RemoveBuilding(buildinglib.BuildingFortress)
+    city.RemoveBuilding(buildinglib.BuildingSummoningCircle)
+
+    // the new owner decides for themselves whether the city should be governed
+    city.Governor = citylib.GovernorNone
 
     switch enchantmentChange {
         case ChangeCityKeepEnchantments:
@@ -7540,7 +7875,7 @@ func (game *Game) doChaosRift() {
 
             for _, building := range destroyedBuildings {
                 // emit a notice?
-                city.Buildings.Remove(building)
+                city.RemoveBuilding(building)
             }
         }
     }
@@ -7620,7 +7955,7 @@ func (game *Game) doMeteorStorm() {
                 }
 
                 for _, building := range destroyedBuildings {
-                    city.Buildings.Remove(building)
+                    city.RemoveBuilding(building)
                 }
 
             }
@@ -7628,6 +7963,10 @@ func (game *Game) doMeteorStorm() {
             for _, city := range removeCities {
                 player.RemoveCity(city)
             }
+
+            if len(removeCities) > 0 {
+                game.Model.InvalidateMovementCosts()
+            }
         }
     }
 
@@ -7678,6 +8017,9 @@ func (game *Game) EndOfTurn() {
         }
     }
 
+    game.Model.RecordStatistics()
+    game.writeStatisticsFile()
+
     game.revertVolcanos()
 
     // FIXME: the wiki says armageddon will not do anything while time stop is in effect.
@@ -7692,6 +8034,9 @@ func (game *Game) EndOfTurn() {
 
     game.doMeteorStorm()
 
+    game.Model.ScheduleGaiasBlessing()
+    game.Model.ApplyTerrainTransforms()
+
     game.Model.TurnNumber += 1
 
      // gate random-event rolls behind the user toggle; in-flight events continue
@@ -7757,6 +8102,47 @@ func (game *Game) DoNextTurn(){
     }
 }
 
+// a line in the color of the owner along each side of a tile that borders another wizard or unclaimed land
+func (overworld *Overworld) DrawBorders(screen *ebiten.Image, geom ebiten.GeoM){
+    tileWidth := float64(overworld.Map.TileWidth())
+    tileHeight := float64(overworld.Map.TileHeight())
+
+    screenGeom := scale.ScaleGeom(geom)
+    thickness := float32(scale.Scale(1.5) * overworld.Camera.GetAnimatedZoom())
+
+    minX, minY, maxX, maxY := overworld.Camera.GetTileBounds()
+    for x := minX; x < maxX; x++ {
+        for y := minY; y < maxY; y++ {
+            banner, ok := overworld.Territory.Owner(x, y)
+            if !ok {
+                continue
+            }
+
+            sides := overworld.Territory.BorderSides(x, y)
+
+            // inset the line a little so the borders of two wizards that touch are both visible
+            inset := 1.0
+            x1, y1 := screenGeom.Apply(float64(x) * tileWidth + inset, float64(y) * tileHeight + inset)
+            x2, y2 := screenGeom.Apply(float64(x + 1) * tileWidth - inset, float64(y + 1) * tileHeight - inset)
+
+            lineColor := banner.Color()
+
+            if sides[0] {
+                vector.StrokeLine(screen, float32(x1), float32(y1), float32(x2), float32(y1), thickness, lineColor, false)
+            }
+            if sides[1] {
+                vector.StrokeLine(screen, float32(x2), float32(y1), float32(x2), float32(y2), thickness, lineColor, false)
+            }
+            if sides[2] {
+                vector.StrokeLine(screen, float32(x1), float32(y2), float32(x2), float32(y2), thickness, lineColor, false)
+            }
+            if sides[3] {
+                vector.StrokeLine(screen, float32(x1), float32(y1), float32(x1), float32(y2), thickness, lineColor, false)
+            }
+        }
+    }
+}
+
 func (overworld *Overworld) DrawFog(screen *ebiten.Image, geom ebiten.GeoM){
 
     fogImage := func(index int) *ebiten.Image {
@@ -7938,7 +8324,7 @@ func (overworld *Overworld) DrawFog(screen *ebiten.Image, geom ebiten.GeoM){
 
 type OverworldMap interface {
     XDistance(x1 int, x2 int) int
-    DrawMinimap(screen *ebiten.Image, cities []maplib.MiniMapCity, centerX int, centerY int, zoom float64, fog data.FogMap, counter uint64, crosshairs bool)
+    DrawMinimapView(screen *ebiten.Image, view maplib.MinimapView)
     Width() int
     Height() int
     WrapX(x int) int
@@ -7964,6 +8350,11 @@ type Overworld struct {
     Fog data.FogMap
     ShowAnimation bool
     FogBlack *ebiten.Image
+    MinimapFit bool
+    MinimapLayer maplib.MinimapLayer
+    // nil if borders are not shown and the minimap does not need it
+    Territory *maplib.Territory
+    ShowBorders bool
 }
 
 func (overworld *Overworld) ToCameraCoordinates(x int, y int) (int, int) {
@@ -7971,7 +8362,24 @@ func (overworld *Overworld) ToCameraCoordinates(x int, y int) (int, int) {
 }
 
 func (overworld *Overworld) DrawMinimap(screen *ebiten.Image){
-    overworld.Map.DrawMinimap(screen, overworld.CitiesMiniMap, overworld.Camera.GetX(), overworld.Camera.GetY(), overworld.Camera.GetZoom(), overworld.Fog, overworld.Counter, true)
+    zoom := max(overworld.Camera.GetZoom(), 0.1)
+
+    overworld.Map.DrawMinimapView(screen, maplib.MinimapView{
+        Cities: overworld.CitiesMiniMap,
+        CenterX: overworld.Camera.GetX(),
+        CenterY: overworld.Camera.GetY(),
+        Zoom: overworld.Camera.GetZoom(),
+        Fog: overworld.Fog,
+        Counter: overworld.Counter,
+        Crosshairs: true,
+        Layer: overworld.MinimapLayer,
+        Territory: overworld.Territory,
+        Borders: overworld.ShowBorders,
+        Fit: overworld.MinimapFit,
+        // the overworld is 240x182 pixels
+        ViewWidth: int(240 / float64(overworld.Map.TileWidth()) / zoom),
+        ViewHeight: int(182 / float64(overworld.Map.TileHeight()) / zoom),
+    })
 }
 
 // FIXME: pass in an UnscaledGeom here
@@ -8127,6 +8535,10 @@ func (overworld *Overworld) DrawOverworld(screen *ebiten.Image, geom ebiten.GeoM
 
     overworld.Map.DrawLayer2(overworld.Camera, overworld.Counter / 8, overworld.ImageCache, screen, geom)
 
+    if overworld.ShowBorders && overworld.Territory != nil {
+        overworld.DrawBorders(screen, geom)
+    }
+
     if overworld.Fog != nil {
         overworld.DrawFog(screen, geom)
     }
@@ -8265,11 +8677,23 @@ func (game *Game) DrawGame(screen *ebiten.Image){
         Fog: fog,
         ShowAnimation: game.State == GameStateUnitMoving,
         FogBlack: game.GetFogImage(),
+        MinimapFit: game.MinimapFit,
+        MinimapLayer: game.MinimapLayer,
+        ShowBorders: game.ShowBorders,
+    }
+
+    if (game.ShowBorders || game.MinimapLayer == maplib.MinimapLayerPolitical) && len(game.Model.Players) > 0 {
+        overworld.Territory = game.knownTerritory(game.Model.Players[0], game.Model.Plane)
     }
 
     if !game.WatchMode {
         overworldScreen := screen.SubImage(image.Rect(0, scale.Scale(18), scale.Scale(240), scale.Scale(data.ScreenHeight))).(*ebiten.Image)
         overworld.DrawOverworld(overworldScreen, ebiten.GeoM{})
+        if len(game.Model.Players) > 0 {
+            game.drawTradeRoutes(overworldScreen, game.Model.Players[0])
+            game.drawCitySites(overworldScreen, game.Model.Players[0], selectedStack)
+            game.drawOrders(overworldScreen, game.Model.Players[0], selectedStack)
+        }
 
         mini := screen.SubImage(game.GetMinimapRect()).(*ebiten.Image)
         if mini.Bounds().Dx() > 0 {
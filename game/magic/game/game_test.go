@@ -78,6 +78,10 @@ func (no *NoServices) PlagueActive(city *citylib.City) bool {
     return false
 }
 
+func (no *NoServices) FoodLogistics() bool {
+    return false
+}
+
 func (no *NoServices) GetSpellByName(name string) spellbook.Spell {
     return spellbook.Spell{}
 }
@@ -98,6 +102,7 @@ func TestChangeCityOwner(test *testing.T){
     city.ResetCitizens()
     city.AddBuilding(buildinglib.BuildingFortress)
     city.AddEnchantment(data.CityEnchantmentAltarOfBattle, player1.GetBanner())
+    city.Governor = citylib.GovernorProduction
     player1.AddCity(city)
 
     if city.ComputeUnrest() != 0 {
@@ -122,6 +127,14 @@ func TestChangeCityOwner(test *testing.T){
         test.Errorf("City still has the fortress")
     }
 
+    if city.Layout.Contains(buildinglib.BuildingFortress) {
+        test.Errorf("the fortress should be taken out of the city scape right away")
+    }
+
+    if city.Governor != citylib.GovernorNone {
+        test.Errorf("the governor of the previous owner should be turned off but was %v", city.Governor)
+    }
+
     if city.ComputeUnrest() != 3 {
         test.Errorf("Unrest is not updated")
     }
@@ -1,10 +1,13 @@
 package game
 
 import (
+    "fmt"
     "image"
     "slices"
+    "cmp"
     "math"
     "math/rand/v2"
+    "sync"
     _ "log"
 
     "github.com/kazzmir/master-of-magic/game/magic/data"
@@ -36,6 +39,12 @@ type GameModel struct {
 
     Settings setup.NewGameSettings
 
+    // the scenario the game was started from, nil for a random map
+    Scenario *maplib.Scenario
+
+    // how fair the start locations of the wizards were on each plane. this is not saved
+    StartPlacements map[data.Plane]maplib.StartPlacement
+
     heroNames map[int]map[herolib.HeroType]string
     allSpells spellbook.Spells
 
@@ -44,6 +53,15 @@ type GameModel struct {
     // this is lazily initialized on first use
     WaterBodies map[data.Plane][]*set.Set[image.Point]
 
+    // per player memory of the enemy cities seen through the fog of war, used by the ai and for the
+    // borders drawn for the human player. saved with the game
+    FogViews map[*playerlib.Player]*ai.FogServices
+
+    // the cost of entering each tile for each kind of stack, reset every turn. see movement.go
+    movementCosts map[MovementClass]*movementGrid
+    movementCostsTurn uint64
+    movementCostsLock sync.Mutex
+
     CurrentPlayer int
 
     TurnNumber uint64
@@ -59,9 +77,20 @@ type GameModel struct {
     // https://masterofmagic.fandom.com/wiki/Event
     RandomEvents []*RandomEvent
     LastEventTurn uint64
+
+    // a sample of every wizard taken each turn, see statistics.go
+    Statistics []*PlayerStatistics
+
+    // the turn until which pirates disrupt each wizard's trade routes, see trade.go
+    Piracy map[data.BannerType]uint64
+
+    // the trade routes as of the given turn, see trade.go
+    tradeRoutes []*TradeRoute
+    tradeRoutesTurn uint64
+    tradeRoutesValid bool
 }
 
-func MakeGameModel(terrainData *terrain.TerrainData, settings setup.NewGameSettings,
+func MakeGameModel(terrainData *terrain.TerrainData, settings setup.NewGameSettings, scenario *maplib.Scenario,
                    startingPlane data.Plane, events chan GameEvent,
                    heroNames map[int]map[herolib.HeroType]string, allSpells spellbook.Spells,
                    artifactPool *artifact.Catalog,
@@ -79,10 +108,19 @@ func MakeGameModel(terrainData *terrain.TerrainData, settings setup.NewGameSetti
         CurrentPlayer: -1,
         Events: events,
         BuildingInfo: buildingInfo,
+        Scenario: scenario,
+        StartPlacements: make(map[data.Plane]maplib.StartPlacement),
+    }
+
+    if scenario != nil {
+        model.ArcanusMap = scenario.ReconstructMap(data.PlaneArcanus, terrainData, &model, nil)
+        model.MyrrorMap = scenario.ReconstructMap(data.PlaneMyrror, terrainData, &model, nil)
+        return &model
     }
 
-    model.ArcanusMap = maplib.MakeMap(terrainData, settings.LandSize, settings.Magic, settings.Difficulty, data.PlaneArcanus, &model, planeTowers)
-    model.MyrrorMap = maplib.MakeMap(terrainData, settings.LandSize, settings.Magic, settings.Difficulty, data.PlaneMyrror, &model, planeTowers)
+    parameters := maplib.LandSizeParameters(settings.LandSize).WithSettings(settings.Water, settings.Continents, settings.Climate)
+    model.ArcanusMap = maplib.MakeMapWithParameters(terrainData, parameters, settings.Magic, settings.Difficulty, data.PlaneArcanus, &model, planeTowers)
+    model.MyrrorMap = maplib.MakeMapWithParameters(terrainData, parameters, settings.Magic, settings.Difficulty, data.PlaneMyrror, &model, planeTowers)
     return &model
 }
 
@@ -129,6 +167,7 @@ func (model *GameModel) AddPlayer(wizard setup.WizardCustom, human bool) *player
     }
 
     newPlayer := playerlib.MakePlayer(wizard, human, model.CurrentMap().Width(), model.CurrentMap().Height(), useNames, model)
+    newPlayer.DifficultyProvider = model
 
     if !human {
         newPlayer.AIBehavior = ai.MakeEnemy2AI()
@@ -213,8 +252,6 @@ func (model *GameModel) FindPath(oldX int, oldY int, newX int, newY int, player
         return nil, true
     }
 
-    allFlyers := stack.AllFlyers()
-
     // this is to avoid doing path finding at all so that we don't spend time trying to compute an impossible path
     // such as a water unit trying to move to land
     if fog.GetFog(useMap.WrapX(newX), newY) != data.FogTypeUnexplored {
@@ -255,6 +292,38 @@ func (model *GameModel) FindPath(oldX int, oldY int, newX int, newY int, player
         */
     }
 
+    target := image.Pt(useMap.WrapX(newX), newY)
+    search := model.makePathSearch(player, stack, fog, func (point image.Point) bool {
+        return point == target
+    })
+
+    path, ok := pathfinding.FindPath(image.Pt(oldX, oldY), image.Pt(newX, newY), 10000, search.TileCost, search.Neighbors, search.SamePoint)
+    if ok {
+        // ignore the start point
+        return path[1:], true
+    }
+
+    return nil, false
+}
+
+// the functions that describe the map to the path finding algorithms for one stack
+type pathSearch struct {
+    TileCost pathfinding.TileCostFunc
+    Neighbors pathfinding.NeighborsFunc
+    Normalize pathfinding.NormalizeFunc
+    SamePoint pathfinding.PointEqFunc
+    // true for tiles with an enemy or an encounter, which can be attacked but not moved through
+    Stop pathfinding.StopFunc
+}
+
+/* isTarget is true for the tiles the stack may end its move on even if there is an enemy there,
+ * the points passed to it are normalized
+ */
+func (model *GameModel) makePathSearch(player *playerlib.Player, stack playerlib.PathStack, fog data.FogMap, isTarget func(image.Point) bool) pathSearch {
+    useMap := model.GetMap(stack.Plane())
+    allFlyers := stack.AllFlyers()
+    class := MakeMovementClass(stack)
+
     normalized := func (a image.Point) image.Point {
         return image.Pt(useMap.WrapX(a.X), a.Y)
     }
@@ -299,6 +368,10 @@ func (model *GameModel) FindPath(oldX int, oldY int, newX int, newY int, player
         return false
     })
 
+    stop := func (point image.Point) bool {
+        return useMap.GetEncounter(point.X, point.Y) != nil || containsEnemy(point.X, point.Y)
+    }
+
     tileCost := func (x1 int, y1 int, x2 int, y2 int) float64 {
         x1 = useMap.WrapX(x1)
         x2 = useMap.WrapX(x2)
@@ -313,16 +386,8 @@ func (model *GameModel) FindPath(oldX int, oldY int, newX int, newY int, player
 
         // FIXME: it might be more optimal to put the infinity cases into the neighbors function instead
 
-        // avoid encounters
-        encounter := useMap.GetEncounter(x2, y2)
-        if encounter != nil {
-            if !tileEqual(image.Pt(x2, y2), image.Pt(newX, newY)) {
-                return pathfinding.Infinity
-            }
-        }
-
-        // avoid enemy units/cities
-        if !tileEqual(image.Pt(x2, y2), image.Pt(newX, newY)) && containsEnemy(x2, y2) {
+        // avoid encounters and enemy units/cities unless they are what the stack is moving to
+        if !isTarget(image.Pt(x2, y2)) && stop(image.Pt(x2, y2)) {
             return pathfinding.Infinity
         }
 
@@ -340,7 +405,7 @@ func (model *GameModel) FindPath(oldX int, oldY int, newX int, newY int, player
             return baseCost + 3
         }
 
-        cost, ok := model.ComputeTerrainCost(stack, x1, y1, x2, y2, useMap, getStack)
+        cost, ok := model.computeTerrainCost(stack, class, x1, y1, x2, y2, useMap, getStack)
         if !ok {
             return pathfinding.Infinity
         }
@@ -391,13 +456,13 @@ func (model *GameModel) FindPath(oldX int, oldY int, newX int, newY int, player
         return out
     }
 
-    path, ok := pathfinding.FindPath(image.Pt(oldX, oldY), image.Pt(newX, newY), 10000, tileCost, neighbors, tileEqual)
-    if ok {
-        // ignore the start point
-        return path[1:], true
+    return pathSearch{
+        TileCost: tileCost,
+        Neighbors: neighbors,
+        Normalize: normalized,
+        SamePoint: tileEqual,
+        Stop: stop,
     }
-
-    return nil, false
 }
 
 func (model *GameModel) AllCities() []*citylib.City {
@@ -440,10 +505,13 @@ func (model *GameModel) GetWaterBody(mapUse *maplib.Map, x int, y int) *set.Set[
 
 /* return the cost to move from the current position the stack is on to the new given coordinates.
  * also return true/false if the move is even possible
- * FIXME: some values used by this logic could be precomputed and passed in as an argument. Things like 'containsFriendlyCity' could be a map of all cities
- * on the same plane as the unit, thus avoiding the expensive player.FindCity() call
  */
 func (model *GameModel) ComputeTerrainCost(stack playerlib.PathStack, sourceX int, sourceY int, destX int, destY int, mapUse *maplib.Map, getStack func(int, int) (playerlib.PathStack, bool)) (fraction.Fraction, bool) {
+    return model.computeTerrainCost(stack, MakeMovementClass(stack), sourceX, sourceY, destX, destY, mapUse, getStack)
+}
+
+// the cost of entering the tile comes from the movement cost cache, see movement.go
+func (model *GameModel) computeTerrainCost(stack playerlib.PathStack, class MovementClass, sourceX int, sourceY int, destX int, destY int, mapUse *maplib.Map, getStack func(int, int) (playerlib.PathStack, bool)) (fraction.Fraction, bool) {
     /*
     if stack.OutOfMoves() {
         return fraction.Zero(), false
@@ -454,15 +522,15 @@ func (model *GameModel) ComputeTerrainCost(stack playerlib.PathStack, sourceX in
         return fraction.Zero(), true
     }
 
-    tileFrom := mapUse.GetTile(sourceX, sourceY)
     tileTo := mapUse.GetTile(destX, destY)
 
     if !tileTo.Valid() {
         return fraction.Zero(), false
     }
 
-    if stack.AllFlyers() {
-        return fraction.FromInt(1), true
+    // flyers can go anywhere
+    if class.Mode == MovementFly {
+        return model.tileEnterCost(class, mapUse, destX, destY), true
     }
 
     // can't move from land to ocean unless all units are flyers or swimmers
@@ -487,18 +555,6 @@ func (model *GameModel) ComputeTerrainCost(stack playerlib.PathStack, sourceX in
         */
     }
 
-    containsFriendlyCity := func (x int, y int) bool {
-        for _, player := range model.Players {
-            if player.GetBanner() == stack.GetBanner() {
-                if player.FindCity(x, y, stack.Plane()) != nil {
-                    return true
-                }
-            }
-        }
-
-        return false
-    }
-
     // sailing units cannot move onto land. Check ALL units (not just active
     // ones) so an inactive ship in the stack is never dragged ashore when the
     // active land units walk onto land.
@@ -509,66 +565,38 @@ func (model *GameModel) ComputeTerrainCost(stack playerlib.PathStack, sourceX in
     }
 
     // this feels like it can be improved
-    if tileFrom.Tile.IsWater() && tileTo.Tile.IsWater() && !stack.CanMoveOnLand(true) {
-        dx := mapUse.XDistance(sourceX, destX)
-        dy := destY - sourceY
-        if !tileFrom.CanTraverse(terrain.ToDirection(dx, dy), maplib.TraverseWater) {
-            return fraction.Zero(), false
-        }
-    }
-
-    road_v, ok := tileTo.Extras[maplib.ExtraKindRoad]
-    if ok {
-        road := road_v.(*maplib.ExtraRoad)
-        if road.Enchanted {
-            if stack.ActiveUnitsDoesntHaveAbility(data.AbilityNonCorporeal) {
-                return fraction.Zero(), true
+    if tileTo.Tile.IsWater() && !stack.CanMoveOnLand(true) {
+        tileFrom := mapUse.GetTile(sourceX, sourceY)
+        if tileFrom.Tile.IsWater() {
+            dx := mapUse.XDistance(sourceX, destX)
+            dy := destY - sourceY
+            if !tileFrom.CanTraverse(terrain.ToDirection(dx, dy), maplib.TraverseWater) {
+                return fraction.Zero(), false
             }
         }
-
-        return fraction.Make(1, 2), true
     }
 
-    if containsFriendlyCity(destX, destY) {
-        return fraction.Make(1, 2), true
-    }
+    return model.tileEnterCost(class, mapUse, destX, destY), true
+}
 
-    if stack.HasPathfinding() {
-        return fraction.Make(1, 2), true
+// which wizard controls which tiles of the plane
+// what the player remembers about the enemy cities it has seen, created on first use
+func (model *GameModel) FogView(player *playerlib.Player) *ai.FogServices {
+    if model.FogViews == nil {
+        model.FogViews = make(map[*playerlib.Player]*ai.FogServices)
     }
 
-    // FIXME: handle swimming, sailing properties
-    switch tileTo.Tile.TerrainType() {
-        case terrain.Desert: return fraction.FromInt(1), true
-        case terrain.SorceryNode: return fraction.FromInt(1), true
-        case terrain.Grass: return fraction.FromInt(1), true
-        case terrain.Forest:
-            if stack.ActiveUnitsHasAbility(data.AbilityForester) {
-                return fraction.FromInt(1), true
-            }
-            return fraction.FromInt(2), true
-        case terrain.River: return fraction.FromInt(2), true
-        case terrain.Tundra: return fraction.FromInt(2), true
-        case terrain.Hill:
-            if stack.ActiveUnitsHasAbility(data.AbilityMountaineer) {
-                return fraction.FromInt(1), true
-            }
-            return fraction.FromInt(3), true
-        case terrain.Swamp: return fraction.FromInt(3), true
-        case terrain.Mountain:
-            if stack.ActiveUnitsHasAbility(data.AbilityMountaineer) {
-                return fraction.FromInt(1), true
-            }
-            return fraction.FromInt(4), true
-        case terrain.Volcano:
-            if stack.ActiveUnitsHasAbility(data.AbilityMountaineer) {
-                return fraction.FromInt(1), true
-            }
-
-            return fraction.FromInt(4), true
+    view, ok := model.FogViews[player]
+    if !ok {
+        view = ai.MakeFogServices(player, model)
+        model.FogViews[player] = view
     }
 
-    return fraction.FromInt(1), true
+    return view
+}
+
+func (model *GameModel) GetTerritory(plane data.Plane) *maplib.Territory {
+    return citylib.ComputeTerritory(model.GetMap(plane), model.AllCities())
 }
 
 func (model *GameModel) ComputeCityStackInfo() playerlib.CityStackInfo {
@@ -741,6 +769,7 @@ func (model *GameModel) DoBuildRoads(player *playerlib.Player) {
             amount += math.Pow(tileWork.WorkPerEngineer, float64(engineerCount))
             if amount >= tileWork.TotalWork {
                 model.GetMap(plane).SetRoad(x, y, plane == data.PlaneMyrror)
+                model.InvalidateMovementCosts()
 
                 for _, unit := range stack.Units() {
                     if unit.GetBusy() == units.BusyStatusBuildRoad {
@@ -930,6 +959,10 @@ func (model *GameModel) PlagueActive(city *citylib.City) bool {
     })
 }
 
+func (model *GameModel) FoodLogistics() bool {
+    return model.Settings.FoodLogistics
+}
+
 func (model *GameModel) GoodMoonActive() bool {
     return slices.ContainsFunc(model.RandomEvents, func(event *RandomEvent) bool {
         return event.Type == RandomEventGoodMoon
@@ -1077,7 +1110,15 @@ func (model *GameModel) DoRandomEvents() {
                         gold := rand.N(target.Gold / 5) + target.Gold * 3 / 10
                         target.Gold = max(0, target.Gold - gold)
 
-                        return MakePiracyEvent(model.TurnNumber, gold, target), nil
+                        event := MakePiracyEvent(model.TurnNumber, gold, target)
+
+                        if model.Settings.TradeRoutes {
+                            model.InterruptTrade(target)
+                            model.UpdateTradeRoutes()
+                            event.Message += fmt.Sprintf(" Your trade routes are disrupted for %v turns.", PiracyTradeTurns)
+                        }
+
+                        return event, nil
                     case RandomEventGift:
                         var out []*artifact.Artifact
                         for _, candidate := range model.ArtifactPool.AvailableArtifacts() {
@@ -1139,6 +1180,7 @@ func (model *GameModel) DoRandomEvents() {
                                 }
 
                                 ChangeCityOwner(city, neutral, target, ChangeCityRemoveAllEnchantments)
+                                model.InvalidateMovementCosts()
 
                                 return MakeDiplomaticMarriageEvent(model.TurnNumber, city), nil
                             }
@@ -1233,35 +1275,15 @@ func (model *GameModel) DoRandomEvents() {
                         if neutralPlayer != nil {
                             var choices []*citylib.City
                             for _, city := range target.Cities {
-                                if city.HasFortress() || city.HasSummoningCircle() {
-                                    continue
-                                }
-
-                                // cannot target a city with a hero in it
-                                stack := target.FindStack(city.X, city.Y, city.Plane)
-                                if stack != nil && stack.HasHero() {
-                                    continue
+                                if model.CanCityDefect(city, target) {
+                                    choices = append(choices, city)
                                 }
-
-                                choices = append(choices, city)
                             }
 
                             if len(choices) > 0 {
                                 city := choices[rand.N(len(choices))]
 
-                                // disband any fantastic units garrisoned at the city, and convert to neutral all other normal units
-                                stack := target.FindStack(city.X, city.Y, city.Plane)
-                                if stack != nil {
-                                    for _, unit := range stack.Units() {
-                                        target.RemoveUnit(unit)
-                                        if unit.GetRace() != data.RaceFantastic {
-                                            unit.SetBanner(neutralPlayer.GetBanner())
-                                            neutralPlayer.AddUnit(unit)
-                                        }
-                                    }
-                                }
-
-                                ChangeCityOwner(city, target, neutralPlayer, ChangeCityRemoveAllEnchantments)
+                                model.DefectCity(city, target, neutralPlayer, ChangeCityRemoveAllEnchantments)
 
                                 // plague/population boom might still be active for the city. just leave them for now
 
@@ -1343,14 +1365,17 @@ func (model *GameModel) DoRandomEvents() {
  * add up all melded node tiles, all buildings that produce power, etc
  */
 func (model *GameModel) ComputePower(player *playerlib.Player) int {
-    if model.ManaShortActive() {
-        return 0
-    }
+    return model.ComputePowerSources(player).Total()
+}
+
+// the power of the wizard split up by where it comes from
+func (model *GameModel) ComputePowerSources(player *playerlib.Player) playerlib.PowerSources {
+    var sources playerlib.PowerSources
 
-    power := float64(0)
+    sources.ManaShort = model.ManaShortActive()
 
     for _, city := range player.Cities {
-        power += float64(city.ComputePower())
+        sources.Cities += city.ComputePower()
     }
 
     magicBonus := float64(1)
@@ -1412,21 +1437,17 @@ func (model *GameModel) ComputePower(player *playerlib.Player) int {
     }
 
     for _, node := range model.ArcanusMap.GetMeldedNodes(player) {
-        power += applyConjunction(node)
+        sources.Nodes += applyConjunction(node)
     }
 
     for _, node := range model.MyrrorMap.GetMeldedNodes(player) {
-        power += applyConjunction(node)
+        sources.Nodes += applyConjunction(node)
     }
 
-    power += float64(len(model.ArcanusMap.GetCastedVolcanoes(player)))
-    power += float64(len(model.MyrrorMap.GetCastedVolcanoes(player)))
-
-    if power < 0 {
-        power = 0
-    }
+    sources.Volcanoes += len(model.ArcanusMap.GetCastedVolcanoes(player))
+    sources.Volcanoes += len(model.MyrrorMap.GetCastedVolcanoes(player))
 
-    return int(power)
+    return sources
 }
 
 // returns all cities that are connected to this one via roads
@@ -1586,7 +1607,7 @@ func (model *GameModel) doEarthquake(city *citylib.City, player *playerlib.Playe
         roll := rand.N(100)
         if roll < 15 {
             destroyedBuildings = append(destroyedBuildings, building)
-            city.Buildings.Remove(building)
+            city.RemoveBuilding(building)
         }
     }
 
@@ -1602,7 +1623,7 @@ func (model *GameModel) doCallTheVoid(city *citylib.City, player *playerlib.Play
     for _, building := range city.Buildings.Values() {
         if rand.N(2) == 0 {
             destroyedBuildings = append(destroyedBuildings, building)
-            city.Buildings.Remove(building)
+            city.RemoveBuilding(building)
         }
     }
 
@@ -1683,31 +1704,37 @@ func (model *GameModel) GetEnemies(player *playerlib.Player) []*playerlib.Player
 }
 
 func (model *GameModel) ComputeMaximumPopulation(x int, y int, plane data.Plane) int {
-    // find catchment area of x, y
-    // for each square, compute food production
-    // maximum pop is food production
     maybeCity, _ := model.FindCity(x, y, plane)
     if maybeCity != nil {
         return maybeCity.MaximumCitySize()
     }
 
-    mapUse := model.GetMap(plane)
-    catchment := mapUse.GetCatchmentArea(x, y)
+    return model.GetMap(plane).ScoreCitySite(x, y).MaxPopulation
+}
 
-    food := fraction.Zero()
+/* the best places to build a city on the continent containing x, y, best first. only explored tiles
+ * where a city can be built are considered
+ */
+func (model *GameModel) RecommendCitySites(x int, y int, plane data.Plane, fog data.FogMap, count int) []maplib.CitySite {
+    mapUse := model.GetMap(plane)
 
-    for _, tile := range catchment {
-        food = food.Add(tile.FoodBonus())
-        bonus := tile.GetBonus()
-        food = food.Add(fraction.FromInt(bonus.FoodBonus()))
+    var sites []maplib.CitySite
+    for _, location := range model.FindSettlableLocations(x, y, plane, fog) {
+        if model.IsSettlableLocation(location.X, location.Y, plane) {
+            sites = append(sites, mapUse.ScoreCitySite(location.X, location.Y))
+        }
     }
 
-    maximum := int(food.ToFloat())
-    if maximum > 25 {
-        maximum = 25
+    slices.SortFunc(sites, func(a maplib.CitySite, b maplib.CitySite) int {
+        return cmp.Or(cmp.Compare(b.Score(), a.Score()),
+                      cmp.Compare(mapUse.TileDistance(x, y, a.X, a.Y), mapUse.TileDistance(x, y, b.X, b.Y)))
+    })
+
+    if len(sites) > count {
+        sites = sites[:count]
     }
 
-    return maximum
+    return sites
 }
 
 // all the cities on the continent containing the given x, y with the given plane, beloning to the given player
@@ -0,0 +1,216 @@
+package game
+
+import (
+    "image"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
+    "github.com/kazzmir/master-of-magic/game/magic/pathfinding"
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/lib/fraction"
+)
+
+/* the movement points it takes to enter a tile only depend on the terrain, roads, friendly cities and
+ * a few abilities of the moving stack, so the cost of each tile is computed once for every kind of
+ * stack and reused by all the path searches of a turn. whether a stack may enter a tile at all is still
+ * checked on every move since it depends on the exact units in the stack, such as a land unit boarding
+ * a ship.
+ *
+ * the cache is dropped at the start of every turn and whenever roads, terrain or the owner of a city
+ * changes, see InvalidateMovementCosts. the AI searches for paths on its own goroutine while the main
+ * thread draws the path of the selected stack, so the cache is guarded by a lock.
+ */
+
+type MovementMode int
+
+const (
+    MovementWalk MovementMode = iota
+    // every tile costs 1
+    MovementFly
+    // water costs 1 and land costs the same as walking
+    MovementSwim
+    // the stack has a ship, so it can only move on water where every tile costs 1
+    MovementSail
+)
+
+func (mode MovementMode) String() string {
+    switch mode {
+        case MovementWalk: return "walk"
+        case MovementFly: return "fly"
+        case MovementSwim: return "swim"
+        case MovementSail: return "sail"
+    }
+
+    return "?"
+}
+
+// stacks with the same movement class pay the same cost for every tile
+type MovementClass struct {
+    Mode MovementMode
+    Plane data.Plane
+    // friendly cities cost 1/2 like roads
+    Banner data.BannerType
+    Forester bool
+    Mountaineer bool
+    Pathfinding bool
+    // enchanted roads are free unless the stack has a non corporeal unit
+    NonCorporeal bool
+}
+
+func MakeMovementClass(stack playerlib.PathStack) MovementClass {
+    class := MovementClass{
+        Plane: stack.Plane(),
+        Banner: stack.GetBanner(),
+        Pathfinding: stack.HasPathfinding(),
+        NonCorporeal: !stack.ActiveUnitsDoesntHaveAbility(data.AbilityNonCorporeal),
+    }
+
+    if stack.AllFlyers() {
+        class.Mode = MovementFly
+    } else if !stack.CanMoveOnLand(true) {
+        class.Mode = MovementSail
+    } else if stack.AllSwimmers() {
+        class.Mode = MovementSwim
+    }
+
+    // every tile costs the same with pathfinding, so those stacks can all share one grid
+    if !class.Pathfinding {
+        class.Forester = stack.ActiveUnitsHasAbility(data.AbilityForester)
+        class.Mountaineer = stack.ActiveUnitsHasAbility(data.AbilityMountaineer)
+    }
+
+    return class
+}
+
+// the cost of entering each tile of a plane, filled in as tiles are looked at
+type movementGrid struct {
+    Height int
+    Costs []fraction.Fraction
+    Known []bool
+}
+
+// forget all cached movement costs. call this when a road is built, terrain changes or a city is founded,
+// captured or destroyed
+func (model *GameModel) InvalidateMovementCosts() {
+    model.movementCostsLock.Lock()
+    model.movementCosts = nil
+    model.movementCostsLock.Unlock()
+    // trade routes follow the same roads and cities
+    model.InvalidateTradeRoutes()
+}
+
+func (model *GameModel) getMovementGrid(class MovementClass, mapUse *maplib.Map) *movementGrid {
+    if model.movementCosts == nil || model.movementCostsTurn != model.TurnNumber {
+        model.movementCosts = make(map[MovementClass]*movementGrid)
+        model.movementCostsTurn = model.TurnNumber
+    }
+
+    grid, ok := model.movementCosts[class]
+    if !ok {
+        grid = &movementGrid{
+            Height: mapUse.Height(),
+            Costs: make([]fraction.Fraction, mapUse.Width() * mapUse.Height()),
+            Known: make([]bool, mapUse.Width() * mapUse.Height()),
+        }
+        model.movementCosts[class] = grid
+    }
+
+    return grid
+}
+
+// the movement points needed to enter the tile, assuming the stack can enter it at all
+func (model *GameModel) tileEnterCost(class MovementClass, mapUse *maplib.Map, x int, y int) fraction.Fraction {
+    x = mapUse.WrapX(x)
+    // the grid belongs to the plane of the map, not necessarily the plane the stack is on
+    class.Plane = mapUse.Plane
+
+    model.movementCostsLock.Lock()
+    defer model.movementCostsLock.Unlock()
+
+    grid := model.getMovementGrid(class, mapUse)
+    index := x * grid.Height + y
+    if !grid.Known[index] {
+        grid.Costs[index] = model.computeEnterCost(class, mapUse.GetTile(x, y))
+        grid.Known[index] = true
+    }
+
+    return grid.Costs[index]
+}
+
+func (model *GameModel) containsFriendlyCity(class MovementClass, x int, y int) bool {
+    for _, player := range model.Players {
+        if player.GetBanner() == class.Banner && player.FindCity(x, y, class.Plane) != nil {
+            return true
+        }
+    }
+
+    return false
+}
+
+func (model *GameModel) computeEnterCost(class MovementClass, tile maplib.FullTile) fraction.Fraction {
+    if class.Mode == MovementFly {
+        return fraction.FromInt(1)
+    }
+
+    road_v, ok := tile.Extras[maplib.ExtraKindRoad]
+    if ok {
+        road := road_v.(*maplib.ExtraRoad)
+        if road.Enchanted && !class.NonCorporeal {
+            return fraction.Zero()
+        }
+
+        return fraction.Make(1, 2)
+    }
+
+    if model.containsFriendlyCity(class, tile.X, tile.Y) {
+        return fraction.Make(1, 2)
+    }
+
+    // ships, swimmers and the land units carried by a ship all pay 1 for water
+    if tile.Tile.IsWater() {
+        return fraction.FromInt(1)
+    }
+
+    if class.Pathfinding {
+        return fraction.Make(1, 2)
+    }
+
+    switch tile.Tile.TerrainType() {
+        case terrain.Desert: return fraction.FromInt(1)
+        case terrain.SorceryNode: return fraction.FromInt(1)
+        case terrain.Grass: return fraction.FromInt(1)
+        case terrain.Forest:
+            if class.Forester {
+                return fraction.FromInt(1)
+            }
+            return fraction.FromInt(2)
+        case terrain.River: return fraction.FromInt(2)
+        case terrain.Tundra: return fraction.FromInt(2)
+        case terrain.Hill:
+            if class.Mountaineer {
+                return fraction.FromInt(1)
+            }
+            return fraction.FromInt(3)
+        case terrain.Swamp: return fraction.FromInt(3)
+        case terrain.Mountain, terrain.Volcano:
+            if class.Mountaineer {
+                return fraction.FromInt(1)
+            }
+            return fraction.FromInt(4)
+    }
+
+    return fraction.FromInt(1)
+}
+
+/* every tile the stack can reach from x, y with a path that costs at most maxCost, using the same costs
+ * as FindPath. tiles with enemies or encounters are included since they can be attacked, but the search
+ * does not go past them. use this instead of calling FindPath for each of many destinations
+ */
+func (model *GameModel) FindReachable(x int, y int, player *playerlib.Player, stack playerlib.PathStack, fog data.FogMap, maxCost float64) *pathfinding.ReachableSet {
+    search := model.makePathSearch(player, stack, fog, func (image.Point) bool {
+        return true
+    })
+
+    return pathfinding.FindReachable(image.Pt(x, y), maxCost, search.TileCost, search.Neighbors, search.Normalize, search.Stop)
+}
@@ -0,0 +1,337 @@
+package game
+
+import (
+    "fmt"
+    "errors"
+    "image"
+    "image/color"
+    "slices"
+    "cmp"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/pathfinding"
+    "github.com/kazzmir/master-of-magic/game/magic/units"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    "github.com/kazzmir/master-of-magic/lib/fraction"
+    "github.com/kazzmir/master-of-magic/lib/font"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// at most this many cities are offered by the go to city order
+const gotoCityChoices = 10
+
+/* give the stack the path to the next waypoint of its orders if it is not already moving somewhere.
+ * returns true if the stack has a path to follow. an error is returned if the orders had to be
+ * cancelled, such as when there is no way to reach the next waypoint
+ */
+func (model *GameModel) FollowOrders(player *playerlib.Player, stack *playerlib.UnitStack) (bool, error) {
+    if len(stack.CurrentPath) > 0 {
+        return true, nil
+    }
+
+    orders := stack.Orders
+    if orders == nil || orders.Kind == playerlib.OrderSentry {
+        return false, nil
+    }
+
+    mapUse := model.GetMap(stack.Plane())
+
+    if orders.Kind == playerlib.OrderGotoCity {
+        target, _ := orders.Target()
+        if player.FindCity(mapUse.WrapX(target.X), target.Y, stack.Plane()) == nil {
+            stack.Orders = nil
+            return false, errors.New("The city the units were going to is no longer yours.")
+        }
+    }
+
+    // skip the waypoints the stack is already standing on
+    for range len(orders.Waypoints) {
+        target, ok := orders.Target()
+        if !ok {
+            break
+        }
+
+        if mapUse.WrapX(target.X) != stack.X() || target.Y != stack.Y() {
+            break
+        }
+
+        if !orders.Arrived() {
+            break
+        }
+    }
+
+    target, ok := orders.Target()
+    if !ok || (mapUse.WrapX(target.X) == stack.X() && target.Y == stack.Y()) {
+        // all done
+        stack.Orders = nil
+        return false, nil
+    }
+
+    if !orders.StartLeg(model.TurnNumber) {
+        return false, nil
+    }
+
+    path, ok := model.FindPath(stack.X(), stack.Y(), target.X, target.Y, player, stack, player.GetFog(stack.Plane()))
+    if !ok || len(path) == 0 {
+        stack.Orders = nil
+        return false, fmt.Errorf("The units can not reach %v, %v and their orders were cancelled.", mapUse.WrapX(target.X), target.Y)
+    }
+
+    stack.CurrentPath = path
+    return true, nil
+}
+
+// the turn on which the stack reaches each point of the path, 0 is this turn. like the original game a unit
+// with any movement left can always make one more move
+func (model *GameModel) PathTurns(player *playerlib.Player, stack *playerlib.UnitStack, path pathfinding.Path) []int {
+    speed := stack.GetMovementSpeed()
+    if !speed.GreaterThan(fraction.Zero()) {
+        return nil
+    }
+
+    mapUse := model.GetMap(stack.Plane())
+    getStack := func(x int, y int) (playerlib.PathStack, bool) {
+        found := player.FindStack(mapUse.WrapX(x), y, stack.Plane())
+        return found, found != nil
+    }
+
+    moves := stack.GetRemainingMoves()
+    turn := 0
+    last := image.Pt(stack.X(), stack.Y())
+
+    out := make([]int, 0, len(path))
+    for _, point := range path {
+        if !moves.GreaterThan(fraction.Zero()) {
+            turn += 1
+            moves = speed
+        }
+
+        cost, ok := model.ComputeTerrainCost(stack, last.X, last.Y, point.X, point.Y, mapUse, getStack)
+        if !ok {
+            // unexplored tiles are assumed to be passable
+            cost = fraction.FromInt(1)
+        }
+
+        moves = moves.Subtract(cost)
+        out = append(out, turn)
+        last = point
+    }
+
+    return out
+}
+
+// wake up the stacks on sentry that can see an enemy stack
+func (model *GameModel) WakeSentries(player *playerlib.Player) []*playerlib.UnitStack {
+    var woken []*playerlib.UnitStack
+
+    for _, stack := range player.Stacks {
+        if stack.Orders == nil || stack.Orders.Kind != playerlib.OrderSentry {
+            continue
+        }
+
+        mapUse := model.GetMap(stack.Plane())
+        sightRange := stack.GetSightRange()
+
+        spotted := false
+        for _, enemy := range model.Players {
+            if enemy == player {
+                continue
+            }
+
+            for _, enemyStack := range enemy.Stacks {
+                if enemyStack.Plane() == stack.Plane() &&
+                   mapUse.TileDistance(stack.X(), stack.Y(), enemyStack.X(), enemyStack.Y()) <= sightRange &&
+                   player.IsVisible(enemyStack.X(), enemyStack.Y(), enemyStack.Plane()) {
+                    spotted = true
+                    break
+                }
+            }
+
+            if spotted {
+                break
+            }
+        }
+
+        if spotted {
+            stack.Orders = nil
+            for _, unit := range stack.Units() {
+                if unit.GetBusy() == units.BusyStatusPatrol {
+                    unit.SetBusy(units.BusyStatusNone)
+                }
+            }
+            stack.EnableMovers()
+            woken = append(woken, stack)
+        }
+    }
+
+    return woken
+}
+
+func (game *Game) ordersNotice(player *playerlib.Player, err error) {
+    if err != nil && player.IsHuman() {
+        select {
+            case game.Events <- &GameEventNotice{Message: err.Error()}:
+            default:
+        }
+    }
+}
+
+// start moving the stack along its orders right away
+func (game *Game) startOrders(player *playerlib.Player, stack *playerlib.UnitStack) {
+    stack.SetBuildRoadPath(nil)
+
+    ok, err := game.Model.FollowOrders(player, stack)
+    game.ordersNotice(player, err)
+
+    if ok && stack.HasMoves() {
+        select {
+            case game.Events <- &GameEventMoveUnit{Player: player}:
+            default:
+        }
+    }
+
+    game.RefreshUI()
+}
+
+// add a waypoint to the route of the stack. the first waypoint is wherever the stack is already going
+func (game *Game) AddWaypoint(player *playerlib.Player, stack *playerlib.UnitStack, x int, y int) {
+    point := image.Pt(game.GetMap(stack.Plane()).WrapX(x), y)
+
+    if stack.Orders != nil && stack.Orders.Kind == playerlib.OrderRoute {
+        stack.Orders.Waypoints = append(stack.Orders.Waypoints, point)
+        game.RefreshUI()
+        return
+    }
+
+    var waypoints []image.Point
+    if len(stack.CurrentPath) > 0 {
+        waypoints = append(waypoints, stack.CurrentPath[len(stack.CurrentPath) - 1])
+    }
+    waypoints = append(waypoints, point)
+
+    stack.Orders = playerlib.MakeRouteOrders(waypoints)
+    if len(stack.CurrentPath) == 0 {
+        game.startOrders(player, stack)
+    } else {
+        game.RefreshUI()
+    }
+}
+
+// patrol between where the stack is now and where it is going
+func (game *Game) PatrolStack(player *playerlib.Player, stack *playerlib.UnitStack) {
+    if len(stack.CurrentPath) == 0 {
+        game.ordersNotice(player, errors.New("Choose where to patrol to first, then give the patrol order."))
+        return
+    }
+
+    destination := stack.CurrentPath[len(stack.CurrentPath) - 1]
+    stack.Orders = playerlib.MakePatrolOrders(image.Pt(stack.X(), stack.Y()), destination)
+    game.startOrders(player, stack)
+}
+
+// stay in place until an enemy stack comes into view
+func (game *Game) SentryStack(player *playerlib.Player, stack *playerlib.UnitStack) {
+    stack.CurrentPath = nil
+    stack.SetBuildRoadPath(nil)
+    for _, unit := range stack.ActiveUnits() {
+        unit.SetBusy(units.BusyStatusPatrol)
+    }
+    stack.Orders = playerlib.MakeSentryOrders()
+    stack.EnableMovers()
+
+    game.DoNextUnit(player)
+    game.RefreshUI()
+}
+
+// choose one of the closest cities on the plane of the stack to go to
+func (game *Game) ShowGotoCity(player *playerlib.Player, stack *playerlib.UnitStack) {
+    mapUse := game.GetMap(stack.Plane())
+
+    var cities []data.PlanePoint
+    for point := range player.Cities {
+        if point.Plane == stack.Plane() && (point.X != stack.X() || point.Y != stack.Y()) {
+            cities = append(cities, point)
+        }
+    }
+
+    if len(cities) == 0 {
+        game.ordersNotice(player, errors.New("There are no other cities on this plane to go to."))
+        return
+    }
+
+    slices.SortFunc(cities, func(a data.PlanePoint, b data.PlanePoint) int {
+        return cmp.Compare(mapUse.TileDistance(stack.X(), stack.Y(), a.X, a.Y), mapUse.TileDistance(stack.X(), stack.Y(), b.X, b.Y))
+    })
+
+    var choices []uilib.Selection
+    for _, point := range cities[:min(len(cities), gotoCityChoices)] {
+        city := player.Cities[point]
+        choices = append(choices, uilib.Selection{
+            Name: city.Name,
+            Action: func(){
+                stack.CurrentPath = nil
+                stack.Orders = playerlib.MakeGotoCityOrders(image.Pt(city.X, city.Y))
+                game.startOrders(player, stack)
+            },
+        })
+    }
+
+    game.HudUI.AddElements(uilib.MakeSelectionUI(game.HudUI, game.Cache, &game.ImageCache, 40, 10, "Go To City", choices, true))
+}
+
+/* show how many turns the selected stack needs to get to the end of each turn's movement along its path,
+ * and draw a line through the waypoints of its orders that come after the current path
+ */
+func (game *Game) drawOrders(screen *ebiten.Image, player *playerlib.Player, stack *playerlib.UnitStack) {
+    if stack == nil || stack.Plane() != game.Model.Plane {
+        return
+    }
+
+    if len(stack.CurrentPath) > 0 {
+        turns := game.Model.PathTurns(player, stack, stack.CurrentPath)
+        for i, turn := range turns {
+            // the last tile the stack gets to on each turn
+            if i == len(turns) - 1 || turns[i + 1] != turn {
+                point := stack.CurrentPath[i]
+                x, y := game.TileToScreen(point.X, point.Y)
+                game.Fonts.WhiteFont.PrintOptions(screen, float64(x), float64(y), font.FontOptions{Justify: font.FontJustifyCenter, DropShadow: true, Scale: scale.ScaleAmount}, fmt.Sprintf("%v", turn + 1))
+            }
+        }
+    }
+
+    if stack.Orders == nil {
+        return
+    }
+
+    var waypoints []image.Point
+    if len(stack.CurrentPath) == 0 {
+        target, ok := stack.Orders.Target()
+        if ok {
+            waypoints = append(waypoints, target)
+        }
+    }
+    waypoints = append(waypoints, stack.Orders.Remaining()...)
+
+    if len(waypoints) == 0 {
+        return
+    }
+
+    lineColor := color.RGBA{R: 0xe0, G: 0xd0, B: 0x60, A: 0xc0}
+
+    last := image.Pt(stack.X(), stack.Y())
+    if len(stack.CurrentPath) > 0 {
+        last = stack.CurrentPath[len(stack.CurrentPath) - 1]
+    }
+
+    for _, point := range waypoints {
+        x1, y1 := game.TileToScreen(last.X, last.Y)
+        x2, y2 := game.TileToScreen(point.X, point.Y)
+        vector.StrokeLine(screen, scale.Scale(float32(x1)), scale.Scale(float32(y1)), scale.Scale(float32(x2)), scale.Scale(float32(y2)), float32(scale.ScaleAmount), lineColor, true)
+        vector.StrokeCircle(screen, scale.Scale(float32(x2)), scale.Scale(float32(y2)), scale.Scale(float32(3)), float32(scale.ScaleAmount), lineColor, true)
+        last = point
+    }
+}
@@ -3,6 +3,8 @@ package game
 import (
     "testing"
     "image"
+    "math/rand/v2"
+    "sync"
 
     playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
     herolib "github.com/kazzmir/master-of-magic/game/magic/hero"
@@ -11,6 +13,7 @@ import (
     "github.com/kazzmir/master-of-magic/game/magic/data"
     "github.com/kazzmir/master-of-magic/game/magic/setup"
     "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/pathfinding"
 )
 
 func TestPathBasic(test *testing.T) {
@@ -221,3 +224,296 @@ func TestPathBasic(test *testing.T) {
         }
     }()
 }
+
+// a land map of mixed terrain for measuring path finding, with a road along the middle row
+func makePathBenchmarkModel(columns int, rows int) (*GameModel, *playerlib.Player, *playerlib.UnitStack, data.FogMap) {
+    model := &GameModel{}
+
+    // the ocean is only used by tests that place it themselves
+    terrainData := terrain.MakeTerrainData([]image.Image{nil, nil, nil, nil, nil, nil}, []terrain.TerrainTile{
+        terrain.TerrainTile{TileIndex: 0, Tile: terrain.TileLand},
+        terrain.TerrainTile{TileIndex: 1, Tile: terrain.TileForest1},
+        terrain.TerrainTile{TileIndex: 2, Tile: terrain.TileHills1},
+        terrain.TerrainTile{TileIndex: 3, Tile: terrain.TileMountain1},
+        terrain.TerrainTile{TileIndex: 4, Tile: terrain.TileSwamp1},
+        terrain.TerrainTile{TileIndex: 5, Tile: terrain.TileOcean},
+    })
+
+    xmap := &maplib.Map{
+        Map: terrain.MakeMap(rows, columns),
+        Data: terrainData,
+        Plane: data.PlaneArcanus,
+        ExtraMap: make(map[image.Point]map[maplib.ExtraKind]maplib.ExtraTile),
+    }
+
+    random := rand.New(rand.NewPCG(1, 2))
+    for x := range columns {
+        for y := range rows {
+            xmap.Map.Terrain[x][y] = random.IntN(5)
+        }
+    }
+
+    for x := range columns {
+        xmap.SetRoad(x, rows / 2, false)
+    }
+
+    model.ArcanusMap = xmap
+
+    player := playerlib.MakePlayer(setup.WizardCustom{}, true, columns, rows, map[herolib.HeroType]string{}, model)
+    player.AddUnit(units.MakeOverworldUnit(units.HighMenSwordsmen, 0, 0, data.PlaneArcanus))
+
+    fog := make(data.FogMap, columns)
+    for x := range columns {
+        fog[x] = make([]data.FogType, rows)
+        for y := range rows {
+            fog[x][y] = data.FogTypeVisible
+        }
+    }
+
+    return model, player, player.FindStack(0, 0, data.PlaneArcanus), fog
+}
+
+func pathCost(model *GameModel, stack playerlib.PathStack, start image.Point, path pathfinding.Path) float64 {
+    total := float64(0)
+    last := start
+    for _, point := range path {
+        cost, _ := model.ComputeTerrainCost(stack, last.X, last.Y, point.X, point.Y, model.ArcanusMap, func (int, int) (playerlib.PathStack, bool) {
+            return nil, false
+        })
+        total += cost.ToFloat()
+        last = point
+    }
+
+    return total
+}
+
+func TestFindReachable(test *testing.T){
+    model, player, stack, fog := makePathBenchmarkModel(30, 20)
+
+    reachable := model.FindReachable(0, 0, player, stack, fog, pathfinding.Infinity)
+    if reachable.Size() != 30 * 20 {
+        test.Errorf("expected every tile to be reachable but only %v were", reachable.Size())
+    }
+
+    // the cheapest path found by the reachable set costs the same as the one from FindPath
+    for _, target := range []image.Point{image.Pt(29, 19), image.Pt(15, 10), image.Pt(5, 17), image.Pt(-3, 4)} {
+        path, ok := model.FindPath(0, 0, target.X, target.Y, player, stack, fog)
+        if !ok {
+            test.Errorf("no path to %v", target)
+            continue
+        }
+
+        reachablePath, ok := reachable.PathTo(target)
+        if !ok {
+            test.Errorf("%v is not in the reachable set", target)
+            continue
+        }
+
+        if pathCost(model, stack, image.Pt(0, 0), path) != pathCost(model, stack, image.Pt(0, 0), reachablePath[1:]) {
+            test.Errorf("paths to %v have different costs: %v and %v", target, path, reachablePath)
+        }
+    }
+
+    // the search stops at an enemy but still reaches it
+    enemy := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerRed}, false, 30, 20, map[herolib.HeroType]string{}, model)
+    enemy.AddUnit(units.MakeOverworldUnit(units.HighMenSwordsmen, 1, 1, data.PlaneArcanus))
+    model.ArcanusMap.Map.Terrain[1][1] = 0
+    model.InvalidateMovementCosts()
+    model.Players = []*playerlib.Player{player, enemy}
+
+    limited := model.FindReachable(0, 0, player, stack, fog, 1.5)
+    if !limited.Contains(image.Pt(1, 1)) {
+        test.Errorf("enemy tile should be reachable")
+    }
+
+    if path, _ := limited.PathTo(image.Pt(1, 1)); len(path) != 2 {
+        test.Errorf("expected to attack the enemy directly but the path was %v", path)
+    }
+}
+
+func TestMovementCostCache(test *testing.T){
+    model, _, stack, _ := makePathBenchmarkModel(10, 10)
+
+    noStack := func (int, int) (playerlib.PathStack, bool) {
+        return nil, false
+    }
+
+    model.ArcanusMap.Map.Terrain[1][0] = 3
+
+    cost, ok := model.ComputeTerrainCost(stack, 0, 0, 1, 0, model.ArcanusMap, noStack)
+    if !ok || cost.ToFloat() != 4 {
+        test.Errorf("expected a mountain to cost 4 but was %v", cost.ToFloat())
+    }
+
+    // the cached cost is used until the cache is invalidated
+    model.ArcanusMap.SetRoad(1, 0, false)
+    cost, _ = model.ComputeTerrainCost(stack, 0, 0, 1, 0, model.ArcanusMap, noStack)
+    if cost.ToFloat() != 4 {
+        test.Errorf("expected the cached cost of 4 but was %v", cost.ToFloat())
+    }
+
+    model.InvalidateMovementCosts()
+    cost, _ = model.ComputeTerrainCost(stack, 0, 0, 1, 0, model.ArcanusMap, noStack)
+    if cost.ToFloat() != 0.5 {
+        test.Errorf("expected a road to cost 1/2 but was %v", cost.ToFloat())
+    }
+
+    // a new turn starts with an empty cache
+    model.ArcanusMap.RemoveRoad(1, 0)
+    model.TurnNumber += 1
+    cost, _ = model.ComputeTerrainCost(stack, 0, 0, 1, 0, model.ArcanusMap, noStack)
+    if cost.ToFloat() != 4 {
+        test.Errorf("expected the mountain to cost 4 again but was %v", cost.ToFloat())
+    }
+
+    // stacks with pathfinding use their own costs
+    pathfinder := units.MakeOverworldUnit(units.HighMenSwordsmen, 0, 0, data.PlaneArcanus)
+    pathfinder.AddEnchantment(data.UnitEnchantmentPathFinding)
+    cost, _ = model.ComputeTerrainCost(playerlib.MakeUnitStackFromUnits([]units.StackUnit{pathfinder}), 0, 0, 1, 0, model.ArcanusMap, noStack)
+    if cost.ToFloat() != 0.5 {
+        test.Errorf("expected pathfinding to cost 1/2 but was %v", cost.ToFloat())
+    }
+}
+
+// the ai searches on its own goroutine while the main thread draws the path of the selected stack
+func TestMovementCostsConcurrent(test *testing.T){
+    model, player, stack, fog := makePathBenchmarkModel(40, 30)
+    targets := pathBenchmarkTargets(40, 30)
+
+    var group sync.WaitGroup
+    for range 4 {
+        group.Go(func(){
+            for _, target := range targets {
+                model.FindPath(0, 0, target.X, target.Y, player, stack, fog)
+                model.InvalidateMovementCosts()
+            }
+        })
+    }
+
+    group.Wait()
+}
+
+func TestMovementModes(test *testing.T){
+    model, _, _, _ := makePathBenchmarkModel(10, 10)
+
+    noStack := func (int, int) (playerlib.PathStack, bool) {
+        return nil, false
+    }
+
+    // a mountain next to two tiles of ocean
+    model.ArcanusMap.Map.Terrain[1][0] = 3
+    model.ArcanusMap.Map.Terrain[2][0] = 5
+    model.ArcanusMap.Map.Terrain[3][0] = 5
+
+    makeStack := func(unit units.Unit) *playerlib.UnitStack {
+        return playerlib.MakeUnitStackFromUnits([]units.StackUnit{units.MakeOverworldUnit(unit, 0, 0, data.PlaneArcanus)})
+    }
+
+    type move struct {
+        From image.Point
+        To image.Point
+        Cost float64
+        Ok bool
+    }
+
+    mountain := func(cost float64) move {
+        return move{From: image.Pt(0, 0), To: image.Pt(1, 0), Cost: cost, Ok: true}
+    }
+
+    expected := []struct {
+        Stack *playerlib.UnitStack
+        Mode MovementMode
+        Moves []move
+    }{
+        {
+            Stack: makeStack(units.HighMenSwordsmen),
+            Mode: MovementWalk,
+            Moves: []move{mountain(4), move{From: image.Pt(1, 0), To: image.Pt(2, 0), Ok: false}},
+        },
+        {
+            Stack: makeStack(units.DraconianSpearmen),
+            Mode: MovementFly,
+            Moves: []move{mountain(1), move{From: image.Pt(2, 0), To: image.Pt(3, 0), Cost: 1, Ok: true}},
+        },
+        {
+            Stack: makeStack(units.LizardSpearmen),
+            Mode: MovementSwim,
+            Moves: []move{mountain(4), move{From: image.Pt(1, 0), To: image.Pt(2, 0), Cost: 1, Ok: true}},
+        },
+        {
+            Stack: makeStack(units.Trireme),
+            Mode: MovementSail,
+            Moves: []move{move{From: image.Pt(2, 0), To: image.Pt(3, 0), Cost: 1, Ok: true}, move{From: image.Pt(2, 0), To: image.Pt(1, 0), Ok: false}},
+        },
+    }
+
+    for _, check := range expected {
+        if MakeMovementClass(check.Stack).Mode != check.Mode {
+            test.Errorf("expected the stack to %v but it would %v", check.Mode, MakeMovementClass(check.Stack).Mode)
+        }
+
+        for _, move := range check.Moves {
+            cost, ok := model.ComputeTerrainCost(check.Stack, move.From.X, move.From.Y, move.To.X, move.To.Y, model.ArcanusMap, noStack)
+            if ok != move.Ok || (ok && cost.ToFloat() != move.Cost) {
+                test.Errorf("%v: moving from %v to %v should be %v with cost %v but was %v with cost %v", check.Mode, move.From, move.To, move.Ok, move.Cost, ok, cost.ToFloat())
+            }
+        }
+    }
+}
+
+// the destinations an ai checks in one turn, such as the lairs it could scout
+func pathBenchmarkTargets(columns int, rows int) []image.Point {
+    var out []image.Point
+    random := rand.New(rand.NewPCG(3, 4))
+    for range 10 {
+        out = append(out, image.Pt(random.IntN(columns), random.IntN(rows)))
+    }
+
+    return out
+}
+
+// every search starts on a fresh model, so none of the tile costs are cached yet
+func BenchmarkFindPathUncached(bench *testing.B){
+    targets := pathBenchmarkTargets(200, 150)
+
+    for bench.Loop() {
+        for _, target := range targets {
+            bench.StopTimer()
+            model, player, stack, fog := makePathBenchmarkModel(200, 150)
+            stack.SetX(100)
+            stack.SetY(75)
+            bench.StartTimer()
+
+            model.FindPath(stack.X(), stack.Y(), target.X, target.Y, player, stack, fog)
+        }
+    }
+}
+
+// the cost of each tile is computed once and reused by the later queries
+func BenchmarkFindPathCached(bench *testing.B){
+    model, player, stack, fog := makePathBenchmarkModel(200, 150)
+    targets := pathBenchmarkTargets(200, 150)
+    stack.SetX(100)
+    stack.SetY(75)
+
+    for bench.Loop() {
+        for _, target := range targets {
+            model.FindPath(stack.X(), stack.Y(), target.X, target.Y, player, stack, fog)
+        }
+    }
+}
+
+// one search for all the targets
+func BenchmarkFindReachable(bench *testing.B){
+    model, player, stack, fog := makePathBenchmarkModel(200, 150)
+    targets := pathBenchmarkTargets(200, 150)
+    stack.SetX(100)
+    stack.SetY(75)
+
+    for bench.Loop() {
+        reachable := model.FindReachable(stack.X(), stack.Y(), player, stack, fog, pathfinding.Infinity)
+        for _, target := range targets {
+            reachable.PathTo(target)
+        }
+    }
+}
@@ -0,0 +1,208 @@
+package game
+
+import (
+    "fmt"
+    "log"
+    "image"
+    "math/rand/v2"
+
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/ai"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/units"
+)
+
+/* rebellions are an optional rule that goes beyond the original game, enabled with
+ * setup.NewGameSettings.Rebellions. the city decides when its rebels rise up, see city/rebellion.go,
+ * and then one of these happens
+ *  - the city defects to the most famous wizard that has a city nearby, if that wizard is more famous
+ *    than the owner
+ *  - the city secedes and becomes a neutral city
+ *  - the rebels take up arms and appear as neutral units next to the city
+ *
+ * a city with the wizard's fortress or summoning circle, or with a hero in it, can't change hands,
+ * so its rebels always take up arms. if there is no room next to the city for them then nothing happens.
+ */
+
+// how close a city of another wizard must be for the rebels to defect to that wizard
+const DefectionDistance = 10
+
+type RebellionOutcome int
+
+const (
+    // the rebels had nowhere to go
+    RebellionNone RebellionOutcome = iota
+    RebellionRevolt
+    RebellionSecede
+    RebellionDefect
+)
+
+// whether the city can be taken from its owner by an event rather than by force
+func (model *GameModel) CanCityDefect(city *citylib.City, owner *playerlib.Player) bool {
+    if city.HasFortress() || city.HasSummoningCircle() {
+        return false
+    }
+
+    // cannot take a city with a hero in it
+    stack := owner.FindStack(city.X, city.Y, city.Plane)
+    if stack != nil && stack.HasHero() {
+        return false
+    }
+
+    return true
+}
+
+// give the city to another player without a fight
+func (model *GameModel) DefectCity(city *citylib.City, owner *playerlib.Player, newOwner *playerlib.Player, enchantmentChange ChangeCityEnchantments) {
+    // disband any fantastic units garrisoned at the city, and give all other normal units to the new owner
+    stack := owner.FindStack(city.X, city.Y, city.Plane)
+    if stack != nil {
+        for _, unit := range stack.Units() {
+            owner.RemoveUnit(unit)
+            if unit.GetRace() != data.RaceFantastic {
+                unit.SetBanner(newOwner.GetBanner())
+                newOwner.AddUnit(unit)
+            }
+        }
+    }
+
+    ChangeCityOwner(city, owner, newOwner, enchantmentChange)
+    model.InvalidateMovementCosts()
+
+    // the neutral player runs the towns that rebel against their wizard
+    if takeover, ok := newOwner.AIBehavior.(ai.CityTakeover); ok {
+        takeover.TakeOverCity(newOwner, city)
+    }
+}
+
+// the wizard the city would rather belong to, or nil if there is none
+func (model *GameModel) FindDefectionTarget(city *citylib.City, owner *playerlib.Player) *playerlib.Player {
+    var best *playerlib.Player
+
+    mapUse := model.GetMap(city.Plane)
+
+    for _, player := range model.Players {
+        if player == owner || player.Defeated || player.GetBanner() == data.BannerBrown {
+            continue
+        }
+
+        if player.GetFame() <= owner.GetFame() || (best != nil && player.GetFame() <= best.GetFame()) {
+            continue
+        }
+
+        for _, other := range player.Cities {
+            if other.Plane == city.Plane && mapUse.TileDistance(city.X, city.Y, other.X, other.Y) <= DefectionDistance {
+                best = player
+                break
+            }
+        }
+    }
+
+    return best
+}
+
+// the free land next to the city where rebel units can appear
+func (model *GameModel) findRebelPositions(city *citylib.City) []image.Point {
+    mapUse := model.GetMap(city.Plane)
+
+    var out []image.Point
+    for dx := -1; dx <= 1; dx++ {
+        for dy := -1; dy <= 1; dy++ {
+            x := mapUse.WrapX(city.X + dx)
+            y := city.Y + dy
+
+            if (dx == 0 && dy == 0) || y < 0 || y >= mapUse.Height() {
+                continue
+            }
+
+            if mapUse.GetTile(x, y).Tile.IsWater() || mapUse.GetEncounter(x, y) != nil {
+                continue
+            }
+
+            stack, _ := model.FindStack(x, y, city.Plane)
+            if stack == nil && !model.ContainsCity(x, y, city.Plane) {
+                out = append(out, image.Pt(x, y))
+            }
+        }
+    }
+
+    return out
+}
+
+// the rebels leave the city and gather in a neutral stack next to it. returns how many units appeared
+func (model *GameModel) spawnRebels(city *citylib.City, neutral *playerlib.Player) int {
+    positions := model.findRebelPositions(city)
+    if len(positions) == 0 {
+        return 0
+    }
+
+    position := positions[rand.N(len(positions))]
+    count := min(data.MaxUnitsInStack, max(1, city.ComputeUnrest() / 2))
+
+    for range count {
+        unit := units.MakeOverworldUnitFromUnit(units.ChooseRandomUnit(city.Race), position.X, position.Y, city.Plane, neutral.GetBanner(), neutral.MakeExperienceInfo(), neutral.MakeUnitEnchantmentProvider())
+        neutral.AddUnit(unit)
+    }
+
+    // the angriest citizens left the city
+    city.Population = max(1000, city.Population - count * 1000 / 2)
+    city.UpdateUnrest()
+
+    return count
+}
+
+// decide what the rebels of the city do and carry it out
+func (model *GameModel) DoRebellion(city *citylib.City, owner *playerlib.Player) (RebellionOutcome, *playerlib.Player) {
+    neutral := model.GetNeutralPlayer()
+
+    if model.CanCityDefect(city, owner) {
+        target := model.FindDefectionTarget(city, owner)
+        if target != nil {
+            model.DefectCity(city, owner, target, ChangeCityRemoveOwnerEnchantments)
+            return RebellionDefect, target
+        }
+
+        if neutral != nil && rand.N(2) == 0 {
+            model.DefectCity(city, owner, neutral, ChangeCityRemoveAllEnchantments)
+            return RebellionSecede, neutral
+        }
+    }
+
+    if neutral == nil || model.spawnRebels(city, neutral) == 0 {
+        return RebellionNone, nil
+    }
+
+    return RebellionRevolt, neutral
+}
+
+// handle the rebellion of a city at the end of the owner's turn
+func (game *Game) doRebellion(city *citylib.City, owner *playerlib.Player) {
+    outcome, newOwner := game.Model.DoRebellion(city, owner)
+
+    var message string
+    switch outcome {
+        case RebellionNone:
+            return
+        case RebellionDefect:
+            log.Printf("Year=%v city %v of %v defected to %v", game.Model.TurnNumber, city.Name, owner.Wizard.Name, newOwner.Wizard.Name)
+            if newOwner.IsHuman() {
+                message = fmt.Sprintf("Tired of the rule of %v, the people of %v have rebelled and joined your empire.", owner.Wizard.Name, city.Name)
+            } else {
+                message = fmt.Sprintf("Rebellion! The people of %v have overthrown your rule and joined %v.", city.Name, newOwner.Wizard.Name)
+            }
+        case RebellionSecede:
+            log.Printf("Year=%v city %v of %v seceded", game.Model.TurnNumber, city.Name, owner.Wizard.Name)
+            message = fmt.Sprintf("Rebellion! The %v of %v has rebelled and become a neutral city.", city.GetSize(), city.Name)
+        case RebellionRevolt:
+            log.Printf("Year=%v rebels rose up in city %v of %v", game.Model.TurnNumber, city.Name, owner.Wizard.Name)
+            message = fmt.Sprintf("Rebellion! The rebels of %v have taken up arms against you.", city.Name)
+    }
+
+    if owner.IsHuman() || (newOwner != nil && newOwner.IsHuman() && outcome == RebellionDefect) {
+        select {
+            case game.Events<- &GameEventNotice{Message: message}:
+            default:
+        }
+    }
+}
@@ -0,0 +1,194 @@
+package game
+
+import (
+    "testing"
+    "image"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    herolib "github.com/kazzmir/master-of-magic/game/magic/hero"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+    "github.com/kazzmir/master-of-magic/game/magic/setup"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/units"
+    "github.com/kazzmir/master-of-magic/game/magic/ai"
+)
+
+// a city of the owner at 10,5 with a city of the rival 5 tiles away, and a neutral player
+func makeRebellionModel() (*GameModel, *playerlib.Player, *playerlib.Player, *playerlib.Player, *citylib.City) {
+    model, _, _, _ := makePathBenchmarkModel(30, 12)
+
+    makePlayer := func(banner data.BannerType) *playerlib.Player {
+        player := playerlib.MakePlayer(setup.WizardCustom{Banner: banner}, false, 30, 12, map[herolib.HeroType]string{}, model)
+        model.Players = append(model.Players, player)
+        return player
+    }
+
+    owner := makePlayer(data.BannerRed)
+    rival := makePlayer(data.BannerGreen)
+    neutral := makePlayer(data.BannerBrown)
+
+    city := citylib.MakeCity("xyz", 10, 5, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, owner)
+    city.Population = 6000
+    owner.AddCity(city)
+
+    rivalCity := citylib.MakeCity("abc", 15, 5, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, rival)
+    rivalCity.Population = 6000
+    rival.AddCity(rivalCity)
+
+    return model, owner, rival, neutral, city
+}
+
+func TestFindDefectionTarget(test *testing.T) {
+    model, owner, rival, neutral, city := makeRebellionModel()
+    owner.Fame = 5
+
+    rival.Fame = 5
+    if model.FindDefectionTarget(city, owner) != nil {
+        test.Errorf("the city should not defect to a wizard that is only as famous as its owner")
+    }
+
+    rival.Fame = 10
+    if model.FindDefectionTarget(city, owner) != rival {
+        test.Errorf("the city should defect to the more famous wizard nearby")
+    }
+
+    neutral.Fame = 50
+    if model.FindDefectionTarget(city, owner) != rival {
+        test.Errorf("the city should never defect to the neutral player")
+    }
+
+    // a more famous wizard whose only city is too far away
+    famous := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerBlue}, false, 30, 12, map[herolib.HeroType]string{}, model)
+    famous.Fame = 20
+    model.Players = append(model.Players, famous)
+    farCity := citylib.MakeCity("far", 10 + DefectionDistance + 1, 5, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, famous)
+    famous.AddCity(farCity)
+
+    if model.FindDefectionTarget(city, owner) != rival {
+        test.Errorf("the city should not defect to a wizard more than %v tiles away", DefectionDistance)
+    }
+
+    farCity.X = 10 + DefectionDistance
+    if model.FindDefectionTarget(city, owner) != famous {
+        test.Errorf("the city should defect to the most famous wizard nearby")
+    }
+}
+
+func TestDoRebellionDefect(test *testing.T) {
+    model, owner, rival, _, city := makeRebellionModel()
+    rival.Fame = 10
+
+    outcome, newOwner := model.DoRebellion(city, owner)
+    if outcome != RebellionDefect || newOwner != rival {
+        test.Fatalf("the city should have defected to the rival but was %v, rival %v", outcome, newOwner == rival)
+    }
+
+    if owner.OwnsCity(city) || !rival.OwnsCity(city) {
+        test.Errorf("the rival should own the city now")
+    }
+}
+
+func TestDoRebellionCannotDefect(test *testing.T) {
+    makeFortress := func(city *citylib.City, owner *playerlib.Player) {
+        city.AddBuilding(buildinglib.BuildingFortress)
+    }
+
+    makeHero := func(city *citylib.City, owner *playerlib.Player) {
+        hero := herolib.MakeHero(units.MakeOverworldUnitFromUnit(units.HeroValana, city.X, city.Y, city.Plane, owner.GetBanner(), owner.MakeExperienceInfo(), owner.MakeUnitEnchantmentProvider()), herolib.HeroValana, "Valana")
+        if !owner.AddHero(hero, city.X, city.Y, city.Plane) {
+            test.Fatalf("could not add the hero")
+        }
+    }
+
+    for _, guard := range []func(*citylib.City, *playerlib.Player){makeFortress, makeHero} {
+        model, owner, rival, neutral, city := makeRebellionModel()
+        rival.Fame = 10
+        guard(city, owner)
+
+        outcome, newOwner := model.DoRebellion(city, owner)
+        if outcome != RebellionRevolt || newOwner != neutral {
+            test.Errorf("the rebels should have taken up arms but was %v, neutral %v", outcome, newOwner == neutral)
+        }
+
+        if !owner.OwnsCity(city) {
+            test.Errorf("the owner should still own the city")
+        }
+
+        if neutral.UnitCount() == 0 {
+            test.Errorf("rebel units should have appeared")
+        }
+    }
+}
+
+func TestDoRebellionNoRoom(test *testing.T) {
+    model, owner, _, neutral, city := makeRebellionModel()
+    city.AddBuilding(buildinglib.BuildingFortress)
+
+    // every tile around the city is taken
+    for dx := -1; dx <= 1; dx++ {
+        for dy := -1; dy <= 1; dy++ {
+            if dx != 0 || dy != 0 {
+                owner.AddUnit(units.MakeOverworldUnit(units.HighMenSwordsmen, city.X + dx, city.Y + dy, city.Plane))
+            }
+        }
+    }
+
+    population := city.Population
+    outcome, newOwner := model.DoRebellion(city, owner)
+    if outcome != RebellionNone || newOwner != nil {
+        test.Errorf("nothing should have happened but was %v, new owner %v", outcome, newOwner != nil)
+    }
+
+    if neutral.UnitCount() != 0 || city.Population != population {
+        test.Errorf("no rebels should have left the city")
+    }
+
+    // without a neutral player there is nobody for the rebels to join
+    model.Players = []*playerlib.Player{owner}
+    clear := image.Pt(city.X + 1, city.Y)
+    stack := owner.FindStack(clear.X, clear.Y, city.Plane)
+    for _, unit := range stack.Units() {
+        owner.RemoveUnit(unit)
+    }
+
+    outcome, _ = model.DoRebellion(city, owner)
+    if outcome != RebellionNone {
+        test.Errorf("nothing should have happened without a neutral player but was %v", outcome)
+    }
+}
+
+func TestDefectCityToNeutral(test *testing.T) {
+    model, owner, _, neutral, city := makeRebellionModel()
+    neutral.AIBehavior = ai.MakeRaiderAI()
+
+    city.ProducingUnit = units.HighMenSwordsmen
+    city.ProducingBuilding = buildinglib.BuildingNone
+    city.Production = 10
+    city.Queue = []citylib.QueueItem{citylib.MakeQueueBuilding(buildinglib.BuildingBarracks)}
+
+    guard := owner.AddUnit(units.MakeOverworldUnit(units.HighMenSpearmen, city.X, city.Y, city.Plane))
+    guard.SetBusy(units.BusyStatusBuildRoad)
+
+    // same as the Rebellion random event
+    model.DefectCity(city, owner, neutral, ChangeCityRemoveAllEnchantments)
+
+    if !neutral.OwnsCity(city) {
+        test.Fatalf("the neutral player should own the city")
+    }
+
+    if len(city.Queue) != 0 || !city.ProducingUnit.Equals(units.UnitNone) || city.Production != 0 {
+        test.Errorf("the neutral player should drop what the old owner was building")
+    }
+
+    stack := neutral.FindStack(city.X, city.Y, city.Plane)
+    if stack == nil || stack.Size() != 1 {
+        test.Fatalf("the garrison should have joined the neutral player")
+    }
+
+    for _, unit := range stack.Units() {
+        if unit.GetBusy() != units.BusyStatusPatrol {
+            test.Errorf("the garrison should guard the city but was %v", unit.GetBusy())
+        }
+    }
+}
@@ -82,6 +82,10 @@ type SerializedGame struct {
     LastEventTurn uint64 `json:"last-event-turn"`
     Players []playerlib.SerializedPlayer `json:"players"`
     Events []SerializedRandomEvent `json:"events"`
+    Statistics []*PlayerStatistics `json:"statistics,omitempty"`
+    Piracy map[data.BannerType]uint64 `json:"piracy,omitempty"`
+    // what each ai player remembers of the other wizards, for the ai players that don't see through the fog
+    FogViews map[data.BannerType]ai.SerializedFogServices `json:"fog-views,omitempty"`
 }
 
 func SerializeModel(model *GameModel, saveName string) SerializedGame {
@@ -90,6 +94,14 @@ func SerializeModel(model *GameModel, saveName string) SerializedGame {
         players = append(players, playerlib.SerializePlayer(player))
     }
 
+    var views map[data.BannerType]ai.SerializedFogServices
+    for player, view := range model.FogViews {
+        if views == nil {
+            views = make(map[data.BannerType]ai.SerializedFogServices)
+        }
+        views[player.GetBanner()] = view.Serialize()
+    }
+
     return SerializedGame{
         Metadata: serialize.SaveMetadata{
             Version: SerializeVersion,
@@ -108,6 +120,9 @@ func SerializeModel(model *GameModel, saveName string) SerializedGame {
         LastEventTurn: model.LastEventTurn,
         Players: players,
         Events: serializeRandomEvents(model.RandomEvents),
+        Statistics: model.Statistics,
+        Piracy: model.Piracy,
+        FogViews: views,
     }
 }
 
@@ -178,6 +193,8 @@ func MakeModelFromSerialized(
         Events: events,
         BuildingInfo: buildingInfo,
         LastEventTurn: serializedGame.LastEventTurn,
+        Statistics: serializedGame.Statistics,
+        Piracy: serializedGame.Piracy,
     }
 
     var players []*playerlib.Player
@@ -187,6 +204,7 @@ func MakeModelFromSerialized(
 
     for _, serializedPlayer := range serializedGame.Players {
         player, initializeCities, initializePlayer := playerlib.ReconstructPlayer(&serializedPlayer, model, allSpells, buildingInfo, model)
+        player.DifficultyProvider = model
         cityInitializers = append(cityInitializers, initializeCities)
         playerInitializers = append(playerInitializers, initializePlayer)
 
@@ -218,6 +236,8 @@ func MakeModelFromSerialized(
 
     model.ArcanusMap = maplib.ReconstructMap(serializedGame.Arcanus, terrainData, model, wizards)
     model.MyrrorMap = maplib.ReconstructMap(serializedGame.Myrror, terrainData, model, wizards)
+    model.ArcanusMap.Plane = data.PlaneArcanus
+    model.MyrrorMap.Plane = data.PlaneMyrror
 
     for _, initializer := range cityInitializers {
         initializer(model.ArcanusMap, model.MyrrorMap)
@@ -225,5 +245,18 @@ func MakeModelFromSerialized(
 
     model.RandomEvents = reconstructRandomEvents(serializedGame.Events, model)
 
+    for banner, view := range serializedGame.FogViews {
+        for _, player := range model.Players {
+            if player.GetBanner() == banner {
+                if model.FogViews == nil {
+                    model.FogViews = make(map[*playerlib.Player]*ai.FogServices)
+                }
+                model.FogViews[player] = ai.MakeFogServicesFromSerialized(player, model, view)
+            }
+        }
+    }
+
+    model.UpdateTradeRoutes()
+
     return model
 }
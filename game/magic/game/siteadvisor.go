@@ -0,0 +1,173 @@
+package game
+
+import (
+    "fmt"
+    "image"
+    "image/color"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    "github.com/kazzmir/master-of-magic/game/magic/inputmanager"
+    "github.com/kazzmir/master-of-magic/lib/font"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+/* while a settler is selected the overworld is tinted by how good a city would be on each explored
+ * land tile, and the best few sites on the settler's continent are numbered. the scores come from
+ * maplib.ScoreCitySite, which the ai also uses to choose where to settle.
+ */
+
+// how many recommended sites are marked
+const recommendedSites = 5
+
+// a score this high gets the greenest tint
+const bestSiteScore = 250
+
+type citySiteTile struct {
+    Site maplib.CitySite
+    Settlable bool
+}
+
+type CitySiteAdvice struct {
+    Plane data.Plane
+    Turn uint64
+    X int
+    Y int
+    Cities int
+
+    // scored lazily as tiles come into view
+    Tiles map[image.Point]citySiteTile
+    Best []maplib.CitySite
+}
+
+func (advice *CitySiteAdvice) get(model *GameModel, x int, y int) citySiteTile {
+    point := image.Pt(x, y)
+    tile, ok := advice.Tiles[point]
+    if !ok {
+        tile = citySiteTile{
+            Site: model.GetMap(advice.Plane).ScoreCitySite(x, y),
+            Settlable: model.IsSettlableLocation(x, y, advice.Plane),
+        }
+        advice.Tiles[point] = tile
+    }
+
+    return tile
+}
+
+func stackCanSettle(stack *playerlib.UnitStack) bool {
+    for _, unit := range stack.Units() {
+        if unit.HasAbility(data.AbilityCreateOutpost) {
+            return true
+        }
+    }
+
+    return false
+}
+
+// the advice for the selected stack, or nil if the stack can't build a city
+func (game *Game) getCitySiteAdvice(player *playerlib.Player, stack *playerlib.UnitStack) *CitySiteAdvice {
+    if stack == nil || stack.Plane() != game.Model.Plane || !stackCanSettle(stack) {
+        return nil
+    }
+
+    cities := len(game.Model.AllCities())
+
+    advice := game.citySiteAdvice
+    if advice != nil && advice.Plane == stack.Plane() && advice.Turn == game.Model.TurnNumber &&
+       advice.X == stack.X() && advice.Y == stack.Y() && advice.Cities == cities {
+        return advice
+    }
+
+    advice = &CitySiteAdvice{
+        Plane: stack.Plane(),
+        Turn: game.Model.TurnNumber,
+        X: stack.X(),
+        Y: stack.Y(),
+        Cities: cities,
+        Tiles: make(map[image.Point]citySiteTile),
+        Best: game.Model.RecommendCitySites(stack.X(), stack.Y(), stack.Plane(), player.GetFog(stack.Plane()), recommendedSites),
+    }
+
+    game.citySiteAdvice = advice
+    return advice
+}
+
+// red for poor sites through yellow to green for the best
+func citySiteColor(score int, settlable bool) color.RGBA {
+    amount := min(1, max(0, float64(score) / bestSiteScore))
+
+    var red, green float64
+    if amount < 0.5 {
+        red = 1
+        green = amount * 2
+    } else {
+        red = (1 - amount) * 2
+        green = 1
+    }
+
+    alpha := 0.35
+    if !settlable {
+        alpha = 0.12
+    }
+
+    return color.RGBA{R: uint8(red * 255 * alpha), G: uint8(green * 255 * alpha), A: uint8(255 * alpha)}
+}
+
+func (game *Game) drawCitySites(screen *ebiten.Image, player *playerlib.Player, stack *playerlib.UnitStack) {
+    advice := game.getCitySiteAdvice(player, stack)
+    if advice == nil {
+        return
+    }
+
+    mapUse := game.GetMap(advice.Plane)
+    fog := player.GetFog(advice.Plane)
+
+    zoom := game.Camera.GetAnimatedZoom()
+    halfWidth := float64(mapUse.TileWidth()) * zoom / 2
+    halfHeight := float64(mapUse.TileHeight()) * zoom / 2
+
+    minX, minY, maxX, maxY := game.Camera.GetTileBounds()
+    for x := minX; x < maxX; x++ {
+        for y := max(0, minY); y < min(maxY, mapUse.Height()); y++ {
+            tileX := mapUse.WrapX(x)
+            if fog.GetFog(tileX, y) == data.FogTypeUnexplored || !mapUse.GetTile(tileX, y).Tile.IsLand() {
+                continue
+            }
+
+            tile := advice.get(game.Model, tileX, y)
+
+            centerX, centerY := game.TileToScreen(tileX, y)
+            x1, y1 := scale.Scale2(float64(centerX) - halfWidth, float64(centerY) - halfHeight)
+            vector.DrawFilledRect(screen, float32(x1), float32(y1), float32(scale.Scale(halfWidth * 2)), float32(scale.Scale(halfHeight * 2)), citySiteColor(tile.Site.Score(), tile.Settlable), false)
+        }
+    }
+
+    markerColor := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+    for i, site := range advice.Best {
+        centerX, centerY := game.TileToScreen(site.X, site.Y)
+        vector.StrokeCircle(screen, scale.Scale(float32(centerX)), scale.Scale(float32(centerY)), float32(scale.Scale(halfWidth)), float32(scale.ScaleAmount), markerColor, true)
+        game.Fonts.WhiteFont.PrintOptions(screen, float64(centerX), float64(centerY) - 3, font.FontOptions{Justify: font.FontJustifyCenter, DropShadow: true, Scale: scale.ScaleAmount}, fmt.Sprintf("%v", i + 1))
+    }
+
+    // the details of the tile under the mouse
+    mouseX, mouseY := inputmanager.MousePosition()
+    if game.InOverworldArea(mouseX, mouseY) {
+        tileX, tileY := game.ScreenToTile(float64(mouseX), float64(mouseY))
+        tileX = mapUse.WrapX(tileX)
+        if tileY >= 0 && tileY < mapUse.Height() && fog.GetFog(tileX, tileY) != data.FogTypeUnexplored && mapUse.GetTile(tileX, tileY).Tile.IsLand() {
+            tile := advice.get(game.Model, tileX, tileY)
+            site := tile.Site
+
+            text := fmt.Sprintf("Pop %v  Prod +%v%%  Gold +%v%%  Shared %v", site.MaxPopulation, site.ProductionBonus, site.GoldBonus, site.Shared)
+            if !tile.Settlable {
+                text += "  (can not build)"
+            }
+
+            game.Fonts.WhiteFont.PrintOptions(screen, 4, 21, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount}, text)
+        }
+    }
+}
@@ -0,0 +1,228 @@
+package game
+
+import (
+    "os"
+    "fmt"
+    "log"
+    "image"
+    "image/color"
+    "context"
+
+    "github.com/kazzmir/master-of-magic/lib/font"
+    "github.com/kazzmir/master-of-magic/lib/set"
+    "github.com/kazzmir/master-of-magic/lib/coroutine"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    "github.com/kazzmir/master-of-magic/game/magic/util"
+    fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
+    uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// write the statistics to the file given on the command line, if any
+func (game *Game) writeStatisticsFile() {
+    if game.StatisticsPath == "" {
+        return
+    }
+
+    _, err := game.exportStatistics(game.StatisticsPath)
+    if err != nil {
+        log.Printf("Unable to write statistics to %v: %v", game.StatisticsPath, err)
+    }
+}
+
+func (game *Game) exportStatistics(path string) (string, error) {
+    if path == "" {
+        path = fmt.Sprintf("statistics-%v.csv", game.Model.TurnNumber)
+    }
+
+    out, err := os.Create(path)
+    if err != nil {
+        return path, err
+    }
+    defer out.Close()
+
+    return path, game.Model.WriteStatisticsCSV(out)
+}
+
+// the wizards whose statistics can be seen. in watch mode that is everyone
+func (game *Game) visibleStatistics() []*PlayerStatistics {
+    if game.WatchMode {
+        return game.Model.Statistics
+    }
+
+    human := game.Model.GetHumanPlayer()
+    if human == nil {
+        return nil
+    }
+
+    known := set.NewSet(human.GetBanner())
+    for _, player := range human.GetKnownPlayers() {
+        known.Insert(player.GetBanner())
+    }
+
+    var out []*PlayerStatistics
+    for _, stats := range game.Model.Statistics {
+        if known.Contains(stats.Banner) {
+            out = append(out, stats)
+        }
+    }
+
+    return out
+}
+
+func (game *Game) ShowStatistics(yield coroutine.YieldFunc) {
+    group := uilib.MakeGroup()
+
+    quit, cancel := context.WithCancel(context.Background())
+
+    fade := group.MakeFadeIn(7)
+
+    loader, err := fontslib.Loader(game.Cache)
+    if err != nil {
+        log.Printf("Error: statistics: unable to load font: %v", err)
+        cancel()
+        return
+    }
+
+    titleFont := loader(fontslib.BigOrangeGradient2)
+    dateFont := loader(fontslib.LightFontSmall)
+    normalFont := loader(fontslib.SmallWhite)
+    bannerFonts := fontslib.MakeBannerFonts(game.Cache, 3)
+
+    statistics := game.visibleStatistics()
+    selected := StatisticPower
+    status := ""
+
+    graphRect := image.Rect(82, 30, 310, 160)
+    highlightColor := util.PremultiplyAlpha(color.RGBA{R: 255, G: 255, B: 255, A: 70})
+    panelColor := util.PremultiplyAlpha(color.RGBA{A: 120})
+    axisColor := color.RGBA{R: 0xec, G: 0x8d, B: 0x13, A: 0xff}
+
+    drawGraph := func(screen *ebiten.Image, options ebiten.DrawImageOptions) {
+        maxTurn := uint64(12)
+        maxValue := 10
+        for _, stats := range statistics {
+            for _, sample := range stats.History {
+                maxTurn = max(maxTurn, sample.Turn)
+                maxValue = max(maxValue, sample.Get(selected))
+            }
+        }
+
+        toScreen := func(turn uint64, value int) (float32, float32) {
+            x := float64(graphRect.Min.X) + float64(turn) * float64(graphRect.Dx()) / float64(maxTurn)
+            y := float64(graphRect.Max.Y) - float64(max(0, value)) * float64(graphRect.Dy()) / float64(maxValue)
+            return scale.Scale(float32(x)), scale.Scale(float32(y))
+        }
+
+        vector.FillRect(screen, scale.Scale(float32(graphRect.Min.X)), scale.Scale(float32(graphRect.Min.Y)), scale.Scale(float32(graphRect.Dx())), scale.Scale(float32(graphRect.Dy())), panelColor, false)
+        vector.StrokeLine(screen, scale.Scale(float32(graphRect.Min.X)), scale.Scale(float32(graphRect.Max.Y)), scale.Scale(float32(graphRect.Max.X)), scale.Scale(float32(graphRect.Max.Y)), float32(scale.ScaleAmount), axisColor, false)
+        vector.StrokeLine(screen, scale.Scale(float32(graphRect.Min.X)), scale.Scale(float32(graphRect.Min.Y)), scale.Scale(float32(graphRect.Min.X)), scale.Scale(float32(graphRect.Max.Y)), float32(scale.ScaleAmount), axisColor, false)
+
+        normalFont.PrintOptions(screen, float64(graphRect.Min.X + 2), float64(graphRect.Min.Y + 2), font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, fmt.Sprintf("%v (max %v)", selected, maxValue))
+        normalFont.PrintOptions(screen, float64(graphRect.Max.X), float64(graphRect.Max.Y + 2), font.FontOptions{DropShadow: true, Justify: font.FontJustifyRight, Scale: scale.ScaleAmount, Options: &options}, fmt.Sprintf("Turn %v", maxTurn))
+
+        for _, stats := range statistics {
+            lineColor := stats.Banner.Color()
+            for i := 1; i < len(stats.History); i++ {
+                x1, y1 := toScreen(stats.History[i - 1].Turn, stats.History[i - 1].Get(selected))
+                x2, y2 := toScreen(stats.History[i].Turn, stats.History[i].Get(selected))
+                vector.StrokeLine(screen, x1, y1, x2, y2, float32(scale.ScaleAmount), lineColor, true)
+            }
+        }
+
+        // the legend, with the latest value of each wizard
+        for i, stats := range statistics {
+            value := 0
+            if len(stats.History) > 0 {
+                value = stats.History[len(stats.History) - 1].Get(selected)
+            }
+
+            nameFont := bannerFonts[stats.Banner]
+            x := graphRect.Min.X + (i % 3) * 76
+            y := graphRect.Max.Y + 10 + (i / 3) * 8
+            nameFont.PrintOptions(screen, float64(x), float64(y), font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, fmt.Sprintf("%v %v", stats.Name, value))
+        }
+    }
+
+    group.AddElement(&uilib.UIElement{
+        Layer: 1,
+        Rect: image.Rect(0, 0, data.ScreenWidth, data.ScreenHeight),
+        Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+            background, _ := game.ImageCache.GetImage("reload.lbx", 0, 0)
+            var options ebiten.DrawImageOptions
+            options.ColorScale.ScaleAlpha(fade())
+            scale.DrawScaled(screen, background, &options)
+
+            titleFont.PrintOptions(screen, 160, 10, font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount, Options: &options}, "Statistics")
+            dateFont.PrintOptions(screen, 312, 11, font.FontOptions{DropShadow: true, Justify: font.FontJustifyRight, Scale: scale.ScaleAmount, Options: &options}, game.TurnDate())
+
+            drawGraph(screen, options)
+
+            if status != "" {
+                normalFont.PrintOptions(screen, 10, 188, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, status)
+            }
+        },
+    })
+
+    // one button per kind of statistic
+    for i, kind := range StatisticKinds {
+        rect := image.Rect(8, 30 + i * 10, 78, 39 + i * 10)
+        group.AddElement(&uilib.UIElement{
+            Layer: 1,
+            Order: 1,
+            Rect: rect,
+            PlaySoundLeftClick: true,
+            LeftClick: func(element *uilib.UIElement){
+                selected = kind
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                if selected == kind {
+                    vector.FillRect(screen, scale.Scale(float32(rect.Min.X)), scale.Scale(float32(rect.Min.Y)), scale.Scale(float32(rect.Dx())), scale.Scale(float32(rect.Dy())), highlightColor, false)
+                }
+                var options ebiten.DrawImageOptions
+                options.ColorScale.ScaleAlpha(fade())
+                normalFont.PrintOptions(screen, float64(rect.Min.X + 2), float64(rect.Min.Y + 2), font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, kind.String())
+            },
+        })
+    }
+
+    makeButton := func(rect image.Rectangle, text string, action func()) *uilib.UIElement {
+        return &uilib.UIElement{
+            Layer: 1,
+            Order: 1,
+            Rect: rect,
+            PlaySoundLeftClick: true,
+            LeftClick: func(element *uilib.UIElement){
+                action()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                vector.FillRect(screen, scale.Scale(float32(rect.Min.X)), scale.Scale(float32(rect.Min.Y)), scale.Scale(float32(rect.Dx())), scale.Scale(float32(rect.Dy())), panelColor, false)
+                var options ebiten.DrawImageOptions
+                options.ColorScale.ScaleAlpha(fade())
+                normalFont.PrintOptions(screen, float64(rect.Min.X + rect.Dx() / 2), float64(rect.Min.Y + 3), font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount, Options: &options}, text)
+            },
+        }
+    }
+
+    group.AddElement(makeButton(image.Rect(200, 184, 262, 196), "Export CSV", func(){
+        path, err := game.exportStatistics(game.StatisticsPath)
+        if err != nil {
+            status = fmt.Sprintf("Unable to write %v", path)
+            log.Printf("Unable to write statistics to %v: %v", path, err)
+        } else {
+            status = fmt.Sprintf("Saved %v", path)
+        }
+    }))
+
+    group.AddElement(makeButton(image.Rect(270, 184, 312, 196), "Done", func(){
+        fade = group.MakeFadeOut(7)
+        group.AddDelay(7, func(){
+            cancel()
+        })
+    }))
+
+    game.doRunUI(yield, group, quit)
+}
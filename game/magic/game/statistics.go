@@ -0,0 +1,207 @@
+package game
+
+import (
+    "io"
+    "fmt"
+    "encoding/csv"
+
+    "github.com/kazzmir/master-of-magic/game/magic/ai"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+)
+
+/* a sample of each wizard's empire is taken at the end of every turn so the statistics screen can graph
+ * how the game went, and so watch mode games can be exported to a csv file and analyzed elsewhere.
+ * wizards are identified by their banner because that is what survives a save.
+ */
+
+type StatisticKind int
+
+const (
+    StatisticPower StatisticKind = iota
+    StatisticGold
+    StatisticMana
+    StatisticResearch
+    StatisticFame
+    StatisticCities
+    StatisticPopulation
+    StatisticArmy
+    StatisticSpells
+    StatisticBattlesWon
+    StatisticBattlesLost
+)
+
+var StatisticKinds = []StatisticKind{
+    StatisticPower, StatisticGold, StatisticMana, StatisticResearch, StatisticFame, StatisticCities,
+    StatisticPopulation, StatisticArmy, StatisticSpells, StatisticBattlesWon, StatisticBattlesLost,
+}
+
+func (kind StatisticKind) String() string {
+    switch kind {
+        case StatisticPower: return "Power"
+        case StatisticGold: return "Gold"
+        case StatisticMana: return "Mana"
+        case StatisticResearch: return "Research"
+        case StatisticFame: return "Fame"
+        case StatisticCities: return "Cities"
+        case StatisticPopulation: return "Population"
+        case StatisticArmy: return "Army Strength"
+        case StatisticSpells: return "Spells Known"
+        case StatisticBattlesWon: return "Battles Won"
+        case StatisticBattlesLost: return "Battles Lost"
+    }
+
+    return "?"
+}
+
+type TurnStatistics struct {
+    Turn uint64 `json:"turn"`
+    Power int `json:"power"`
+    Gold int `json:"gold"`
+    Mana int `json:"mana"`
+    Research int `json:"research"`
+    Fame int `json:"fame"`
+    Cities int `json:"cities"`
+    Population int `json:"population"`
+    Army int `json:"army"`
+    Spells int `json:"spells"`
+    BattlesWon int `json:"battles-won"`
+    BattlesLost int `json:"battles-lost"`
+}
+
+func (stats *TurnStatistics) Get(kind StatisticKind) int {
+    switch kind {
+        case StatisticPower: return stats.Power
+        case StatisticGold: return stats.Gold
+        case StatisticMana: return stats.Mana
+        case StatisticResearch: return stats.Research
+        case StatisticFame: return stats.Fame
+        case StatisticCities: return stats.Cities
+        case StatisticPopulation: return stats.Population
+        case StatisticArmy: return stats.Army
+        case StatisticSpells: return stats.Spells
+        case StatisticBattlesWon: return stats.BattlesWon
+        case StatisticBattlesLost: return stats.BattlesLost
+    }
+
+    return 0
+}
+
+type PlayerStatistics struct {
+    Banner data.BannerType `json:"banner"`
+    Name string `json:"name"`
+    // running totals, copied into each sample
+    BattlesWon int `json:"battles-won"`
+    BattlesLost int `json:"battles-lost"`
+    History []TurnStatistics `json:"history"`
+}
+
+func (model *GameModel) GetStatistics(player *playerlib.Player) *PlayerStatistics {
+    for _, stats := range model.Statistics {
+        if stats.Banner == player.GetBanner() {
+            return stats
+        }
+    }
+
+    stats := &PlayerStatistics{
+        Banner: player.GetBanner(),
+        Name: player.Wizard.Name,
+    }
+
+    model.Statistics = append(model.Statistics, stats)
+    return stats
+}
+
+func (model *GameModel) isPlayer(player *playerlib.Player) bool {
+    for _, check := range model.Players {
+        if check == player {
+            return true
+        }
+    }
+
+    return false
+}
+
+// count a battle between two wizards. battles against lair monsters only count for the wizard
+func (model *GameModel) RecordBattle(winner *playerlib.Player, loser *playerlib.Player) {
+    if model.isPlayer(winner) {
+        model.GetStatistics(winner).BattlesWon += 1
+    }
+
+    if model.isPlayer(loser) {
+        model.GetStatistics(loser).BattlesLost += 1
+    }
+}
+
+func (model *GameModel) computeTurnStatistics(player *playerlib.Player, stats *PlayerStatistics) TurnStatistics {
+    population := 0
+    for _, city := range player.Cities {
+        population += city.Population
+    }
+
+    army := 0
+    for _, stack := range player.Stacks {
+        army += ai.StackCombatStrength(stack)
+    }
+
+    power := player.LatestWizardPower()
+
+    return TurnStatistics{
+        Turn: model.TurnNumber,
+        Power: power.TotalPower(),
+        Gold: player.Gold,
+        Mana: player.Mana,
+        Research: int(player.SpellResearchPerTurn(model.ComputePower(player))),
+        Fame: player.GetFame(),
+        Cities: len(player.Cities),
+        Population: population,
+        Army: army,
+        Spells: len(player.KnownSpells.Spells),
+        BattlesWon: stats.BattlesWon,
+        BattlesLost: stats.BattlesLost,
+    }
+}
+
+// take a sample of every wizard that is still in the game, called once at the end of each turn
+func (model *GameModel) RecordStatistics() {
+    for _, player := range model.Players {
+        if player.Defeated {
+            continue
+        }
+
+        stats := model.GetStatistics(player)
+        stats.History = append(stats.History, model.computeTurnStatistics(player, stats))
+    }
+}
+
+// one row per wizard per turn
+func (model *GameModel) WriteStatisticsCSV(writer io.Writer) error {
+    out := csv.NewWriter(writer)
+
+    header := []string{"turn", "wizard", "banner"}
+    for _, kind := range StatisticKinds {
+        header = append(header, kind.String())
+    }
+
+    err := out.Write(header)
+    if err != nil {
+        return err
+    }
+
+    for _, stats := range model.Statistics {
+        for _, sample := range stats.History {
+            row := []string{fmt.Sprintf("%v", sample.Turn), stats.Name, stats.Banner.String()}
+            for _, kind := range StatisticKinds {
+                row = append(row, fmt.Sprintf("%v", sample.Get(kind)))
+            }
+
+            err = out.Write(row)
+            if err != nil {
+                return err
+            }
+        }
+    }
+
+    out.Flush()
+    return out.Error()
+}
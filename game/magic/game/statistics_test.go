@@ -0,0 +1,71 @@
+package game
+
+import (
+    "testing"
+    "strings"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    herolib "github.com/kazzmir/master-of-magic/game/magic/hero"
+    "github.com/kazzmir/master-of-magic/game/magic/setup"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+func TestRecordBattle(test *testing.T) {
+    model := &GameModel{}
+    player1 := playerlib.MakePlayer(setup.WizardCustom{Name: "Merlin", Banner: data.BannerRed}, true, 1, 1, make(map[herolib.HeroType]string), nil)
+    player2 := playerlib.MakePlayer(setup.WizardCustom{Name: "Raven", Banner: data.BannerBlue}, false, 1, 1, make(map[herolib.HeroType]string), nil)
+    model.Players = []*playerlib.Player{player1, player2}
+
+    // a lair has no player of its own
+    lair := playerlib.MakePlayer(setup.WizardCustom{Name: "Cave"}, false, 1, 1, make(map[herolib.HeroType]string), nil)
+
+    model.RecordBattle(player1, player2)
+    model.RecordBattle(player1, lair)
+    model.RecordBattle(lair, player2)
+
+    if model.GetStatistics(player1).BattlesWon != 2 || model.GetStatistics(player1).BattlesLost != 0 {
+        test.Errorf("merlin should have won 2 battles: %+v", model.GetStatistics(player1))
+    }
+
+    if model.GetStatistics(player2).BattlesLost != 2 {
+        test.Errorf("raven should have lost 2 battles: %+v", model.GetStatistics(player2))
+    }
+
+    if len(model.Statistics) != 2 {
+        test.Errorf("only the two wizards should have statistics but there were %v", len(model.Statistics))
+    }
+}
+
+func TestStatisticsCSV(test *testing.T) {
+    model := &GameModel{
+        Statistics: []*PlayerStatistics{
+            &PlayerStatistics{
+                Banner: data.BannerRed,
+                Name: "Merlin",
+                History: []TurnStatistics{
+                    TurnStatistics{Turn: 1, Gold: 50, Cities: 1},
+                    TurnStatistics{Turn: 2, Gold: 65, Cities: 2},
+                },
+            },
+        },
+    }
+
+    var out strings.Builder
+    err := model.WriteStatisticsCSV(&out)
+    if err != nil {
+        test.Fatalf("unable to write csv: %v", err)
+    }
+
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    if len(lines) != 3 {
+        test.Fatalf("expected a header and 2 rows but got %v lines", len(lines))
+    }
+
+    if !strings.HasPrefix(lines[0], "turn,wizard,banner,Power,Gold") {
+        test.Errorf("unexpected header %v", lines[0])
+    }
+
+    if !strings.HasPrefix(lines[2], "2,Merlin,") || !strings.Contains(lines[2], ",65,") {
+        test.Errorf("unexpected row %v", lines[2])
+    }
+}
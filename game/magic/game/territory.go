@@ -0,0 +1,74 @@
+package game
+
+import (
+    "slices"
+
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* the borders drawn for the human player only come from the cities the player has seen, which are
+ * remembered by the player's ai.FogServices the same as for the ai. computing the territory is too slow
+ * to do every frame, so it is kept until one of the known cities changes.
+ */
+
+// the parts of a city that decide the territory it claims
+type territoryCityKey struct {
+    X, Y int
+    Banner data.BannerType
+    Radius int
+}
+
+type territoryCache struct {
+    Plane data.Plane
+    Cities []territoryCityKey
+    Territory *maplib.Territory
+}
+
+// the player's own cities and the cities of other players that the player has seen
+func (game *Game) knownCities(player *playerlib.Player, plane data.Plane) []*citylib.City {
+    view := game.Model.FogView(player)
+    view.Refresh()
+
+    var out []*citylib.City
+    for _, city := range view.AllCities() {
+        if city.Plane == plane {
+            out = append(out, city)
+        }
+    }
+
+    return out
+}
+
+// the territory on the plane as far as the player knows
+func (game *Game) knownTerritory(player *playerlib.Player, plane data.Plane) *maplib.Territory {
+    cities := game.knownCities(player, plane)
+
+    var keys []territoryCityKey
+    for _, city := range cities {
+        keys = append(keys, territoryCityKey{X: city.X, Y: city.Y, Banner: city.GetBanner(), Radius: city.InfluenceRadius()})
+    }
+
+    // player.Cities is a map, so the order of the keys is not stable
+    slices.SortFunc(keys, func(a territoryCityKey, b territoryCityKey) int {
+        if a.X != b.X {
+            return a.X - b.X
+        }
+        return a.Y - b.Y
+    })
+
+    cache := game.territoryCache
+    if cache != nil && cache.Plane == plane && slices.Equal(cache.Cities, keys) {
+        return cache.Territory
+    }
+
+    game.territoryCache = &territoryCache{
+        Plane: plane,
+        Cities: keys,
+        Territory: citylib.ComputeTerritory(game.Model.GetMap(plane), cities),
+    }
+
+    return game.territoryCache.Territory
+}
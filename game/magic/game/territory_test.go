@@ -0,0 +1,77 @@
+package game
+
+import (
+    "testing"
+
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    herolib "github.com/kazzmir/master-of-magic/game/magic/hero"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/setup"
+)
+
+func TestKnownTerritory(test *testing.T) {
+    model, _, _, _ := makePathBenchmarkModel(30, 20)
+
+    human := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerBlue}, true, 30, 20, make(map[herolib.HeroType]string), model)
+    enemy := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerRed}, false, 30, 20, make(map[herolib.HeroType]string), model)
+    model.Players = []*playerlib.Player{human, enemy}
+
+    human.AddCity(citylib.MakeCity("a", 5, 10, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, human))
+    enemy.AddCity(citylib.MakeCity("b", 20, 10, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, enemy))
+
+    game := &Game{Model: model}
+
+    territory := game.knownTerritory(human, data.PlaneArcanus)
+    if territory.Size(data.BannerBlue) == 0 {
+        test.Errorf("the player's own territory should be known")
+    }
+
+    if territory.Size(data.BannerRed) != 0 {
+        test.Errorf("the territory of a city the player has never seen should not be known")
+    }
+
+    if game.knownTerritory(human, data.PlaneArcanus) != territory {
+        test.Errorf("the territory should be reused while the known cities are the same")
+    }
+
+    // having explored the tile is not enough, the city has to be seen
+    human.ExploreFogSquare(20, 10, 1, data.PlaneArcanus)
+    territory = game.knownTerritory(human, data.PlaneArcanus)
+    if territory.Size(data.BannerRed) != 0 {
+        test.Errorf("the territory of a city on an explored tile should not be known")
+    }
+
+    human.LiftFog(20, 10, 1, data.PlaneArcanus)
+    territory = game.knownTerritory(human, data.PlaneArcanus)
+    if territory.Size(data.BannerRed) == 0 {
+        test.Errorf("the territory of a city the player has seen should be known")
+    }
+
+    // the city is still remembered once the tile is no longer visible
+    human.ArcanusFog[20][10] = data.FogTypeExplored
+    if game.knownTerritory(human, data.PlaneArcanus).Size(data.BannerRed) == 0 {
+        test.Errorf("the territory of a city the player has seen before should still be known")
+    }
+}
+
+func TestKnownTerritoryNewCity(test *testing.T) {
+    model, _, _, _ := makePathBenchmarkModel(30, 20)
+
+    human := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerBlue}, true, 30, 20, make(map[herolib.HeroType]string), model)
+    enemy := playerlib.MakePlayer(setup.WizardCustom{Banner: data.BannerRed}, false, 30, 20, make(map[herolib.HeroType]string), model)
+    model.Players = []*playerlib.Player{human, enemy}
+
+    game := &Game{Model: model}
+
+    // the player looked at the tile while it was empty
+    human.LiftFog(20, 10, 1, data.PlaneArcanus)
+    game.knownTerritory(human, data.PlaneArcanus)
+    human.ArcanusFog[20][10] = data.FogTypeExplored
+
+    enemy.AddCity(citylib.MakeCity("b", 20, 10, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, enemy))
+
+    if game.knownTerritory(human, data.PlaneArcanus).Size(data.BannerRed) != 0 {
+        test.Errorf("a city founded after the tile was last seen should not be known")
+    }
+}
@@ -0,0 +1,202 @@
+package game
+
+import (
+    "fmt"
+    "log"
+    "math"
+    "image"
+    "image/color"
+    "context"
+    "slices"
+    "cmp"
+
+    "github.com/kazzmir/master-of-magic/lib/font"
+    "github.com/kazzmir/master-of-magic/lib/coroutine"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/scale"
+    "github.com/kazzmir/master-of-magic/game/magic/util"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
+    uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
+
+    "github.com/hajimehoshi/ebiten/v2"
+    "github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+func tradeRouteColor(route *TradeRoute) color.RGBA {
+    switch route.Interrupted {
+        case TradeInterruptedWar: return color.RGBA{R: 0xc0, G: 0x20, B: 0x20, A: 0xff}
+        case TradeInterruptedPiracy: return color.RGBA{R: 0x70, G: 0x70, B: 0x70, A: 0xff}
+    }
+
+    if route.EnchantedTiles > 0 {
+        return color.RGBA{R: 0x60, G: 0xd0, B: 0xff, A: 0xff}
+    }
+
+    return color.RGBA{R: 0xff, G: 0xd7, B: 0x30, A: 0xff}
+}
+
+// draw the player's trade routes along their roads
+func (game *Game) drawTradeRoutes(screen *ebiten.Image, player *playerlib.Player) {
+    if !game.ShowTradeRoutes || !game.Model.Settings.TradeRoutes {
+        return
+    }
+
+    mapUse := game.Model.CurrentMap()
+    // segments longer than this cross the edge of the map
+    maxSegment := float64(mapUse.TileWidth()) * game.Camera.GetAnimatedZoom() * 2
+
+    for _, route := range game.Model.GetPlayerTradeRoutes(player) {
+        if route.Plane != game.Model.Plane {
+            continue
+        }
+
+        lineColor := tradeRouteColor(route)
+
+        for i := 1; i < len(route.Path); i++ {
+            x1, y1 := game.TileToScreen(route.Path[i - 1].X, route.Path[i - 1].Y)
+            x2, y2 := game.TileToScreen(route.Path[i].X, route.Path[i].Y)
+
+            if math.Hypot(float64(x2 - x1), float64(y2 - y1)) > maxSegment {
+                continue
+            }
+
+            vector.StrokeLine(screen, scale.Scale(float32(x1)), scale.Scale(float32(y1)), scale.Scale(float32(x2)), scale.Scale(float32(y2)), float32(scale.ScaleAmount), lineColor, true)
+        }
+    }
+}
+
+func (game *Game) ShowTrade(yield coroutine.YieldFunc) {
+    group := uilib.MakeGroup()
+
+    quit, cancel := context.WithCancel(context.Background())
+
+    fade := group.MakeFadeIn(7)
+
+    loader, err := fontslib.Loader(game.Cache)
+    if err != nil {
+        log.Printf("Error: trade routes: unable to load font: %v", err)
+        cancel()
+        return
+    }
+
+    titleFont := loader(fontslib.BigOrangeGradient2)
+    dateFont := loader(fontslib.LightFontSmall)
+    normalFont := loader(fontslib.SmallWhite)
+    bannerFonts := fontslib.MakeBannerFonts(game.Cache, 3)
+
+    player := game.Model.GetHumanPlayer()
+
+    routes := slices.Clone(game.Model.GetPlayerTradeRoutes(player))
+    slices.SortFunc(routes, func(a *TradeRoute, b *TradeRoute) int {
+        return cmp.Compare(b.Value, a.Value)
+    })
+
+    panelColor := util.PremultiplyAlpha(color.RGBA{A: 120})
+
+    listRect := image.Rect(8, 30, 312, 170)
+    rowHeight := 9
+    visibleRows := (listRect.Dy() - 12) / rowHeight
+    scroll := 0
+
+    // the city of the route that belongs to the player comes first
+    routeCities := func(route *TradeRoute) (string, string, *playerlib.Player) {
+        if route.FromOwner == player {
+            return route.From.Name, route.To.Name, route.ToOwner
+        }
+
+        return route.To.Name, route.From.Name, route.FromOwner
+    }
+
+    group.AddElement(&uilib.UIElement{
+        Layer: 1,
+        Rect: image.Rect(0, 0, data.ScreenWidth, data.ScreenHeight),
+        Scroll: func(element *uilib.UIElement, x float64, y float64){
+            if y < 0 {
+                scroll = min(scroll + 1, max(0, len(routes) - visibleRows))
+            } else if y > 0 {
+                scroll = max(0, scroll - 1)
+            }
+        },
+        Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+            background, _ := game.ImageCache.GetImage("reload.lbx", 0, 0)
+            var options ebiten.DrawImageOptions
+            options.ColorScale.ScaleAlpha(fade())
+            scale.DrawScaled(screen, background, &options)
+
+            titleFont.PrintOptions(screen, 160, 10, font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount, Options: &options}, "Trade Routes")
+            dateFont.PrintOptions(screen, 312, 11, font.FontOptions{DropShadow: true, Justify: font.FontJustifyRight, Scale: scale.ScaleAmount, Options: &options}, game.TurnDate())
+
+            vector.FillRect(screen, scale.Scale(float32(listRect.Min.X)), scale.Scale(float32(listRect.Min.Y)), scale.Scale(float32(listRect.Dx())), scale.Scale(float32(listRect.Dy())), panelColor, false)
+
+            headerY := float64(listRect.Min.Y + 2)
+            columns := []int{listRect.Min.X + 2, listRect.Min.X + 90, listRect.Min.X + 180, listRect.Min.X + 215, listRect.Min.X + 250}
+            for i, header := range []string{"City", "Trades With", "Road", "Gold", "Status"} {
+                normalFont.PrintOptions(screen, float64(columns[i]), headerY, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, header)
+            }
+
+            if len(routes) == 0 {
+                normalFont.PrintOptions(screen, float64(listRect.Min.X + listRect.Dx() / 2), float64(listRect.Min.Y + 40), font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount, Options: &options}, "Connect cities with roads to open trade routes")
+            }
+
+            for i, route := range routes[scroll:min(len(routes), scroll + visibleRows)] {
+                y := float64(listRect.Min.Y + 12 + i * rowHeight)
+                ours, theirs, other := routeCities(route)
+
+                road := fmt.Sprintf("%v", route.Distance())
+                if route.EnchantedTiles > 0 {
+                    road += "*"
+                }
+
+                normalFont.PrintOptions(screen, float64(columns[0]), y, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, ours)
+                bannerFonts[other.GetBanner()].PrintOptions(screen, float64(columns[1]), y, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, theirs)
+                normalFont.PrintOptions(screen, float64(columns[2]), y, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, road)
+                normalFont.PrintOptions(screen, float64(columns[3]), y, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, fmt.Sprintf("%v", route.Value))
+                normalFont.PrintOptions(screen, float64(columns[4]), y, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, route.Interrupted.String())
+            }
+
+            summary := fmt.Sprintf("%v routes, %v gold per turn", len(routes), player.TradeIncome)
+            if game.Model.IsTradePlundered(player) {
+                summary += fmt.Sprintf(", pirates for %v turns", game.Model.Piracy[player.GetBanner()] - game.Model.TurnNumber)
+            }
+            normalFont.PrintOptions(screen, 10, 176, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, summary)
+            normalFont.PrintOptions(screen, 10, 186, font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Options: &options}, "* enchanted road")
+        },
+    })
+
+    makeButton := func(rect image.Rectangle, text func() string, action func()) *uilib.UIElement {
+        return &uilib.UIElement{
+            Layer: 1,
+            Order: 1,
+            Rect: rect,
+            PlaySoundLeftClick: true,
+            LeftClick: func(element *uilib.UIElement){
+                action()
+            },
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                vector.FillRect(screen, scale.Scale(float32(rect.Min.X)), scale.Scale(float32(rect.Min.Y)), scale.Scale(float32(rect.Dx())), scale.Scale(float32(rect.Dy())), panelColor, false)
+                var options ebiten.DrawImageOptions
+                options.ColorScale.ScaleAlpha(fade())
+                normalFont.PrintOptions(screen, float64(rect.Min.X + rect.Dx() / 2), float64(rect.Min.Y + 3), font.FontOptions{DropShadow: true, Justify: font.FontJustifyCenter, Scale: scale.ScaleAmount, Options: &options}, text())
+            },
+        }
+    }
+
+    group.AddElement(makeButton(image.Rect(180, 184, 262, 196), func() string {
+        if game.ShowTradeRoutes {
+            return "Map Routes: On"
+        }
+        return "Map Routes: Off"
+    }, func(){
+        game.ShowTradeRoutes = !game.ShowTradeRoutes
+    }))
+
+    group.AddElement(makeButton(image.Rect(270, 184, 312, 196), func() string { return "Done" }, func(){
+        fade = group.MakeFadeOut(7)
+        group.AddDelay(7, func(){
+            cancel()
+        })
+    }))
+
+    game.doRunUI(yield, group, quit)
+}
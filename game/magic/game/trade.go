@@ -0,0 +1,361 @@
+package game
+
+import (
+    "image"
+    "slices"
+
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* trade routes are an optional rule that goes beyond the original game, enabled with
+ * setup.NewGameSettings.TradeRoutes. two cities joined by a road can form a route, including a city
+ * of another wizard that has a pact or an alliance with the owner. each city on a route earns the
+ * value of the route in gold every turn, on top of the usual foreign trade bonus.
+ *
+ * a city only keeps its MaxTradeRoutesPerCity most valuable routes, so the income of a wizard grows
+ * with the number of cities rather than with the number of pairs of cities.
+ *
+ * a route is interrupted while the two wizards are at war, and for a few turns after pirates
+ * plunder either wizard's treasury.
+ */
+
+// how many turns trade is interrupted after a piracy event
+const PiracyTradeTurns = 5
+
+// the most routes a single city can be part of
+const MaxTradeRoutesPerCity = 3
+
+type TradeInterruption int
+
+const (
+    TradeOpen TradeInterruption = iota
+    TradeInterruptedWar
+    TradeInterruptedPiracy
+)
+
+func (interruption TradeInterruption) String() string {
+    switch interruption {
+        case TradeOpen: return "Open"
+        case TradeInterruptedWar: return "War"
+        case TradeInterruptedPiracy: return "Pirates"
+    }
+
+    return "?"
+}
+
+type TradeRoute struct {
+    From *citylib.City
+    FromOwner *playerlib.Player
+    To *citylib.City
+    ToOwner *playerlib.Player
+    Plane data.Plane
+    // the tiles of the road, starting at From and ending at To
+    Path []image.Point
+    // how many tiles of the road are enchanted
+    EnchantedTiles int
+    Value int
+    Interrupted TradeInterruption
+}
+
+func (route *TradeRoute) Distance() int {
+    return max(0, len(route.Path) - 1)
+}
+
+func (route *TradeRoute) Foreign() bool {
+    return route.FromOwner != route.ToOwner
+}
+
+// the gold each city on the route earns per turn
+func (route *TradeRoute) Income() int {
+    if route.Interrupted != TradeOpen {
+        return 0
+    }
+
+    return route.Value
+}
+
+func (route *TradeRoute) Involves(player *playerlib.Player) bool {
+    return route.FromOwner == player || route.ToOwner == player
+}
+
+/* the gold a route is worth to each of its cities
+ *  - a big city trading with a small one moves more goods than two cities of the same size
+ *  - cities of different races trade 50% more, like the foreign trade bonus
+ *  - longer roads carry scarcer goods, up to twice as much at 20 tiles
+ *  - an enchanted road adds up to 50% depending on how much of it is enchanted
+ */
+func tradeValue(citizens1 int, citizens2 int, sameRace bool, distance int, enchantedTiles int) int {
+    larger := max(citizens1, citizens2)
+    smaller := min(citizens1, citizens2)
+
+    value := 1 + float64(smaller) / 3 + float64(larger - smaller) / 2
+
+    if !sameRace {
+        value *= 1.5
+    }
+
+    value *= 1 + float64(min(distance, 20)) / 20
+
+    if distance > 0 {
+        value *= 1 + 0.5 * float64(min(enchantedTiles, distance)) / float64(distance)
+    }
+
+    return int(value)
+}
+
+type tradeCity struct {
+    City *citylib.City
+    Owner *playerlib.Player
+}
+
+// true while pirates are still disrupting this wizard's trade
+func (model *GameModel) IsTradePlundered(player *playerlib.Player) bool {
+    return model.Piracy[player.GetBanner()] > model.TurnNumber
+}
+
+// pirates plundered this wizard, so its routes stop for a while
+func (model *GameModel) InterruptTrade(player *playerlib.Player) {
+    if model.Piracy == nil {
+        model.Piracy = make(map[data.BannerType]uint64)
+    }
+
+    model.Piracy[player.GetBanner()] = model.TurnNumber + PiracyTradeTurns
+}
+
+// whether two wizards trade with each other, and if so whether the trade is interrupted
+func (model *GameModel) tradeRelation(player1 *playerlib.Player, player2 *playerlib.Player) (TradeInterruption, bool) {
+    interruption := TradeOpen
+
+    if player1 != player2 {
+        // treaties are kept by each wizard, so either side declaring war stops the trade
+        treaty := data.TreatyNone
+        for _, pair := range [][2]*playerlib.Player{{player1, player2}, {player2, player1}} {
+            relation, ok := pair[0].GetDiplomaticRelation(pair[1])
+            if ok && (treaty == data.TreatyNone || relation.Treaty == data.TreatyWar) {
+                treaty = relation.Treaty
+            }
+        }
+
+        switch treaty {
+            case data.TreatyPact, data.TreatyAlliance:
+            case data.TreatyWar: interruption = TradeInterruptedWar
+            default: return TradeOpen, false
+        }
+    }
+
+    if interruption == TradeOpen && (model.IsTradePlundered(player1) || model.IsTradePlundered(player2)) {
+        interruption = TradeInterruptedPiracy
+    }
+
+    return interruption, true
+}
+
+/* walk the roads out of the city and return the shortest road to every city that can be reached.
+ * like IsCityRoadConnected, a road may pass through other cities
+ */
+func (model *GameModel) findRoadPaths(city *citylib.City) map[image.Point][]image.Point {
+    mapUse := model.GetMap(city.Plane)
+
+    start := image.Pt(city.X, city.Y)
+    parents := map[image.Point]image.Point{start: start}
+    queue := []image.Point{start}
+
+    out := make(map[image.Point][]image.Point)
+
+    for len(queue) > 0 {
+        current := queue[0]
+        queue = queue[1:]
+
+        if current != start && model.ContainsCity(current.X, current.Y, city.Plane) {
+            var path []image.Point
+            for point := current; point != start; point = parents[point] {
+                path = append(path, point)
+            }
+            path = append(path, start)
+
+            // the path was built backwards
+            for i, j := 0, len(path) - 1; i < j; i, j = i + 1, j - 1 {
+                path[i], path[j] = path[j], path[i]
+            }
+
+            out[current] = path
+        }
+
+        for dx := -1; dx <= 1; dx++ {
+            for dy := -1; dy <= 1; dy++ {
+                if dx == 0 && dy == 0 {
+                    continue
+                }
+
+                next := image.Pt(mapUse.WrapX(current.X + dx), current.Y + dy)
+                if next.Y < 0 || next.Y >= mapUse.Height() {
+                    continue
+                }
+
+                _, visited := parents[next]
+                if visited {
+                    continue
+                }
+
+                if mapUse.ContainsRoad(next.X, next.Y) || model.ContainsCity(next.X, next.Y, city.Plane) {
+                    parents[next] = current
+                    queue = append(queue, next)
+                }
+            }
+        }
+    }
+
+    return out
+}
+
+// compute every trade route in the game. returns nothing if trade routes are not enabled
+func (model *GameModel) ComputeTradeRoutes() []*TradeRoute {
+    if !model.Settings.TradeRoutes {
+        return nil
+    }
+
+    var cities []tradeCity
+    for _, player := range model.Players {
+        if player.Defeated {
+            continue
+        }
+
+        for _, city := range player.Cities {
+            if !city.Outpost {
+                cities = append(cities, tradeCity{City: city, Owner: player})
+            }
+        }
+    }
+
+    var routes []*TradeRoute
+
+    for i, from := range cities {
+        var paths map[image.Point][]image.Point
+
+        for _, to := range cities[i + 1:] {
+            if from.City.Plane != to.City.Plane {
+                continue
+            }
+
+            interruption, trades := model.tradeRelation(from.Owner, to.Owner)
+            if !trades {
+                continue
+            }
+
+            if paths == nil {
+                paths = model.findRoadPaths(from.City)
+            }
+
+            path, ok := paths[image.Pt(to.City.X, to.City.Y)]
+            if !ok {
+                continue
+            }
+
+            mapUse := model.GetMap(from.City.Plane)
+            enchanted := 0
+            for _, point := range path {
+                if mapUse.ContainsEnchantedRoad(point.X, point.Y) {
+                    enchanted += 1
+                }
+            }
+
+            routes = append(routes, &TradeRoute{
+                From: from.City,
+                FromOwner: from.Owner,
+                To: to.City,
+                ToOwner: to.Owner,
+                Plane: from.City.Plane,
+                Path: path,
+                EnchantedTiles: enchanted,
+                Value: tradeValue(from.City.Citizens(), to.City.Citizens(), from.City.Race == to.City.Race, len(path) - 1, enchanted),
+                Interrupted: interruption,
+            })
+        }
+    }
+
+    return limitTradeRoutes(routes)
+}
+
+/* keep the most valuable routes such that no city is part of more than MaxTradeRoutesPerCity of them.
+ * the routes are looked at from the most valuable down, and a route is dropped if either of its
+ * cities already has all the routes it can have
+ */
+func limitTradeRoutes(routes []*TradeRoute) []*TradeRoute {
+    slices.SortStableFunc(routes, func(a *TradeRoute, b *TradeRoute) int {
+        if a.Value != b.Value {
+            return b.Value - a.Value
+        }
+
+        if a.Distance() != b.Distance() {
+            return a.Distance() - b.Distance()
+        }
+
+        // player.Cities is a map, so break ties by position to keep the same routes every time
+        for _, pair := range [][2]int{{a.From.X, b.From.X}, {a.From.Y, b.From.Y}, {a.To.X, b.To.X}, {a.To.Y, b.To.Y}} {
+            if pair[0] != pair[1] {
+                return pair[0] - pair[1]
+            }
+        }
+
+        return 0
+    })
+
+    count := make(map[*citylib.City]int)
+
+    var out []*TradeRoute
+    for _, route := range routes {
+        if count[route.From] >= MaxTradeRoutesPerCity || count[route.To] >= MaxTradeRoutesPerCity {
+            continue
+        }
+
+        count[route.From] += 1
+        count[route.To] += 1
+        out = append(out, route)
+    }
+
+    return out
+}
+
+// recompute the trade routes and each wizard's trade income
+func (model *GameModel) UpdateTradeRoutes() {
+    model.tradeRoutes = model.ComputeTradeRoutes()
+    model.tradeRoutesTurn = model.TurnNumber
+    model.tradeRoutesValid = true
+
+    for _, player := range model.Players {
+        player.TradeIncome = 0
+    }
+
+    for _, route := range model.tradeRoutes {
+        route.FromOwner.TradeIncome += route.Income()
+        route.ToOwner.TradeIncome += route.Income()
+    }
+}
+
+// forget the trade routes. call this when a road is built, a city changes hands or a treaty changes
+func (model *GameModel) InvalidateTradeRoutes() {
+    model.tradeRoutesValid = false
+}
+
+// the current trade routes, recomputed once per turn or after InvalidateTradeRoutes
+func (model *GameModel) GetTradeRoutes() []*TradeRoute {
+    if !model.tradeRoutesValid || model.tradeRoutesTurn != model.TurnNumber {
+        model.UpdateTradeRoutes()
+    }
+
+    return model.tradeRoutes
+}
+
+// the routes that the given wizard is part of
+func (model *GameModel) GetPlayerTradeRoutes(player *playerlib.Player) []*TradeRoute {
+    var out []*TradeRoute
+    for _, route := range model.GetTradeRoutes() {
+        if route.Involves(player) {
+            out = append(out, route)
+        }
+    }
+
+    return out
+}
@@ -0,0 +1,233 @@
+package game
+
+import (
+    "testing"
+    "image"
+
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
+    herolib "github.com/kazzmir/master-of-magic/game/magic/hero"
+    "github.com/kazzmir/master-of-magic/game/magic/setup"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+func TestTradeValue(test *testing.T) {
+    base := tradeValue(4, 4, true, 10, 0)
+
+    if tradeValue(4, 12, true, 10, 0) <= base {
+        test.Errorf("a bigger difference in population should be worth more")
+    }
+
+    if tradeValue(4, 4, false, 10, 0) <= base {
+        test.Errorf("different races should be worth more")
+    }
+
+    if tradeValue(4, 4, true, 20, 0) <= base {
+        test.Errorf("a longer road should be worth more")
+    }
+
+    if tradeValue(4, 4, true, 10, 10) <= base {
+        test.Errorf("an enchanted road should be worth more")
+    }
+
+    if tradeValue(4, 4, true, 40, 0) != tradeValue(4, 4, true, 20, 0) {
+        test.Errorf("the distance bonus should stop at 20 tiles")
+    }
+}
+
+func TestTradeRelation(test *testing.T) {
+    model := &GameModel{TurnNumber: 10}
+    player1 := playerlib.MakePlayer(setup.WizardCustom{Name: "Merlin", Banner: data.BannerRed}, true, 1, 1, make(map[herolib.HeroType]string), nil)
+    player2 := playerlib.MakePlayer(setup.WizardCustom{Name: "Raven", Banner: data.BannerBlue}, false, 1, 1, make(map[herolib.HeroType]string), nil)
+
+    _, trades := model.tradeRelation(player1, player2)
+    if trades {
+        test.Errorf("wizards that have not met should not trade")
+    }
+
+    interruption, trades := model.tradeRelation(player1, player1)
+    if !trades || interruption != TradeOpen {
+        test.Errorf("a wizard should always trade with itself")
+    }
+
+    player1.PactWithPlayer(player2)
+    interruption, trades = model.tradeRelation(player1, player2)
+    if !trades || interruption != TradeOpen {
+        test.Errorf("a pact should open trade: %v %v", trades, interruption)
+    }
+
+    model.InterruptTrade(player2)
+    interruption, _ = model.tradeRelation(player1, player2)
+    if interruption != TradeInterruptedPiracy {
+        test.Errorf("pirates should interrupt trade but was %v", interruption)
+    }
+
+    model.TurnNumber += PiracyTradeTurns
+    interruption, _ = model.tradeRelation(player1, player2)
+    if interruption != TradeOpen {
+        test.Errorf("trade should open again once the pirates leave but was %v", interruption)
+    }
+
+    player1.WarWithPlayer(player2)
+    interruption, trades = model.tradeRelation(player1, player2)
+    if !trades || interruption != TradeInterruptedWar {
+        test.Errorf("war should interrupt trade: %v %v", trades, interruption)
+    }
+}
+
+// a model with trade routes enabled and no roads, and a player that owns cities at the given points
+func makeTradeModel(points ...image.Point) (*GameModel, *playerlib.Player, []*citylib.City) {
+    model, player, _, _ := makePathBenchmarkModel(30, 12)
+    model.Settings.TradeRoutes = true
+    model.Players = []*playerlib.Player{player}
+
+    mapUse := model.GetMap(data.PlaneArcanus)
+    for x := range mapUse.Width() {
+        mapUse.RemoveRoad(x, mapUse.Height() / 2)
+    }
+
+    var cities []*citylib.City
+    for _, point := range points {
+        city := citylib.MakeCity("xyz", point.X, point.Y, data.RaceHighMen, nil, &NoCatchment{}, &NoServices{}, player)
+        city.Population = 4000
+        player.AddCity(city)
+        cities = append(cities, city)
+    }
+
+    return model, player, cities
+}
+
+func TestTradeRoutePaths(test *testing.T) {
+    model, player, cities := makeTradeModel(image.Pt(2, 3), image.Pt(8, 3), image.Pt(2, 8))
+    mapUse := model.GetMap(data.PlaneArcanus)
+
+    for x := 3; x <= 7; x++ {
+        mapUse.SetRoad(x, 3, x <= 4)
+    }
+
+    paths := model.findRoadPaths(cities[0])
+    path, ok := paths[image.Pt(8, 3)]
+    if !ok || len(path) != 7 || path[0] != image.Pt(2, 3) || path[6] != image.Pt(8, 3) {
+        test.Fatalf("expected a road of 7 tiles from the first city to the second but was %v", path)
+    }
+
+    if _, ok := paths[image.Pt(2, 8)]; ok {
+        test.Errorf("a city without a road should not be reachable")
+    }
+
+    routes := model.ComputeTradeRoutes()
+    if len(routes) != 1 {
+        test.Fatalf("expected 1 route but got %v", len(routes))
+    }
+
+    route := routes[0]
+    if route.Distance() != 6 || route.EnchantedTiles != 2 || route.Foreign() {
+        test.Errorf("unexpected route: distance %v, enchanted %v, foreign %v", route.Distance(), route.EnchantedTiles, route.Foreign())
+    }
+
+    if route.Value != tradeValue(4, 4, true, 6, 2) {
+        test.Errorf("route value should be %v but was %v", tradeValue(4, 4, true, 6, 2), route.Value)
+    }
+
+    model.UpdateTradeRoutes()
+    if player.TradeIncome != route.Value * 2 {
+        test.Errorf("both cities should earn the route value: expected %v but was %v", route.Value * 2, player.TradeIncome)
+    }
+
+    // a new road during the turn connects the third city through the first one
+    for y := 4; y <= 7; y++ {
+        mapUse.SetRoad(2, y, false)
+    }
+    model.InvalidateMovementCosts()
+
+    if len(model.GetTradeRoutes()) != 3 {
+        test.Errorf("the new road should add two routes but there are %v", len(model.GetTradeRoutes()))
+    }
+
+    model.Settings.TradeRoutes = false
+    if model.ComputeTradeRoutes() != nil {
+        test.Errorf("there should be no routes when trade routes are disabled")
+    }
+}
+
+func TestTradeRouteLimit(test *testing.T) {
+    var points []image.Point
+    for x := 1; x < 25; x += 3 {
+        points = append(points, image.Pt(x, 3))
+    }
+
+    model, player, cities := makeTradeModel(points...)
+    mapUse := model.GetMap(data.PlaneArcanus)
+    for x := range mapUse.Width() {
+        mapUse.SetRoad(x, 3, false)
+    }
+
+    routes := model.GetTradeRoutes()
+
+    count := make(map[*citylib.City]int)
+    income := 0
+    for _, route := range routes {
+        count[route.From] += 1
+        count[route.To] += 1
+        income += route.Income() * 2
+    }
+
+    for _, city := range cities {
+        if count[city] > MaxTradeRoutesPerCity {
+            test.Errorf("city at %v, %v has %v routes", city.X, city.Y, count[city])
+        }
+    }
+
+    if len(routes) > len(cities) * MaxTradeRoutesPerCity / 2 {
+        test.Errorf("%v cities should have at most %v routes but have %v", len(cities), len(cities) * MaxTradeRoutesPerCity / 2, len(routes))
+    }
+
+    if player.TradeIncome != income {
+        test.Errorf("trade income should be %v but was %v", income, player.TradeIncome)
+    }
+}
+
+func TestTradeRouteForeign(test *testing.T) {
+    model, player1, _ := makeTradeModel(image.Pt(2, 3))
+    player2 := playerlib.MakePlayer(setup.WizardCustom{Name: "Raven", Banner: data.BannerBlue}, false, 30, 12, make(map[herolib.HeroType]string), model)
+    model.Players = append(model.Players, player2)
+
+    city := citylib.MakeCity("abc", 6, 3, data.RaceDwarf, nil, &NoCatchment{}, &NoServices{}, player2)
+    city.Population = 8000
+    player2.AddCity(city)
+
+    mapUse := model.GetMap(data.PlaneArcanus)
+    for x := 3; x <= 5; x++ {
+        mapUse.SetRoad(x, 3, false)
+    }
+
+    if len(model.GetTradeRoutes()) != 0 {
+        test.Errorf("wizards without a treaty should not trade")
+    }
+
+    player1.PactWithPlayer(player2)
+    player2.PactWithPlayer(player1)
+
+    // the routes are only recomputed once they are invalidated
+    if len(model.GetTradeRoutes()) != 0 {
+        test.Errorf("routes should be cached until they are invalidated")
+    }
+
+    model.InvalidateTradeRoutes()
+    routes := model.GetTradeRoutes()
+    if len(routes) != 1 || !routes[0].Foreign() {
+        test.Fatalf("a pact should open a foreign route: %v", routes)
+    }
+
+    value := tradeValue(4, 8, false, 4, 0)
+    if routes[0].Value != value || player1.TradeIncome != value || player2.TradeIncome != value {
+        test.Errorf("each wizard should earn %v but got %v and %v", value, player1.TradeIncome, player2.TradeIncome)
+    }
+
+    player2.WarWithPlayer(player1)
+    model.InvalidateTradeRoutes()
+    routes = model.GetTradeRoutes()
+    if len(routes) != 1 || routes[0].Interrupted != TradeInterruptedWar || player1.TradeIncome != 0 {
+        test.Errorf("war should interrupt the route: %v, income %v", routes, player1.TradeIncome)
+    }
+}
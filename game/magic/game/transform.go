@@ -0,0 +1,100 @@
+package game
+
+import (
+    "image"
+    "math/rand/v2"
+
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+)
+
+// the source of the transforms scheduled by gaia's blessing
+const GaiasBlessingSource = "Gaia's Blessing"
+
+// a desert or swamp tile around a blessed city turns into grassland after this many turns, plus up to GaiasBlessingRandomDelay more
+const GaiasBlessingMinimumDelay = 3
+const GaiasBlessingRandomDelay = 10
+
+// a volcano chosen by armageddon rises after this many turns, plus up to ArmageddonRandomDelay more
+const ArmageddonMinimumDelay = 1
+const ArmageddonRandomDelay = 2
+
+// a tile chosen by the great wasting is corrupted after this many turns, plus up to GreatWastingRandomDelay more
+const GreatWastingMinimumDelay = 1
+const GreatWastingRandomDelay = 2
+
+// the food and production of cities depends on their catchment area, so recompute the citizens of
+// every city whose catchment area contains one of the changed tiles
+func (model *GameModel) TerrainChanged(plane data.Plane, points []image.Point) {
+    if len(points) == 0 {
+        return
+    }
+
+    model.InvalidateMovementCosts()
+
+    mapObject := model.GetMap(plane)
+    for _, city := range model.AllCities() {
+        if city.Plane != plane {
+            continue
+        }
+
+        for _, point := range points {
+            if mapObject.InCatchmentArea(city.X, city.Y, point.X, point.Y) {
+                city.ResetCitizens()
+                break
+            }
+        }
+    }
+}
+
+// change a tile right away, such as by a spell that takes effect immediately
+func (model *GameModel) TransformNow(plane data.Plane, transform maplib.TerrainTransform) bool {
+    if model.GetMap(plane).ApplyTransform(transform) {
+        model.TerrainChanged(plane, []image.Point{image.Pt(model.GetMap(plane).WrapX(transform.X), transform.Y)})
+        return true
+    }
+
+    return false
+}
+
+// gaia's blessing slowly turns the desert and swamp around a city into grassland and removes corruption
+func (model *GameModel) ScheduleGaiasBlessing() {
+    blessed := make(map[data.PlanePoint]bool)
+
+    for _, city := range model.AllCities() {
+        if !city.HasEnchantment(data.CityEnchantmentGaiasBlessing) {
+            continue
+        }
+
+        mapObject := model.GetMap(city.Plane)
+        for point, tile := range mapObject.GetCatchmentArea(city.X, city.Y) {
+            blessed[data.PlanePoint{X: point.X, Y: point.Y, Plane: city.Plane}] = true
+
+            turn := model.TurnNumber + GaiasBlessingMinimumDelay + uint64(rand.IntN(GaiasBlessingRandomDelay + 1))
+
+            switch tile.Tile.TerrainType() {
+                case terrain.Desert, terrain.Swamp:
+                    mapObject.ScheduleTransform(maplib.TerrainTransform{X: point.X, Y: point.Y, Kind: maplib.TransformTerrain, Terrain: terrain.Grass, Turn: turn, Source: GaiasBlessingSource})
+            }
+
+            if mapObject.HasCorruption(point.X, point.Y) {
+                mapObject.ScheduleTransform(maplib.TerrainTransform{X: point.X, Y: point.Y, Kind: maplib.TransformPurify, Turn: turn, Source: GaiasBlessingSource})
+            }
+        }
+    }
+
+    // the land of cities that lost the blessing stays as it is
+    for _, mapObject := range []*maplib.Map{model.ArcanusMap, model.MyrrorMap} {
+        mapObject.CancelTransforms(func (transform maplib.TerrainTransform) bool {
+            return transform.Source == GaiasBlessingSource && !blessed[data.PlanePoint{X: transform.X, Y: transform.Y, Plane: mapObject.Plane}]
+        })
+    }
+}
+
+// run the transforms that are due this turn on both planes
+func (model *GameModel) ApplyTerrainTransforms() {
+    for _, mapObject := range []*maplib.Map{model.ArcanusMap, model.MyrrorMap} {
+        model.TerrainChanged(mapObject.Plane, mapObject.ApplyTransforms(model.TurnNumber))
+    }
+}
@@ -31,6 +31,14 @@ const (
     ActionNextTurn
     ActionQuitWithoutSaving
     ActionDefaultItemEditor
+    ActionMinimapZoom
+    ActionMinimapLayer
+    ActionTerritoryBorders
+    ActionPatrol
+    ActionGotoCity
+    ActionSentry
+    ActionEconomy
+    ActionStatistics
 )
 
 // AllActions lists every rebindable action, in the order they should be
@@ -55,6 +63,14 @@ var AllActions = []Action{
     ActionNextTurn,
     ActionQuitWithoutSaving,
     ActionDefaultItemEditor,
+    ActionMinimapZoom,
+    ActionMinimapLayer,
+    ActionTerritoryBorders,
+    ActionPatrol,
+    ActionGotoCity,
+    ActionSentry,
+    ActionEconomy,
+    ActionStatistics,
 }
 
 func (action Action) Name() string {
@@ -78,6 +94,14 @@ func (action Action) Name() string {
         case ActionNextTurn: return "Next Turn"
         case ActionQuitWithoutSaving: return "Quit Without Saving"
         case ActionDefaultItemEditor: return "Default Item Editor"
+        case ActionMinimapZoom: return "Minimap Zoom"
+        case ActionMinimapLayer: return "Minimap Layer"
+        case ActionTerritoryBorders: return "Territory Borders"
+        case ActionPatrol: return "Patrol"
+        case ActionGotoCity: return "Go To City"
+        case ActionSentry: return "Sentry"
+        case ActionEconomy: return "Economy Report"
+        case ActionStatistics: return "Statistics"
     }
 
     return "Unknown"
@@ -107,6 +131,17 @@ func (action Action) Default() ebiten.Key {
         case ActionQuitWithoutSaving: return Unbound
         // remake default: original/CP left this unbound. E is free.
         case ActionDefaultItemEditor: return ebiten.KeyE
+        // remake additions: switch the minimap between the area around the camera and the whole map, and cycle what it shows
+        case ActionMinimapZoom: return ebiten.KeyZ
+        case ActionMinimapLayer: return ebiten.KeyL
+        case ActionTerritoryBorders: return ebiten.KeyB
+        // standing orders for the selected stack: patrol between here and its destination, go to a city, wait for enemies
+        case ActionPatrol: return ebiten.KeyR
+        case ActionGotoCity: return ebiten.KeyT
+        case ActionSentry: return ebiten.KeyW
+        // remake addition: the empire wide economy report, next to the other advisors
+        case ActionEconomy: return ebiten.KeyF10
+        case ActionStatistics: return ebiten.KeyF11
     }
 
     return Unbound
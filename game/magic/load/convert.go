@@ -787,6 +787,7 @@ func (saveGame *SaveGame) convertPlayer(playerIndex int, wizards []setup.WizardC
         // FIXME: RemainingCastingSkill
         GlobalEnchantments: globalEnchantments,
         GlobalEnchantmentsProvider: game.Model,
+        DifficultyProvider: game.Model,
         PlayerRelations: playerRelations,
         // FIXME: HeroPool createHeroes(herolib.ReadNamesPerWizard(game.Cache))
         // FIXME: Heroes
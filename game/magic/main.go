@@ -14,6 +14,9 @@ import (
     "compress/gzip"
     "encoding/json"
     "image"
+    "os"
+    "path/filepath"
+    "strings"
     // "image/color"
 
     // for trace/pprof
@@ -22,7 +25,6 @@ import (
     /*
     "runtime"
     "runtime/debug"
-    "os"
     */
 
     "github.com/kazzmir/master-of-magic/lib/lbx"
@@ -47,6 +49,7 @@ import (
     playerlib "github.com/kazzmir/master-of-magic/game/magic/player"
     mouselib "github.com/kazzmir/master-of-magic/lib/mouse"
     "github.com/kazzmir/master-of-magic/game/magic/mainview"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
     gamelib "github.com/kazzmir/master-of-magic/game/magic/game"
     citylib "github.com/kazzmir/master-of-magic/game/magic/city"
     buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
@@ -66,6 +69,51 @@ type MagicGame struct {
 
     Settings *settingslib.Settings
     Music *musiclib.Music
+
+    // if set, ai turn traces are written to this file as json lines
+    AITracePath string
+
+    // if set, the per turn statistics of every wizard are written to this csv file
+    StatisticsPath string
+
+    // scenario files that can be chosen on the new game screen instead of a random map
+    Scenarios []string
+
+    // if set, quick and watch games are played on this scenario file instead of a random map
+    StartScenario string
+}
+
+// the scenario files at the given path, which is either a single scenario or a directory of them
+func findScenarios(path string) []string {
+    info, err := os.Stat(path)
+    if err != nil {
+        log.Printf("Unable to read scenarios from %v: %v", path, err)
+        return nil
+    }
+
+    if !info.IsDir() {
+        return []string{path}
+    }
+
+    entries, err := os.ReadDir(path)
+    if err != nil {
+        log.Printf("Unable to read scenarios from %v: %v", path, err)
+        return nil
+    }
+
+    var out []string
+    for _, entry := range entries {
+        if entry.IsDir() {
+            continue
+        }
+
+        // the myrror half of a terrain file is loaded along with the arcanus half
+        if strings.HasSuffix(entry.Name(), ".json") || (maplib.IsTerrainFile(entry.Name()) && !maplib.IsMyrrorTerrainPath(entry.Name())) {
+            out = append(out, filepath.Join(path, entry.Name()))
+        }
+    }
+
+    return out
 }
 
 func randomChoose[T any](choices... T) T {
@@ -101,6 +149,7 @@ func runIntro(yield coroutine.YieldFunc, game *MagicGame) {
 
 func runNewGame(yield coroutine.YieldFunc, game *MagicGame) (bool, setup.NewGameSettings) {
     newGame := setup.MakeNewGameScreen(game.Cache)
+    newGame.Scenarios = game.Scenarios
 
     game.Drawer = func(screen *ebiten.Image) {
         newGame.Draw(screen)
@@ -260,19 +309,108 @@ func findCityLocation(game *gamelib.Game, startingPlane data.Plane, cityArea gam
     return -1, -1
 }
 
-func initializePlayer(game *gamelib.Game, wizard setup.WizardCustom, isHuman bool, arcanusCityArea gamelib.CityValidArea, myrrorCityArea gamelib.CityValidArea) *playerlib.Player {
-    area := arcanusCityArea
-    startingPlane := data.PlaneArcanus
+// the start locations that have not been given to a wizard yet
+type startLocations []maplib.ScenarioStart
+
+// take the first unused start location on the given plane
+func (starts *startLocations) take(plane data.Plane) (int, int, bool) {
+    for i, start := range *starts {
+        if start.Plane == plane {
+            *starts = slices.Delete(*starts, i, i + 1)
+            return start.X, start.Y, true
+        }
+    }
+
+    return 0, 0, false
+}
+
+func wizardStartingPlane(wizard setup.WizardCustom) data.Plane {
     if wizard.RetortEnabled(data.RetortMyrran) {
-        startingPlane = data.PlaneMyrror
+        return data.PlaneMyrror
+    }
+
+    return data.PlaneArcanus
+}
+
+// choose where the wizards start. the start locations of a scenario are used first, the rest are chosen
+// by the start placement solver so that no wizard starts much worse off than the others
+func chooseStarts(game *gamelib.Game, players []*playerlib.Player, arcanusCityArea gamelib.CityValidArea, myrrorCityArea gamelib.CityValidArea) startLocations {
+    var starts startLocations
+
+    for _, plane := range []data.Plane{data.PlaneArcanus, data.PlaneMyrror} {
+        area := arcanusCityArea
+        if plane == data.PlaneMyrror {
+            area = myrrorCityArea
+        }
+
+        wanted := 0
+        for _, player := range players {
+            if wizardStartingPlane(player.Wizard) == plane {
+                wanted += 1
+            }
+        }
+
+        if wanted == 0 {
+            continue
+        }
+
+        var fixed []image.Point
+        if game.Model.Scenario != nil {
+            for _, start := range game.Model.Scenario.StartsOn(plane) {
+                if len(fixed) < wanted {
+                    fixed = append(fixed, image.Pt(start.X, start.Y))
+                }
+            }
+        }
+
+        mapUse := game.GetMap(plane)
+        candidates := mapUse.StartCandidates(func(x int, y int) bool {
+            return area[image.Pt(x, y)]
+        })
+        placement := mapUse.SolveStarts(candidates, wanted - len(fixed), fixed)
+
+        chosen := slices.Clone(fixed)
+        for _, site := range placement.Sites {
+            chosen = append(chosen, site.Point())
+        }
+
+        // shuffle so the human isn't always given the same kind of site
+        rand.Shuffle(len(chosen), func(i, j int) {
+            chosen[i], chosen[j] = chosen[j], chosen[i]
+        })
+
+        for _, point := range chosen {
+            starts = append(starts, maplib.ScenarioStart{X: point.X, Y: point.Y, Plane: plane})
+        }
+
+        // score the fixed starts too so the report covers every wizard on the plane
+        report := mapUse.ScoreStarts(chosen)
+        game.Model.StartPlacements[plane] = report
+        for _, line := range report.Report() {
+            log.Printf("%v: %v", plane, line)
+        }
+    }
+
+    return starts
+}
+
+func initializePlayer(game *gamelib.Game, player *playerlib.Player, arcanusCityArea gamelib.CityValidArea, myrrorCityArea gamelib.CityValidArea, starts *startLocations) {
+    area := arcanusCityArea
+    startingPlane := wizardStartingPlane(player.Wizard)
+    if startingPlane == data.PlaneMyrror {
         area = myrrorCityArea
     }
 
-    player := game.AddPlayer(wizard, isHuman)
+    wizard := player.Wizard
+    isHuman := player.IsHuman()
 
     cityName := game.SuggestCityName(player.Wizard.Race)
 
-    cityX, cityY := findCityLocation(game, startingPlane, area)
+    // wizards beyond the number of chosen start locations get a random location
+    cityX, cityY, ok := starts.take(startingPlane)
+    if !ok {
+        cityX, cityY = findCityLocation(game, startingPlane, area)
+    }
     area[image.Pt(cityX, cityY)] = false
 
     game.GetMap(startingPlane).SetRoad(cityX, cityY, startingPlane == data.PlaneMyrror)
@@ -283,12 +421,12 @@ func initializePlayer(game *gamelib.Game, wizard setup.WizardCustom, isHuman boo
 
     for _, building := range []buildinglib.Building{buildinglib.BuildingSmithy, buildinglib.BuildingBarracks, buildinglib.BuildingBuildersHall} {
         if introCity.GetBuildableBuildings().Contains(building) {
-            introCity.Buildings.Insert(building)
+            introCity.AddBuilding(building)
         }
     }
 
-    introCity.Buildings.Insert(buildinglib.BuildingFortress)
-    introCity.Buildings.Insert(buildinglib.BuildingSummoningCircle)
+    introCity.AddBuilding(buildinglib.BuildingFortress)
+    introCity.AddBuilding(buildinglib.BuildingSummoningCircle)
     introCity.ProducingBuilding = buildinglib.BuildingHousing
     introCity.ProducingUnit = units.UnitNone
     introCity.Farmers = 4
@@ -319,8 +457,21 @@ func initializePlayer(game *gamelib.Game, wizard setup.WizardCustom, isHuman boo
         game.Camera.Center(cityX, cityY)
         game.Model.Plane = startingPlane
     }
+}
 
-    return player
+func makeNeutralCity(game *gamelib.Game, player *playerlib.Player, name string, x int, y int, plane data.Plane, race data.Race, population int) *citylib.City {
+    if name == "" {
+        name = game.SuggestCityName(race)
+    }
+
+    city := citylib.MakeCity(name, x, y, race, game.Model.BuildingInfo, game.GetMap(plane), game.Model, player)
+    city.Population = population
+    city.ProducingBuilding = buildinglib.BuildingHousing
+    city.Plane = plane
+    city.Farmers = city.Citizens()
+    city.ResetCitizens()
+
+    return city
 }
 
 func initializeNeutralPlayer(game *gamelib.Game, arcanusCityArea gamelib.CityValidArea, myrrorCityArea gamelib.CityValidArea) *playerlib.Player {
@@ -335,6 +486,21 @@ func initializeNeutralPlayer(game *gamelib.Game, arcanusCityArea gamelib.CityVal
     player.AIBehavior = ai.MakeRaiderAI()
     player.TaxRate = fraction.Zero()
 
+    // the towns of a scenario are placed by hand. a scenario imported from a terrain file has no towns,
+    // so they are placed at random like in a generated map
+    if game.Model.Scenario != nil && len(game.Model.Scenario.Cities) > 0 {
+        for _, town := range game.Model.Scenario.Cities {
+            population := town.Population
+            if population <= 0 {
+                population = rand.N(5) * 1000 + 2000
+            }
+
+            player.AddCity(makeNeutralCity(game, player, town.Name, town.X, town.Y, town.Plane, town.Race, population))
+        }
+
+        return player
+    }
+
     for _, plane := range []data.Plane{data.PlaneArcanus, data.PlaneMyrror} {
         randomRace := func() data.Race {
             switch plane {
@@ -355,13 +521,7 @@ func initializeNeutralPlayer(game *gamelib.Game, arcanusCityArea gamelib.CityVal
 
             // should every neutral town be a random race, or should they all be related?
             race := randomRace()
-            cityName := game.SuggestCityName(race)
-            city := citylib.MakeCity(cityName, cityX, cityY, race, game.Model.BuildingInfo, game.GetMap(plane), game.Model, player)
-            city.Population = rand.N(5) * 1000 + 2000
-            city.ProducingBuilding = buildinglib.BuildingHousing
-            city.Plane = plane
-            city.Farmers = city.Citizens()
-            city.ResetCitizens()
+            city := makeNeutralCity(game, player, "", cityX, cityY, plane, race, rand.N(5) * 1000 + 2000)
 
             area[image.Pt(cityX, cityY)] = false
 
@@ -482,6 +642,26 @@ func runGameInstance(game *gamelib.Game, yield coroutine.YieldFunc, magic *Magic
     }()
     game.GameLoader = gameLoader
 
+    // traces are always kept in memory in watch mode so the ai debugger has something to show
+    var aiTrace *gamelib.AITraceLog
+    if magic.AITracePath != "" {
+        out, err := os.Create(magic.AITracePath)
+        if err != nil {
+            log.Printf("Unable to create ai trace file %v: %v", magic.AITracePath, err)
+        } else {
+            log.Printf("Writing ai traces to %v", magic.AITracePath)
+            aiTrace = gamelib.MakeAITraceLog(out)
+        }
+    }
+    if aiTrace == nil && game.WatchMode {
+        aiTrace = gamelib.MakeAITraceLog(nil)
+    }
+    if aiTrace != nil {
+        defer aiTrace.Close()
+    }
+    game.AITrace = aiTrace
+    game.StatisticsPath = magic.StatisticsPath
+
     magic.Drawer = func(screen *ebiten.Image) {
         game.Draw(screen)
     }
@@ -505,6 +685,8 @@ func runGameInstance(game *gamelib.Game, yield coroutine.YieldFunc, magic *Magic
                 game.Shutdown()
                 game = newGame
                 game.GameLoader = gameLoader
+                game.AITrace = aiTrace
+                game.StatisticsPath = magic.StatisticsPath
                 game.Model.CurrentPlayer = 0
 
                 centerOnCity(game)
@@ -537,17 +719,24 @@ func initializeGame(magic *MagicGame, settings setup.NewGameSettings, humanWizar
     arcanusCityArea := game.MakeCityValidArea(data.PlaneArcanus)
     myrrorCityArea := game.MakeCityValidArea(data.PlaneMyrror)
 
-    human := initializePlayer(game, humanWizard, true, arcanusCityArea, myrrorCityArea)
+    // all the wizards are known before any city is placed so their start locations can be chosen together
+    human := game.AddPlayer(humanWizard, true)
+    players := []*playerlib.Player{human}
 
     for range settings.Opponents {
         wizard, ok := game.ChooseWizard()
         if ok {
-            initializePlayer(game, wizard, false, arcanusCityArea, myrrorCityArea)
+            players = append(players, game.AddPlayer(wizard, false))
         } else {
             log.Printf("Warning: unable to add another wizard to the game")
         }
     }
 
+    starts := chooseStarts(game, players, arcanusCityArea, myrrorCityArea)
+    for _, player := range players {
+        initializePlayer(game, player, arcanusCityArea, myrrorCityArea, &starts)
+    }
+
     log.Printf("Create neutral player")
     neutral := initializeNeutralPlayer(game, arcanusCityArea, myrrorCityArea)
     log.Printf("done create neutral player with %v cities", len(neutral.Cities))
@@ -608,6 +797,8 @@ func startWatchMode(yield coroutine.YieldFunc, game *MagicGame) error {
         LandSize: rand.N(3),
     }
 
+    settings.Scenario = game.StartScenario
+
     spells, err := spellbook.ReadSpellsFromCache(game.Cache)
     if err != nil {
         return err
@@ -670,6 +861,8 @@ func startQuickGame(yield coroutine.YieldFunc, game *MagicGame, gameLoader *Orig
         LandSize: rand.N(3),
     }
 
+    settings.Scenario = game.StartScenario
+
     spells, err := spellbook.ReadSpellsFromCache(game.Cache)
     if err != nil {
         return err
@@ -868,12 +1061,20 @@ func main() {
     var enableMusic bool
     var loadSave string
     var watchMode bool
+    var aiTracePath string
+    var statisticsPath string
+    var scenarioPath string
+    var startScenario string
     flag.StringVar(&dataPath, "data", "", "path to master of magic lbx data files. Give either a directory or a zip file. Data is searched for in the current directory if not given.")
     flag.BoolVar(&enableMusic, "music", true, "enable music playback")
     flag.BoolVar(&startGame, "start", false, "start the game immediately with a random wizard")
     flag.BoolVar(&trace, "trace", false, "enable profiling (pprof)")
     flag.StringVar(&loadSave, "load", "", "load a saved game from the given file and start immediately")
     flag.BoolVar(&watchMode, "watch", false, "run in watch mode, where you can watch the AI play against itself (no human players)")
+    flag.StringVar(&statisticsPath, "statistics", "", "write the statistics of every wizard to the given csv file at the end of each turn")
+    flag.StringVar(&aiTracePath, "ai-trace", "", "write a json trace of every ai turn to the given file. In watch mode press D to open the ai debugger")
+    flag.StringVar(&scenarioPath, "scenario", "", "a scenario file made with the map editor, a terrain png or text file, or a directory of them, that can be chosen instead of a random map on the new game screen")
+    flag.StringVar(&startScenario, "start-scenario", "", "play quick games (-start) and watch mode (-watch) on this scenario or terrain file instead of a random map")
     flag.Parse()
 
     if trace {
@@ -904,6 +1105,13 @@ func main() {
         return
     }
 
+    game.AITracePath = aiTracePath
+    game.StatisticsPath = statisticsPath
+    if scenarioPath != "" {
+        game.Scenarios = findScenarios(scenarioPath)
+    }
+    game.StartScenario = startScenario
+
     err = ebiten.RunGame(game)
     if err != nil {
         log.Printf("Error: %v", err)
@@ -827,6 +827,9 @@ type Map struct {
 
     Data *terrain.TerrainData
 
+    // changes to the terrain that happen on a later turn
+    Transforms []TerrainTransform
+
     TileCache map[int]*ebiten.Image
 
     miniMapPixels []byte
@@ -838,15 +841,42 @@ func getLandSize(size int) (int, int) {
         case 0: return 50, 50
         case 1: return 100, 100
         case 2: return 200, 150
+        case 3: return terrain.OriginalColumns, terrain.OriginalRows
     }
 
     return 100, 100
 }
 
+// the world generator parameters for one of the land size settings
+func LandSizeParameters(landSize int) terrain.GeneratorParameters {
+    width, height := getLandSize(landSize)
+    return terrain.DefaultGeneratorParameters(width, height)
+}
+
 func MakeMap(terrainData *terrain.TerrainData, landSize int, magicSetting data.MagicSetting, difficulty data.DifficultySetting, plane data.Plane, cityProvider CityProvider, planeTowers []image.Point) *Map {
-    landWidth, landHeight := getLandSize(landSize)
+    return MakeMapWithParameters(terrainData, LandSizeParameters(landSize), magicSetting, difficulty, plane, cityProvider, planeTowers)
+}
+
+func MakeMapWithParameters(terrainData *terrain.TerrainData, parameters terrain.GeneratorParameters, magicSetting data.MagicSetting, difficulty data.DifficultySetting, plane data.Plane, cityProvider CityProvider, planeTowers []image.Point) *Map {
+    generator := terrain.MakeGenerator(parameters, terrainData, plane)
+    map_ := generator.Generate()
 
-    map_ := terrain.GenerateLandCellularAutomata(landWidth, landHeight, terrainData, plane)
+    return makeMapFromTerrain(terrainData, map_, generator.Lairs, magicSetting, difficulty, plane, cityProvider, planeTowers)
+}
+
+// make a map from terrain that was drawn by hand, such as an imported image. magic nodes are added if the
+// terrain doesn't have any, and lairs and minerals are placed the same way as on a generated map
+func MakeMapFromTerrain(terrainData *terrain.TerrainData, map_ *terrain.Map, magicSetting data.MagicSetting, difficulty data.DifficultySetting, plane data.Plane, cityProvider CityProvider, planeTowers []image.Point) *Map {
+    generator := terrain.MakeGenerator(terrain.DefaultGeneratorParameters(map_.Columns(), map_.Rows()), terrainData, plane)
+    generator.Stages = terrain.PopulateStages()
+    generator.Run(map_)
+
+    return makeMapFromTerrain(terrainData, map_, generator.Lairs, magicSetting, difficulty, plane, cityProvider, planeTowers)
+}
+
+// add plane towers, nodes, lairs and minerals to generated terrain
+func makeMapFromTerrain(terrainData *terrain.TerrainData, map_ *terrain.Map, lairs []image.Point, magicSetting data.MagicSetting, difficulty data.DifficultySetting, plane data.Plane, cityProvider CityProvider, planeTowers []image.Point) *Map {
+    landWidth, landHeight := map_.Columns(), map_.Rows()
 
     extraMap := make(map[image.Point]map[ExtraKind]ExtraTile)
 
@@ -1009,28 +1039,18 @@ func MakeMap(terrainData *terrain.TerrainData, landSize int, magicSetting data.M
         return false
     }
 
+    // place some encounter nodes down (lair, cave, etc) at the sites the generator spread out.
+    // sites that ended up next to a plane tower are skipped
+    for _, point := range lairs {
+        if canPlaceEncounter(point.X, point.Y) {
+            extraMap[point][ExtraKindEncounter] = makeEncounter(randomEncounterType(), difficulty, rand.N(2) == 0, plane)
+        }
+    }
+
     continents := map_.FindContinents()
 
-    // place some encounter nodes down (lair, cave, etc)
     for i := range len(continents) {
-
-        // try to place N encounters. if we can't place them all, then we just place as many as we can
-        maxEncounters := continents[i].Size() / 10
         points := continents[i].Values()
-        for _, index := range rand.Perm(len(points)) {
-
-            if maxEncounters == 0 {
-                break
-            }
-
-            x, y := points[index].X, points[index].Y
-
-            if canPlaceEncounter(x, y) {
-                // log.Printf("Place encounter at %v, %v", x, y)
-                extraMap[image.Pt(x, y)][ExtraKindEncounter] = makeEncounter(randomEncounterType(), difficulty, rand.N(2) == 0, plane)
-                maxEncounters -= 1
-            }
-        }
 
         var candidates []image.Point
         for _, point := range points {
@@ -1082,7 +1102,11 @@ func MakeMap(terrainData *terrain.TerrainData, landSize int, magicSetting data.M
 
 func GeneratePlaneTowerPositions(landSize int, count int) []image.Point {
     width, height := getLandSize(landSize)
+    return PlaneTowerPositions(width, height, count)
+}
 
+// positions for plane towers on a map of the given size
+func PlaneTowerPositions(width int, height int, count int) []image.Point {
     var out []image.Point
 
     for range count {
@@ -1304,6 +1328,12 @@ func (mapObject *Map) ContainsRoad(x int, y int) bool {
     return ok
 }
 
+// true if the road on this tile was enchanted, either by Enchant Road or by being built on Myrror
+func (mapObject *Map) ContainsEnchantedRoad(x int, y int) bool {
+    road := getExtra[*ExtraRoad](mapObject.ExtraMap[image.Pt(x, y)], ExtraKindRoad)
+    return road != nil && road.Enchanted
+}
+
 func (mapObject *Map) RemoveRoad(x int, y int) {
     delete(mapObject.ExtraMap[image.Pt(x, y)], ExtraKindRoad)
 }
@@ -1885,6 +1915,25 @@ func (mapObject *Map) DrawMinimap2(screen *ebiten.Image, cities []MiniMapCity, c
 */
 
 func (mapObject *Map) DrawMinimap(screen *ebiten.Image, cities []MiniMapCity, centerX int, centerY int, zoom float64, fog data.FogMap, counter uint64, crosshairs bool){
+    mapObject.DrawMinimapView(screen, MinimapView{
+        Cities: cities,
+        CenterX: centerX,
+        CenterY: centerY,
+        Zoom: zoom,
+        Fog: fog,
+        Counter: counter,
+        Crosshairs: crosshairs,
+    })
+}
+
+func (mapObject *Map) DrawMinimapView(screen *ebiten.Image, view MinimapView){
+    cities := view.Cities
+    centerX := view.CenterX
+    centerY := view.CenterY
+    zoom := view.Zoom
+    fog := view.Fog
+    counter := view.Counter
+
     if len(mapObject.miniMapPixels) != screen.Bounds().Dx() * screen.Bounds().Dy() * 4 {
         // log.Printf("set minimap pixels to %v", screen.Bounds().Dx() * screen.Bounds().Dy() * 4)
         mapObject.miniMapPixels = make([]byte, screen.Bounds().Dx() * screen.Bounds().Dy() * 4)
@@ -2021,17 +2070,35 @@ func (mapObject *Map) DrawMinimap(screen *ebiten.Image, cities []MiniMapCity, ce
         return use
     })
 
+    fit := mapObject.makeMinimapFit(screen.Bounds().Dx(), screen.Bounds().Dy(), centerX)
+
     for x := range screen.Bounds().Dx() {
         for y := range screen.Bounds().Dy() {
             tileX := mapObject.WrapX(scale.Unscale(x + cameraX))
             tileY := scale.Unscale(y + cameraY)
 
+            if view.Fit {
+                tileX, tileY = fit.tile(x, y)
+                tileX = mapObject.WrapX(tileX)
+            }
+
             if tileX < 0 || tileX >= mapObject.Map.Columns() || tileY < 0 || tileY >= mapObject.Map.Rows() || fog[tileX][tileY] == data.FogTypeUnexplored {
                 set(x, y, black)
                 continue
             }
 
             use := getMapColor(terrain.GetTile(mapObject.Map.Terrain[tileX][tileY]).TerrainType(), fog[tileX][tileY])
+            if view.Layer != MinimapLayerTerrain {
+                use = mapObject.minimapLayerColor(view.Layer, use, tileX, tileY, fog, view.Territory)
+            }
+
+            if view.Borders && view.Territory != nil {
+                sides := view.Territory.BorderSides(tileX, tileY)
+                if sides[0] || sides[1] || sides[2] || sides[3] {
+                    banner, _ := view.Territory.Owner(tileX, tileY)
+                    use = bannerColor(banner)
+                }
+            }
 
             if cityColor, ok := cityLocations[image.Pt(tileX, tileY)]; ok {
                 use = getCityColor(cityColor, fog[tileX][tileY])
@@ -2041,7 +2108,38 @@ func (mapObject *Map) DrawMinimap(screen *ebiten.Image, cities []MiniMapCity, ce
         }
     }
 
-    if crosshairs {
+    if view.Fit {
+        width := screen.Bounds().Dx()
+        height := screen.Bounds().Dy()
+
+        setInside := func(x int, y int, c color.RGBA){
+            if x >= 0 && y >= 0 && x < width && y < height {
+                set(x, y, c)
+            }
+        }
+
+        // a pixel can cover several tiles, so draw cities and sites on top to make sure they show up
+        for point, markerColor := range mapObject.minimapMarkers(view, getCityColor) {
+            x, y := fit.pixel(point.X, point.Y)
+            setInside(x, y, markerColor)
+        }
+
+        // the part of the map shown in the overworld
+        if view.ViewWidth > 0 && view.ViewHeight > 0 {
+            viewport := fit.viewport(view)
+            viewColor := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+            for x := viewport.Min.X; x <= viewport.Max.X; x++ {
+                setInside((x + width) % width, viewport.Min.Y, viewColor)
+                setInside((x + width) % width, viewport.Max.Y, viewColor)
+            }
+            for y := viewport.Min.Y; y <= viewport.Max.Y; y++ {
+                setInside((viewport.Min.X + width) % width, y, viewColor)
+                setInside((viewport.Max.X + width) % width, y, viewColor)
+            }
+        }
+    }
+
+    if view.Crosshairs && !view.Fit {
         cursorColorBlue := math.Sin(float64(counter) * 3 * math.Pi / 180) * 127.0 + 127.0
         if cursorColorBlue > 255 {
             cursorColorBlue = 255
@@ -3,6 +3,7 @@ package maplib
 import (
     "testing"
     "image"
+    "bytes"
 
     "github.com/kazzmir/master-of-magic/game/magic/terrain"
     "github.com/kazzmir/master-of-magic/game/magic/data"
@@ -276,3 +277,63 @@ func TestWaterBodies(test *testing.T) {
     }
 
 }
+
+func TestScenarioRoundTrip(test *testing.T) {
+    makeMap := func(plane data.Plane) *Map {
+        return &Map{
+            Map: terrain.MakeMap(4, 6),
+            Plane: plane,
+            ExtraMap: make(map[image.Point]map[ExtraKind]ExtraTile),
+        }
+    }
+
+    arcanus := makeMap(data.PlaneArcanus)
+    myrror := makeMap(data.PlaneMyrror)
+
+    arcanus.Map.Terrain[2][1] = 7
+    arcanus.ExtraMap[image.Pt(2, 1)] = map[ExtraKind]ExtraTile{
+        ExtraKindBonus: &ExtraBonus{Bonus: data.BonusGem},
+    }
+    myrror.SetRoad(3, 2, true)
+
+    cities := []ScenarioCity{{Name: "Keep", X: 1, Y: 1, Plane: data.PlaneMyrror, Race: data.RaceDwarf, Population: 5000}}
+    starts := []ScenarioStart{{X: 4, Y: 3, Plane: data.PlaneArcanus}, {X: 0, Y: 2, Plane: data.PlaneMyrror}}
+
+    var buffer bytes.Buffer
+    err := WriteScenario(&buffer, MakeScenario(arcanus, myrror, cities, starts))
+    if err != nil {
+        test.Fatalf("Unable to write scenario: %v", err)
+    }
+
+    scenario, err := ReadScenario(&buffer)
+    if err != nil {
+        test.Fatalf("Unable to read scenario: %v", err)
+    }
+
+    if len(scenario.Cities) != 1 || scenario.Cities[0] != cities[0] {
+        test.Errorf("Cities were not preserved: %+v", scenario.Cities)
+    }
+
+    if len(scenario.StartsOn(data.PlaneMyrror)) != 1 || scenario.StartsOn(data.PlaneMyrror)[0] != starts[1] {
+        test.Errorf("Myrror start was not preserved: %+v", scenario.Starts)
+    }
+
+    loaded := scenario.ReconstructMap(data.PlaneArcanus, nil, nil, nil)
+    if loaded.Plane != data.PlaneArcanus || loaded.Width() != 6 || loaded.Height() != 4 {
+        test.Errorf("Wrong map: plane %v size %vx%v", loaded.Plane, loaded.Width(), loaded.Height())
+    }
+
+    if loaded.Map.Terrain[2][1] != 7 || loaded.GetBonusTile(2, 1) != data.BonusGem {
+        test.Errorf("Terrain or bonus was not preserved")
+    }
+
+    // every tile has an extras entry so the game can add to it
+    if loaded.ExtraMap[image.Pt(5, 3)] == nil {
+        test.Errorf("Missing extras for an empty tile")
+    }
+
+    loadedMyrror := scenario.ReconstructMap(data.PlaneMyrror, nil, nil, nil)
+    if loadedMyrror.Plane != data.PlaneMyrror || !loadedMyrror.ContainsRoad(3, 2) {
+        test.Errorf("Road was not preserved on myrror")
+    }
+}
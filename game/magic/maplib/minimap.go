@@ -0,0 +1,188 @@
+package maplib
+
+import (
+    "math"
+    "image"
+    "image/color"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+// what the minimap shows
+type MinimapLayer int
+
+const (
+    MinimapLayerTerrain MinimapLayer = iota
+    // the territory of each wizard in the color of its banner
+    MinimapLayerPolitical
+    // which tiles are visible, explored or unexplored
+    MinimapLayerExplored
+    // magic nodes and encounters
+    MinimapLayerSites
+)
+
+func (layer MinimapLayer) String() string {
+    switch layer {
+        case MinimapLayerTerrain: return "Terrain"
+        case MinimapLayerPolitical: return "Political"
+        case MinimapLayerExplored: return "Explored"
+        case MinimapLayerSites: return "Nodes and Lairs"
+    }
+
+    return "?"
+}
+
+func (layer MinimapLayer) Next() MinimapLayer {
+    return (layer + 1) % (MinimapLayerSites + 1)
+}
+
+type MinimapView struct {
+    Cities []MiniMapCity
+    // the tile at the center of the overworld
+    CenterX int
+    CenterY int
+    Zoom float64
+    Fog data.FogMap
+    Counter uint64
+    Crosshairs bool
+    Layer MinimapLayer
+
+    // used by the political layer and to draw borders
+    Territory *Territory
+    // draw the borders of the territory in the color of the banner
+    Borders bool
+
+    // if true the whole map is scaled to fit the minimap, otherwise one tile is one pixel around the center
+    Fit bool
+    // the number of tiles visible in the overworld, drawn as a rectangle when Fit is true
+    ViewWidth int
+    ViewHeight int
+}
+
+// how a minimap that shows the whole map maps pixels to tiles. the map is centered horizontally on centerX
+// since it wraps around, and centered vertically with black bars if the minimap is taller than the map
+type minimapFit struct {
+    OriginX float64
+    OriginY float64
+    TilesPerPixel float64
+    Columns int
+}
+
+func (mapObject *Map) makeMinimapFit(width int, height int, centerX int) minimapFit {
+    tilesPerPixel := math.Max(float64(mapObject.Width()) / float64(max(1, width)), float64(mapObject.Height()) / float64(max(1, height)))
+
+    return minimapFit{
+        OriginX: float64(centerX) + 0.5 - float64(width) * tilesPerPixel / 2,
+        OriginY: (float64(mapObject.Height()) - float64(height) * tilesPerPixel) / 2,
+        TilesPerPixel: tilesPerPixel,
+        Columns: mapObject.Width(),
+    }
+}
+
+// the tile shown at the given pixel, the x coordinate is not wrapped
+func (fit minimapFit) tile(x int, y int) (int, int) {
+    tileX := int(math.Floor(fit.OriginX + (float64(x) + 0.5) * fit.TilesPerPixel))
+    tileY := int(math.Floor(fit.OriginY + (float64(y) + 0.5) * fit.TilesPerPixel))
+    return tileX, tileY
+}
+
+// the pixel that shows the middle of the given tile
+func (fit minimapFit) pixel(tileX int, tileY int) (int, int) {
+    dx := math.Mod(float64(tileX) + 0.5 - fit.OriginX, float64(fit.Columns))
+    if dx < 0 {
+        dx += float64(fit.Columns)
+    }
+
+    return int(dx / fit.TilesPerPixel), int((float64(tileY) + 0.5 - fit.OriginY) / fit.TilesPerPixel)
+}
+
+// the tile under the pixel x, y of a minimap of the given size that shows the whole map. used to jump to a clicked tile
+func (mapObject *Map) FitMinimapTile(width int, height int, centerX int, x int, y int) (int, int) {
+    tileX, tileY := mapObject.makeMinimapFit(width, height, centerX).tile(x, y)
+    return mapObject.WrapX(tileX), max(0, min(mapObject.Height() - 1, tileY))
+}
+
+func mixColor(a color.RGBA, b color.RGBA, amount float64) color.RGBA {
+    mix := func(x uint8, y uint8) uint8 {
+        return uint8(float64(x) * (1 - amount) + float64(y) * amount)
+    }
+
+    return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 255}
+}
+
+func magicNodeColor(kind MagicNode) color.RGBA {
+    switch kind {
+        case MagicNodeNature: return color.RGBA{R: 0, G: 255, B: 0, A: 255}
+        case MagicNodeSorcery: return color.RGBA{R: 0, G: 128, B: 255, A: 255}
+        case MagicNodeChaos: return color.RGBA{R: 255, G: 64, B: 0, A: 255}
+    }
+
+    return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+}
+
+// the color of an explored tile on the given layer. terrainColor is the color the terrain layer uses
+func (mapObject *Map) minimapLayerColor(layer MinimapLayer, terrainColor color.RGBA, tileX int, tileY int, fog data.FogMap, territory *Territory) color.RGBA {
+    switch layer {
+        case MinimapLayerPolitical:
+            if banner, ok := territory.Owner(tileX, tileY); ok {
+                return mixColor(terrainColor, bannerColor(banner), 0.6)
+            }
+            return mixColor(terrainColor, color.RGBA{R: 40, G: 40, B: 40, A: 255}, 0.5)
+        case MinimapLayerExplored:
+            use := color.RGBA{R: 0xd0, G: 0xd0, B: 0xd0, A: 255}
+            if terrain.GetTile(mapObject.Map.Terrain[tileX][tileY]).IsWater() {
+                use = color.RGBA{R: 0x70, G: 0x70, B: 0xb0, A: 255}
+            }
+            if fog[tileX][tileY] != data.FogTypeVisible {
+                use = mixColor(use, color.RGBA{A: 255}, 0.5)
+            }
+            return use
+        case MinimapLayerSites:
+            if node := mapObject.GetMagicNode(tileX, tileY); node != nil {
+                return magicNodeColor(node.Kind)
+            }
+            if mapObject.GetEncounter(tileX, tileY) != nil {
+                return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+            }
+            return mixColor(terrainColor, color.RGBA{A: 255}, 0.6)
+    }
+
+    return terrainColor
+}
+
+// the tiles that must stay visible when the minimap is scaled down so far that a pixel covers several tiles
+func (mapObject *Map) minimapMarkers(view MinimapView, cityColor func(color.RGBA, data.FogType) color.RGBA) map[image.Point]color.RGBA {
+    out := make(map[image.Point]color.RGBA)
+
+    if view.Layer == MinimapLayerSites {
+        for _, point := range mapObject.GetEncounterLocations() {
+            if view.Fog[point.X][point.Y] != data.FogTypeUnexplored {
+                out[point] = mapObject.minimapLayerColor(view.Layer, color.RGBA{}, point.X, point.Y, view.Fog, nil)
+            }
+        }
+
+        for _, point := range mapObject.GetMagicNodeLocations() {
+            if view.Fog[point.X][point.Y] != data.FogTypeUnexplored {
+                out[point] = mapObject.minimapLayerColor(view.Layer, color.RGBA{}, point.X, point.Y, view.Fog, nil)
+            }
+        }
+    }
+
+    for _, city := range view.Cities {
+        fog := view.Fog[city.GetX()][city.GetY()]
+        if fog != data.FogTypeUnexplored {
+            out[image.Pt(city.GetX(), city.GetY())] = cityColor(bannerColor(city.GetBanner()), fog)
+        }
+    }
+
+    return out
+}
+
+// the rectangle of the overworld view on a minimap that shows the whole map
+func (fit minimapFit) viewport(view MinimapView) image.Rectangle {
+    x1, y1 := fit.pixel(view.CenterX, view.CenterY)
+    halfWidth := int(float64(view.ViewWidth) / 2 / fit.TilesPerPixel)
+    halfHeight := int(float64(view.ViewHeight) / 2 / fit.TilesPerPixel)
+    return image.Rect(x1 - halfWidth, y1 - halfHeight, x1 + halfWidth, y1 + halfHeight)
+}
@@ -0,0 +1,57 @@
+package maplib
+
+import (
+    "testing"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+)
+
+func TestFitMinimapTile(test *testing.T) {
+    xmap := makeStartMap(60, 40, terrain.TileGrasslands1)
+
+    // the middle of the minimap is the center of the camera
+    x, y := xmap.FitMinimapTile(60, 31, 30, 30, 15)
+    if x < 29 || x > 31 || y < 19 || y > 21 {
+        test.Errorf("Expected the middle of the map but got %v,%v", x, y)
+    }
+
+    // the whole height of the map is visible
+    _, top := xmap.FitMinimapTile(60, 31, 30, 30, 0)
+    _, bottom := xmap.FitMinimapTile(60, 31, 30, 30, 30)
+    if top != 0 || bottom != 39 {
+        test.Errorf("Expected rows 0 to 39 but got %v to %v", top, bottom)
+    }
+
+    // the map wraps around on the left
+    left, _ := xmap.FitMinimapTile(60, 31, 30, 0, 15)
+    if left < 45 || left >= 60 {
+        test.Errorf("Expected the left edge to wrap around but got %v", left)
+    }
+}
+
+func TestMinimapFitPixel(test *testing.T) {
+    // a map smaller than the minimap, so every tile covers at least one pixel
+    xmap := makeStartMap(40, 20, terrain.TileGrasslands1)
+    fit := xmap.makeMinimapFit(60, 31, 20)
+
+    for _, tileX := range []int{0, 10, 20, 39} {
+        x, y := fit.pixel(tileX, 10)
+        backX, backY := fit.tile(x, y)
+        if xmap.WrapX(backX) != tileX || backY != 10 {
+            test.Errorf("Tile %v,10 is drawn at %v,%v which maps back to %v,%v", tileX, x, y, xmap.WrapX(backX), backY)
+        }
+    }
+}
+
+func TestMinimapLayerNext(test *testing.T) {
+    layer := MinimapLayerTerrain
+    seen := make(map[MinimapLayer]bool)
+    for range 4 {
+        seen[layer] = true
+        layer = layer.Next()
+    }
+
+    if layer != MinimapLayerTerrain || len(seen) != 4 {
+        test.Errorf("Expected to cycle through all 4 layers")
+    }
+}
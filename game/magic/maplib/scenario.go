@@ -0,0 +1,120 @@
+package maplib
+
+import (
+    "io"
+    "os"
+    "image"
+    "encoding/json"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* a scenario is a hand made map that a new game can start from instead of a randomly generated one.
+ * it contains both planes, including their extras (nodes, lairs, towers, bonuses, roads, volcanoes),
+ * the neutral towns and the locations the wizards start at.
+ * scenarios are written by util/mapeditor.
+ */
+
+type ScenarioCity struct {
+    // if empty a name is chosen when the game starts
+    Name string `json:"name"`
+    X int `json:"x"`
+    Y int `json:"y"`
+    Plane data.Plane `json:"plane"`
+    Race data.Race `json:"race"`
+    Population int `json:"population"`
+}
+
+type ScenarioStart struct {
+    X int `json:"x"`
+    Y int `json:"y"`
+    Plane data.Plane `json:"plane"`
+}
+
+type Scenario struct {
+    Arcanus SerializedMap `json:"arcanus"`
+    Myrror SerializedMap `json:"myrror"`
+    Cities []ScenarioCity `json:"cities"`
+    Starts []ScenarioStart `json:"starts"`
+}
+
+func MakeScenario(arcanus *Map, myrror *Map, cities []ScenarioCity, starts []ScenarioStart) Scenario {
+    return Scenario{
+        Arcanus: SerializeMap(arcanus),
+        Myrror: SerializeMap(myrror),
+        Cities: cities,
+        Starts: starts,
+    }
+}
+
+func WriteScenario(writer io.Writer, scenario Scenario) error {
+    encoder := json.NewEncoder(writer)
+    return encoder.Encode(scenario)
+}
+
+func ReadScenario(reader io.Reader) (Scenario, error) {
+    var scenario Scenario
+    err := json.NewDecoder(reader).Decode(&scenario)
+    return scenario, err
+}
+
+func LoadScenarioFile(path string) (Scenario, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return Scenario{}, err
+    }
+    defer file.Close()
+
+    return ReadScenario(file)
+}
+
+func SaveScenarioFile(path string, scenario Scenario) error {
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+
+    err = WriteScenario(file, scenario)
+    if err != nil {
+        file.Close()
+        return err
+    }
+
+    return file.Close()
+}
+
+// the start locations on the given plane
+func (scenario *Scenario) StartsOn(plane data.Plane) []ScenarioStart {
+    var out []ScenarioStart
+    for _, start := range scenario.Starts {
+        if start.Plane == plane {
+            out = append(out, start)
+        }
+    }
+
+    return out
+}
+
+// build the map for one plane. wizards can be nil since a fresh scenario has no melded nodes or explored lairs
+func (scenario *Scenario) ReconstructMap(plane data.Plane, terrainData *terrain.TerrainData, cityProvider CityProvider, wizards []Wizard) *Map {
+    serialized := scenario.Arcanus
+    if plane == data.PlaneMyrror {
+        serialized = scenario.Myrror
+    }
+
+    out := ReconstructMap(serialized, terrainData, cityProvider, wizards)
+    out.Plane = plane
+
+    // ReconstructMap only creates extras for tiles that have some, but the game expects every tile to have an entry
+    for x := range out.Width() {
+        for y := range out.Height() {
+            point := image.Pt(x, y)
+            if _, ok := out.ExtraMap[point]; !ok {
+                out.ExtraMap[point] = make(map[ExtraKind]ExtraTile)
+            }
+        }
+    }
+
+    return out
+}
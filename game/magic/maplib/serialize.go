@@ -24,6 +24,7 @@ type SerializedMap struct {
     Height int `json:"height"`
     Map [][]int `json:"map"`
     Extra []ExtraMapData `json:"extra"`
+    Transforms []SerializedTransform `json:"transforms,omitempty"`
 }
 
 func SerializeMap(useMap *Map) SerializedMap {
@@ -50,6 +51,7 @@ func SerializeMap(useMap *Map) SerializedMap {
         Height: useMap.Height(),
         Map: useMap.Map.Terrain,
         Extra: extraData,
+        Transforms: serializeTransforms(useMap.Transforms),
     }
 }
 
@@ -289,6 +291,7 @@ func ReconstructMap(mapData SerializedMap, terrainData *terrain.TerrainData, cit
         Data: terrainData,
         CityProvider: cityProvider,
         ExtraMap: extras,
+        Transforms: reconstructTransforms(mapData.Transforms, wizards),
     }
 
     for _, extra := range mapData.Extra {
@@ -0,0 +1,54 @@
+package maplib
+
+import (
+    "github.com/kazzmir/master-of-magic/lib/fraction"
+)
+
+// what a city built on a tile would get out of its catchment area
+type CitySite struct {
+    X int
+    Y int
+    // the population the food in the catchment area can support
+    MaxPopulation int
+    // percent bonuses, 3 = 3%
+    ProductionBonus int
+    GoldBonus int
+    // catchment tiles that are also in the catchment area of an existing city
+    Shared int
+}
+
+/* a single number for comparing sites. food matters the most since it limits how big the city can grow,
+ * and tiles that have to be shared with another city are worth about half a point of population each
+ */
+func (site CitySite) Score() int {
+    return site.MaxPopulation * 10 + site.ProductionBonus + site.GoldBonus - site.Shared * 5
+}
+
+func (mapObject *Map) ScoreCitySite(x int, y int) CitySite {
+    x = mapObject.WrapX(x)
+
+    site := CitySite{
+        X: x,
+        Y: y,
+    }
+
+    food := fraction.Zero()
+
+    for _, tile := range mapObject.GetCatchmentArea(x, y) {
+        food = food.Add(tile.FoodBonus())
+        food = food.Add(fraction.FromInt(tile.GetBonus().FoodBonus()))
+        site.ProductionBonus += tile.ProductionBonus(false)
+        if tile.IsShared {
+            site.Shared += 1
+        }
+    }
+
+    site.MaxPopulation = min(25, int(food.ToFloat()))
+
+    center := mapObject.GetTile(x, y)
+    if center.Valid() {
+        site.GoldBonus = center.GoldBonus(mapObject)
+    }
+
+    return site
+}
@@ -0,0 +1,46 @@
+package maplib
+
+import (
+    "testing"
+    "image"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+)
+
+func TestScoreCitySite(test *testing.T) {
+    cities := TestCityProvider{Cities: make(map[image.Point]bool)}
+
+    xmap := makeStartMap(40, 30, terrain.TileGrasslands1)
+    xmap.CityProvider = &cities
+
+    site := xmap.ScoreCitySite(10, 15)
+    // 21 grassland tiles is more food than the largest city can use
+    if site.MaxPopulation != 25 {
+        test.Errorf("expected maximum population of 25 but got %v", site.MaxPopulation)
+    }
+
+    if site.Shared != 0 || site.ProductionBonus != 0 || site.GoldBonus != 0 {
+        test.Errorf("expected no bonuses or shared tiles but got %+v", site)
+    }
+
+    cities.Cities[image.Pt(13, 15)] = true
+
+    shared := xmap.ScoreCitySite(10, 15)
+    if shared.Shared == 0 {
+        test.Errorf("expected some tiles to be shared with the city at 13, 15")
+    }
+
+    if shared.Score() >= site.Score() {
+        test.Errorf("sharing tiles should lower the score: %v >= %v", shared.Score(), site.Score())
+    }
+
+    // the edge of the map has less food
+    edge := xmap.ScoreCitySite(10, 2)
+    if edge.MaxPopulation >= site.MaxPopulation {
+        test.Errorf("a site next to the ocean should support fewer people: %v", edge.MaxPopulation)
+    }
+
+    if xmap.ScoreCitySite(50, 15).X != 10 {
+        test.Errorf("x should wrap around")
+    }
+}
@@ -0,0 +1,365 @@
+package maplib
+
+import (
+    "fmt"
+    "image"
+    "slices"
+    "cmp"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+)
+
+/* the start placement solver chooses where the fortress cities of the wizards go.
+ * every candidate site is scored on what its catchment area provides (food, production, gold)
+ * and on what is nearby (lairs and nodes). capitals that are too close to each other lose points.
+ * the solver then picks the set of sites whose worst score is as high as possible, so that no
+ * wizard starts much worse off than the others.
+ */
+
+// capitals closer than this many tiles are penalized
+const StartCapitalDistance = 12
+
+// lairs and nodes within this many tiles of a site count towards its score
+const StartNearbyDistance = 6
+
+// a lair this close to a site is a threat rather than an opportunity
+const StartDangerDistance = 2
+
+// the number of candidate sites the solver considers
+const StartCandidateLimit = 60
+
+// candidate sites are at least this far apart. a rich area of the map would otherwise fill the whole pool
+// with sites that are too close to each other to be used together
+const StartCandidateSpacing = StartCapitalDistance / 2
+
+// a capital is only placed on a continent with more tiles than this
+const StartMinimumContinent = 100
+
+type StartSite struct {
+    X int
+    Y int
+    // food produced by the catchment area
+    Food float64
+    // percent production bonus of the catchment area
+    Production int
+    // gold from minerals in the catchment area plus the trade bonus of the city tile
+    Gold float64
+    // lairs within StartNearbyDistance
+    Lairs int
+    // lairs within StartDangerDistance
+    DangerousLairs int
+    // nodes within StartNearbyDistance
+    Nodes int
+    // penalty for other capitals within StartCapitalDistance
+    Crowding float64
+    Score float64
+}
+
+// the score of the site without taking other capitals into account
+func (site *StartSite) baseScore() float64 {
+    return site.Food * 2 + float64(site.Production) * 0.5 + site.Gold * 1.5 +
+           float64(site.Lairs) + float64(site.Nodes) * 2 - float64(site.DangerousLairs) * 4
+}
+
+func (site *StartSite) Point() image.Point {
+    return image.Pt(site.X, site.Y)
+}
+
+func (site *StartSite) String() string {
+    return fmt.Sprintf("%v,%v score %.1f: food %.1f, production %v%%, gold %.1f, %v nodes, %v lairs (%v close), crowding %.1f",
+        site.X, site.Y, site.Score, site.Food, site.Production, site.Gold, site.Nodes, site.Lairs, site.DangerousLairs, site.Crowding)
+}
+
+type StartPlacement struct {
+    Sites []StartSite
+}
+
+func (placement *StartPlacement) Minimum() float64 {
+    if len(placement.Sites) == 0 {
+        return 0
+    }
+
+    return slices.MinFunc(placement.Sites, func(a StartSite, b StartSite) int {
+        return cmp.Compare(a.Score, b.Score)
+    }).Score
+}
+
+func (placement *StartPlacement) Maximum() float64 {
+    if len(placement.Sites) == 0 {
+        return 0
+    }
+
+    return slices.MaxFunc(placement.Sites, func(a StartSite, b StartSite) int {
+        return cmp.Compare(a.Score, b.Score)
+    }).Score
+}
+
+// the worst score divided by the best score, 1 means every wizard starts equally well off
+func (placement *StartPlacement) Fairness() float64 {
+    maximum := placement.Maximum()
+    if maximum <= 0 {
+        return 1
+    }
+
+    return max(0, placement.Minimum() / maximum)
+}
+
+// a human readable description of every site and the overall fairness
+func (placement *StartPlacement) Report() []string {
+    var out []string
+    for i := range placement.Sites {
+        out = append(out, fmt.Sprintf("Start %v at %v", i + 1, placement.Sites[i].String()))
+    }
+
+    out = append(out, fmt.Sprintf("Fairness %.0f%% (worst %.1f, best %.1f)", placement.Fairness() * 100, placement.Minimum(), placement.Maximum()))
+    return out
+}
+
+// the tiles that a capital could be placed on: plain land on a large continent away from the poles.
+// if allowed is not nil then only the tiles it allows are included
+func (mapObject *Map) StartCandidates(allowed func(x int, y int) bool) []image.Point {
+    var out []image.Point
+
+    for _, continent := range mapObject.Map.FindContinents() {
+        if continent.Size() <= StartMinimumContinent {
+            continue
+        }
+
+        for _, point := range continent.Values() {
+            tile := terrain.GetTile(mapObject.Map.Terrain[point.X][point.Y])
+            if point.Y > 3 && point.Y < mapObject.Height() - 3 && tile.IsLand() && !tile.IsMagic() && mapObject.GetEncounter(point.X, point.Y) == nil && (allowed == nil || allowed(point.X, point.Y)) {
+                out = append(out, point)
+            }
+        }
+    }
+
+    return out
+}
+
+// score a site on its own. the catchment area is computed directly because there are no cities yet
+func (mapObject *Map) scoreStartSite(x int, y int, lairs []image.Point, nodes []image.Point) StartSite {
+    site := StartSite{X: x, Y: y}
+
+    for dx := -2; dx <= 2; dx++ {
+        for dy := -2; dy <= 2; dy++ {
+            // ignore corners, same as GetCatchmentArea
+            if (dx == -2 || dx == 2) && (dy == -2 || dy == 2) {
+                continue
+            }
+
+            tile := mapObject.GetTile(x + dx, y + dy)
+            if !tile.Valid() {
+                continue
+            }
+
+            site.Food += tile.FoodBonus().ToFloat() + float64(tile.GetBonus().FoodBonus())
+            site.Production += tile.ProductionBonus(false)
+            site.Gold += float64(tile.GetBonus().GoldBonus())
+        }
+    }
+
+    center := mapObject.GetTile(x, y)
+    site.Gold += float64(center.GoldBonus(mapObject)) / 10
+
+    for _, lair := range lairs {
+        distance := mapObject.TileDistance(x, y, lair.X, lair.Y)
+        if distance <= StartDangerDistance {
+            site.DangerousLairs += 1
+        }
+        if distance <= StartNearbyDistance {
+            site.Lairs += 1
+        }
+    }
+
+    for _, node := range nodes {
+        if mapObject.TileDistance(x, y, node.X, node.Y) <= StartNearbyDistance {
+            site.Nodes += 1
+        }
+    }
+
+    site.Score = site.baseScore()
+    return site
+}
+
+// the penalty a site receives for the capitals around it
+func (mapObject *Map) startCrowding(site image.Point, others []image.Point) float64 {
+    crowding := 0.0
+    for _, other := range others {
+        if other == site {
+            continue
+        }
+
+        distance := mapObject.TileDistance(site.X, site.Y, other.X, other.Y)
+        if distance < StartCapitalDistance {
+            crowding += float64(StartCapitalDistance - distance) * 2
+        }
+    }
+
+    return crowding
+}
+
+// the lairs and nodes that count towards the score of a site. nodes are guarded by an encounter too
+func (mapObject *Map) startFeatures() ([]image.Point, []image.Point) {
+    var lairs []image.Point
+    for _, point := range mapObject.GetEncounterLocations() {
+        if !mapObject.HasMagicNode(point.X, point.Y) {
+            lairs = append(lairs, point)
+        }
+    }
+
+    return lairs, mapObject.GetMagicNodeLocations()
+}
+
+// score start locations that were chosen some other way, such as by hand in a scenario
+func (mapObject *Map) ScoreStarts(points []image.Point) StartPlacement {
+    lairs, nodes := mapObject.startFeatures()
+
+    var placement StartPlacement
+    for _, point := range points {
+        site := mapObject.scoreStartSite(point.X, point.Y, lairs, nodes)
+        site.Crowding = mapObject.startCrowding(point, points)
+        site.Score -= site.Crowding
+        placement.Sites = append(placement.Sites, site)
+    }
+
+    return placement
+}
+
+// keep the best sites, but skip sites near a better one so the candidates are spread out
+func (mapObject *Map) startPool(sites []StartSite) []StartSite {
+    slices.SortStableFunc(sites, func(a StartSite, b StartSite) int {
+        return cmp.Compare(b.Score, a.Score)
+    })
+
+    var pool []StartSite
+    for _, site := range sites {
+        if len(pool) >= StartCandidateLimit {
+            break
+        }
+
+        tooClose := slices.ContainsFunc(pool, func(other StartSite) bool {
+            return mapObject.TileDistance(site.X, site.Y, other.X, other.Y) < StartCandidateSpacing
+        })
+
+        if !tooClose {
+            pool = append(pool, site)
+        }
+    }
+
+    return pool
+}
+
+// choose count start sites from the candidates. fixed are capitals that are already placed, such as the
+// start locations of a scenario. they crowd the chosen sites but are not part of the result.
+// fewer than count sites are returned if there are not enough candidates
+func (mapObject *Map) SolveStarts(candidates []image.Point, count int, fixed []image.Point) StartPlacement {
+    lairs, nodes := mapObject.startFeatures()
+
+    var sites []StartSite
+    for _, candidate := range candidates {
+        sites = append(sites, mapObject.scoreStartSite(candidate.X, candidate.Y, lairs, nodes))
+    }
+
+    pool := mapObject.startPool(sites)
+
+    count = min(count, len(pool))
+    if count <= 0 {
+        return StartPlacement{}
+    }
+
+    // the worst and total score of the chosen pool indices
+    evaluate := func(chosen []int) (float64, float64) {
+        var points []image.Point
+        for _, index := range chosen {
+            points = append(points, pool[index].Point())
+        }
+        points = append(points, fixed...)
+
+        worst := 0.0
+        total := 0.0
+        for i, index := range chosen {
+            score := pool[index].Score - mapObject.startCrowding(pool[index].Point(), points)
+            if i == 0 || score < worst {
+                worst = score
+            }
+            total += score
+        }
+
+        return worst, total
+    }
+
+    better := func(worst1 float64, total1 float64, worst2 float64, total2 float64) bool {
+        return worst1 > worst2 || (worst1 == worst2 && total1 > total2)
+    }
+
+    var best []int
+    var bestWorst, bestTotal float64
+
+    // greedily grow a set from every possible first site
+    for first := range pool {
+        chosen := []int{first}
+        for len(chosen) < count {
+            next := -1
+            var nextWorst, nextTotal float64
+            for candidate := range pool {
+                if slices.Contains(chosen, candidate) {
+                    continue
+                }
+
+                worst, total := evaluate(append(chosen, candidate))
+                if next == -1 || better(worst, total, nextWorst, nextTotal) {
+                    next = candidate
+                    nextWorst = worst
+                    nextTotal = total
+                }
+            }
+
+            chosen = append(chosen, next)
+        }
+
+        worst, total := evaluate(chosen)
+        if best == nil || better(worst, total, bestWorst, bestTotal) {
+            best = chosen
+            bestWorst = worst
+            bestTotal = total
+        }
+    }
+
+    // then swap sites in and out of the set while that helps
+    for improved := true; improved; {
+        improved = false
+        for i := range best {
+            for candidate := range pool {
+                if slices.Contains(best, candidate) {
+                    continue
+                }
+
+                swapped := slices.Clone(best)
+                swapped[i] = candidate
+                worst, total := evaluate(swapped)
+                if better(worst, total, bestWorst, bestTotal) {
+                    best = swapped
+                    bestWorst = worst
+                    bestTotal = total
+                    improved = true
+                }
+            }
+        }
+    }
+
+    var points []image.Point
+    for _, index := range best {
+        points = append(points, pool[index].Point())
+    }
+    points = append(points, fixed...)
+
+    var placement StartPlacement
+    for _, index := range best {
+        site := pool[index]
+        site.Crowding = mapObject.startCrowding(site.Point(), points)
+        site.Score -= site.Crowding
+        placement.Sites = append(placement.Sites, site)
+    }
+
+    return placement
+}
@@ -0,0 +1,194 @@
+package maplib
+
+import (
+    "testing"
+    "image"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+// terrain data with every arcanus tile
+func testTerrainData() *terrain.TerrainData {
+    var tiles []terrain.TerrainTile
+    for index := range terrain.MyrrorStart {
+        tiles = append(tiles, terrain.TerrainTile{TileIndex: index, Tile: terrain.GetTile(index)})
+    }
+
+    return terrain.MakeTerrainData([]image.Image{nil}, tiles)
+}
+
+// a map of the given tile with two rows of ocean at the top and bottom
+func makeStartMap(columns int, rows int, tile terrain.Tile) *Map {
+    rawMap := terrain.MakeMap(rows, columns)
+    for x := range columns {
+        for y := range rows {
+            if y < 2 || y >= rows - 2 {
+                rawMap.Terrain[x][y] = terrain.TileOcean.Index(data.PlaneArcanus)
+            } else {
+                rawMap.Terrain[x][y] = tile.Index(data.PlaneArcanus)
+            }
+        }
+    }
+
+    out := &Map{
+        Data: testTerrainData(),
+        Plane: data.PlaneArcanus,
+        Map: rawMap,
+        ExtraMap: make(map[image.Point]map[ExtraKind]ExtraTile),
+    }
+
+    for x := range columns {
+        for y := range rows {
+            out.ExtraMap[image.Pt(x, y)] = make(map[ExtraKind]ExtraTile)
+        }
+    }
+
+    return out
+}
+
+func TestSolveStartsSpread(test *testing.T) {
+    xmap := makeStartMap(48, 30, terrain.TileGrasslands1)
+
+    placement := xmap.SolveStarts(xmap.StartCandidates(nil), 3, nil)
+    if len(placement.Sites) != 3 {
+        test.Fatalf("Expected 3 sites but got %v", len(placement.Sites))
+    }
+
+    for i := range placement.Sites {
+        if placement.Sites[i].Crowding != 0 {
+            test.Errorf("Site %v is crowded", placement.Sites[i].String())
+        }
+
+        for j := i + 1; j < len(placement.Sites); j++ {
+            a := placement.Sites[i]
+            b := placement.Sites[j]
+            if xmap.TileDistance(a.X, a.Y, b.X, b.Y) < StartCapitalDistance {
+                test.Errorf("Sites %v,%v and %v,%v are too close", a.X, a.Y, b.X, b.Y)
+            }
+        }
+    }
+
+    // every site has a full catchment area of grass
+    if placement.Fairness() != 1 {
+        test.Errorf("Expected a fair placement but got %v", placement.Report())
+    }
+
+    if len(placement.Report()) != 4 {
+        test.Errorf("Expected a line per site and a fairness line but got %v", placement.Report())
+    }
+}
+
+// a rich band of land shouldn't take up the whole pool of candidates, the wizards that can't fit in it
+// still need sites elsewhere
+func TestStartPoolRichBand(test *testing.T) {
+    xmap := makeStartMap(60, 40, terrain.TileGrasslands1)
+
+    // a band of gold down the middle of the map
+    for x := 20; x < 40; x++ {
+        for y := 2; y < 38; y++ {
+            xmap.ExtraMap[image.Pt(x, y)][ExtraKindBonus] = &ExtraBonus{Bonus: data.BonusGoldOre}
+        }
+    }
+
+    lairs, nodes := xmap.startFeatures()
+    var sites []StartSite
+    for _, candidate := range xmap.StartCandidates(nil) {
+        sites = append(sites, xmap.scoreStartSite(candidate.X, candidate.Y, lairs, nodes))
+    }
+
+    pool := xmap.startPool(sites)
+
+    outside := 0
+    for i, site := range pool {
+        if site.Gold == 0 {
+            outside += 1
+        }
+
+        for _, other := range pool[i+1:] {
+            if xmap.TileDistance(site.X, site.Y, other.X, other.Y) < StartCandidateSpacing {
+                test.Errorf("Candidates %v,%v and %v,%v are too close", site.X, site.Y, other.X, other.Y)
+            }
+        }
+    }
+
+    if outside == 0 {
+        test.Errorf("Expected candidates outside of the rich band")
+    }
+}
+
+func TestSolveStartsPrefersGoodLand(test *testing.T) {
+    xmap := makeStartMap(60, 30, terrain.TileGrasslands1)
+
+    // the western third of the map is desert
+    for x := range 20 {
+        for y := 2; y < 28; y++ {
+            xmap.Map.Terrain[x][y] = terrain.TileAllDesert1.Index(data.PlaneArcanus)
+        }
+    }
+
+    lair := image.Pt(40, 15)
+    xmap.ExtraMap[lair][ExtraKindEncounter] = &ExtraEncounter{Type: EncounterTypeLair}
+
+    placement := xmap.SolveStarts(xmap.StartCandidates(nil), 2, nil)
+    if len(placement.Sites) != 2 {
+        test.Fatalf("Expected 2 sites but got %v", len(placement.Sites))
+    }
+
+    for _, site := range placement.Sites {
+        if site.Food != 31.5 {
+            test.Errorf("Expected a catchment area of only grass at %v", site.String())
+        }
+
+        if site.DangerousLairs != 0 {
+            test.Errorf("Site is next to a lair: %v", site.String())
+        }
+    }
+}
+
+func TestSolveStartsFixed(test *testing.T) {
+    xmap := makeStartMap(48, 30, terrain.TileGrasslands1)
+
+    fixed := []image.Point{image.Pt(10, 15)}
+    placement := xmap.SolveStarts(xmap.StartCandidates(nil), 1, fixed)
+    if len(placement.Sites) != 1 {
+        test.Fatalf("Expected 1 site but got %v", len(placement.Sites))
+    }
+
+    site := placement.Sites[0]
+    if xmap.TileDistance(site.X, site.Y, fixed[0].X, fixed[0].Y) < StartCapitalDistance {
+        test.Errorf("Site %v is too close to the fixed start", site.String())
+    }
+}
+
+func TestScoreStarts(test *testing.T) {
+    xmap := makeStartMap(48, 30, terrain.TileGrasslands1)
+
+    placement := xmap.ScoreStarts([]image.Point{image.Pt(10, 15), image.Pt(15, 15)})
+    if len(placement.Sites) != 2 {
+        test.Fatalf("Expected 2 sites but got %v", len(placement.Sites))
+    }
+
+    for _, site := range placement.Sites {
+        if site.Crowding != float64(StartCapitalDistance - 5) * 2 {
+            test.Errorf("Expected crowding for two capitals 5 tiles apart: %v", site.String())
+        }
+    }
+
+    if placement.Fairness() != 1 {
+        test.Errorf("Expected equal sites: %v", placement.Report())
+    }
+}
+
+func TestStartCandidatesContinentSize(test *testing.T) {
+    // ten columns of ten land tiles
+    small := makeStartMap(10, 14, terrain.TileGrasslands1)
+    if len(small.StartCandidates(nil)) != 0 {
+        test.Errorf("A continent of exactly %v tiles should not get a capital", StartMinimumContinent)
+    }
+
+    large := makeStartMap(11, 14, terrain.TileGrasslands1)
+    if len(large.StartCandidates(nil)) == 0 {
+        test.Errorf("A continent of more than %v tiles should get a capital", StartMinimumContinent)
+    }
+}
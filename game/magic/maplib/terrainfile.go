@@ -0,0 +1,313 @@
+package maplib
+
+import (
+    "io"
+    "os"
+    "fmt"
+    "bufio"
+    "bytes"
+    "image"
+    "image/color"
+    "image/png"
+    "strings"
+    "path/filepath"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* terrain can be exchanged with other programs as a png, where every pixel is one tile, or as a text
+ * grid, where every character is one tile. only the terrain type of a tile is stored, the exact tile
+ * (shores, river bends) is chosen again by ResolveTiles when the file is imported. shores and lakes
+ * are written as ocean since they are recreated from the land around them.
+ */
+
+type terrainSymbol struct {
+    Type terrain.TerrainType
+    Color color.RGBA
+    Character byte
+}
+
+var terrainSymbols = []terrainSymbol{
+    {Type: terrain.Ocean, Color: color.RGBA{R: 24, G: 48, B: 160, A: 255}, Character: '~'},
+    {Type: terrain.River, Color: color.RGBA{R: 64, G: 160, B: 255, A: 255}, Character: 'r'},
+    {Type: terrain.Grass, Color: color.RGBA{R: 80, G: 176, B: 48, A: 255}, Character: '.'},
+    {Type: terrain.Forest, Color: color.RGBA{R: 16, G: 96, B: 16, A: 255}, Character: 'f'},
+    {Type: terrain.Mountain, Color: color.RGBA{R: 128, G: 128, B: 128, A: 255}, Character: '^'},
+    {Type: terrain.Hill, Color: color.RGBA{R: 160, G: 128, B: 64, A: 255}, Character: 'h'},
+    {Type: terrain.Swamp, Color: color.RGBA{R: 96, G: 112, B: 64, A: 255}, Character: 's'},
+    {Type: terrain.Desert, Color: color.RGBA{R: 232, G: 208, B: 112, A: 255}, Character: 'd'},
+    {Type: terrain.Tundra, Color: color.RGBA{R: 240, G: 240, B: 240, A: 255}, Character: 't'},
+    {Type: terrain.Volcano, Color: color.RGBA{R: 160, G: 32, B: 16, A: 255}, Character: 'v'},
+    {Type: terrain.SorceryNode, Color: color.RGBA{R: 0, G: 255, B: 255, A: 255}, Character: 'S'},
+    {Type: terrain.NatureNode, Color: color.RGBA{R: 0, G: 255, B: 0, A: 255}, Character: 'N'},
+    {Type: terrain.ChaosNode, Color: color.RGBA{R: 255, G: 64, B: 0, A: 255}, Character: 'C'},
+}
+
+// the symbol a terrain type is written as
+func symbolFor(terrainType terrain.TerrainType) terrainSymbol {
+    switch terrainType {
+        case terrain.Shore, terrain.Lake, terrain.Unknown:
+            terrainType = terrain.Ocean
+    }
+
+    for _, symbol := range terrainSymbols {
+        if symbol.Type == terrainType {
+            return symbol
+        }
+    }
+
+    return terrainSymbols[0]
+}
+
+// the terrain type whose color is closest to the given color, so slightly off colors from a paint program still work
+func TerrainTypeForColor(value color.Color) terrain.TerrainType {
+    r, g, b, _ := value.RGBA()
+
+    best := terrainSymbols[0]
+    bestDistance := -1
+    for _, symbol := range terrainSymbols {
+        dr := int(r >> 8) - int(symbol.Color.R)
+        dg := int(g >> 8) - int(symbol.Color.G)
+        db := int(b >> 8) - int(symbol.Color.B)
+        distance := dr * dr + dg * dg + db * db
+        if bestDistance == -1 || distance < bestDistance {
+            best = symbol
+            bestDistance = distance
+        }
+    }
+
+    return best.Type
+}
+
+func TerrainColor(terrainType terrain.TerrainType) color.RGBA {
+    return symbolFor(terrainType).Color
+}
+
+// the terrain type of a character in a text grid, false if the character is not used
+func TerrainTypeForCharacter(character byte) (terrain.TerrainType, bool) {
+    for _, symbol := range terrainSymbols {
+        if symbol.Character == character {
+            return symbol.Type, true
+        }
+    }
+
+    return terrain.Unknown, false
+}
+
+// build a terrain map from a grid of terrain types indexed by [x][y], and pick the right tiles for the shores and rivers
+func makeTerrainMap(types [][]terrain.TerrainType, terrainData *terrain.TerrainData, plane data.Plane) *terrain.Map {
+    columns := len(types)
+    rows := len(types[0])
+
+    out := terrain.MakeMap(rows, columns)
+    for x := range columns {
+        for y := range rows {
+            out.Terrain[x][y] = terrain.BaseTileIndex(types[x][y], plane)
+        }
+    }
+
+    if terrainData != nil {
+        out.ResolveTiles(terrainData, plane)
+    }
+
+    return out
+}
+
+func ImportTerrainImage(reader io.Reader, terrainData *terrain.TerrainData, plane data.Plane) (*terrain.Map, error) {
+    picture, err := png.Decode(reader)
+    if err != nil {
+        return nil, err
+    }
+
+    bounds := picture.Bounds()
+    if bounds.Dx() == 0 || bounds.Dy() == 0 {
+        return nil, fmt.Errorf("image is empty")
+    }
+
+    types := make([][]terrain.TerrainType, bounds.Dx())
+    for x := range bounds.Dx() {
+        types[x] = make([]terrain.TerrainType, bounds.Dy())
+        for y := range bounds.Dy() {
+            types[x][y] = TerrainTypeForColor(picture.At(bounds.Min.X + x, bounds.Min.Y + y))
+        }
+    }
+
+    return makeTerrainMap(types, terrainData, plane), nil
+}
+
+func ImportTerrainText(reader io.Reader, terrainData *terrain.TerrainData, plane data.Plane) (*terrain.Map, error) {
+    var lines []string
+    scanner := bufio.NewScanner(reader)
+    for scanner.Scan() {
+        line := strings.TrimRight(scanner.Text(), " \r")
+        if line != "" {
+            lines = append(lines, line)
+        }
+    }
+
+    if scanner.Err() != nil {
+        return nil, scanner.Err()
+    }
+
+    if len(lines) == 0 {
+        return nil, fmt.Errorf("no terrain rows")
+    }
+
+    columns := len(lines[0])
+    types := make([][]terrain.TerrainType, columns)
+    for x := range columns {
+        types[x] = make([]terrain.TerrainType, len(lines))
+    }
+
+    for y, line := range lines {
+        if len(line) != columns {
+            return nil, fmt.Errorf("row %v has %v tiles but the first row has %v", y + 1, len(line), columns)
+        }
+
+        for x := range columns {
+            terrainType, ok := TerrainTypeForCharacter(line[x])
+            if !ok {
+                return nil, fmt.Errorf("unknown terrain '%c' at row %v column %v", line[x], y + 1, x + 1)
+            }
+            types[x][y] = terrainType
+        }
+    }
+
+    return makeTerrainMap(types, terrainData, plane), nil
+}
+
+// true if the path is a terrain image or text grid rather than a scenario
+func IsTerrainFile(path string) bool {
+    switch strings.ToLower(filepath.Ext(path)) {
+        case ".png", ".txt": return true
+    }
+
+    return false
+}
+
+// import a png or text file, depending on the extension of the path
+func LoadTerrainFile(path string, terrainData *terrain.TerrainData, plane data.Plane) (*terrain.Map, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    switch strings.ToLower(filepath.Ext(path)) {
+        case ".png": return ImportTerrainImage(file, terrainData, plane)
+        case ".txt": return ImportTerrainText(file, terrainData, plane)
+    }
+
+    return nil, fmt.Errorf("unknown terrain file type %v", path)
+}
+
+// one pixel per tile
+func (mapObject *Map) TerrainImage() *image.RGBA {
+    out := image.NewRGBA(image.Rect(0, 0, mapObject.Width(), mapObject.Height()))
+    for x := range mapObject.Width() {
+        for y := range mapObject.Height() {
+            out.SetRGBA(x, y, TerrainColor(terrain.GetTile(mapObject.Map.Terrain[x][y]).TerrainType()))
+        }
+    }
+
+    return out
+}
+
+func (mapObject *Map) ExportTerrainImage(writer io.Writer) error {
+    return png.Encode(writer, mapObject.TerrainImage())
+}
+
+// one line per row of the map
+func (mapObject *Map) ExportTerrainText(writer io.Writer) error {
+    buffered := bufio.NewWriter(writer)
+    for y := range mapObject.Height() {
+        for x := range mapObject.Width() {
+            buffered.WriteByte(symbolFor(terrain.GetTile(mapObject.Map.Terrain[x][y]).TerrainType()).Character)
+        }
+        buffered.WriteByte('\n')
+    }
+
+    return buffered.Flush()
+}
+
+// write a png or text file, depending on the extension of the path
+func (mapObject *Map) SaveTerrainFile(path string) error {
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+
+    switch strings.ToLower(filepath.Ext(path)) {
+        case ".png": err = mapObject.ExportTerrainImage(file)
+        case ".txt": err = mapObject.ExportTerrainText(file)
+        default: err = fmt.Errorf("unknown terrain file type %v", path)
+    }
+
+    if err != nil {
+        file.Close()
+        return err
+    }
+
+    return file.Close()
+}
+
+// the file holding the myrror terrain that goes with the arcanus terrain at path. world.png goes with world-myrror.png
+func MyrrorTerrainPath(path string) string {
+    extension := filepath.Ext(path)
+    return strings.TrimSuffix(path, extension) + "-myrror" + extension
+}
+
+// true if the path is the myrror half of another terrain file
+func IsMyrrorTerrainPath(path string) bool {
+    return strings.HasSuffix(strings.TrimSuffix(path, filepath.Ext(path)), "-myrror")
+}
+
+// make a scenario from a terrain file for arcanus. myrror is read from MyrrorTerrainPath if it exists, otherwise
+// it is generated at the same size. nodes, lairs, towers and minerals are placed as in a generated game. the
+// scenario has no cities, so the game places the neutral towns at random
+func ImportScenario(path string, terrainData *terrain.TerrainData, magicSetting data.MagicSetting, difficulty data.DifficultySetting) (Scenario, error) {
+    arcanusTerrain, err := LoadTerrainFile(path, terrainData, data.PlaneArcanus)
+    if err != nil {
+        return Scenario{}, err
+    }
+
+    columns, rows := arcanusTerrain.Columns(), arcanusTerrain.Rows()
+    towers := PlaneTowerPositions(columns, rows, 6)
+
+    arcanus := MakeMapFromTerrain(terrainData, arcanusTerrain, magicSetting, difficulty, data.PlaneArcanus, nil, towers)
+
+    var myrror *Map
+    if _, err := os.Stat(MyrrorTerrainPath(path)); err == nil {
+        myrrorTerrain, err := LoadTerrainFile(MyrrorTerrainPath(path), terrainData, data.PlaneMyrror)
+        if err != nil {
+            return Scenario{}, err
+        }
+
+        if myrrorTerrain.Columns() != columns || myrrorTerrain.Rows() != rows {
+            return Scenario{}, fmt.Errorf("myrror is %vx%v but arcanus is %vx%v", myrrorTerrain.Columns(), myrrorTerrain.Rows(), columns, rows)
+        }
+
+        myrror = MakeMapFromTerrain(terrainData, myrrorTerrain, magicSetting, difficulty, data.PlaneMyrror, nil, towers)
+    } else {
+        myrror = MakeMapWithParameters(terrainData, terrain.DefaultGeneratorParameters(columns, rows), magicSetting, difficulty, data.PlaneMyrror, nil, towers)
+    }
+
+    // go through the scenario file format so the maps are rebuilt exactly like a scenario that was loaded from disk
+    var buffer bytes.Buffer
+    err = WriteScenario(&buffer, MakeScenario(arcanus, myrror, nil, nil))
+    if err != nil {
+        return Scenario{}, err
+    }
+
+    return ReadScenario(&buffer)
+}
+
+// load a scenario file, or import a terrain file as a scenario
+func LoadScenario(path string, terrainData *terrain.TerrainData, magicSetting data.MagicSetting, difficulty data.DifficultySetting) (Scenario, error) {
+    if IsTerrainFile(path) {
+        return ImportScenario(path, terrainData, magicSetting, difficulty)
+    }
+
+    return LoadScenarioFile(path)
+}
@@ -0,0 +1,115 @@
+package maplib
+
+import (
+    "testing"
+    "image"
+    "image/color"
+    "image/png"
+    "bytes"
+    "strings"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+const testTerrainText = `~~~~~~~~~~
+~~....ff~~
+~.^^h.ff.~
+~.rr..ds.~
+~~t..S..~~
+~~~~~~~~~~
+`
+
+func TestTerrainTextRoundTrip(test *testing.T) {
+    terrainData := testTerrainData()
+
+    imported, err := ImportTerrainText(strings.NewReader(testTerrainText), terrainData, data.PlaneArcanus)
+    if err != nil {
+        test.Fatalf("Unable to import: %v", err)
+    }
+
+    if imported.Columns() != 10 || imported.Rows() != 6 {
+        test.Fatalf("Expected a 10x6 map but got %vx%v", imported.Columns(), imported.Rows())
+    }
+
+    // the ocean next to land became shore
+    if terrain.GetTile(imported.Terrain[1][1]).TerrainType() != terrain.Shore {
+        test.Errorf("Expected shore at 1,1 but got %v", terrain.GetTile(imported.Terrain[1][1]).TerrainType())
+    }
+
+    if terrain.GetTile(imported.Terrain[5][4]).TerrainType() != terrain.SorceryNode {
+        test.Errorf("Expected a sorcery node at 5,4")
+    }
+
+    xmap := Map{Map: imported, Data: terrainData, Plane: data.PlaneArcanus}
+
+    var buffer bytes.Buffer
+    err = xmap.ExportTerrainText(&buffer)
+    if err != nil {
+        test.Fatalf("Unable to export: %v", err)
+    }
+
+    if buffer.String() != testTerrainText {
+        test.Errorf("Exported text differs:\n%v\nexpected:\n%v", buffer.String(), testTerrainText)
+    }
+}
+
+func TestTerrainTextErrors(test *testing.T) {
+    _, err := ImportTerrainText(strings.NewReader("~~~\n~~\n"), nil, data.PlaneArcanus)
+    if err == nil {
+        test.Errorf("Expected an error for rows of different lengths")
+    }
+
+    _, err = ImportTerrainText(strings.NewReader("~x~\n"), nil, data.PlaneArcanus)
+    if err == nil {
+        test.Errorf("Expected an error for an unknown character")
+    }
+}
+
+func TestTerrainImage(test *testing.T) {
+    picture := image.NewRGBA(image.Rect(0, 0, 4, 3))
+    for x := range 4 {
+        for y := range 3 {
+            picture.Set(x, y, TerrainColor(terrain.Ocean))
+        }
+    }
+
+    // a slightly different shade of grass, as a paint program might produce
+    picture.Set(1, 1, color.RGBA{R: 90, G: 170, B: 40, A: 255})
+    picture.Set(2, 1, TerrainColor(terrain.Mountain))
+
+    var buffer bytes.Buffer
+    err := png.Encode(&buffer, picture)
+    if err != nil {
+        test.Fatalf("Unable to encode: %v", err)
+    }
+
+    imported, err := ImportTerrainImage(&buffer, nil, data.PlaneMyrror)
+    if err != nil {
+        test.Fatalf("Unable to import: %v", err)
+    }
+
+    if imported.Terrain[1][1] != terrain.TileGrasslands1.Index(data.PlaneMyrror) {
+        test.Errorf("Expected myrror grass at 1,1")
+    }
+
+    if terrain.GetTile(imported.Terrain[2][1]).TerrainType() != terrain.Mountain {
+        test.Errorf("Expected a mountain at 2,1")
+    }
+
+    xmap := Map{Map: imported, Plane: data.PlaneMyrror}
+    exported := xmap.TerrainImage()
+    if exported.RGBAAt(1, 1) != TerrainColor(terrain.Grass) || exported.RGBAAt(0, 0) != TerrainColor(terrain.Ocean) {
+        test.Errorf("Exported colors are wrong")
+    }
+}
+
+func TestMyrrorTerrainPath(test *testing.T) {
+    if MyrrorTerrainPath("maps/world.png") != "maps/world-myrror.png" {
+        test.Errorf("Wrong myrror path %v", MyrrorTerrainPath("maps/world.png"))
+    }
+
+    if !IsMyrrorTerrainPath("world-myrror.txt") || IsMyrrorTerrainPath("world.txt") {
+        test.Errorf("Myrror paths were not recognized")
+    }
+}
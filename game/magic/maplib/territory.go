@@ -0,0 +1,201 @@
+package maplib
+
+import (
+    "image"
+
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* the territory of a wizard is the land its cities control. every city claims its catchment area
+ * and every tile within its influence radius, which grows with the size of the city. a tile claimed
+ * by cities of different wizards goes to the closest city, and stays unclaimed if two wizards are
+ * equally close.
+ */
+
+type TerritoryCity interface {
+    GetX() int
+    GetY() int
+    GetBanner() data.BannerType
+    // tiles within this distance of the city are claimed by it
+    InfluenceRadius() int
+}
+
+type Territory struct {
+    Owners map[image.Point]data.BannerType
+    // the map the territory was computed on, used to wrap x coordinates
+    Map *Map
+}
+
+// the claim of the closest city on a tile so far
+type territoryClaim struct {
+    Banner data.BannerType
+    Distance int
+    Contested bool
+}
+
+// squared euclidean distance, so that the closest city wins ties in TileDistance
+func (mapObject *Map) territoryDistance(x1 int, y1 int, x2 int, y2 int) int {
+    dx := mapObject.XDistance(x1, x2)
+    dy := y2 - y1
+    return dx * dx + dy * dy
+}
+
+func (mapObject *Map) ComputeTerritory(cities []TerritoryCity) *Territory {
+    claims := make(map[image.Point]territoryClaim)
+
+    for _, city := range cities {
+        radius := max(2, city.InfluenceRadius())
+
+        for dx := -radius; dx <= radius; dx++ {
+            for dy := -radius; dy <= radius; dy++ {
+                x := mapObject.WrapX(city.GetX() + dx)
+                y := city.GetY() + dy
+                if y < 0 || y >= mapObject.Height() {
+                    continue
+                }
+
+                // the catchment area is always claimed, the rest of the square is cut down to a circle
+                inCatchment := max(abs(dx), abs(dy)) <= 2 && abs(dx) + abs(dy) < 4
+                if !inCatchment && dx * dx + dy * dy > radius * radius {
+                    continue
+                }
+
+                point := image.Pt(x, y)
+                distance := mapObject.territoryDistance(city.GetX(), city.GetY(), x, y)
+
+                existing, ok := claims[point]
+                switch {
+                    case !ok || distance < existing.Distance:
+                        claims[point] = territoryClaim{Banner: city.GetBanner(), Distance: distance}
+                    case distance == existing.Distance && existing.Banner != city.GetBanner():
+                        existing.Contested = true
+                        claims[point] = existing
+                }
+            }
+        }
+    }
+
+    territory := &Territory{
+        Owners: make(map[image.Point]data.BannerType),
+        Map: mapObject,
+    }
+
+    for point, claim := range claims {
+        if !claim.Contested {
+            territory.Owners[point] = claim.Banner
+        }
+    }
+
+    return territory
+}
+
+func abs(x int) int {
+    if x < 0 {
+        return -x
+    }
+    return x
+}
+
+// the wizard that controls the tile, false if nobody does
+func (territory *Territory) Owner(x int, y int) (data.BannerType, bool) {
+    if territory == nil {
+        return data.BannerBrown, false
+    }
+
+    banner, ok := territory.Owners[image.Pt(territory.Map.WrapX(x), y)]
+    return banner, ok
+}
+
+// the number of tiles controlled by the wizard
+func (territory *Territory) Size(banner data.BannerType) int {
+    count := 0
+    for _, owner := range territory.Owners {
+        if owner == banner {
+            count += 1
+        }
+    }
+
+    return count
+}
+
+// which sides of the tile are a border: north, east, south, west
+func (territory *Territory) BorderSides(x int, y int) [4]bool {
+    var out [4]bool
+
+    owner, ok := territory.Owner(x, y)
+    if !ok {
+        return out
+    }
+
+    neighbors := [4]image.Point{image.Pt(x, y - 1), image.Pt(x + 1, y), image.Pt(x, y + 1), image.Pt(x - 1, y)}
+    for i, neighbor := range neighbors {
+        other, ok := territory.Owner(neighbor.X, neighbor.Y)
+        out[i] = !ok || other != owner
+    }
+
+    return out
+}
+
+// the tiles of the wizard that touch a tile the wizard does not control
+func (territory *Territory) BorderTiles(banner data.BannerType) []image.Point {
+    if territory == nil {
+        return nil
+    }
+
+    var out []image.Point
+    for point, owner := range territory.Owners {
+        if owner != banner {
+            continue
+        }
+
+        sides := territory.BorderSides(point.X, point.Y)
+        if sides[0] || sides[1] || sides[2] || sides[3] {
+            out = append(out, point)
+        }
+    }
+
+    return out
+}
+
+// the number of border tiles the wizard shares with each other wizard
+func (territory *Territory) Neighbors(banner data.BannerType) map[data.BannerType]int {
+    out := make(map[data.BannerType]int)
+    for _, point := range territory.BorderTiles(banner) {
+        seen := make(map[data.BannerType]bool)
+        for _, neighbor := range []image.Point{image.Pt(point.X, point.Y - 1), image.Pt(point.X + 1, point.Y), image.Pt(point.X, point.Y + 1), image.Pt(point.X - 1, point.Y)} {
+            other, ok := territory.Owner(neighbor.X, neighbor.Y)
+            if ok && other != banner && !seen[other] {
+                seen[other] = true
+                out[other] += 1
+            }
+        }
+    }
+
+    return out
+}
+
+// unclaimed land next to the territory of the wizard, where it has room to expand
+func (territory *Territory) Frontier(banner data.BannerType) []image.Point {
+    found := make(map[image.Point]bool)
+    var out []image.Point
+
+    for _, point := range territory.BorderTiles(banner) {
+        for _, neighbor := range []image.Point{image.Pt(point.X, point.Y - 1), image.Pt(point.X + 1, point.Y), image.Pt(point.X, point.Y + 1), image.Pt(point.X - 1, point.Y)} {
+            neighbor.X = territory.Map.WrapX(neighbor.X)
+            if neighbor.Y < 0 || neighbor.Y >= territory.Map.Height() || found[neighbor] {
+                continue
+            }
+
+            if _, owned := territory.Owner(neighbor.X, neighbor.Y); owned {
+                continue
+            }
+
+            if territory.Map.GetTile(neighbor.X, neighbor.Y).Tile.IsLand() {
+                found[neighbor] = true
+                out = append(out, neighbor)
+            }
+        }
+    }
+
+    return out
+}
@@ -0,0 +1,103 @@
+package maplib
+
+import (
+    "testing"
+    "image"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+type testTerritoryCity struct {
+    X int
+    Y int
+    Banner data.BannerType
+    Radius int
+}
+
+func (city *testTerritoryCity) GetX() int {
+    return city.X
+}
+
+func (city *testTerritoryCity) GetY() int {
+    return city.Y
+}
+
+func (city *testTerritoryCity) GetBanner() data.BannerType {
+    return city.Banner
+}
+
+func (city *testTerritoryCity) InfluenceRadius() int {
+    return city.Radius
+}
+
+func TestTerritoryCatchment(test *testing.T) {
+    xmap := makeStartMap(40, 30, terrain.TileGrasslands1)
+
+    territory := xmap.ComputeTerritory([]TerritoryCity{&testTerritoryCity{X: 10, Y: 15, Banner: data.BannerBlue, Radius: 2}})
+
+    // the catchment area is 21 tiles
+    if territory.Size(data.BannerBlue) != 21 {
+        test.Errorf("Expected 21 tiles but got %v", territory.Size(data.BannerBlue))
+    }
+
+    if _, ok := territory.Owner(12, 17); ok {
+        test.Errorf("The corner of the catchment area should not be claimed")
+    }
+
+    sides := territory.BorderSides(10, 13)
+    if !sides[0] || sides[2] {
+        test.Errorf("Expected a border only to the north of 10,13 but got %v", sides)
+    }
+
+    if len(territory.Frontier(data.BannerBlue)) == 0 {
+        test.Errorf("Expected room to expand")
+    }
+}
+
+func TestTerritoryContested(test *testing.T) {
+    xmap := makeStartMap(40, 30, terrain.TileGrasslands1)
+
+    territory := xmap.ComputeTerritory([]TerritoryCity{
+        &testTerritoryCity{X: 10, Y: 15, Banner: data.BannerBlue, Radius: 4},
+        &testTerritoryCity{X: 15, Y: 15, Banner: data.BannerRed, Radius: 4},
+    })
+
+    // closer to blue
+    if owner, ok := territory.Owner(12, 15); !ok || owner != data.BannerBlue {
+        test.Errorf("Expected 12,15 to be blue")
+    }
+
+    // closer to red
+    if owner, ok := territory.Owner(13, 15); !ok || owner != data.BannerRed {
+        test.Errorf("Expected 13,15 to be red")
+    }
+
+    if territory.Neighbors(data.BannerBlue)[data.BannerRed] == 0 {
+        test.Errorf("Expected blue and red to share a border")
+    }
+
+    territory = xmap.ComputeTerritory([]TerritoryCity{
+        &testTerritoryCity{X: 10, Y: 15, Banner: data.BannerBlue, Radius: 4},
+        &testTerritoryCity{X: 16, Y: 15, Banner: data.BannerRed, Radius: 4},
+    })
+
+    // the same distance from both
+    if _, ok := territory.Owner(13, 15); ok {
+        test.Errorf("Expected 13,15 to be contested")
+    }
+}
+
+func TestTerritoryWraps(test *testing.T) {
+    xmap := makeStartMap(40, 30, terrain.TileGrasslands1)
+
+    territory := xmap.ComputeTerritory([]TerritoryCity{&testTerritoryCity{X: 0, Y: 15, Banner: data.BannerGreen, Radius: 3}})
+
+    if _, ok := territory.Owner(39, 15); !ok {
+        test.Errorf("Expected the territory to wrap around the edge of the map")
+    }
+
+    if _, ok := territory.Owners[image.Pt(-1, 15)]; ok {
+        test.Errorf("Territory should only store wrapped points")
+    }
+}
@@ -0,0 +1,249 @@
+package maplib
+
+import (
+    "image"
+    "slices"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+)
+
+/* terrain transforms are changes to a tile that happen on a given turn, such as gaia's blessing
+ * slowly turning desert into grassland or a volcano cooling down into a mountain. spells that take
+ * effect immediately can apply a transform directly with ApplyTransform, long running effects
+ * schedule transforms for a later turn and ApplyTransforms runs the ones that are due at the end of
+ * every turn. the tiles around a changed tile are resolved again so shores and rivers still line up.
+ *
+ * the caller is responsible for updating anything that depends on the terrain, such as the food and
+ * production of cities whose catchment area contains a changed tile.
+ */
+
+type TransformKind int
+
+const (
+    // change the terrain type of the tile
+    TransformTerrain TransformKind = iota
+    TransformRaiseVolcano
+    // the volcano becomes a mountain
+    TransformRevertVolcano
+    TransformCorrupt
+    TransformPurify
+)
+
+func (kind TransformKind) String() string {
+    switch kind {
+        case TransformTerrain: return "terrain"
+        case TransformRaiseVolcano: return "raise volcano"
+        case TransformRevertVolcano: return "revert volcano"
+        case TransformCorrupt: return "corrupt"
+        case TransformPurify: return "purify"
+    }
+
+    return "unknown"
+}
+
+func transformKindFromString(name string) TransformKind {
+    for _, kind := range []TransformKind{TransformTerrain, TransformRaiseVolcano, TransformRevertVolcano, TransformCorrupt, TransformPurify} {
+        if kind.String() == name {
+            return kind
+        }
+    }
+
+    return TransformTerrain
+}
+
+type TerrainTransform struct {
+    X int
+    Y int
+    Kind TransformKind
+    // the new terrain type for TransformTerrain
+    Terrain terrain.TerrainType
+    // the turn the transform happens on
+    Turn uint64
+    // the wizard that caused the transform, can be nil
+    Caster Wizard
+    // what scheduled the transform, such as the name of a spell, so it can be cancelled
+    Source string
+}
+
+// true if the transform would change the tile
+func (mapObject *Map) canTransform(transform TerrainTransform) bool {
+    if transform.Y < 0 || transform.Y >= mapObject.Height() {
+        return false
+    }
+
+    x := mapObject.WrapX(transform.X)
+    y := transform.Y
+
+    // nodes keep their terrain
+    if mapObject.HasMagicNode(x, y) {
+        return false
+    }
+
+    tile := terrain.GetTile(mapObject.Map.Terrain[x][y])
+
+    switch transform.Kind {
+        case TransformTerrain:
+            return tile.TerrainType() != transform.Terrain
+        case TransformRaiseVolcano:
+            return !mapObject.HasVolcano(x, y) && tile.IsLand()
+        case TransformRevertVolcano:
+            return mapObject.HasVolcano(x, y)
+        case TransformCorrupt:
+            return !mapObject.HasCorruption(x, y) && tile.IsLand()
+        case TransformPurify:
+            return mapObject.HasCorruption(x, y)
+    }
+
+    return false
+}
+
+// change the tile now, returns true if anything changed
+func (mapObject *Map) ApplyTransform(transform TerrainTransform) bool {
+    if !mapObject.canTransform(transform) {
+        return false
+    }
+
+    x := mapObject.WrapX(transform.X)
+    y := transform.Y
+
+    switch transform.Kind {
+        case TransformTerrain:
+            // a volcano that is changed into something else is gone
+            delete(mapObject.ExtraMap[image.Pt(x, y)], ExtraKindVolcano)
+            mapObject.Map.SetTerrainAt(x, y, transform.Terrain, mapObject.Data, mapObject.Plane)
+        case TransformRaiseVolcano:
+            mapObject.SetVolcano(x, y, transform.Caster)
+        case TransformRevertVolcano:
+            mapObject.RemoveVolcano(x, y)
+        case TransformCorrupt:
+            mapObject.SetCorruption(x, y)
+        case TransformPurify:
+            mapObject.RemoveCorruption(x, y)
+    }
+
+    return true
+}
+
+// add a transform to run on transform.Turn. a transform of the same kind that is already
+// scheduled for the tile is kept and false is returned
+func (mapObject *Map) ScheduleTransform(transform TerrainTransform) bool {
+    transform.X = mapObject.WrapX(transform.X)
+
+    if mapObject.HasScheduledTransform(transform.X, transform.Y, transform.Kind) {
+        return false
+    }
+
+    mapObject.Transforms = append(mapObject.Transforms, transform)
+    return true
+}
+
+func (mapObject *Map) HasScheduledTransform(x int, y int, kind TransformKind) bool {
+    x = mapObject.WrapX(x)
+    return slices.ContainsFunc(mapObject.Transforms, func(transform TerrainTransform) bool {
+        return transform.X == x && transform.Y == y && transform.Kind == kind
+    })
+}
+
+// remove the scheduled transforms that cancel returns true for, returns how many were removed
+func (mapObject *Map) CancelTransforms(cancel func(TerrainTransform) bool) int {
+    before := len(mapObject.Transforms)
+    mapObject.Transforms = slices.DeleteFunc(mapObject.Transforms, cancel)
+    return before - len(mapObject.Transforms)
+}
+
+// run every scheduled transform whose turn is at or before the given turn, and return the tiles that changed
+func (mapObject *Map) ApplyTransforms(turn uint64) []image.Point {
+    var due []TerrainTransform
+    var later []TerrainTransform
+    for _, transform := range mapObject.Transforms {
+        if transform.Turn <= turn {
+            due = append(due, transform)
+        } else {
+            later = append(later, transform)
+        }
+    }
+
+    mapObject.Transforms = later
+
+    var changed []image.Point
+    for _, transform := range due {
+        if mapObject.ApplyTransform(transform) {
+            point := image.Pt(mapObject.WrapX(transform.X), transform.Y)
+            if !slices.Contains(changed, point) {
+                changed = append(changed, point)
+            }
+        }
+    }
+
+    return changed
+}
+
+// the name of the wizard that caused the transform, used for serialization
+func transformCaster(transform TerrainTransform) string {
+    if transform.Caster == nil {
+        return ""
+    }
+
+    return transform.Caster.GetBanner().String()
+}
+
+func findWizard(banner string, wizards []Wizard) Wizard {
+    for _, wizard := range wizards {
+        if wizard.GetBanner().String() == banner {
+            return wizard
+        }
+    }
+
+    return nil
+}
+
+type SerializedTransform struct {
+    X int `json:"x"`
+    Y int `json:"y"`
+    Kind string `json:"kind"`
+    Terrain int `json:"terrain,omitempty"`
+    Turn uint64 `json:"turn"`
+    Caster string `json:"caster,omitempty"`
+    Source string `json:"source,omitempty"`
+}
+
+func serializeTransforms(transforms []TerrainTransform) []SerializedTransform {
+    var out []SerializedTransform
+    for _, transform := range transforms {
+        out = append(out, SerializedTransform{
+            X: transform.X,
+            Y: transform.Y,
+            Kind: transform.Kind.String(),
+            Terrain: int(transform.Terrain),
+            Turn: transform.Turn,
+            Caster: transformCaster(transform),
+            Source: transform.Source,
+        })
+    }
+
+    return out
+}
+
+func reconstructTransforms(serialized []SerializedTransform, wizards []Wizard) []TerrainTransform {
+    var out []TerrainTransform
+    for _, transform := range serialized {
+        out = append(out, TerrainTransform{
+            X: transform.X,
+            Y: transform.Y,
+            Kind: transformKindFromString(transform.Kind),
+            Terrain: terrain.TerrainType(transform.Terrain),
+            Turn: transform.Turn,
+            Caster: findWizard(transform.Caster, wizards),
+            Source: transform.Source,
+        })
+    }
+
+    return out
+}
+
+// true if x, y is in the catchment area of a city at cityX, cityY
+func (mapObject *Map) InCatchmentArea(cityX int, cityY int, x int, y int) bool {
+    dx := mapObject.XDistance(cityX, x)
+    dy := y - cityY
+    return abs(dx) <= 2 && abs(dy) <= 2 && abs(dx) + abs(dy) < 4
+}
@@ -0,0 +1,107 @@
+package maplib
+
+import (
+    "testing"
+    "image"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+)
+
+func TestApplyTransformsWhenDue(test *testing.T) {
+    xmap := makeStartMap(20, 20, terrain.TileGrasslands1)
+
+    xmap.ScheduleTransform(TerrainTransform{X: 5, Y: 5, Kind: TransformTerrain, Terrain: terrain.Desert, Turn: 3})
+    xmap.ScheduleTransform(TerrainTransform{X: 6, Y: 5, Kind: TransformCorrupt, Turn: 5})
+
+    // already scheduled
+    if xmap.ScheduleTransform(TerrainTransform{X: 5, Y: 5, Kind: TransformTerrain, Terrain: terrain.Forest, Turn: 1}) {
+        test.Errorf("Expected a second terrain change of the same tile to be ignored")
+    }
+
+    if changed := xmap.ApplyTransforms(2); len(changed) != 0 {
+        test.Errorf("Nothing should change before turn 3 but got %v", changed)
+    }
+
+    changed := xmap.ApplyTransforms(3)
+    if len(changed) != 1 || changed[0] != image.Pt(5, 5) {
+        test.Errorf("Expected only 5,5 to change but got %v", changed)
+    }
+
+    if xmap.GetTile(5, 5).Tile.TerrainType() != terrain.Desert {
+        test.Errorf("Expected desert but got %v", xmap.GetTile(5, 5).Tile.TerrainType())
+    }
+
+    if len(xmap.Transforms) != 1 {
+        test.Errorf("Expected the corruption to still be scheduled")
+    }
+
+    xmap.ApplyTransforms(10)
+    if !xmap.HasCorruption(6, 5) {
+        test.Errorf("Expected 6,5 to be corrupted")
+    }
+}
+
+func TestCancelTransforms(test *testing.T) {
+    xmap := makeStartMap(20, 20, terrain.TileGrasslands1)
+
+    xmap.ScheduleTransform(TerrainTransform{X: 5, Y: 5, Kind: TransformCorrupt, Turn: 1, Source: "a"})
+    xmap.ScheduleTransform(TerrainTransform{X: 6, Y: 5, Kind: TransformCorrupt, Turn: 1, Source: "b"})
+
+    removed := xmap.CancelTransforms(func (transform TerrainTransform) bool {
+        return transform.Source == "a"
+    })
+
+    if removed != 1 {
+        test.Errorf("Expected one transform to be cancelled but got %v", removed)
+    }
+
+    xmap.ApplyTransforms(1)
+    if xmap.HasCorruption(5, 5) || !xmap.HasCorruption(6, 5) {
+        test.Errorf("Only the transform from b should have happened")
+    }
+}
+
+func TestVolcanoTransforms(test *testing.T) {
+    xmap := makeStartMap(20, 20, terrain.TileGrasslands1)
+
+    if !xmap.ApplyTransform(TerrainTransform{X: 5, Y: 5, Kind: TransformRaiseVolcano}) {
+        test.Fatalf("Expected a volcano to be raised")
+    }
+
+    if !xmap.HasVolcano(5, 5) || xmap.GetTile(5, 5).Tile.TerrainType() != terrain.Volcano {
+        test.Errorf("Expected a volcano at 5,5")
+    }
+
+    if !xmap.ApplyTransform(TerrainTransform{X: 5, Y: 5, Kind: TransformRevertVolcano}) {
+        test.Fatalf("Expected the volcano to revert")
+    }
+
+    if xmap.HasVolcano(5, 5) || xmap.GetTile(5, 5).Tile.TerrainType() != terrain.Mountain {
+        test.Errorf("Expected a mountain at 5,5")
+    }
+
+    // there is nothing left to revert
+    if xmap.ApplyTransform(TerrainTransform{X: 5, Y: 5, Kind: TransformRevertVolcano}) {
+        test.Errorf("Expected no change")
+    }
+
+    // water can't be corrupted
+    if xmap.ApplyTransform(TerrainTransform{X: 5, Y: 0, Kind: TransformCorrupt}) {
+        test.Errorf("Expected water to stay clean")
+    }
+}
+
+func TestSerializeTransforms(test *testing.T) {
+    xmap := makeStartMap(20, 20, terrain.TileGrasslands1)
+    xmap.ScheduleTransform(TerrainTransform{X: 5, Y: 5, Kind: TransformTerrain, Terrain: terrain.Swamp, Turn: 7, Source: "test"})
+
+    loaded := ReconstructMap(SerializeMap(xmap), xmap.Data, nil, nil)
+    if len(loaded.Transforms) != 1 {
+        test.Fatalf("Expected one transform but got %v", len(loaded.Transforms))
+    }
+
+    transform := loaded.Transforms[0]
+    if transform.X != 5 || transform.Y != 5 || transform.Kind != TransformTerrain || transform.Terrain != terrain.Swamp || transform.Turn != 7 || transform.Source != "test" {
+        test.Errorf("Transform did not survive serialization: %+v", transform)
+    }
+}
@@ -0,0 +1,149 @@
+package pathfinding
+
+import (
+    "image"
+    "slices"
+    "cmp"
+
+    "github.com/kazzmir/master-of-magic/lib/priority"
+)
+
+/* the reachable set is the cost of the cheapest path from a start point to every point that can be
+ * reached from it, found with a single run of dijkstra's algorithm. use it instead of FindPath when
+ * the path to many destinations is needed, such as finding the closest of several targets.
+ */
+
+// maps a point to the point that is used as its key, such as by wrapping x around the map
+type NormalizeFunc func(image.Point) image.Point
+
+// true for points that can be reached but not moved through, such as a tile with an enemy
+type StopFunc func(image.Point) bool
+
+type ReachableSet struct {
+    Start image.Point
+    costs map[image.Point]float64
+    previous map[image.Point]image.Point
+    normalize NormalizeFunc
+}
+
+/* find every point that can be reached from start with a total cost of at most maxCost.
+ * normalize and stop can be nil
+ */
+func FindReachable(start image.Point, maxCost float64, tileCost TileCostFunc, neighbors NeighborsFunc, normalize NormalizeFunc, stop StopFunc) *ReachableSet {
+    if normalize == nil {
+        normalize = func(point image.Point) image.Point {
+            return point
+        }
+    }
+
+    start = normalize(start)
+
+    reachable := &ReachableSet{
+        Start: start,
+        costs: make(map[image.Point]float64),
+        previous: make(map[image.Point]image.Point),
+        normalize: normalize,
+    }
+
+    type Node struct {
+        point image.Point
+        cost float64
+        // keep track of when nodes were added to enforce ordering for equal cost nodes
+        time uint64
+    }
+
+    compare := func (a Node, b Node) int {
+        if a.cost != b.cost {
+            return cmp.Compare(a.cost, b.cost)
+        }
+
+        return cmp.Compare(a.time, b.time)
+    }
+
+    best := make(map[image.Point]float64)
+    best[start] = 0
+
+    unvisited := priority.MakePriorityQueue[Node](compare)
+    unvisited.Insert(Node{point: start})
+
+    var globalTime uint64 = 0
+    for !unvisited.IsEmpty() {
+        node := unvisited.ExtractMin()
+
+        if _, visited := reachable.costs[node.point]; visited {
+            continue
+        }
+
+        reachable.costs[node.point] = node.cost
+
+        if node.point != start && stop != nil && stop(node.point) {
+            continue
+        }
+
+        for _, neighbor := range neighbors(node.point.X, node.point.Y) {
+            cost := tileCost(node.point.X, node.point.Y, neighbor.X, neighbor.Y)
+
+            neighbor = normalize(neighbor)
+            if _, visited := reachable.costs[neighbor]; visited {
+                continue
+            }
+
+            newCost := node.cost + cost
+            if newCost > maxCost || newCost == Infinity {
+                continue
+            }
+
+            oldCost, ok := best[neighbor]
+            if !ok || newCost < oldCost {
+                globalTime += 1
+                best[neighbor] = newCost
+                reachable.previous[neighbor] = node.point
+                unvisited.Insert(Node{point: neighbor, cost: newCost, time: globalTime})
+            }
+        }
+    }
+
+    return reachable
+}
+
+// the cost of the cheapest path to the point, or false if it cannot be reached
+func (reachable *ReachableSet) Cost(point image.Point) (float64, bool) {
+    cost, ok := reachable.costs[reachable.normalize(point)]
+    return cost, ok
+}
+
+func (reachable *ReachableSet) Contains(point image.Point) bool {
+    _, ok := reachable.costs[reachable.normalize(point)]
+    return ok
+}
+
+// the cheapest path from the start to the point, including both the start and the point, like FindPath
+func (reachable *ReachableSet) PathTo(point image.Point) (Path, bool) {
+    point = reachable.normalize(point)
+    if !reachable.Contains(point) {
+        return nil, false
+    }
+
+    out := Path{point}
+    for point != reachable.Start {
+        point = reachable.previous[point]
+        out = append(out, point)
+    }
+
+    slices.Reverse(out)
+    return out, true
+}
+
+// all the points that can be reached, in no particular order
+func (reachable *ReachableSet) Points() []image.Point {
+    out := make([]image.Point, 0, len(reachable.costs))
+    for point := range reachable.costs {
+        out = append(out, point)
+    }
+
+    return out
+}
+
+func (reachable *ReachableSet) Size() int {
+    return len(reachable.costs)
+}
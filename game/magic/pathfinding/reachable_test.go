@@ -0,0 +1,134 @@
+package pathfinding
+
+import (
+    "testing"
+    "image"
+    "math/rand/v2"
+)
+
+func TestReachableCosts(test *testing.T){
+    tiles := makeMap(`
+1223
+8123
+2153
+2111
+`)
+
+    reachable := FindReachable(image.Pt(0, 0), 10, makeTileCost(tiles), makeNeighbors(tiles), nil, nil)
+
+    if cost, ok := reachable.Cost(image.Pt(0, 0)); !ok || cost != 0 {
+        test.Errorf("start should cost 0 but was %v", cost)
+    }
+
+    // same path as TestPath1: 1 + 1 + 1 + 1
+    if cost, ok := reachable.Cost(image.Pt(3, 3)); !ok || cost != 4 {
+        test.Errorf("expected cost 4 to the corner but was %v", cost)
+    }
+
+    path, ok := reachable.PathTo(image.Pt(3, 3))
+    if !ok || len(path) != 5 || path[0] != image.Pt(0, 0) || path[4] != image.Pt(3, 3) {
+        test.Errorf("unexpected path %v", path)
+    }
+
+    // the max cost limits how far the search goes
+    small := FindReachable(image.Pt(0, 0), 1, makeTileCost(tiles), makeNeighbors(tiles), nil, nil)
+    if small.Contains(image.Pt(3, 3)) {
+        test.Errorf("corner should not be reachable with a max cost of 1")
+    }
+
+    // the start and the 1 diagonally below it
+    if small.Size() != 2 {
+        test.Errorf("expected 2 reachable points but got %v", small.Points())
+    }
+}
+
+func TestReachableStop(test *testing.T){
+    tiles := makeMap(`
+1111
+XX1X
+1111
+`)
+
+    // the opening in the wall can be entered but not moved through
+    stop := func(point image.Point) bool {
+        return point == image.Pt(2, 1)
+    }
+
+    reachable := FindReachable(image.Pt(0, 0), 100, makeTileCost(tiles), makeNeighbors(tiles), nil, stop)
+
+    if !reachable.Contains(image.Pt(2, 1)) {
+        test.Errorf("stop point should be reachable")
+    }
+
+    if reachable.Contains(image.Pt(0, 2)) {
+        test.Errorf("points past the stop point should not be reachable")
+    }
+}
+
+func TestReachableNormalize(test *testing.T){
+    // a single row that wraps around
+    width := 5
+    tileCost := func(x1 int, y1 int, x2 int, y2 int) float64 {
+        return 1
+    }
+
+    neighbors := func(x int, y int) []image.Point {
+        return []image.Point{image.Pt(x - 1, y), image.Pt(x + 1, y)}
+    }
+
+    normalize := func(point image.Point) image.Point {
+        return image.Pt(((point.X % width) + width) % width, point.Y)
+    }
+
+    reachable := FindReachable(image.Pt(0, 0), 100, tileCost, neighbors, normalize, nil)
+    if reachable.Size() != width {
+        test.Errorf("expected %v points but got %v", width, reachable.Points())
+    }
+
+    if cost, _ := reachable.Cost(image.Pt(-1, 0)); cost != 1 {
+        test.Errorf("expected to wrap around to the last point with cost 1 but was %v", cost)
+    }
+}
+
+// the reachable set must agree with FindPath on the cost of every point
+func TestReachableMatchesFindPath(test *testing.T){
+    tiles := makeRandomMap(30, 30, 10)
+    tileCost := makeTileCost(tiles)
+    neighbors := makeNeighbors(tiles)
+
+    start := image.Pt(rand.IntN(30), rand.IntN(30))
+    reachable := FindReachable(start, Infinity, tileCost, neighbors, nil, nil)
+
+    for range 20 {
+        end := image.Pt(rand.IntN(30), rand.IntN(30))
+        if end == start {
+            continue
+        }
+
+        path, ok := FindPath(start, end, 100000, tileCost, neighbors, PointEqual)
+        if !ok {
+            test.Errorf("unable to find path")
+            continue
+        }
+
+        pathCost := float64(0)
+        for i := 1; i < len(path); i++ {
+            pathCost += tileCost(path[i-1].X, path[i-1].Y, path[i].X, path[i].Y)
+        }
+
+        cost, ok := reachable.Cost(end)
+        if !ok || cost != pathCost {
+            test.Errorf("reachable cost %v to %v does not match path cost %v", cost, end, pathCost)
+        }
+    }
+}
+
+func BenchmarkReachableLarge(bench *testing.B){
+    tiles := makeRandomMap(100, 100, 10)
+    tileCost := makeTileCost(tiles)
+    neighbors := makeNeighbors(tiles)
+    bench.ResetTimer()
+    for bench.Loop() {
+        FindReachable(image.Pt(0, 0), Infinity, tileCost, neighbors, nil, nil)
+    }
+}
@@ -0,0 +1,282 @@
+package player
+
+import (
+    "slices"
+    "cmp"
+
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+)
+
+/* the economy report breaks the empire's income down into where it comes from and where it goes. each
+ * city has its own lines, and the empire has lines for the things that don't belong to a city such as
+ * unit upkeep. every line comes from the same calculation the rest of the game uses for that part of
+ * the total, so the lines add up to the totals without any adjustment.
+ */
+
+type EconomyCategory int
+
+const (
+    EconomyGold EconomyCategory = iota
+    EconomyFood
+    EconomyProduction
+    EconomyPower
+    EconomyMana
+    EconomyResearch
+)
+
+var EconomyCategories = []EconomyCategory{EconomyGold, EconomyFood, EconomyProduction, EconomyPower, EconomyMana, EconomyResearch}
+
+func (category EconomyCategory) String() string {
+    switch category {
+        case EconomyGold: return "Gold"
+        case EconomyFood: return "Food"
+        case EconomyProduction: return "Production"
+        case EconomyPower: return "Power"
+        case EconomyMana: return "Mana"
+        case EconomyResearch: return "Research"
+    }
+
+    return "?"
+}
+
+// a source if positive, a sink if negative
+type EconomyLine struct {
+    Name string
+    Amount int
+}
+
+type EconomyLines map[EconomyCategory][]EconomyLine
+
+func (lines EconomyLines) Total(category EconomyCategory) int {
+    total := 0
+    for _, line := range lines[category] {
+        total += line.Amount
+    }
+    return total
+}
+
+// add a line unless it is zero
+func (lines EconomyLines) add(category EconomyCategory, name string, amount int) {
+    if amount != 0 {
+        lines[category] = append(lines[category], EconomyLine{Name: name, Amount: amount})
+    }
+}
+
+/* lines for a total that the game computes with fractions. each line carries its fraction over to the
+ * next one, so the lines add up to the total rounded down the same way the game rounds it
+ */
+type fractionalLines[T float32 | float64] struct {
+    Lines EconomyLines
+    Category EconomyCategory
+    total T
+    shown int
+}
+
+func makeFractionalLines[T float32 | float64](lines EconomyLines, category EconomyCategory, start int) *fractionalLines[T] {
+    return &fractionalLines[T]{
+        Lines: lines,
+        Category: category,
+        total: T(start),
+        shown: start,
+    }
+}
+
+func (lines *fractionalLines[T]) add(name string, amount T) {
+    lines.total += amount
+    lines.show(name)
+}
+
+func (lines *fractionalLines[T]) multiply(name string, factor T) {
+    lines.total *= factor
+    lines.show(name)
+}
+
+func (lines *fractionalLines[T]) set(name string, total T) {
+    lines.total = total
+    lines.show(name)
+}
+
+func (lines *fractionalLines[T]) show(name string) {
+    amount := int(lines.total) - lines.shown
+    lines.shown += amount
+    lines.Lines.add(lines.Category, name, amount)
+}
+
+// where the power of a wizard comes from, see GameModel.ComputePowerSources
+type PowerSources struct {
+    // the power of all the cities of the wizard
+    Cities int
+    // melded magic nodes, with conjunctions and retorts applied
+    Nodes float64
+    Volcanoes int
+    // the mana short event takes away all power
+    ManaShort bool
+}
+
+func (sources PowerSources) Total() int {
+    if sources.ManaShort {
+        return 0
+    }
+
+    power := float64(sources.Cities) + sources.Nodes + float64(sources.Volcanoes)
+    if power < 0 {
+        power = 0
+    }
+
+    return int(power)
+}
+
+type CityEconomy struct {
+    City *citylib.City
+    Lines EconomyLines
+}
+
+type EconomyReport struct {
+    Cities []CityEconomy
+    // lines that don't belong to any city
+    Empire EconomyLines
+
+    Gold int
+    Mana int
+}
+
+// the total of a category over all cities and the empire, which is the projected change for next turn
+func (report *EconomyReport) Total(category EconomyCategory) int {
+    total := report.Empire.Total(category)
+    for _, city := range report.Cities {
+        total += city.Lines.Total(category)
+    }
+    return total
+}
+
+func makeCityEconomy(city *citylib.City) CityEconomy {
+    lines := make(EconomyLines)
+
+    lines.add(EconomyGold, "Taxes", city.GoldTaxation())
+    lines.add(EconomyGold, "Trade Goods", city.GoldTradeGoods())
+    lines.add(EconomyGold, "Minerals", city.GoldMinerals())
+    lines.add(EconomyGold, "Marketplace", city.GoldMarketplace())
+    lines.add(EconomyGold, "Bank", city.GoldBank())
+    lines.add(EconomyGold, "Merchants Guild", city.GoldMerchantsGuild())
+    lines.add(EconomyGold, "Roads and Rivers", city.GoldBonus(city.ComputeTotalBonusPercent()))
+    lines.add(EconomyGold, "Prosperity", city.GoldProsperity())
+    lines.add(EconomyGold, "Difficulty", city.GoldDifficulty())
+    lines.add(EconomyGold, "Building Upkeep", -city.ComputeUpkeep())
+
+    lines.add(EconomyFood, "Farmers", city.FoodFarmers())
+    lines.add(EconomyFood, "Wild Game", city.ComputeWildGame())
+    lines.add(EconomyFood, "Granary", city.FoodGranary())
+    lines.add(EconomyFood, "Farmers Market", city.FoodFarmersMarket())
+    lines.add(EconomyFood, "Foresters Guild", city.FoodForestersGuild())
+    lines.add(EconomyFood, "Famine", city.FoodFamine())
+    lines.add(EconomyFood, "Citizens", -city.RequiredFood())
+    // food carried between cities by the food logistics rule, which adds up to zero over the empire
+    if city.FoodImported > 0 {
+        lines.add(EconomyFood, "Imported", city.FoodImported)
+    } else {
+        lines.add(EconomyFood, "Exported", city.FoodImported)
+    }
+
+    // in the same order as the city adds them up, so the fractions come out the same
+    production := makeFractionalLines[float32](lines, EconomyProduction, 0)
+    production.add("Workers", city.ProductionWorkers())
+    production.add("Farmers", city.ProductionFarmers())
+    production.add("Miners Guild", city.ProductionMinersGuild())
+    production.add("Mechanicians Guild", city.ProductionMechaniciansGuild())
+    production.add("Terrain", city.ProductionTerrain())
+    production.add("Sawmill", city.ProductionSawmill())
+    production.add("Foresters Guild", city.ProductionForestersGuild())
+    production.add("Inspirations", city.ProductionInspirations())
+    if city.HasEnchantment(data.CityEnchantmentCursedLands) {
+        production.multiply("Cursed Lands", 0.5)
+    }
+    production.multiply("Difficulty", float32(city.ReignProvider.GetDifficultyModifiers().Production))
+
+    religious := city.PowerShrine() + city.PowerTemple() + city.PowerParthenon() + city.PowerCathedral() + city.PowerDarkRituals()
+
+    lines.add(EconomyPower, "Citizens", city.PowerCitizens())
+    lines.add(EconomyPower, "Minerals", city.PowerMinerals())
+    lines.add(EconomyPower, "Fortress", city.PowerFortress())
+    lines.add(EconomyPower, "Alchemists Guild", city.PowerAlchemistsGuild())
+    lines.add(EconomyPower, "Wizards Guild", city.PowerWizardsGuild())
+    lines.add(EconomyPower, "Religious Buildings", int(religious))
+
+    buildings := city.Buildings.Values()
+    slices.Sort(buildings)
+    for _, building := range buildings {
+        lines.add(EconomyResearch, city.BuildingInfo.Name(building), city.BuildingInfo.ResearchProduction(building))
+    }
+
+    return CityEconomy{
+        City: city,
+        Lines: lines,
+    }
+}
+
+/* the economy of the whole empire. the power comes from the cities, which the report already has, and
+ * from the sources outside of cities that the game computes
+ */
+func (player *Player) MakeEconomyReport(powerSources PowerSources, cityEnchantmentsProvider CityEnchantmentsProvider) *EconomyReport {
+    report := &EconomyReport{
+        Empire: make(EconomyLines),
+        Gold: player.Gold,
+        Mana: player.Mana,
+    }
+
+    for _, city := range player.Cities {
+        report.Cities = append(report.Cities, makeCityEconomy(city))
+    }
+
+    slices.SortFunc(report.Cities, func(a CityEconomy, b CityEconomy) int {
+        return cmp.Compare(a.City.Name, b.City.Name)
+    })
+
+    cityTotal := func(category EconomyCategory) int {
+        total := 0
+        for _, city := range report.Cities {
+            total += city.Lines.Total(category)
+        }
+        return total
+    }
+
+    empire := report.Empire
+
+    empire.add(EconomyGold, "Unit Upkeep", -player.TotalUnitUpkeepGold())
+    empire.add(EconomyGold, "Noble Heroes", 10 * player.GetNobleHeroes())
+    empire.add(EconomyGold, "Surplus Food", player.FoodPerTurn() / 2)
+    empire.add(EconomyGold, "Trade Routes", player.TradeIncome)
+
+    empire.add(EconomyFood, "Unit Upkeep", -player.TotalUnitUpkeepFood())
+
+    powerLines := makeFractionalLines[float64](empire, EconomyPower, cityTotal(EconomyPower))
+    powerLines.add("Magic Nodes", powerSources.Nodes)
+    powerLines.add("Volcanoes", float64(powerSources.Volcanoes))
+    if powerLines.total < 0 {
+        powerLines.set("Lower Limit", 0)
+    }
+    if powerSources.ManaShort {
+        powerLines.set("Mana Short", 0)
+    }
+
+    power := report.Total(EconomyPower)
+
+    empire.add(EconomyMana, "Power", player.ManaFromPower(power))
+    empire.add(EconomyMana, "Unit Upkeep", -player.TotalUnitUpkeepMana())
+    empire.add(EconomyMana, "Global Enchantments", -player.GlobalEnchantmentUpkeep())
+    empire.add(EconomyMana, "City Enchantments", -player.CityEnchantmentUpkeep(cityEnchantmentsProvider))
+
+    // time stop stops all income
+    if player.HasEnchantment(data.EnchantmentTimeStop) {
+        for _, category := range []EconomyCategory{EconomyGold, EconomyFood, EconomyMana} {
+            empire.add(category, "Time Stop", -report.Total(category))
+        }
+    }
+
+    research := makeFractionalLines[float64](empire, EconomyResearch, cityTotal(EconomyResearch))
+    research.add("Heroes", float64(player.HeroResearch()))
+    research.add("Power", float64(power) * player.PowerDistribution.Research)
+    research.multiply("Difficulty", player.GetDifficultyModifiers().Research)
+
+    return report
+}
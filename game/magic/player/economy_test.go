@@ -0,0 +1,255 @@
+package player
+
+import (
+    "testing"
+    "image"
+
+    "github.com/kazzmir/master-of-magic/game/magic/setup"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/hero"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/spellbook"
+    buildinglib "github.com/kazzmir/master-of-magic/game/magic/building"
+    citylib "github.com/kazzmir/master-of-magic/game/magic/city"
+    "github.com/kazzmir/master-of-magic/lib/set"
+)
+
+// a catchment area of grassland
+type grassCatchment struct {
+}
+
+func (catchment *grassCatchment) GetCatchmentArea(x int, y int) map[image.Point]maplib.FullTile {
+    out := make(map[image.Point]maplib.FullTile)
+    for dx := -2; dx <= 2; dx++ {
+        for dy := -2; dy <= 2; dy++ {
+            out[image.Pt(x + dx, y + dy)] = maplib.FullTile{Tile: terrain.TileGrasslands1}
+        }
+    }
+    return out
+}
+
+func (catchment *grassCatchment) GetGoldBonus(x int, y int) int {
+    return 0
+}
+
+func (catchment *grassCatchment) OnShore(x int, y int) bool {
+    return false
+}
+
+func (catchment *grassCatchment) ByRiver(x int, y int) bool {
+    return false
+}
+
+func (catchment *grassCatchment) TileDistance(x1 int, y1 int, x2 int, y2 int) int {
+    return max(x2 - x1, x1 - x2, y2 - y1, y1 - y2)
+}
+
+type noCityServices struct {
+}
+
+func (services *noCityServices) FindRoadConnectedCities(city *citylib.City) []*citylib.City {
+    return nil
+}
+
+func (services *noCityServices) GoodMoonActive() bool {
+    return false
+}
+
+func (services *noCityServices) BadMoonActive() bool {
+    return false
+}
+
+func (services *noCityServices) PopulationBoomActive(city *citylib.City) bool {
+    return false
+}
+
+func (services *noCityServices) PlagueActive(city *citylib.City) bool {
+    return false
+}
+
+func (services *noCityServices) FoodLogistics() bool {
+    return false
+}
+
+func (services *noCityServices) GetAllGlobalEnchantments() map[data.BannerType]*set.Set[data.Enchantment] {
+    return make(map[data.BannerType]*set.Set[data.Enchantment])
+}
+
+func (services *noCityServices) GetSpellByName(name string) spellbook.Spell {
+    return spellbook.Spell{}
+}
+
+func makeEconomyPlayer(human bool) *Player {
+    player := MakePlayer(setup.WizardCustom{Banner: data.BannerRed}, human, 1, 1, make(map[hero.HeroType]string), &NoGlobalEnchantments{})
+    player.DifficultyProvider = &fixedDifficulty{Difficulty: data.DifficultyHard}
+    player.AddEnchantment(data.EnchantmentAwareness)
+    player.PowerDistribution = PowerDistribution{Mana: 0.5, Research: 0.5}
+    player.Gold = 100
+    player.Mana = 50
+    return player
+}
+
+func makeEconomyCity(player *Player) *citylib.City {
+    city := citylib.MakeCity("xyz", 5, 5, data.RaceHighMen, nil, &grassCatchment{}, &noCityServices{}, player)
+    city.BuildingInfo = make([]buildinglib.BuildingInfo, 40)
+    city.Population = 5000
+    city.Farmers = 4
+    city.Workers = 1
+    city.Buildings.Insert(buildinglib.BuildingGranary)
+    city.Buildings.Insert(buildinglib.BuildingFarmersMarket)
+    city.Buildings.Insert(buildinglib.BuildingForestersGuild)
+    city.Buildings.Insert(buildinglib.BuildingSawmill)
+    city.Buildings.Insert(buildinglib.BuildingTemple)
+    player.AddCity(city)
+    return city
+}
+
+func findLine(lines []EconomyLine, name string) int {
+    for _, line := range lines {
+        if line.Name == name {
+            return line.Amount
+        }
+    }
+    return 0
+}
+
+// the lines of the report should add up to the same totals the rest of the game uses
+func TestEconomyReportTotals(test *testing.T) {
+    for _, human := range []bool{true, false} {
+        player := makeEconomyPlayer(human)
+        city := makeEconomyCity(player)
+
+        sources := PowerSources{Cities: city.ComputePower(), Nodes: 7.5, Volcanoes: 2}
+        power := sources.Total()
+        enchantments := &noCityEnchantments{}
+
+        report := player.MakeEconomyReport(sources, enchantments)
+
+        if report.Total(EconomyGold) != player.GoldPerTurn() {
+            test.Errorf("gold should total %v but was %v", player.GoldPerTurn(), report.Total(EconomyGold))
+        }
+
+        if report.Total(EconomyFood) != player.FoodPerTurn() {
+            test.Errorf("food should total %v but was %v", player.FoodPerTurn(), report.Total(EconomyFood))
+        }
+
+        if report.Cities[0].Lines.Total(EconomyProduction) != int(city.WorkProductionRate()) {
+            test.Errorf("production should total %v but was %v", int(city.WorkProductionRate()), report.Cities[0].Lines.Total(EconomyProduction))
+        }
+
+        if report.Total(EconomyMana) != player.ManaPerTurn(power, enchantments) {
+            test.Errorf("mana should total %v but was %v", player.ManaPerTurn(power, enchantments), report.Total(EconomyMana))
+        }
+
+        if report.Total(EconomyResearch) != int(player.SpellResearchPerTurn(power)) {
+            test.Errorf("research should total %v but was %v", int(player.SpellResearchPerTurn(power)), report.Total(EconomyResearch))
+        }
+
+        if report.Total(EconomyPower) != power {
+            test.Errorf("power should total %v but was %v", power, report.Total(EconomyPower))
+        }
+
+        for _, line := range report.Empire[EconomyMana] {
+            if line.Name == "Global Enchantments" && line.Amount >= 0 {
+                test.Errorf("the enchantment upkeep should be a sink: %v", line)
+            }
+        }
+    }
+}
+
+func TestEconomyReportFoodLines(test *testing.T) {
+    player := makeEconomyPlayer(true)
+    city := makeEconomyCity(player)
+    city.AddEnchantment(data.CityEnchantmentFamine, data.BannerBlue)
+
+    report := player.MakeEconomyReport(PowerSources{Cities: city.ComputePower()}, &noCityEnchantments{})
+    food := report.Cities[0].Lines[EconomyFood]
+
+    // 4 farmers grow 2 food each, which the grassland can support even under famine
+    expected := map[string]int{
+        "Farmers": 8,
+        "Granary": 2,
+        "Farmers Market": 3,
+        "Foresters Guild": 2,
+        // famine halves the 15 food the city would make
+        "Famine": -8,
+        "Citizens": -5,
+    }
+
+    for name, amount := range expected {
+        if findLine(food, name) != amount {
+            test.Errorf("food line %v should be %v but was %v", name, amount, findLine(food, name))
+        }
+    }
+
+    if findLine(food, "Wild Game") != 0 {
+        test.Errorf("grassland has no wild game")
+    }
+
+    if report.Cities[0].Lines.Total(EconomyFood) != city.SurplusFood() {
+        test.Errorf("food should total %v but was %v", city.SurplusFood(), report.Cities[0].Lines.Total(EconomyFood))
+    }
+}
+
+func TestEconomyReportGoldLines(test *testing.T) {
+    human := makeEconomyPlayer(true)
+    ai := makeEconomyPlayer(false)
+
+    humanCity := makeEconomyCity(human)
+    aiCity := makeEconomyCity(ai)
+
+    humanReport := human.MakeEconomyReport(PowerSources{}, &noCityEnchantments{})
+    aiReport := ai.MakeEconomyReport(PowerSources{}, &noCityEnchantments{})
+
+    humanGold := humanReport.Cities[0].Lines[EconomyGold]
+    aiGold := aiReport.Cities[0].Lines[EconomyGold]
+
+    if findLine(humanGold, "Taxes") == 0 || findLine(humanGold, "Taxes") != findLine(aiGold, "Taxes") {
+        test.Errorf("both wizards should collect the same taxes: %v %v", findLine(humanGold, "Taxes"), findLine(aiGold, "Taxes"))
+    }
+
+    if findLine(humanGold, "Difficulty") != 0 {
+        test.Errorf("a human wizard should have no difficulty line")
+    }
+
+    modifiers := ai.GetDifficultyModifiers()
+    income := aiCity.GoldIncome()
+    if findLine(aiGold, "Difficulty") != int(float64(income) * modifiers.Gold) - income {
+        test.Errorf("difficulty line should be %v but was %v", int(float64(income) * modifiers.Gold) - income, findLine(aiGold, "Difficulty"))
+    }
+
+    if humanReport.Cities[0].Lines.Total(EconomyGold) != humanCity.GoldSurplus() || aiReport.Cities[0].Lines.Total(EconomyGold) != aiCity.GoldSurplus() {
+        test.Errorf("gold lines should add up to the surplus of each city")
+    }
+}
+
+func TestEconomyReportPowerLines(test *testing.T) {
+    player := makeEconomyPlayer(true)
+    city := makeEconomyCity(player)
+
+    sources := PowerSources{Cities: city.ComputePower(), Nodes: 7.5, Volcanoes: 2}
+    report := player.MakeEconomyReport(sources, &noCityEnchantments{})
+
+    if findLine(report.Empire[EconomyPower], "Magic Nodes") != 7 || findLine(report.Empire[EconomyPower], "Volcanoes") != 2 {
+        test.Errorf("unexpected power lines: %v", report.Empire[EconomyPower])
+    }
+
+    if findLine(report.Cities[0].Lines[EconomyPower], "Religious Buildings") != int(city.PowerTemple()) {
+        test.Errorf("the temple should give %v power: %v", int(city.PowerTemple()), report.Cities[0].Lines[EconomyPower])
+    }
+
+    sources.ManaShort = true
+    report = player.MakeEconomyReport(sources, &noCityEnchantments{})
+    if report.Total(EconomyPower) != 0 || findLine(report.Empire[EconomyPower], "Mana Short") >= 0 {
+        test.Errorf("mana short should take away all power: %v", report.Empire[EconomyPower])
+    }
+
+    player.AddEnchantment(data.EnchantmentTimeStop)
+    report = player.MakeEconomyReport(PowerSources{Cities: city.ComputePower()}, &noCityEnchantments{})
+    for _, category := range []EconomyCategory{EconomyGold, EconomyFood, EconomyMana} {
+        if report.Total(category) != 0 {
+            test.Errorf("time stop should stop all %v but was %v", category, report.Total(category))
+        }
+    }
+}
@@ -0,0 +1,140 @@
+package player
+
+import (
+    "image"
+)
+
+/* standing orders keep a stack busy over several turns without clicking its destination again every
+ * turn: follow a route of waypoints, patrol back and forth between two points, go to a city, or stay on
+ * sentry until an enemy comes into view. the game turns the next leg of the orders into CurrentPath
+ * whenever the stack has no path left, so a path that got blocked is simply found again.
+ */
+
+type OrderKind int
+
+const (
+    // visit each waypoint in turn
+    OrderRoute OrderKind = iota
+    // move between the two waypoints until told otherwise
+    OrderPatrol
+    // the only waypoint is a city
+    OrderGotoCity
+    // wait until an enemy is seen
+    OrderSentry
+)
+
+func (kind OrderKind) String() string {
+    switch kind {
+        case OrderRoute: return "route"
+        case OrderPatrol: return "patrol"
+        case OrderGotoCity: return "goto city"
+        case OrderSentry: return "sentry"
+    }
+
+    return "unknown"
+}
+
+func orderKindFromString(name string) OrderKind {
+    for _, kind := range []OrderKind{OrderRoute, OrderPatrol, OrderGotoCity, OrderSentry} {
+        if kind.String() == name {
+            return kind
+        }
+    }
+
+    return OrderRoute
+}
+
+type StackOrders struct {
+    Kind OrderKind
+    Waypoints []image.Point
+    // the index of the waypoint the stack is moving to
+    Next int
+
+    // how many legs were started on Turn, so that a patrol over enchanted roads that cost nothing
+    // can't go back and forth forever in one turn
+    Turn uint64
+    Legs int
+}
+
+func MakeRouteOrders(waypoints []image.Point) *StackOrders {
+    return &StackOrders{
+        Kind: OrderRoute,
+        Waypoints: waypoints,
+    }
+}
+
+// the stack starts at from and moves to to first
+func MakePatrolOrders(from image.Point, to image.Point) *StackOrders {
+    return &StackOrders{
+        Kind: OrderPatrol,
+        Waypoints: []image.Point{from, to},
+        Next: 1,
+    }
+}
+
+func MakeGotoCityOrders(city image.Point) *StackOrders {
+    return &StackOrders{
+        Kind: OrderGotoCity,
+        Waypoints: []image.Point{city},
+    }
+}
+
+func MakeSentryOrders() *StackOrders {
+    return &StackOrders{
+        Kind: OrderSentry,
+    }
+}
+
+// the waypoint the stack is moving to, false if there is nowhere to go
+func (orders *StackOrders) Target() (image.Point, bool) {
+    if orders == nil || orders.Kind == OrderSentry || orders.Next >= len(orders.Waypoints) {
+        return image.Point{}, false
+    }
+
+    return orders.Waypoints[orders.Next], true
+}
+
+// move on to the next waypoint after reaching the current one. returns false if the orders are done
+func (orders *StackOrders) Arrived() bool {
+    switch orders.Kind {
+        case OrderPatrol:
+            orders.Next = 1 - orders.Next
+            return true
+        case OrderSentry:
+            return true
+    }
+
+    orders.Next += 1
+    return orders.Next < len(orders.Waypoints)
+}
+
+// the waypoints to visit after the current one, in order. a patrol ends with the waypoint it goes back to
+func (orders *StackOrders) Remaining() []image.Point {
+    if orders == nil || orders.Next >= len(orders.Waypoints) {
+        return nil
+    }
+
+    switch orders.Kind {
+        case OrderPatrol:
+            return []image.Point{orders.Waypoints[1 - orders.Next]}
+        case OrderSentry:
+            return nil
+    }
+
+    return orders.Waypoints[orders.Next + 1:]
+}
+
+// count a new leg of the orders, returns false if the orders already used up their legs for this turn
+func (orders *StackOrders) StartLeg(turn uint64) bool {
+    if orders.Turn != turn {
+        orders.Turn = turn
+        orders.Legs = 0
+    }
+
+    if orders.Legs >= max(2, len(orders.Waypoints)) {
+        return false
+    }
+
+    orders.Legs += 1
+    return true
+}
@@ -0,0 +1,91 @@
+package player
+
+import (
+    "testing"
+    "image"
+)
+
+func TestRouteOrders(test *testing.T) {
+    orders := MakeRouteOrders([]image.Point{image.Pt(1, 1), image.Pt(2, 2), image.Pt(3, 3)})
+
+    target, ok := orders.Target()
+    if !ok || target != image.Pt(1, 1) {
+        test.Errorf("first target should be 1, 1 but was %v %v", target, ok)
+    }
+
+    if len(orders.Remaining()) != 2 {
+        test.Errorf("expected 2 remaining waypoints but got %v", orders.Remaining())
+    }
+
+    if !orders.Arrived() || !orders.Arrived() {
+        test.Errorf("route should not be done yet")
+    }
+
+    target, _ = orders.Target()
+    if target != image.Pt(3, 3) {
+        test.Errorf("last target should be 3, 3 but was %v", target)
+    }
+
+    if orders.Arrived() {
+        test.Errorf("route should be done")
+    }
+
+    _, ok = orders.Target()
+    if ok {
+        test.Errorf("finished route should not have a target")
+    }
+}
+
+func TestPatrolOrders(test *testing.T) {
+    orders := MakePatrolOrders(image.Pt(0, 0), image.Pt(5, 0))
+
+    for i := range 4 {
+        target, ok := orders.Target()
+        expected := image.Pt(5, 0)
+        if i % 2 == 1 {
+            expected = image.Pt(0, 0)
+        }
+
+        if !ok || target != expected {
+            test.Errorf("patrol leg %v should go to %v but went to %v", i, expected, target)
+        }
+
+        if !orders.Arrived() {
+            test.Errorf("patrol should never be done")
+        }
+    }
+}
+
+func TestOrdersLegsPerTurn(test *testing.T) {
+    orders := MakePatrolOrders(image.Pt(0, 0), image.Pt(5, 0))
+
+    if !orders.StartLeg(1) || !orders.StartLeg(1) {
+        test.Errorf("should be able to start two legs")
+    }
+
+    if orders.StartLeg(1) {
+        test.Errorf("should not be able to start a third leg in the same turn")
+    }
+
+    if !orders.StartLeg(2) {
+        test.Errorf("should be able to start a leg on the next turn")
+    }
+}
+
+func TestSentryOrders(test *testing.T) {
+    var nothing *StackOrders
+    _, ok := nothing.Target()
+    if ok {
+        test.Errorf("nil orders should not have a target")
+    }
+
+    orders := MakeSentryOrders()
+    _, ok = orders.Target()
+    if ok {
+        test.Errorf("sentry should not have a target")
+    }
+
+    if len(orders.Remaining()) != 0 {
+        test.Errorf("sentry should not have waypoints")
+    }
+}
@@ -38,6 +38,8 @@ type AIProduceDecision struct {
     City *citylib.City
     Building buildinglib.Building
     Unit units.Unit
+    // add to the end of the production queue rather than replacing what the city is producing
+    Enqueue bool
 }
 
 // request the city to have the given number of farmers and workers, with farmers taking precedence
@@ -138,6 +140,8 @@ type AIServices interface {
     CityEnchantmentsProvider
 
     FindPath(oldX int, oldY int, newX int, newY int, player *Player, stack PathStack, fog data.FogMap) (pathfinding.Path, bool)
+    // every tile the stack can reach, for choosing between many destinations with a single search
+    FindReachable(x int, y int, player *Player, stack PathStack, fog data.FogMap, maxCost float64) *pathfinding.ReachableSet
     FindSettlableLocations(x int, y int, plane data.Plane, fog data.FogMap) []image.Point
     IsSettlableLocation(x int, y int, plane data.Plane) bool
     GetDifficulty() data.DifficultySetting
@@ -156,6 +160,9 @@ type AIServices interface {
     ComputeCityStackInfo() CityStackInfo
     GetEnemies(player *Player) []*Player
     GetBuildingInfos() buildinglib.BuildingInfos
+
+    // the land controlled by each wizard, to find borders and room to expand
+    GetTerritory(plane data.Plane) *maplib.Territory
 }
 
 type AIBehavior interface {
@@ -300,6 +307,11 @@ func (*NoGlobalEnchantments) HasRivalEnchantment(player *Player, enchantment dat
     return false
 }
 
+// gives access to the difficulty level of the game, implemented by the game model
+type DifficultyProvider interface {
+    GetDifficulty() data.DifficultySetting
+}
+
 type WizardPower struct {
     Army int
     Magic int
@@ -357,6 +369,9 @@ type Player struct {
     // to get enchantments owned by any player
     GlobalEnchantmentsProvider GlobalEnchantmentsProvider
 
+    // used to look up the AI bonuses for the current difficulty. can be nil, in which case there are no bonuses
+    DifficultyProvider DifficultyProvider
+
     PowerDistribution PowerDistribution
 
     AIBehavior AIBehavior
@@ -403,6 +418,9 @@ type Player struct {
     Stacks []*UnitStack
     Cities map[data.PlanePoint]*citylib.City
 
+    // gold earned from trade routes each turn, kept up to date by the game when trade routes are enabled
+    TradeIncome int
+
     SelectedStack *UnitStack
     MovedStacksThisTurn int
     // set when the player declines the end-of-turn disband warning, so the
@@ -824,6 +842,8 @@ func (player *Player) TotalUnitUpkeepGold() int {
         total += unit.GetUpkeepGold()
     }
 
+    total = int(float64(total) * player.GetDifficultyModifiers().Upkeep)
+
     total -= player.GetFame()
     if total < 0 {
         total = 0
@@ -854,7 +874,7 @@ func (player *Player) TotalUnitUpkeepMana() int {
         }
     }
 
-    return total
+    return int(float64(total) * player.GetDifficultyModifiers().Upkeep)
 }
 
 func (player *Player) LearnSpell(spell spellbook.Spell) {
@@ -1130,10 +1150,17 @@ func (player *Player) BaseResearchPerTurn() float64 {
         research += float64(city.ResearchProduction())
     }
 
-    // add in sage heroes
+    research += float64(player.HeroResearch())
+
+    return research
+}
+
+// research from sage heroes
+func (player *Player) HeroResearch() int {
+    research := 0
     for _, hero := range player.Heroes {
         if hero != nil && hero.Status == herolib.StatusEmployed {
-            research += float64(hero.GetAbilityResearch())
+            research += hero.GetAbilityResearch()
         }
     }
 
@@ -1142,7 +1169,7 @@ func (player *Player) BaseResearchPerTurn() float64 {
 
 // this returns the raw research production per turn, not accounting for retorts or spellbooks
 func (player *Player) SpellResearchPerTurn(power int) float64 {
-    return player.BaseResearchPerTurn() + float64(power) * player.PowerDistribution.Research
+    return (player.BaseResearchPerTurn() + float64(power) * player.PowerDistribution.Research) * player.GetDifficultyModifiers().Research
 }
 
 func (player *Player) ComputeTurnsToCast(cost int) int {
@@ -1176,6 +1203,8 @@ func (player *Player) GoldPerTurn() int {
 
     gold += player.FoodPerTurn() / 2
 
+    gold += player.TradeIncome
+
     return gold
 }
 
@@ -1209,17 +1238,27 @@ func (player *Player) FoodPerTurn() int {
 
 // the total mana upkeep from global enchantments and city enchantments (but not unit enchantments)
 func (player *Player) TotalEnchantmentUpkeep(cityEnchantmentsProvider CityEnchantmentsProvider) int {
+    return player.GlobalEnchantmentUpkeep() + player.CityEnchantmentUpkeep(cityEnchantmentsProvider)
+}
+
+func (player *Player) GlobalEnchantmentUpkeep() int {
     upkeep := 0
 
     for _, enchantment := range player.GlobalEnchantments.Values() {
         upkeep += enchantment.UpkeepMana()
     }
 
+    return int(float64(upkeep) * player.GetDifficultyModifiers().Upkeep)
+}
+
+func (player *Player) CityEnchantmentUpkeep(cityEnchantmentsProvider CityEnchantmentsProvider) int {
+    upkeep := 0
+
     for _, cityEnchanment := range cityEnchantmentsProvider.GetCityEnchantmentsByBanner(player.GetBanner()) {
         upkeep += cityEnchanment.Enchantment.Enchantment.UpkeepMana()
     }
 
-    return upkeep
+    return int(float64(upkeep) * player.GetDifficultyModifiers().Upkeep)
 }
 
 func (player *Player) ManaPerTurn(power int, cityEnchantmentsProvider CityEnchantmentsProvider) int {
@@ -1232,15 +1271,20 @@ func (player *Player) ManaPerTurn(power int, cityEnchantmentsProvider CityEnchan
     mana -= player.TotalUnitUpkeepMana()
     mana -= player.TotalEnchantmentUpkeep(cityEnchantmentsProvider)
 
+    mana += player.ManaFromPower(power)
+
+    return mana
+}
+
+// the part of the power that goes to mana
+func (player *Player) ManaFromPower(power int) int {
     manaFocusingBonus := float64(1)
 
     if player.Wizard.RetortEnabled(data.RetortManaFocusing) {
         manaFocusingBonus = 1.25
     }
 
-    mana += int(math.Round(float64(power) * player.PowerDistribution.Mana * manaFocusingBonus))
-
-    return mana
+    return int(math.Round(float64(power) * player.PowerDistribution.Mana * manaFocusingBonus * player.GetDifficultyModifiers().Mana))
 }
 
 func (player *Player) UpdateTaxRate(rate fraction.Fraction){
@@ -1532,6 +1576,9 @@ func (player *Player) MergeStacks(stack1 *UnitStack, stack2 *UnitStack) *UnitSta
         player.SelectedStack = stack1
     }
 
+    // the merged stack waits for new orders
+    stack1.Orders = nil
+
     return stack1
 }
 
@@ -1668,6 +1715,15 @@ func (player *Player) GetTaxRate() fraction.Fraction {
     return player.TaxRate
 }
 
+// AI wizards get economic bonuses based on the difficulty, while humans and the neutral player play by the normal rules
+func (player *Player) GetDifficultyModifiers() data.DifficultyModifiers {
+    if player.IsHuman() || player.IsNeutral() || player.DifficultyProvider == nil {
+        return data.NoDifficultyModifiers()
+    }
+
+    return player.DifficultyProvider.GetDifficulty().AIModifiers()
+}
+
 func (player *Player) GetAllCatchmentArea() *set.Set[data.PlanePoint] {
     catchment := set.MakeSet[data.PlanePoint]()
 
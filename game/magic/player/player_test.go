@@ -148,3 +148,80 @@ func TestResearchPool(test *testing.T) {
         test.Errorf("Research pool should have one spell in it, but had %d", len(player.ResearchPoolSpells.Spells))
     }
 }
+
+type fixedDifficulty struct {
+    Difficulty data.DifficultySetting
+}
+
+func (difficulty *fixedDifficulty) GetDifficulty() data.DifficultySetting {
+    return difficulty.Difficulty
+}
+
+type noCityEnchantments struct {
+}
+
+func (provider *noCityEnchantments) GetCityEnchantmentsByBanner(banner data.BannerType) []CityEnchantment {
+    return nil
+}
+
+// each difficulty multiplier should be applied exactly once to an AI wizard, and never to humans or the neutral player
+func TestDifficultyModifiers(test *testing.T) {
+    makeTestPlayer := func(human bool, banner data.BannerType) *Player {
+        player := MakePlayer(setup.WizardCustom{Banner: banner}, human, 1, 1, make(map[hero.HeroType]string), &NoGlobalEnchantments{})
+        player.DifficultyProvider = &fixedDifficulty{Difficulty: data.DifficultyHard}
+        player.AddEnchantment(data.EnchantmentAwareness)
+        player.AddEnchantment(data.EnchantmentCharmOfLife)
+        player.PowerDistribution = PowerDistribution{Mana: 0.5, Research: 0.5}
+        return player
+    }
+
+    human := makeTestPlayer(true, data.BannerBlue)
+    ai := makeTestPlayer(false, data.BannerRed)
+    neutral := makeTestPlayer(false, data.BannerBrown)
+
+    modifiers := data.DifficultyHard.AIModifiers()
+
+    if !human.GetDifficultyModifiers().IsNone() {
+        test.Errorf("human player should not get difficulty modifiers")
+    }
+
+    if !neutral.GetDifficultyModifiers().IsNone() {
+        test.Errorf("neutral player should not get difficulty modifiers")
+    }
+
+    if ai.GetDifficultyModifiers() != modifiers {
+        test.Errorf("ai player should get the hard difficulty modifiers")
+    }
+
+    power := 100
+
+    if ai.SpellResearchPerTurn(power) != human.SpellResearchPerTurn(power) * modifiers.Research {
+        test.Errorf("ai research should be %v but was %v", human.SpellResearchPerTurn(power) * modifiers.Research, ai.SpellResearchPerTurn(power))
+    }
+
+    spell := spellbook.Spell{Magic: data.NatureMagic}
+    humanEffective := human.ComputeEffectiveResearchPerTurn(human.SpellResearchPerTurn(power), spell)
+    aiEffective := ai.ComputeEffectiveResearchPerTurn(ai.SpellResearchPerTurn(power), spell)
+    if aiEffective != int(float64(humanEffective) * modifiers.Research) {
+        test.Errorf("effective ai research should be %v but was %v", int(float64(humanEffective) * modifiers.Research), aiEffective)
+    }
+
+    humanUpkeep := human.TotalEnchantmentUpkeep(&noCityEnchantments{})
+    if humanUpkeep != 13 {
+        test.Errorf("human enchantment upkeep should be 13 but was %v", humanUpkeep)
+    }
+
+    aiUpkeep := ai.TotalEnchantmentUpkeep(&noCityEnchantments{})
+    if aiUpkeep != int(13 * modifiers.Upkeep) {
+        test.Errorf("ai enchantment upkeep should be %v but was %v", int(13 * modifiers.Upkeep), aiUpkeep)
+    }
+
+    // 100 power with half going to mana gives 50 mana before upkeep
+    if human.ManaPerTurn(power, &noCityEnchantments{}) != 50 - humanUpkeep {
+        test.Errorf("human mana should be %v but was %v", 50 - humanUpkeep, human.ManaPerTurn(power, &noCityEnchantments{}))
+    }
+
+    if ai.ManaPerTurn(power, &noCityEnchantments{}) != int(50 * modifiers.Mana) - aiUpkeep {
+        test.Errorf("ai mana should be %v but was %v", int(50 * modifiers.Mana) - aiUpkeep, ai.ManaPerTurn(power, &noCityEnchantments{}))
+    }
+}
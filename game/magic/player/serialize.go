@@ -66,6 +66,37 @@ type SerializedUnitStack struct {
     Active []bool `json:"active"`
 
     CurrentPath pathfinding.Path `json:"current-path"`
+    Orders *SerializedStackOrders `json:"orders,omitempty"`
+}
+
+type SerializedStackOrders struct {
+    Kind string `json:"kind"`
+    Waypoints []image.Point `json:"waypoints,omitempty"`
+    Next int `json:"next"`
+}
+
+func serializeOrders(orders *StackOrders) *SerializedStackOrders {
+    if orders == nil {
+        return nil
+    }
+
+    return &SerializedStackOrders{
+        Kind: orders.Kind.String(),
+        Waypoints: orders.Waypoints,
+        Next: orders.Next,
+    }
+}
+
+func reconstructOrders(serialized *SerializedStackOrders) *StackOrders {
+    if serialized == nil {
+        return nil
+    }
+
+    return &StackOrders{
+        Kind: orderKindFromString(serialized.Kind),
+        Waypoints: serialized.Waypoints,
+        Next: serialized.Next,
+    }
 }
 
 type SerializedPlayer struct {
@@ -127,6 +158,7 @@ func serializeStacks(stacks []*UnitStack) []SerializedUnitStack {
     for _, stack := range stacks {
         serializedStack := SerializedUnitStack{
             CurrentPath: stack.CurrentPath,
+            Orders: serializeOrders(stack.Orders),
         }
 
         for _, unitRaw := range stack.units {
@@ -372,6 +404,7 @@ func reconstructStacks(serialized []SerializedUnitStack, heroes [6]*herolib.Hero
         stack := MakeUnitStack()
 
         stack.CurrentPath = serializedStack.CurrentPath
+        stack.Orders = reconstructOrders(serializedStack.Orders)
 
         for i, serializedUnit := range serializedStack.Units {
             if serializedUnit.Unit != nil {
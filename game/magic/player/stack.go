@@ -14,6 +14,7 @@ type ActiveMap map[units.StackUnit]bool
 
 type PathStack interface {
     AllFlyers() bool
+    AllSwimmers() bool
     AnyLandWalkers() bool
     GetBanner() data.BannerType
     Plane() data.Plane
@@ -29,6 +30,8 @@ type UnitStack struct {
     active ActiveMap
 
     CurrentPath pathfinding.Path
+    // standing orders that give the stack a new path when it reaches the end of CurrentPath, can be nil
+    Orders *StackOrders
 
     // non-zero while animating movement on the overworld
     offsetX float64
@@ -495,11 +498,50 @@ func (stack *UnitStack) GetRemainingMoves() fraction.Fraction {
     }
 }
 
+// the movement points the stack starts a turn with, which is the speed of its slowest active unit
+func (stack *UnitStack) GetMovementSpeed() fraction.Fraction {
+    hasSpeed := false
+    speed := fraction.Make(10000, 1)
+    transport := stack.HasSailingUnits(true)
+    for _, unit := range stack.units {
+        // ignore units being transported
+        if transport && unit.IsLandWalker() {
+            continue
+        }
+        if unit.GetBusy() == units.BusyStatusNone && stack.active[unit] && unit.GetMovementSpeed(true).LessThan(speed) {
+            speed = unit.GetMovementSpeed(true)
+            hasSpeed = true
+        }
+    }
+
+    if !hasSpeed {
+        return fraction.Zero()
+    }
+
+    return speed
+}
+
 // true if any unit in the stack has moves left
 func (stack *UnitStack) HasMoves() bool {
     return !stack.OutOfMoves()
 }
 
+// true if the stack could move this turn but has nowhere to go and isn't fortified
+func (stack *UnitStack) IsIdle() bool {
+    return stack.HasMoves() && len(stack.CurrentPath) == 0 && stack.Orders == nil && !stack.IsFortified()
+}
+
+// true if every unit in the stack is patrolling, which keeps them in place
+func (stack *UnitStack) IsFortified() bool {
+    for _, unit := range stack.units {
+        if unit.GetBusy() != units.BusyStatusPatrol {
+            return false
+        }
+    }
+
+    return len(stack.units) > 0
+}
+
 func (stack *UnitStack) GetBanner() data.BannerType {
     if len(stack.units) > 0 {
         return stack.units[0].GetBanner()
@@ -105,3 +105,8 @@ func UnscaleRect(rect image.Rectangle) image.Rectangle {
         Max: rect.Max.Div(int(ScaleAmount)),
     }
 }
+
+// true if the screen is scaled up enough that text printed at half size is still readable
+func HalfSizeReadable() bool {
+    return ScaleAmount >= 2
+}
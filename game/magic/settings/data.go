@@ -14,6 +14,8 @@ type Settings struct {
     EndOfTurnWait bool
     StrategicCombatOnly bool
     RandomEvents bool
+    // let AI wizards see through the fog of war even on the lower difficulties
+    AISeesThroughFog bool
     Keybindings *keybinds.Keybindings
 }
 
@@ -22,6 +24,7 @@ func MakeSettings(cache *lbx.LbxCache) *Settings {
         EndOfTurnWait: true,
         StrategicCombatOnly: false,
         RandomEvents: true,
+        AISeesThroughFog: false,
         Keybindings: keybinds.MakeKeybindings(),
     }
 }
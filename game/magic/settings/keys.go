@@ -175,7 +175,7 @@ func MakeKeysUI(yield coroutine.YieldFunc, parentUI *uilib.UI, cache *lbx.LbxCac
     })
 
     const rowWidth = 150
-    const rowHeight = 9
+    const rowHeight = 8
     const leftX = 10
     const rightX = 165
     const startY = 42
@@ -263,7 +263,7 @@ func MakeKeysUI(yield coroutine.YieldFunc, parentUI *uilib.UI, cache *lbx.LbxCac
      // sits in the empty band between the action rows and the Back/Ok buttons, so
      // a player can restore the original game's default bindings for every action
      // in one click instead of re-pressing each row by hand.
-    group.AddElement(makeActionButton(10, 150, "Reset To Defaults", 180, func() {
+    group.AddElement(makeActionButton(10, 161, "Reset To Defaults", 180, func() {
         keybindings.ResetToDefaults()
      }))
 
@@ -10,6 +10,7 @@ import (
     "github.com/kazzmir/master-of-magic/lib/font"
     "github.com/kazzmir/master-of-magic/lib/coroutine"
     fontslib "github.com/kazzmir/master-of-magic/game/magic/fonts"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
     "github.com/kazzmir/master-of-magic/game/magic/util"
     "github.com/kazzmir/master-of-magic/game/magic/scale"
     uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
@@ -61,7 +62,29 @@ type MusicSettings interface {
     SetMusicEnabled(bool)
 }
 
-func MakeSettingsUI(yield coroutine.YieldFunc, parentUI *uilib.UI, cache *lbx.LbxCache, imageCache *util.ImageCache, settings *Settings, musicSettings MusicSettings) (*uilib.UIElementGroup, context.Context) {
+// implemented by the game model. only available while a game is in progress
+type DifficultyInfo interface {
+    GetDifficulty() data.DifficultySetting
+}
+
+// the lines of text that describe the AI bonuses for the given difficulty
+func difficultyModifierLines(difficulty data.DifficultySetting) []string {
+    modifiers := difficulty.AIModifiers()
+    if modifiers.IsNone() {
+        return []string{"None"}
+    }
+
+    return []string{
+        fmt.Sprintf("Production x%v", modifiers.Production),
+        fmt.Sprintf("Gold x%v", modifiers.Gold),
+        fmt.Sprintf("Research x%v", modifiers.Research),
+        fmt.Sprintf("Mana x%v", modifiers.Mana),
+        fmt.Sprintf("Upkeep x%v", modifiers.Upkeep),
+    }
+}
+
+// difficultyInfo can be nil, such as when the settings are opened from the main menu
+func MakeSettingsUI(yield coroutine.YieldFunc, parentUI *uilib.UI, cache *lbx.LbxCache, imageCache *util.ImageCache, settings *Settings, musicSettings MusicSettings, difficultyInfo DifficultyInfo) (*uilib.UIElementGroup, context.Context) {
     fonts := fontslib.MakeSettingsFonts(cache)
 
     group := uilib.MakeGroup()
@@ -188,5 +211,32 @@ func MakeSettingsUI(yield coroutine.YieldFunc, parentUI *uilib.UI, cache *lbx.Lb
         func(value bool) { settings.RandomEvents = value },
      )
 
+    // on hard and above the AI always sees everything, see DifficultySetting.AICheatsInformation
+    addCheckbox(group, fonts, &getAlpha, 30, 172, "AI Sees Through Fog",
+        func() bool { return settings.AISeesThroughFog },
+        func(value bool) { settings.AISeesThroughFog = value },
+    )
+
+    // read-only summary of how much the AI wizards are cheating in the current game
+    if difficultyInfo != nil {
+        group.AddElement(&uilib.UIElement{
+            Layer: settingsLayer,
+            Draw: func(element *uilib.UIElement, screen *ebiten.Image){
+                var options ebiten.DrawImageOptions
+                options.ColorScale.ScaleAlpha(getAlpha())
+                fontOptions := font.FontOptions{Scale: scale.ScaleAmount, DropShadow: true, Options: &options}
+
+                difficulty := difficultyInfo.GetDifficulty()
+                fonts.OptionFont.PrintOptions(screen, 180, 40, fontOptions, fmt.Sprintf("AI Bonuses (%v)", difficulty))
+
+                y := 52
+                for _, line := range difficultyModifierLines(difficulty) {
+                    fonts.OptionFont.PrintOptions(screen, 186, float64(y), fontOptions, line)
+                    y += 11
+                }
+            },
+        })
+    }
+
     return group, quit
 }
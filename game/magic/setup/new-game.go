@@ -2,12 +2,16 @@ package setup
 
 import (
     "log"
+    "image"
+    "strings"
+    "path/filepath"
 
     "github.com/kazzmir/master-of-magic/lib/lbx"
     "github.com/kazzmir/master-of-magic/lib/font"
     uilib "github.com/kazzmir/master-of-magic/game/magic/ui"
     "github.com/kazzmir/master-of-magic/game/magic/inputmanager"
     "github.com/kazzmir/master-of-magic/game/magic/data"
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
     "github.com/kazzmir/master-of-magic/game/magic/scale"
     "github.com/kazzmir/master-of-magic/game/magic/util"
 
@@ -16,7 +20,7 @@ import (
 
 const DifficultyMax = 4
 const OpponentsMax = 4
-const LandSizeMax = 2
+const LandSizeMax = 3
 const MagicMax = 2
 
 type NewGameSettings struct {
@@ -24,6 +28,21 @@ type NewGameSettings struct {
     Opponents int
     LandSize int
     Magic data.MagicSetting
+    // path to a scenario file made with the map editor, empty for a random map
+    Scenario string
+
+    // passed to the world generator, see terrain/generate.go
+    Water terrain.WaterLevel
+    Continents terrain.ContinentCount
+    Climate terrain.Climate
+
+    // optional rules that go beyond the original game
+    // cities connected by roads form trade routes, see game/trade.go
+    TradeRoutes bool
+    // road-connected cities share food, and hungry or crowded cities lose people, see city/food.go
+    FoodLogistics bool
+    // cities full of rebels rise up, secede or defect to another wizard, see game/rebellion.go
+    Rebellions bool
 }
 
 func (settings *NewGameSettings) DifficultyNext() {
@@ -58,6 +77,30 @@ func (settings *NewGameSettings) LandSizeNext() {
     }
 }
 
+func (settings *NewGameSettings) WaterNext() {
+    switch settings.Water {
+        case terrain.WaterLow: settings.Water = terrain.WaterNormal
+        case terrain.WaterNormal: settings.Water = terrain.WaterHigh
+        case terrain.WaterHigh: settings.Water = terrain.WaterLow
+    }
+}
+
+func (settings *NewGameSettings) ContinentsNext() {
+    switch settings.Continents {
+        case terrain.ContinentsFew: settings.Continents = terrain.ContinentsNormal
+        case terrain.ContinentsNormal: settings.Continents = terrain.ContinentsMany
+        case terrain.ContinentsMany: settings.Continents = terrain.ContinentsFew
+    }
+}
+
+func (settings *NewGameSettings) ClimateNext() {
+    switch settings.Climate {
+        case terrain.ClimateCold: settings.Climate = terrain.ClimateNormal
+        case terrain.ClimateNormal: settings.Climate = terrain.ClimateHot
+        case terrain.ClimateHot: settings.Climate = terrain.ClimateCold
+    }
+}
+
 func (settings *NewGameSettings) MagicNext() {
     switch settings.Magic {
         case data.MagicSettingWeak: settings.Magic = data.MagicSettingNormal
@@ -67,15 +110,7 @@ func (settings *NewGameSettings) MagicNext() {
 }
 
 func (settings *NewGameSettings) DifficultyString() string {
-    names := map[data.DifficultySetting]string{
-        data.DifficultyIntro: "Intro",
-        data.DifficultyEasy: "Easy",
-        data.DifficultyAverage: "Average",
-        data.DifficultyHard: "Hard",
-        data.DifficultyExtreme: "Extreme",
-        data.DifficultyImpossible: "Impossible",
-    }
-    return names[settings.Difficulty]
+    return settings.Difficulty.String()
 }
 
 func (settings *NewGameSettings) OpponentsString() string {
@@ -84,10 +119,19 @@ func (settings *NewGameSettings) OpponentsString() string {
 }
 
 func (settings *NewGameSettings) LandSizeString() string {
-    kinds := []string{"Small", "Medium", "Large"}
+    kinds := []string{"Small", "Medium", "Large", "Original"}
     return kinds[settings.LandSize]
 }
 
+// the land size, or the name of the scenario the game starts from
+func (settings *NewGameSettings) MapString() string {
+    if settings.Scenario != "" {
+        return strings.TrimSuffix(filepath.Base(settings.Scenario), filepath.Ext(settings.Scenario))
+    }
+
+    return settings.LandSizeString()
+}
+
 func (settings *NewGameSettings) MagicString() string {
     kinds := map[data.MagicSetting]string{
         data.MagicSettingWeak: "Weak",
@@ -97,6 +141,38 @@ func (settings *NewGameSettings) MagicString() string {
     return kinds[settings.Magic]
 }
 
+func (settings *NewGameSettings) WaterString() string {
+    return "Water: " + settings.Water.String()
+}
+
+func (settings *NewGameSettings) ContinentsString() string {
+    return "Continents: " + settings.Continents.String()
+}
+
+func (settings *NewGameSettings) ClimateString() string {
+    return "Climate: " + settings.Climate.String()
+}
+
+func onOffString(on bool) string {
+    if on {
+        return "On"
+    }
+
+    return "Off"
+}
+
+func (settings *NewGameSettings) TradeRoutesString() string {
+    return "Trade Routes: " + onOffString(settings.TradeRoutes)
+}
+
+func (settings *NewGameSettings) FoodLogisticsString() string {
+    return "Food Logistics: " + onOffString(settings.FoodLogistics)
+}
+
+func (settings *NewGameSettings) RebellionsString() string {
+    return "Rebellions: " + onOffString(settings.Rebellions)
+}
+
 type NewGameState int
 const (
     NewGameStateRunning NewGameState = iota
@@ -112,9 +188,42 @@ type NewGameScreen struct {
 
     Settings NewGameSettings
 
+    // scenario files that the land size button cycles through after the random map sizes
+    Scenarios []string
+
     UI *uilib.UI
 }
 
+// cycle through the random map sizes and then the scenarios
+func (newGameScreen *NewGameScreen) MapNext() {
+    settings := &newGameScreen.Settings
+
+    if settings.Scenario != "" {
+        index := -1
+        for i, scenario := range newGameScreen.Scenarios {
+            if scenario == settings.Scenario {
+                index = i
+            }
+        }
+
+        if index + 1 < len(newGameScreen.Scenarios) {
+            settings.Scenario = newGameScreen.Scenarios[index + 1]
+        } else {
+            settings.Scenario = ""
+            settings.LandSize = 0
+        }
+
+        return
+    }
+
+    if settings.LandSize == LandSizeMax && len(newGameScreen.Scenarios) > 0 {
+        settings.Scenario = newGameScreen.Scenarios[0]
+        return
+    }
+
+    settings.LandSizeNext()
+}
+
 func (newGameScreen *NewGameScreen) MakeUI() *uilib.UI {
     fontLbx, err := newGameScreen.Cache.GetLbxFile("FONTS.LBX")
     if err != nil {
@@ -129,6 +238,7 @@ func (newGameScreen *NewGameScreen) MakeUI() *uilib.UI {
     }
 
     buttonFont := font.MakeOptimizedFont(fonts[3])
+    ruleFont := font.MakeOptimizedFont(fonts[1])
 
     var elements []*uilib.UIElement
 
@@ -225,7 +335,7 @@ func (newGameScreen *NewGameScreen) MakeUI() *uilib.UI {
         Rect: util.ImageRect(landsizeX, landsizeY, landSizeBlock),
         IsOffsetWhenPressed: true,
         LeftClick: func(element *uilib.UIElement) {
-            newGameScreen.Settings.LandSizeNext()
+            newGameScreen.MapNext()
         },
         Draw: func(this *uilib.UIElement, screen *ebiten.Image) {
             var options ebiten.DrawImageOptions
@@ -235,7 +345,7 @@ func (newGameScreen *NewGameScreen) MakeUI() *uilib.UI {
             x := this.Rect.Min.X + landSizeBlock.Bounds().Dx() / 2
             y := this.Rect.Min.Y + 4
 
-            buttonFont.PrintOptions(screen, float64(x), float64(y), font.FontOptions{Scale: scale.ScaleAmount, Justify: font.FontJustifyCenter}, newGameScreen.Settings.LandSizeString())
+            buttonFont.PrintOptions(screen, float64(x), float64(y), font.FontOptions{Scale: scale.ScaleAmount, Justify: font.FontJustifyCenter}, newGameScreen.Settings.MapString())
         },
     })
 
@@ -259,6 +369,52 @@ func (newGameScreen *NewGameScreen) MakeUI() *uilib.UI {
         },
     })
 
+    // the map options and the optional rules are listed below the buttons, one line each. the map options
+    // go under the labels on the left and the rules under the buttons on the right
+    mapOptionX := 160 + 5
+    mapOptionY := magicY + magicBlock.Bounds().Dy() + 6
+    ruleY := magicY + magicBlock.Bounds().Dy() + 6
+    addOption := func(x int, y *int, width int, text func() string, change func()) {
+        top := *y
+        *y += 11
+
+        elements = append(elements, &uilib.UIElement{
+            Rect: image.Rect(x, top, x + width, top + 10),
+            IsOffsetWhenPressed: true,
+            LeftClick: func(element *uilib.UIElement) {
+                change()
+            },
+            Draw: func(this *uilib.UIElement, screen *ebiten.Image) {
+                x := this.Rect.Min.X + this.Rect.Dx() / 2
+                ruleFont.PrintOptions(screen, float64(x), float64(this.Rect.Min.Y + 2), font.FontOptions{DropShadow: true, Scale: scale.ScaleAmount, Justify: font.FontJustifyCenter}, text())
+            },
+        })
+    }
+
+    addMapOption := func(text func() string, change func()) {
+        addOption(mapOptionX, &mapOptionY, magicX - mapOptionX - 2, text, change)
+    }
+
+    addRuleOption := func(text func() string, toggle func()) {
+        addOption(magicX, &ruleY, magicBlock.Bounds().Dx(), text, toggle)
+    }
+
+    addMapOption(newGameScreen.Settings.WaterString, newGameScreen.Settings.WaterNext)
+    addMapOption(newGameScreen.Settings.ContinentsString, newGameScreen.Settings.ContinentsNext)
+    addMapOption(newGameScreen.Settings.ClimateString, newGameScreen.Settings.ClimateNext)
+
+    addRuleOption(newGameScreen.Settings.TradeRoutesString, func(){
+        newGameScreen.Settings.TradeRoutes = !newGameScreen.Settings.TradeRoutes
+    })
+
+    addRuleOption(newGameScreen.Settings.FoodLogisticsString, func(){
+        newGameScreen.Settings.FoodLogistics = !newGameScreen.Settings.FoodLogistics
+    })
+
+    addRuleOption(newGameScreen.Settings.RebellionsString, func(){
+        newGameScreen.Settings.Rebellions = !newGameScreen.Settings.Rebellions
+    })
+
     ui := uilib.UI{
         Draw: func(ui *uilib.UI, screen *ebiten.Image) {
             var options ebiten.DrawImageOptions
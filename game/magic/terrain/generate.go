@@ -0,0 +1,792 @@
+package terrain
+
+import (
+    "log"
+    "time"
+    "image"
+    "math"
+    "slices"
+    "math/rand/v2"
+
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+/* the world generator builds a map in named stages that each work on the raw terrain matrix:
+ *  continents - plates are seeded and grown until the requested amount of land exists. plates never
+ *               touch, so each one becomes a separate continent
+ *  islands    - land masses too small to be interesting are sunk
+ *  climate    - base terrain by latitude: tundra at the poles, desert near the equator
+ *  mountains  - mountain ranges with hills on their flanks
+ *  rivers     - rivers start on high ground and flow downhill to the sea, or into another river
+ *  nodes      - magic nodes and lair sites are spread out so that no area of the map is favored
+ *  resolve    - choose the tile images that match their neighbors
+ *
+ * every stage only needs a *Map, so each one can be run and tested on its own.
+ */
+
+// the size of the map in the original game
+const OriginalColumns = 60
+const OriginalRows = 40
+
+type Climate int
+
+const (
+    ClimateNormal Climate = iota
+    // wider polar regions
+    ClimateCold
+    // wider deserts
+    ClimateHot
+)
+
+func (climate Climate) String() string {
+    switch climate {
+        case ClimateNormal: return "Normal"
+        case ClimateCold: return "Cold"
+        case ClimateHot: return "Hot"
+    }
+
+    return "?"
+}
+
+// how much of the map is water, chosen on the new game screen
+type WaterLevel int
+
+const (
+    WaterNormal WaterLevel = iota
+    WaterLow
+    WaterHigh
+)
+
+func (water WaterLevel) String() string {
+    switch water {
+        case WaterNormal: return "Normal"
+        case WaterLow: return "Low"
+        case WaterHigh: return "High"
+    }
+
+    return "?"
+}
+
+// the fraction of the map that is water
+func (water WaterLevel) Ratio() float64 {
+    switch water {
+        case WaterLow: return 0.5
+        case WaterHigh: return 0.7
+    }
+
+    return 0.6
+}
+
+// how many land masses the map is split into, chosen on the new game screen
+type ContinentCount int
+
+const (
+    ContinentsNormal ContinentCount = iota
+    ContinentsFew
+    ContinentsMany
+)
+
+func (count ContinentCount) String() string {
+    switch count {
+        case ContinentsNormal: return "Normal"
+        case ContinentsFew: return "Few"
+        case ContinentsMany: return "Many"
+    }
+
+    return "?"
+}
+
+type GeneratorParameters struct {
+    Columns int
+    Rows int
+    // fraction of the map that is water, between 0 and 1
+    WaterRatio float64
+    // number of land masses the continents stage seeds
+    Continents int
+    Climate Climate
+    // land masses with fewer tiles than this are sunk
+    MinimumIsland int
+    // one mountain range for this many land tiles
+    MountainArea int
+    // one river for this many land tiles
+    RiverArea int
+    // one magic node for this many land tiles
+    NodeArea int
+    // one lair site for this many land tiles
+    LairArea int
+}
+
+func DefaultGeneratorParameters(columns int, rows int) GeneratorParameters {
+    return GeneratorParameters{
+        Columns: columns,
+        Rows: rows,
+        WaterRatio: 0.6,
+        Continents: max(2, columns * rows / 1200),
+        Climate: ClimateNormal,
+        MinimumIsland: 20,
+        MountainArea: 120,
+        RiverArea: 80,
+        NodeArea: 70,
+        LairArea: 30,
+    }
+}
+
+// the parameters with the water, continents and climate chosen on the new game screen
+func (parameters GeneratorParameters) WithSettings(water WaterLevel, continents ContinentCount, climate Climate) GeneratorParameters {
+    parameters.WaterRatio = water.Ratio()
+    parameters.Climate = climate
+
+    switch continents {
+        case ContinentsFew: parameters.Continents = max(1, parameters.Continents / 2)
+        case ContinentsMany: parameters.Continents = parameters.Continents * 2
+    }
+
+    return parameters
+}
+
+type GeneratorStage struct {
+    Name string
+    Run func(generator *Generator, map_ *Map)
+}
+
+type Generator struct {
+    Parameters GeneratorParameters
+    // may be nil, in which case tiles are not resolved against their neighbors
+    Data *TerrainData
+    Plane data.Plane
+    Random *rand.Rand
+    Stages []GeneratorStage
+
+    // filled in by the nodes stage, places where lairs and other encounters can go
+    Lairs []image.Point
+}
+
+func MakeGenerator(parameters GeneratorParameters, terrainData *TerrainData, plane data.Plane) *Generator {
+    return &Generator{
+        Parameters: parameters,
+        Data: terrainData,
+        Plane: plane,
+        Random: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+        Stages: DefaultGeneratorStages(),
+    }
+}
+
+func DefaultGeneratorStages() []GeneratorStage {
+    return []GeneratorStage{
+        {Name: "continents", Run: func(generator *Generator, map_ *Map) {
+            land := int(float64(map_.Columns() * map_.Rows()) * (1 - generator.Parameters.WaterRatio))
+            map_.placeContinents(generator.Random, generator.Parameters.Continents, land, generator.Plane)
+        }},
+        {Name: "islands", Run: func(generator *Generator, map_ *Map) {
+            map_.removeSmallIslands(generator.Parameters.MinimumIsland, generator.Plane)
+        }},
+        {Name: "climate", Run: func(generator *Generator, map_ *Map) {
+            map_.placeClimate(generator.Random, generator.Parameters.Climate, generator.Plane)
+        }},
+        {Name: "mountains", Run: func(generator *Generator, map_ *Map) {
+            map_.placeMountainRanges(generator.Random, map_.countLand() / max(1, generator.Parameters.MountainArea), generator.Plane)
+        }},
+        {Name: "rivers", Run: func(generator *Generator, map_ *Map) {
+            map_.placeRiverNetworks(generator.Random, map_.countLand() / max(1, generator.Parameters.RiverArea), generator.Data, generator.Plane)
+        }},
+        {Name: "nodes", Run: func(generator *Generator, map_ *Map) {
+            land := map_.countLand()
+            nodes := map_.placeSpacedNodes(generator.Random, max(1, land / max(1, generator.Parameters.NodeArea)), generator.Plane)
+            generator.Lairs = map_.chooseLairSites(generator.Random, land / max(1, generator.Parameters.LairArea), nodes)
+        }},
+        {Name: "resolve", Run: func(generator *Generator, map_ *Map) {
+            if generator.Data != nil {
+                map_.ResolveTiles(generator.Data, generator.Plane)
+            }
+        }},
+    }
+}
+
+// stages for a map whose land was drawn by hand. nodes are only placed if the map doesn't have any yet
+func PopulateStages() []GeneratorStage {
+    return []GeneratorStage{
+        {Name: "nodes", Run: func(generator *Generator, map_ *Map) {
+            land := map_.countLand()
+            nodes := map_.magicSites()
+            if len(nodes) == 0 {
+                nodes = map_.placeSpacedNodes(generator.Random, max(1, land / max(1, generator.Parameters.NodeArea)), generator.Plane)
+            }
+            generator.Lairs = map_.chooseLairSites(generator.Random, land / max(1, generator.Parameters.LairArea), nodes)
+        }},
+        {Name: "resolve", Run: func(generator *Generator, map_ *Map) {
+            if generator.Data != nil {
+                map_.ResolveTiles(generator.Data, generator.Plane)
+            }
+        }},
+    }
+}
+
+// run all stages on a new map
+func (generator *Generator) Generate() *Map {
+    map_ := MakeMap(generator.Parameters.Rows, generator.Parameters.Columns)
+    generator.Run(map_)
+    return map_
+}
+
+// run all stages on an existing map
+func (generator *Generator) Run(map_ *Map) {
+    start := time.Now()
+
+    for _, stage := range generator.Stages {
+        stage.Run(generator, map_)
+    }
+
+    log.Printf("Generated %vx%v %v map in %v", map_.Columns(), map_.Rows(), generator.Plane, time.Since(start))
+}
+
+func (map_ *Map) isLand(x int, y int) bool {
+    return GetTile(map_.Terrain[x][y]).IsLand()
+}
+
+func (map_ *Map) countLand() int {
+    count := 0
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if map_.isLand(x, y) {
+                count += 1
+            }
+        }
+    }
+
+    return count
+}
+
+// distance between two tiles, taking the horizontal wrap into account
+func (map_ *Map) wrappedDistance(a image.Point, b image.Point) float64 {
+    dx := math.Abs(float64(a.X - b.X))
+    dx = math.Min(dx, float64(map_.Columns()) - dx)
+    dy := float64(a.Y - b.Y)
+    return math.Sqrt(dx * dx + dy * dy)
+}
+
+// the tiles next to (x, y), wrapping horizontally. diagonal neighbors are included if diagonal is true
+func (map_ *Map) neighbors(x int, y int, diagonal bool) []image.Point {
+    var out []image.Point
+    for dx := -1; dx <= 1; dx++ {
+        for dy := -1; dy <= 1; dy++ {
+            if dx == 0 && dy == 0 {
+                continue
+            }
+
+            if !diagonal && dx != 0 && dy != 0 {
+                continue
+            }
+
+            ny := y + dy
+            if ny < 0 || ny >= map_.Rows() {
+                continue
+            }
+
+            out = append(out, image.Pt(map_.WrapX(x + dx), ny))
+        }
+    }
+
+    return out
+}
+
+// seed count plates and grow them at random points of their border until there are land tiles of land.
+// a plate never grows next to another plate, so the plates stay separate continents
+func (map_ *Map) placeContinents(random *rand.Rand, count int, land int, plane data.Plane) {
+    columns := map_.Columns()
+    rows := map_.Rows()
+
+    // keep the rows closest to the poles as water
+    margin := max(1, rows / 10)
+
+    plates := make([][]int, columns)
+    for x := range columns {
+        plates[x] = make([]int, rows)
+        for y := range rows {
+            plates[x][y] = -1
+            map_.Terrain[x][y] = TileOcean.Index(plane)
+        }
+    }
+
+    if rows <= margin * 2 {
+        return
+    }
+
+    // seeds are spread out by choosing the best of a few random candidates
+    var seeds []image.Point
+    for range count {
+        var best image.Point
+        bestDistance := -1.0
+        for range 20 {
+            candidate := image.Pt(random.IntN(columns), margin + random.IntN(rows - margin * 2))
+            distance := math.MaxFloat64
+            for _, seed := range seeds {
+                distance = math.Min(distance, map_.wrappedDistance(seed, candidate))
+            }
+
+            if distance > bestDistance {
+                best = candidate
+                bestDistance = distance
+            }
+        }
+
+        // too crowded to place another continent
+        if bestDistance >= 0 && bestDistance < 3 {
+            break
+        }
+
+        seeds = append(seeds, best)
+    }
+
+    frontiers := make([][]image.Point, len(seeds))
+    for i, seed := range seeds {
+        frontiers[i] = []image.Point{seed}
+    }
+
+    // true if the tile touches a tile of a different plate
+    touchesOther := func(point image.Point, plate int) bool {
+        for _, neighbor := range map_.neighbors(point.X, point.Y, true) {
+            other := plates[neighbor.X][neighbor.Y]
+            if other != -1 && other != plate {
+                return true
+            }
+        }
+        return false
+    }
+
+    placed := 0
+    for placed < land {
+        var active []int
+        for i, frontier := range frontiers {
+            if len(frontier) > 0 {
+                active = append(active, i)
+            }
+        }
+
+        if len(active) == 0 {
+            break
+        }
+
+        plate := active[random.IntN(len(active))]
+        frontier := frontiers[plate]
+
+        // taking a random border tile instead of the oldest gives ragged coastlines
+        index := random.IntN(len(frontier))
+        point := frontier[index]
+        frontier[index] = frontier[len(frontier) - 1]
+        frontiers[plate] = frontier[:len(frontier) - 1]
+
+        if plates[point.X][point.Y] != -1 || point.Y < margin || point.Y >= rows - margin || touchesOther(point, plate) {
+            continue
+        }
+
+        plates[point.X][point.Y] = plate
+        map_.Terrain[point.X][point.Y] = TileLand.Index(plane)
+        placed += 1
+
+        for _, neighbor := range map_.neighbors(point.X, point.Y, false) {
+            if plates[neighbor.X][neighbor.Y] == -1 {
+                frontiers[plate] = append(frontiers[plate], neighbor)
+            }
+        }
+    }
+}
+
+// 0 at the equator and 1 at the poles
+func latitude(y int, rows int) float64 {
+    half := float64(rows) / 2
+    return math.Abs(float64(y) + 0.5 - half) / half
+}
+
+// where the polar and equatorial bands start for a climate
+func climateBands(climate Climate) (float64, float64) {
+    switch climate {
+        case ClimateCold: return 0.65, 0.05
+        case ClimateHot: return 0.9, 0.3
+    }
+
+    return 0.8, 0.15
+}
+
+// the base terrain of a land tile at the given latitude
+func climateTile(random *rand.Rand, latitude float64, climate Climate, plane data.Plane) int {
+    polar, equator := climateBands(climate)
+
+    grass := []Tile{TileGrasslands1, TileGrasslands2, TileGrasslands3, TileGrasslands4}[random.IntN(4)]
+    forest := []Tile{TileForest1, TileForest2, TileForest3}[random.IntN(3)]
+
+    choices := []int{
+        grass.Index(plane),
+        forest.Index(plane),
+        TileSwamp1.Index(plane),
+        TileAllDesert1.Index(plane),
+        TileTundra.Index(plane),
+    }
+
+    var weights []int
+    switch {
+        case latitude >= polar: weights = []int{1, 0, 0, 0, 9}
+        case latitude >= polar - 0.1: weights = []int{4, 2, 0, 0, 4}
+        case latitude <= equator: weights = []int{25, 10, 5, 60, 0}
+        default: weights = []int{55, 35, 6, 4, 0}
+    }
+
+    return weightedChoice(random, choices, weights)
+}
+
+func weightedChoice[T any](random *rand.Rand, choices []T, weights []int) T {
+    total := 0
+    for _, weight := range weights {
+        total += weight
+    }
+
+    pick := random.IntN(total)
+    for i, weight := range weights {
+        if pick < weight {
+            return choices[i]
+        }
+        pick -= weight
+    }
+
+    return choices[len(choices) - 1]
+}
+
+// replace every land tile with the base terrain of its latitude
+func (map_ *Map) placeClimate(random *rand.Rand, climate Climate, plane data.Plane) {
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if map_.isLand(x, y) {
+                map_.Terrain[x][y] = climateTile(random, latitude(y, map_.Rows()), climate, plane)
+            }
+        }
+    }
+}
+
+// for each land tile the number of steps to the closest water tile, or -1 for water
+func (map_ *Map) distanceToWater() [][]int {
+    distance := make([][]int, map_.Columns())
+    var queue []image.Point
+    for x := range map_.Columns() {
+        distance[x] = make([]int, map_.Rows())
+        for y := range map_.Rows() {
+            if map_.isLand(x, y) {
+                distance[x][y] = math.MaxInt
+            } else {
+                distance[x][y] = -1
+                queue = append(queue, image.Pt(x, y))
+            }
+        }
+    }
+
+    for len(queue) > 0 {
+        point := queue[0]
+        queue = queue[1:]
+
+        current := max(0, distance[point.X][point.Y])
+        for _, neighbor := range map_.neighbors(point.X, point.Y, false) {
+            if distance[neighbor.X][neighbor.Y] == math.MaxInt {
+                distance[neighbor.X][neighbor.Y] = current + 1
+                queue = append(queue, neighbor)
+            }
+        }
+    }
+
+    // land with no water on the map at all
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if distance[x][y] == math.MaxInt {
+                distance[x][y] = map_.Columns() + map_.Rows()
+            }
+        }
+    }
+
+    return distance
+}
+
+var rangeDirections = []image.Point{
+    image.Pt(0, -1), image.Pt(1, -1), image.Pt(1, 0), image.Pt(1, 1),
+    image.Pt(0, 1), image.Pt(-1, 1), image.Pt(-1, 0), image.Pt(-1, -1),
+}
+
+// lay down count mountain ranges. a range starts inland and walks in a mostly straight line until it
+// reaches the coast. tiles beside the range may become hills
+func (map_ *Map) placeMountainRanges(random *rand.Rand, count int, plane data.Plane) {
+    distance := map_.distanceToWater()
+
+    var inland []image.Point
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if distance[x][y] >= 2 {
+                inland = append(inland, image.Pt(x, y))
+            }
+        }
+    }
+
+    if len(inland) == 0 {
+        return
+    }
+
+    for range count {
+        point := inland[random.IntN(len(inland))]
+        direction := random.IntN(len(rangeDirections))
+        length := 3 + random.IntN(6)
+
+        for range length {
+            if distance[point.X][point.Y] < 1 {
+                break
+            }
+
+            map_.Terrain[point.X][point.Y] = TileMountain1.Index(plane)
+
+            for _, neighbor := range map_.neighbors(point.X, point.Y, true) {
+                tile := GetTile(map_.Terrain[neighbor.X][neighbor.Y])
+                if tile.IsLand() && tile.TerrainType() != Mountain && random.IntN(100) < 35 {
+                    map_.Terrain[neighbor.X][neighbor.Y] = TileHills1.Index(plane)
+                }
+            }
+
+            // mostly keep going the same way, sometimes bend 45 degrees
+            switch random.IntN(10) {
+                case 0: direction = (direction + 1) % len(rangeDirections)
+                case 1: direction = (direction + len(rangeDirections) - 1) % len(rangeDirections)
+            }
+
+            step := rangeDirections[direction]
+            next := image.Pt(map_.WrapX(point.X + step.X), point.Y + step.Y)
+            if next.Y < 0 || next.Y >= map_.Rows() {
+                break
+            }
+            point = next
+        }
+    }
+}
+
+func (map_ *Map) isRiver(x int, y int) bool {
+    return GetTile(map_.Terrain[x][y]).TerrainType() == River
+}
+
+// true if the tiles around the river path can all be given a matching image
+func (map_ *Map) riverResolves(path []image.Point, terrainData *TerrainData, plane data.Plane) bool {
+    if terrainData == nil {
+        return true
+    }
+
+    mapCopy := map_.Copy()
+    for _, point := range path {
+        mapCopy.Terrain[point.X][point.Y] = TileRiver0001.Index(plane)
+    }
+
+    checked := make(map[image.Point]bool)
+    for _, point := range path {
+        for _, check := range append(map_.neighbors(point.X, point.Y, false), point) {
+            if checked[check] {
+                continue
+            }
+            checked[check] = true
+
+            _, err := mapCopy.ResolveTile(check.X, check.Y, terrainData, plane)
+            if err != nil {
+                return false
+            }
+        }
+    }
+
+    return true
+}
+
+// the path of a river starting at source that always flows to a tile closer to the sea. the river ends
+// at the coast or where it joins another river. returns false if the river would have to cross a mountain
+func (map_ *Map) riverPath(random *rand.Rand, source image.Point, distance [][]int) ([]image.Point, bool) {
+    path := []image.Point{source}
+    current := source
+
+    for {
+        var downhill []image.Point
+        for _, neighbor := range map_.neighbors(current.X, current.Y, false) {
+            if len(path) > 1 && neighbor == path[len(path) - 2] {
+                continue
+            }
+
+            // reached the sea or another river
+            if distance[neighbor.X][neighbor.Y] < 0 || map_.isRiver(neighbor.X, neighbor.Y) {
+                return path, len(path) > 1
+            }
+
+            tile := GetTile(map_.Terrain[neighbor.X][neighbor.Y])
+            if distance[neighbor.X][neighbor.Y] < distance[current.X][current.Y] && tile.TerrainType() != Mountain && !tile.IsMagic() {
+                downhill = append(downhill, neighbor)
+            }
+        }
+
+        if len(downhill) == 0 {
+            return nil, false
+        }
+
+        current = downhill[random.IntN(len(downhill))]
+        path = append(path, current)
+    }
+}
+
+// place count rivers that start on high ground, preferably next to hills and mountains
+func (map_ *Map) placeRiverNetworks(random *rand.Rand, count int, terrainData *TerrainData, plane data.Plane) {
+    distance := map_.distanceToWater()
+
+    type source struct {
+        Point image.Point
+        Score int
+    }
+
+    var sources []source
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            tile := GetTile(map_.Terrain[x][y])
+            if distance[x][y] < 2 || tile.TerrainType() == Mountain || tile.TerrainType() == River || tile.IsMagic() {
+                continue
+            }
+
+            score := distance[x][y] * 2 + random.IntN(4)
+            for _, neighbor := range map_.neighbors(x, y, true) {
+                switch GetTile(map_.Terrain[neighbor.X][neighbor.Y]).TerrainType() {
+                    case Mountain: score += 3
+                    case Hill: score += 1
+                }
+            }
+
+            sources = append(sources, source{Point: image.Pt(x, y), Score: score})
+        }
+    }
+
+    slices.SortFunc(sources, func(a, b source) int {
+        return b.Score - a.Score
+    })
+
+    placed := 0
+    for _, start := range sources {
+        if placed >= count {
+            break
+        }
+
+        // the source may have been covered by an earlier river, or be right next to one
+        near := map_.isRiver(start.Point.X, start.Point.Y)
+        for _, neighbor := range map_.neighbors(start.Point.X, start.Point.Y, true) {
+            near = near || map_.isRiver(neighbor.X, neighbor.Y)
+        }
+        if near {
+            continue
+        }
+
+        path, ok := map_.riverPath(random, start.Point, distance)
+        if !ok || !map_.riverResolves(path, terrainData, plane) {
+            continue
+        }
+
+        for _, point := range path {
+            map_.Terrain[point.X][point.Y] = TileRiver0001.Index(plane)
+        }
+
+        placed += 1
+    }
+}
+
+// choose up to count of the candidates, each one as far as possible from the ones already chosen and from
+// avoid. stops early if the next site would be closer than spacing to another
+func (map_ *Map) spacedSites(random *rand.Rand, candidates []image.Point, count int, spacing float64, avoid []image.Point) []image.Point {
+    if len(candidates) == 0 || count <= 0 {
+        return nil
+    }
+
+    closest := make([]float64, len(candidates))
+    for i, candidate := range candidates {
+        closest[i] = math.MaxFloat64
+        for _, other := range avoid {
+            closest[i] = math.Min(closest[i], map_.wrappedDistance(candidate, other))
+        }
+    }
+
+    var out []image.Point
+    for len(out) < count {
+        best := -1
+        if len(out) == 0 && len(avoid) == 0 {
+            best = random.IntN(len(candidates))
+        } else {
+            for i := range candidates {
+                if best == -1 || closest[i] > closest[best] {
+                    best = i
+                }
+            }
+        }
+
+        if closest[best] < spacing {
+            break
+        }
+
+        chosen := candidates[best]
+        out = append(out, chosen)
+
+        for i, candidate := range candidates {
+            closest[i] = math.Min(closest[i], map_.wrappedDistance(candidate, chosen))
+        }
+    }
+
+    return out
+}
+
+// tiles a node or lair could go on
+func (map_ *Map) siteCandidates() []image.Point {
+    var out []image.Point
+    for x := range map_.Columns() {
+        for y := 1; y < map_.Rows() - 1; y++ {
+            tile := GetTile(map_.Terrain[x][y])
+            if tile.IsLand() && !tile.IsMagic() && tile.TerrainType() != River {
+                out = append(out, image.Pt(x, y))
+            }
+        }
+    }
+
+    // the order of the candidates breaks ties between equally distant sites
+    slices.SortFunc(out, func(a, b image.Point) int {
+        if a.X != b.X {
+            return a.X - b.X
+        }
+        return a.Y - b.Y
+    })
+
+    return out
+}
+
+// the tiles that already hold a magic node
+func (map_ *Map) magicSites() []image.Point {
+    var out []image.Point
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if GetTile(map_.Terrain[x][y]).IsMagic() {
+                out = append(out, image.Pt(x, y))
+            }
+        }
+    }
+
+    return out
+}
+
+// the closest any two magic nodes or lairs may be, this matches the area kept clear around encounters
+const siteSpacing = 4
+
+// place up to count magic nodes spread evenly over the land, with the same number of each kind. returns the node positions
+func (map_ *Map) placeSpacedNodes(random *rand.Rand, count int, plane data.Plane) []image.Point {
+    sites := map_.spacedSites(random, map_.siteCandidates(), count, siteSpacing, nil)
+
+    kinds := []Tile{TileSorceryLake, TileNatureForest, TileChaosVolcano}
+    random.Shuffle(len(kinds), func(i, j int) {
+        kinds[i], kinds[j] = kinds[j], kinds[i]
+    })
+
+    for i, site := range sites {
+        map_.Terrain[site.X][site.Y] = kinds[i % len(kinds)].Index(plane)
+    }
+
+    return sites
+}
+
+// choose up to count places for lairs, spread out from each other and from the magic nodes
+func (map_ *Map) chooseLairSites(random *rand.Rand, count int, nodes []image.Point) []image.Point {
+    return map_.spacedSites(random, map_.siteCandidates(), count, siteSpacing, nodes)
+}
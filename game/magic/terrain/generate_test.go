@@ -0,0 +1,247 @@
+package terrain
+
+import (
+    "testing"
+    "image"
+    "math/rand/v2"
+
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+func testRandom() *rand.Rand {
+    return rand.New(rand.NewPCG(1, 2))
+}
+
+// a map that is land everywhere except for a ring of ocean along the top and bottom
+func makeLandMap(columns int, rows int) *Map {
+    map_ := MakeMap(rows, columns)
+    for x := range columns {
+        for y := range rows {
+            if y < 2 || y >= rows - 2 {
+                map_.Terrain[x][y] = TileOcean.Index(data.PlaneArcanus)
+            } else {
+                map_.Terrain[x][y] = TileGrasslands1.Index(data.PlaneArcanus)
+            }
+        }
+    }
+
+    return map_
+}
+
+func countType(map_ *Map, terrainType TerrainType) int {
+    count := 0
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if GetTile(map_.Terrain[x][y]).TerrainType() == terrainType {
+                count += 1
+            }
+        }
+    }
+    return count
+}
+
+func TestPlaceContinents(test *testing.T) {
+    map_ := MakeMap(OriginalRows, OriginalColumns)
+    map_.placeContinents(testRandom(), 3, 900, data.PlaneArcanus)
+
+    if map_.countLand() != 900 {
+        test.Errorf("Expected 900 land tiles but got %v", map_.countLand())
+    }
+
+    continents := map_.FindContinents()
+    if len(continents) != 3 {
+        test.Errorf("Expected 3 continents but got %v", len(continents))
+    }
+
+    for x := range map_.Columns() {
+        if map_.isLand(x, 0) || map_.isLand(x, map_.Rows() - 1) {
+            test.Errorf("Land at the edge of the map in column %v", x)
+        }
+    }
+}
+
+func TestPlaceClimate(test *testing.T) {
+    map_ := makeLandMap(OriginalColumns, OriginalRows)
+    map_.placeClimate(testRandom(), ClimateNormal, data.PlaneArcanus)
+
+    polar := 0
+    desert := 0
+    for x := range map_.Columns() {
+        for _, y := range []int{2, map_.Rows() - 3} {
+            switch GetTile(map_.Terrain[x][y]).TerrainType() {
+                case Tundra: polar += 1
+                case Desert: test.Errorf("Desert at the pole %v,%v", x, y)
+            }
+        }
+
+        for _, y := range []int{map_.Rows() / 2 - 1, map_.Rows() / 2} {
+            switch GetTile(map_.Terrain[x][y]).TerrainType() {
+                case Desert: desert += 1
+                case Tundra: test.Errorf("Tundra at the equator %v,%v", x, y)
+            }
+        }
+    }
+
+    if polar < map_.Columns() {
+        test.Errorf("Expected mostly tundra at the poles but got %v tiles", polar)
+    }
+
+    if desert < map_.Columns() / 2 {
+        test.Errorf("Expected a lot of desert at the equator but got %v tiles", desert)
+    }
+
+    // water is left alone
+    if GetTile(map_.Terrain[0][0]).TerrainType() != Ocean {
+        test.Errorf("Ocean was changed by the climate stage")
+    }
+}
+
+func TestPlaceMountainRanges(test *testing.T) {
+    map_ := makeLandMap(OriginalColumns, OriginalRows)
+    map_.placeMountainRanges(testRandom(), 5, data.PlaneArcanus)
+
+    if countType(map_, Mountain) < 5 {
+        test.Errorf("Expected at least 5 mountains but got %v", countType(map_, Mountain))
+    }
+
+    if countType(map_, Hill) == 0 {
+        test.Errorf("Expected hills beside the mountains")
+    }
+
+    if countType(map_, Ocean) != OriginalColumns * 4 {
+        test.Errorf("Mountains were placed in the ocean")
+    }
+}
+
+func TestPlaceRiverNetworks(test *testing.T) {
+    map_ := MakeMap(OriginalRows, OriginalColumns)
+    random := testRandom()
+    map_.placeContinents(random, 2, 1000, data.PlaneArcanus)
+    map_.placeClimate(random, ClimateNormal, data.PlaneArcanus)
+    map_.placeMountainRanges(random, 4, data.PlaneArcanus)
+    map_.placeRiverNetworks(random, 6, nil, data.PlaneArcanus)
+
+    if countType(map_, River) == 0 {
+        test.Fatalf("No rivers were placed")
+    }
+
+    // every river tile must be connected through river tiles to a tile next to water
+    reachesSea := make(map[image.Point]bool)
+    var queue []image.Point
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if !map_.isRiver(x, y) {
+                continue
+            }
+
+            for _, neighbor := range map_.neighbors(x, y, false) {
+                if !map_.isLand(neighbor.X, neighbor.Y) {
+                    reachesSea[image.Pt(x, y)] = true
+                    queue = append(queue, image.Pt(x, y))
+                    break
+                }
+            }
+        }
+    }
+
+    for len(queue) > 0 {
+        point := queue[0]
+        queue = queue[1:]
+        for _, neighbor := range map_.neighbors(point.X, point.Y, false) {
+            if map_.isRiver(neighbor.X, neighbor.Y) && !reachesSea[neighbor] {
+                reachesSea[neighbor] = true
+                queue = append(queue, neighbor)
+            }
+        }
+    }
+
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if map_.isRiver(x, y) && !reachesSea[image.Pt(x, y)] {
+                test.Errorf("River at %v,%v does not flow to the sea", x, y)
+            }
+        }
+    }
+}
+
+func TestSpacedNodesAndLairs(test *testing.T) {
+    map_ := makeLandMap(OriginalColumns, OriginalRows)
+    random := testRandom()
+
+    nodes := map_.placeSpacedNodes(random, 9, data.PlaneArcanus)
+    if len(nodes) != 9 {
+        test.Fatalf("Expected 9 nodes but got %v", len(nodes))
+    }
+
+    kinds := make(map[TerrainType]int)
+    for _, node := range nodes {
+        kinds[GetTile(map_.Terrain[node.X][node.Y]).TerrainType()] += 1
+    }
+
+    for _, kind := range []TerrainType{SorceryNode, NatureNode, ChaosNode} {
+        if kinds[kind] != 3 {
+            test.Errorf("Expected 3 nodes of %v but got %v", kind, kinds[kind])
+        }
+    }
+
+    lairs := map_.chooseLairSites(random, 40, nodes)
+    if len(lairs) == 0 {
+        test.Fatalf("No lair sites were chosen")
+    }
+
+    all := append(append([]image.Point{}, nodes...), lairs...)
+    for i := range all {
+        for j := i + 1; j < len(all); j++ {
+            if map_.wrappedDistance(all[i], all[j]) < siteSpacing {
+                test.Errorf("Sites %v and %v are too close", all[i], all[j])
+            }
+        }
+    }
+
+    for _, lair := range lairs {
+        if !map_.isLand(lair.X, lair.Y) || GetTile(map_.Terrain[lair.X][lair.Y]).IsMagic() {
+            test.Errorf("Lair site %v is not plain land", lair)
+        }
+    }
+}
+
+func TestGenerateOriginalSize(test *testing.T) {
+    generator := MakeGenerator(DefaultGeneratorParameters(OriginalColumns, OriginalRows), nil, data.PlaneMyrror)
+    generator.Random = testRandom()
+
+    map_ := generator.Generate()
+    if map_.Columns() != OriginalColumns || map_.Rows() != OriginalRows {
+        test.Errorf("Expected a %vx%v map but got %vx%v", OriginalColumns, OriginalRows, map_.Columns(), map_.Rows())
+    }
+
+    if map_.countLand() == 0 {
+        test.Errorf("No land was generated")
+    }
+
+    // myrror tiles are used throughout
+    for x := range map_.Columns() {
+        for y := range map_.Rows() {
+            if map_.Terrain[x][y] < MyrrorStart {
+                test.Fatalf("Arcanus tile at %v,%v on a myrror map", x, y)
+            }
+        }
+    }
+}
+
+func TestGeneratorSettings(test *testing.T) {
+    defaults := DefaultGeneratorParameters(OriginalColumns, OriginalRows)
+
+    if defaults.WithSettings(WaterNormal, ContinentsNormal, ClimateNormal) != defaults {
+        test.Errorf("The normal settings should not change the defaults")
+    }
+
+    changed := defaults.WithSettings(WaterHigh, ContinentsMany, ClimateCold)
+    if changed.WaterRatio <= defaults.WaterRatio || changed.Continents <= defaults.Continents || changed.Climate != ClimateCold {
+        test.Errorf("Expected more water, more continents and a cold climate but got %+v", changed)
+    }
+
+    changed = defaults.WithSettings(WaterLow, ContinentsFew, ClimateHot)
+    if changed.WaterRatio >= defaults.WaterRatio || changed.Continents >= defaults.Continents || changed.Climate != ClimateHot {
+        test.Errorf("Expected less water, fewer continents and a hot climate but got %+v", changed)
+    }
+}
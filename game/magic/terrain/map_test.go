@@ -189,6 +189,6 @@ func BenchmarkGeneration(bench *testing.B){
     plane := data.PlaneArcanus
 
     for i := 0; i < bench.N; i++ {
-        GenerateLandCellularAutomata(100, 200, terrainData, plane)
+        MakeGenerator(DefaultGeneratorParameters(100, 200), terrainData, plane).Generate()
     }
 }
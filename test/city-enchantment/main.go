@@ -64,6 +64,10 @@ func (provider *NoCityProvider) GetAllGlobalEnchantments() map[data.BannerType]*
     return enchantments
 }
 
+func (provider *NoCityProvider) FoodLogistics() bool {
+    return false
+}
+
 func NewEngine() (*Engine, error) {
     cache := lbx.AutoCache()
     engine := &Engine{
@@ -91,7 +95,7 @@ func (engine *Engine) MakeUI() (*uilib.UI, context.Context, error) {
 
     gameMap := maplib.Map{
         Data: terrainData,
-        Map: terrain.GenerateLandCellularAutomata(20, 20, terrainData, data.PlaneArcanus),
+        Map: terrain.MakeGenerator(terrain.DefaultGeneratorParameters(20, 20), terrainData, data.PlaneArcanus).Generate(),
         TileCache: make(map[int]*ebiten.Image),
     }
 
@@ -68,6 +68,10 @@ func (provider *NoCityProvider) GetAllGlobalEnchantments() map[data.BannerType]*
     return enchantments
 }
 
+func (provider *NoCityProvider) FoodLogistics() bool {
+    return false
+}
+
 type NoMaplibCityProvider struct {
 }
 
@@ -111,7 +115,7 @@ func NewEngine() (*Engine, error) {
 
     gameMap := maplib.Map{
         Data: terrainData,
-        Map: terrain.GenerateLandCellularAutomata(20, 20, terrainData, data.PlaneArcanus),
+        Map: terrain.MakeGenerator(terrain.DefaultGeneratorParameters(20, 20), terrainData, data.PlaneArcanus).Generate(),
         TileCache: make(map[int]*ebiten.Image),
         CityProvider: &NoMaplibCityProvider{},
     }
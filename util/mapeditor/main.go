@@ -50,6 +50,22 @@ type Editor struct {
     InfoImage *ebiten.Image
 
     Terrain terrain.TerrainType
+
+    Tool EditorTool
+    Bonus data.BonusType
+    Encounter maplib.EncounterType
+    Node maplib.MagicNode
+    EnchantedRoad bool
+    Race data.Race
+
+    // neutral towns and wizard start locations on both planes
+    Cities []maplib.ScenarioCity
+    Starts []maplib.ScenarioStart
+
+    // where F5 saves the scenario and F9 loads it from
+    ScenarioPath string
+    // result of the last save or load
+    Message string
 }
 
 func makeEmptyMap(terrainData *terrain.TerrainData, height int, width int, plane data.Plane) *maplib.Map {
@@ -87,6 +103,10 @@ func (editor *Editor) setMap(mapObject *maplib.Map)  {
     }
 }
 
+func (editor *Editor) inBounds(x int, y int) bool {
+    return x >= 0 && x < editor.getMap().Width() && y >= 0 && y < editor.getMap().Height()
+}
+
 func (editor *Editor) togglePlane() {
     if editor.Plane == data.PlaneArcanus {
         editor.Plane = data.PlaneMyrror
@@ -143,6 +163,11 @@ func (editor *Editor) Update() error {
     keys = inpututil.AppendJustPressedKeys(keys)
 
     for _, key := range keys {
+        switch key {
+            case ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5, ebiten.Key6, ebiten.Key7, ebiten.Key8, ebiten.Key9:
+                editor.Tool = ToolTerrain
+        }
+
         switch key {
             case ebiten.Key1:
                 editor.Terrain = terrain.Grass
@@ -166,11 +191,44 @@ func (editor *Editor) Update() error {
                 editor.togglePlane()
             case ebiten.KeyC:
                 editor.setMap(makeEmptyMap(editor.Data, 100, 200, editor.Plane))
+                editor.clearPlane(editor.Plane)
+            case ebiten.KeyB:
+                editor.selectTool(ToolBonus)
+            case ebiten.KeyE:
+                editor.selectTool(ToolEncounter)
+            case ebiten.KeyN:
+                editor.selectTool(ToolNode)
+            case ebiten.KeyR:
+                editor.selectTool(ToolRoad)
+            case ebiten.KeyV:
+                editor.selectTool(ToolVolcano)
+            case ebiten.KeyH:
+                editor.selectTool(ToolCity)
+            case ebiten.KeyW:
+                editor.selectTool(ToolStart)
+            case ebiten.KeyU:
+                if editor.inBounds(editor.TileX, editor.TileY) {
+                    editor.rerollGuardians(editor.TileX, editor.TileY)
+                }
+            case ebiten.KeyBracketLeft, ebiten.KeyBracketRight:
+                if editor.inBounds(editor.TileX, editor.TileY) {
+                    editor.adjustTile(editor.TileX, editor.TileY, key == ebiten.KeyBracketRight)
+                }
+            case ebiten.KeyF5:
+                editor.saveScenario()
+            case ebiten.KeyF9:
+                editor.loadScenario()
+            case ebiten.KeyF6:
+                editor.exportTerrain()
+            case ebiten.KeyF7:
+                editor.reimportTerrain()
             case ebiten.KeyG:
                 start := time.Now()
-                landSize := 0
+                // the original 60x40 map
+                landSize := 3
                 towers := maplib.GeneratePlaneTowerPositions(landSize, 6)
                 editor.setMap(maplib.MakeMap(editor.Data, landSize, data.MagicSettingNormal, data.DifficultyAverage, editor.Plane, nil, towers))
+                editor.clearPlane(editor.Plane)
                 end := time.Now()
                 log.Printf("Generate map took %v", end.Sub(start))
             case ebiten.KeyS:
@@ -204,12 +262,12 @@ func (editor *Editor) Update() error {
     editor.TileY = y
 
     if leftClick {
-        if x >= 0 && x < editor.getMap().Width() && y >= 0 && y < editor.getMap().Height() {
-            editor.getMap().Map.SetTerrainAt(x, y, editor.Terrain, editor.Data, editor.Plane)
+        if editor.inBounds(x, y) {
+            editor.place(x, y)
         }
     } else if rightClick {
-        if x >= 0 && x < editor.getMap().Width() && y >= 0 && y < editor.getMap().Height() {
-            editor.getMap().Map.SetTerrainAt(x, y, terrain.Ocean, editor.Data, editor.Plane)
+        if editor.inBounds(x, y) {
+            editor.remove(x, y)
         }
     }
 
@@ -261,6 +319,22 @@ func (editor *Editor) Draw(screen *ebiten.Image){
                 options.GeoM.Translate(startX, startY)
                 screen.DrawImage(tileImage, &options)
 
+                if editor.getMap().ContainsRoad(xUse, yUse) {
+                    roadIndex := 45
+                    if editor.getMap().ExtraMap[image.Pt(xUse, yUse)][maplib.ExtraKindRoad].(*maplib.ExtraRoad).Enchanted {
+                        roadIndex = 54
+                    }
+                    roadImage, err := editor.ImageCache.GetImage("mapback.lbx", roadIndex, 0)
+                    if err == nil {
+                        options.GeoM.Reset()
+                        options.GeoM.Scale(float64(xSize) / float64(roadImage.Bounds().Dx()), float64(ySize) / float64(roadImage.Bounds().Dy()))
+                        options.GeoM.Translate(float64(xPos), float64(yPos))
+                        options.GeoM.Scale(editor.Scale, editor.Scale)
+                        options.GeoM.Translate(startX, startY)
+                        screen.DrawImage(roadImage, &options)
+                    }
+                }
+
                 bonus := editor.getMap().GetBonusTile(xUse, yUse)
                 if bonus != data.BonusNone {
                     bonusImage, err := editor.ImageCache.GetImage("mapback.lbx", bonus.LbxIndex(), 0)
@@ -297,6 +371,21 @@ func (editor *Editor) Draw(screen *ebiten.Image){
                     }
                 }
 
+                tileLeft := float32(startX) + float32(xPos * editor.Scale)
+                tileTop := float32(startY) + float32(yPos * editor.Scale)
+                tileWidth := float32(xSize) * float32(editor.Scale)
+                tileHeight := float32(ySize) * float32(editor.Scale)
+
+                if editor.findCity(xUse, yUse, editor.Plane) != -1 {
+                    vector.DrawFilledRect(screen, tileLeft + tileWidth / 4, tileTop + tileHeight / 4, tileWidth / 2, tileHeight / 2, color.RGBA{R: 0x8b, G: 0x5a, B: 0x2b, A: 0xff}, true)
+                    vector.StrokeRect(screen, tileLeft + tileWidth / 4, tileTop + tileHeight / 4, tileWidth / 2, tileHeight / 2, 1, color.Black, true)
+                }
+
+                if editor.findStart(xUse, yUse, editor.Plane) != -1 {
+                    vector.DrawFilledCircle(screen, tileLeft + tileWidth / 2, tileTop + tileHeight / 2, tileHeight / 3, color.RGBA{R: 0xff, G: 0xd7, A: 0xff}, true)
+                    vector.StrokeCircle(screen, tileLeft + tileWidth / 2, tileTop + tileHeight / 2, tileHeight / 3, 1, color.Black, true)
+                }
+
                 if editor.TileX == xUse && editor.TileY == yUse {
                     vector.StrokeRect(screen, float32(startX) + float32(xPos * editor.Scale), float32(startY) + float32(yPos * editor.Scale), float32(xSize) * float32(editor.Scale), float32(ySize) * float32(editor.Scale), 1.5, color.White, true)
                 }
@@ -313,26 +402,51 @@ func (editor *Editor) Draw(screen *ebiten.Image){
         op.ColorScale.ScaleWithColor(color.White)
         text.Draw(editor.InfoImage, fmt.Sprintf("Map Dimensions: %vx%v", editor.getMap().Width(), editor.getMap().Height()), face, op)
         op.GeoM.Translate(0, face.Size + 2)
-        text.Draw(editor.InfoImage, fmt.Sprintf("Selection: %v", editor.Terrain), face, op)
+        text.Draw(editor.InfoImage, fmt.Sprintf("%v: %v", editor.Tool, editor.toolDescription()), face, op)
         op.GeoM.Translate(0, face.Size + 2)
         value := -1
         var type_ terrain.TerrainType = terrain.Unknown
 
-        if editor.TileX >= 0 && editor.TileX < editor.getMap().Width() && editor.TileY >= 0 && editor.TileY < editor.getMap().Height() {
+        if editor.inBounds(editor.TileX, editor.TileY) {
             value = editor.getMap().Map.Terrain[editor.TileX][editor.TileY]
             type_ = editor.Data.Tiles[value].Tile.TerrainType()
         }
 
         text.Draw(editor.InfoImage, fmt.Sprintf("Tile: %v,%v: 0x%x %v", editor.TileX, editor.TileY, value, type_), face, op)
+        op.GeoM.Translate(0, face.Size + 2)
 
-        if editor.TileX >= 0 && editor.TileX < editor.getMap().Width() && editor.TileY >= 0 && editor.TileY < editor.getMap().Height() {
+        if editor.inBounds(editor.TileX, editor.TileY) {
             tileImage := editor.GetTileImage(editor.TileX, editor.TileY)
             var options ebiten.DrawImageOptions
             options.GeoM.Scale(1.5, 1.5)
             options.GeoM.Translate(1, face.Size * 4)
             editor.InfoImage.DrawImage(tileImage, &options)
+
+            op.GeoM.Translate(40, 0)
+            if encounter := editor.getMap().GetEncounter(editor.TileX, editor.TileY); encounter != nil {
+                text.Draw(editor.InfoImage, fmt.Sprintf("%v: %v units", encounter.Type.Name(), len(encounter.Units)), face, op)
+                op.GeoM.Translate(0, face.Size + 2)
+                text.Draw(editor.InfoImage, fmt.Sprintf("Treasure budget: %v", encounter.Budget), face, op)
+                op.GeoM.Translate(0, face.Size + 2)
+            }
+
+            if index := editor.findCity(editor.TileX, editor.TileY, editor.Plane); index != -1 {
+                city := editor.Cities[index]
+                text.Draw(editor.InfoImage, fmt.Sprintf("%v town: %v", city.Race, city.Population), face, op)
+                op.GeoM.Translate(0, face.Size + 2)
+            }
+
+            if index := editor.findStart(editor.TileX, editor.TileY, editor.Plane); index != -1 {
+                text.Draw(editor.InfoImage, fmt.Sprintf("Wizard start %v", index + 1), face, op)
+                op.GeoM.Translate(0, face.Size + 2)
+            }
+            op.GeoM.Translate(-40, 0)
         }
 
+        op.GeoM.Reset()
+        op.GeoM.Translate(1, float64(editor.InfoImage.Bounds().Dy()) - face.Size - 3)
+        text.Draw(editor.InfoImage, fmt.Sprintf("Towns: %v Starts: %v %v", len(editor.Cities), len(editor.Starts), editor.Message), face, op)
+
         var options ebiten.DrawImageOptions
         options.GeoM.Translate(2, 2)
         scale := 0.9
@@ -381,8 +495,14 @@ func MakeEditor() *Editor {
         CameraX: 0,
         CameraY: 0,
         ShowInfo: true,
-        InfoImage: ebiten.NewImage(200, 100),
+        InfoImage: ebiten.NewImage(260, 140),
         Terrain: terrain.Grass,
+        Tool: ToolTerrain,
+        Bonus: data.BonusWildGame,
+        Encounter: maplib.EncounterTypeLair,
+        Node: maplib.MagicNodeSorcery,
+        Race: data.RaceHighMen,
+        ScenarioPath: "scenario.json",
     }
 }
 
@@ -414,19 +534,37 @@ func main() {
     ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 
     var saveGame string
+    var scenario string
+    var importPath string
 
     flag.StringVar(&saveGame, "file", "", "Path to a savegame (optional)")
+    flag.StringVar(&scenario, "scenario", "scenario.json", "Scenario file that F5 saves to and F9 loads from. Loaded at startup if it exists")
+    flag.StringVar(&importPath, "import", "", "Terrain png or text file to edit as arcanus. A file with -myrror added to its name is used for myrror")
     flag.Usage = func() {
         fmt.Fprintf(os.Stderr, "Usage: %v [options] filename\n\n", os.Args[0])
         fmt.Fprintln(os.Stderr, "Options:")
         flag.PrintDefaults()
         fmt.Fprintln(os.Stderr, "\nExample:")
         fmt.Fprintln(os.Stderr, "  ", os.Args[0], "--file SAVE1.GAM")
+        fmt.Fprintln(os.Stderr, "\nKeys:")
+        fmt.Fprintln(os.Stderr, "  1-9 terrain, B bonus, E encounter, N node, R road, V volcano, H neutral town, W wizard start")
+        fmt.Fprintln(os.Stderr, "  press a tool key again to cycle its type. left click places, right click removes")
+        fmt.Fprintln(os.Stderr, "  U new guardians, [ ] change treasure budget or town size, F5 save scenario, F9 load scenario")
+        fmt.Fprintln(os.Stderr, "  F6 export the terrain of the plane as png and text, F7 import it again")
     }
     flag.Parse()
 
+    editor.ScenarioPath = scenario
+
     if saveGame != "" {
         editor.loadFromSavegame(saveGame)
+    } else if importPath != "" {
+        editor.importTerrain(importPath, data.PlaneArcanus)
+        if _, err := os.Stat(maplib.MyrrorTerrainPath(importPath)); err == nil {
+            editor.importTerrain(maplib.MyrrorTerrainPath(importPath), data.PlaneMyrror)
+        }
+    } else if _, err := os.Stat(scenario); err == nil {
+        editor.loadScenario()
     }
 
     err := ebiten.RunGame(editor)
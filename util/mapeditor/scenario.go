@@ -0,0 +1,392 @@
+package main
+
+import (
+    "os"
+    "log"
+    "image"
+    "slices"
+    "strings"
+    "path/filepath"
+    "math/rand/v2"
+
+    "github.com/kazzmir/master-of-magic/game/magic/terrain"
+    "github.com/kazzmir/master-of-magic/game/magic/maplib"
+    "github.com/kazzmir/master-of-magic/game/magic/data"
+)
+
+// what a left click places on the map. a right click removes it again
+type EditorTool int
+
+const (
+    ToolTerrain EditorTool = iota
+    ToolBonus
+    ToolEncounter
+    ToolNode
+    ToolRoad
+    ToolVolcano
+    ToolCity
+    ToolStart
+)
+
+func (tool EditorTool) String() string {
+    switch tool {
+        case ToolTerrain: return "Terrain"
+        case ToolBonus: return "Bonus"
+        case ToolEncounter: return "Encounter"
+        case ToolNode: return "Node"
+        case ToolRoad: return "Road"
+        case ToolVolcano: return "Volcano"
+        case ToolCity: return "Neutral Town"
+        case ToolStart: return "Wizard Start"
+    }
+
+    return "?"
+}
+
+// encounters that can be placed directly, nodes are placed with the node tool
+var placeableEncounters = []maplib.EncounterType{
+    maplib.EncounterTypeLair,
+    maplib.EncounterTypeCave,
+    maplib.EncounterTypePlaneTower,
+    maplib.EncounterTypeAncientTemple,
+    maplib.EncounterTypeFallenTemple,
+    maplib.EncounterTypeRuins,
+    maplib.EncounterTypeAbandonedKeep,
+    maplib.EncounterTypeDungeon,
+}
+
+const defaultTownPopulation = 4000
+
+// the difficulty and magic setting used to create guardians
+const editorDifficulty = data.DifficultyAverage
+const editorMagic = data.MagicSettingNormal
+
+func nextInList[T comparable](values []T, current T) T {
+    index := slices.Index(values, current)
+    return values[(index + 1) % len(values)]
+}
+
+// the races a town on the given plane can be
+func planeRaces(plane data.Plane) []data.Race {
+    if plane == data.PlaneMyrror {
+        return data.MyrranRaces()
+    }
+    return data.ArcanianRaces()
+}
+
+// select a tool, or if it is already selected then cycle through its variations
+func (editor *Editor) selectTool(tool EditorTool) {
+    if editor.Tool != tool {
+        editor.Tool = tool
+        return
+    }
+
+    switch tool {
+        case ToolBonus:
+            editor.Bonus = editor.Bonus % data.BonusCrysxCrystal + 1
+        case ToolEncounter:
+            editor.Encounter = nextInList(placeableEncounters, editor.Encounter)
+        case ToolNode:
+            editor.Node = nextInList([]maplib.MagicNode{maplib.MagicNodeSorcery, maplib.MagicNodeNature, maplib.MagicNodeChaos}, editor.Node)
+        case ToolRoad:
+            editor.EnchantedRoad = !editor.EnchantedRoad
+        case ToolCity:
+            editor.Race = nextInList(planeRaces(editor.Plane), editor.Race)
+    }
+}
+
+// the description of the selected tool shown in the info box
+func (editor *Editor) toolDescription() string {
+    switch editor.Tool {
+        case ToolTerrain: return editor.Terrain.String()
+        case ToolBonus: return editor.Bonus.String()
+        case ToolEncounter: return editor.Encounter.Name()
+        case ToolNode: return editor.Node.Name()
+        case ToolRoad:
+            if editor.EnchantedRoad {
+                return "Enchanted Road"
+            }
+            return "Road"
+        case ToolCity:
+            if !slices.Contains(planeRaces(editor.Plane), editor.Race) {
+                return "Neutral Town: choose race"
+            }
+            return "Neutral Town: " + editor.Race.String()
+    }
+
+    return editor.Tool.String()
+}
+
+// the extras of a tile, created if the tile doesn't have any yet
+func ensureExtras(mapObject *maplib.Map, x int, y int) map[maplib.ExtraKind]maplib.ExtraTile {
+    point := image.Pt(x, y)
+    extras, ok := mapObject.ExtraMap[point]
+    if !ok {
+        extras = make(map[maplib.ExtraKind]maplib.ExtraTile)
+        mapObject.ExtraMap[point] = extras
+    }
+    return extras
+}
+
+func (editor *Editor) findCity(x int, y int, plane data.Plane) int {
+    return slices.IndexFunc(editor.Cities, func(city maplib.ScenarioCity) bool {
+        return city.X == x && city.Y == y && city.Plane == plane
+    })
+}
+
+func (editor *Editor) findStart(x int, y int, plane data.Plane) int {
+    return slices.IndexFunc(editor.Starts, func(start maplib.ScenarioStart) bool {
+        return start.X == x && start.Y == y && start.Plane == plane
+    })
+}
+
+func placeNode(mapObject *maplib.Map, x int, y int, kind maplib.MagicNode, plane data.Plane) {
+    tile := terrain.TileSorceryLake
+    switch kind {
+        case maplib.MagicNodeNature: tile = terrain.TileNatureForest
+        case maplib.MagicNodeChaos: tile = terrain.TileChaosVolcano
+    }
+
+    mapObject.Map.Terrain[x][y] = tile.Index(plane)
+
+    extras := ensureExtras(mapObject, x, y)
+    extras[maplib.ExtraKindMagicNode], extras[maplib.ExtraKindEncounter] = maplib.MakeMagicNode(kind, editorMagic, editorDifficulty, plane)
+}
+
+// place the selected feature at x, y
+func (editor *Editor) place(x int, y int) {
+    mapObject := editor.getMap()
+    extras := ensureExtras(mapObject, x, y)
+
+    switch editor.Tool {
+        case ToolTerrain:
+            mapObject.Map.SetTerrainAt(x, y, editor.Terrain, editor.Data, editor.Plane)
+        case ToolBonus:
+            mapObject.SetBonus(x, y, editor.Bonus)
+        case ToolEncounter:
+            if mapObject.GetEncounter(x, y) == nil && !mapObject.HasMagicNode(x, y) {
+                mapObject.CreateEncounter(x, y, editor.Encounter, editorDifficulty, rand.N(2) == 0, editor.Plane)
+            }
+        case ToolNode:
+            if !mapObject.HasMagicNode(x, y) {
+                delete(extras, maplib.ExtraKindEncounter)
+                placeNode(mapObject, x, y, editor.Node, editor.Plane)
+            }
+        case ToolRoad:
+            mapObject.SetRoad(x, y, editor.EnchantedRoad)
+        case ToolVolcano:
+            if !mapObject.HasVolcano(x, y) {
+                mapObject.SetVolcano(x, y, nil)
+            }
+        case ToolCity:
+            if editor.findCity(x, y, editor.Plane) == -1 {
+                if !slices.Contains(planeRaces(editor.Plane), editor.Race) {
+                    editor.Race = planeRaces(editor.Plane)[0]
+                }
+
+                editor.Cities = append(editor.Cities, maplib.ScenarioCity{
+                    X: x,
+                    Y: y,
+                    Plane: editor.Plane,
+                    Race: editor.Race,
+                    Population: defaultTownPopulation,
+                })
+            }
+        case ToolStart:
+            if editor.findStart(x, y, editor.Plane) == -1 {
+                editor.Starts = append(editor.Starts, maplib.ScenarioStart{X: x, Y: y, Plane: editor.Plane})
+            }
+    }
+}
+
+// remove the feature of the selected tool from x, y
+func (editor *Editor) remove(x int, y int) {
+    mapObject := editor.getMap()
+    extras := ensureExtras(mapObject, x, y)
+
+    switch editor.Tool {
+        case ToolTerrain:
+            mapObject.Map.SetTerrainAt(x, y, terrain.Ocean, editor.Data, editor.Plane)
+        case ToolBonus:
+            mapObject.RemoveBonus(x, y)
+        case ToolEncounter:
+            if !mapObject.HasMagicNode(x, y) {
+                mapObject.RemoveEncounter(x, y)
+            }
+            delete(extras, maplib.ExtraKindOpenTower)
+        case ToolNode:
+            if mapObject.HasMagicNode(x, y) {
+                delete(extras, maplib.ExtraKindMagicNode)
+                delete(extras, maplib.ExtraKindEncounter)
+                mapObject.Map.SetTerrainAt(x, y, terrain.Grass, editor.Data, editor.Plane)
+            }
+        case ToolRoad:
+            mapObject.RemoveRoad(x, y)
+        case ToolVolcano:
+            if mapObject.HasVolcano(x, y) {
+                delete(extras, maplib.ExtraKindVolcano)
+                mapObject.Map.SetTerrainAt(x, y, terrain.Mountain, editor.Data, editor.Plane)
+            }
+        case ToolCity:
+            if index := editor.findCity(x, y, editor.Plane); index != -1 {
+                editor.Cities = slices.Delete(editor.Cities, index, index + 1)
+            }
+        case ToolStart:
+            if index := editor.findStart(x, y, editor.Plane); index != -1 {
+                editor.Starts = slices.Delete(editor.Starts, index, index + 1)
+            }
+    }
+}
+
+// create new guardians for the encounter or node at x, y
+func (editor *Editor) rerollGuardians(x int, y int) {
+    mapObject := editor.getMap()
+
+    if node := mapObject.GetMagicNode(x, y); node != nil {
+        _, encounter := maplib.MakeMagicNode(node.Kind, editorMagic, editorDifficulty, editor.Plane)
+        ensureExtras(mapObject, x, y)[maplib.ExtraKindEncounter] = encounter
+        return
+    }
+
+    if encounter := mapObject.GetEncounter(x, y); encounter != nil {
+        kind := encounter.Type
+        mapObject.RemoveEncounter(x, y)
+        mapObject.CreateEncounter(x, y, kind, editorDifficulty, rand.N(2) == 0, editor.Plane)
+    }
+}
+
+// change the treasure budget of the encounter, or the population of the town, at x, y
+func (editor *Editor) adjustTile(x int, y int, up bool) {
+    if index := editor.findCity(x, y, editor.Plane); index != -1 {
+        city := &editor.Cities[index]
+        if up {
+            city.Population = min(city.Population + 1000, 25000)
+        } else {
+            city.Population = max(city.Population - 1000, 1000)
+        }
+        return
+    }
+
+    if encounter := editor.getMap().GetEncounter(x, y); encounter != nil {
+        if up {
+            encounter.Budget += 50
+        } else {
+            encounter.Budget = max(encounter.Budget - 50, 0)
+        }
+    }
+}
+
+// remove the towns and start locations on the given plane
+func (editor *Editor) clearPlane(plane data.Plane) {
+    editor.Cities = slices.DeleteFunc(editor.Cities, func(city maplib.ScenarioCity) bool {
+        return city.Plane == plane
+    })
+
+    editor.Starts = slices.DeleteFunc(editor.Starts, func(start maplib.ScenarioStart) bool {
+        return start.Plane == plane
+    })
+}
+
+func (editor *Editor) saveScenario() {
+    scenario := maplib.MakeScenario(editor.ArcanusMap, editor.MyrrorMap, editor.Cities, editor.Starts)
+    err := maplib.SaveScenarioFile(editor.ScenarioPath, scenario)
+    if err != nil {
+        log.Printf("Unable to save scenario to %v: %v", editor.ScenarioPath, err)
+        editor.Message = "Save failed"
+        return
+    }
+
+    log.Printf("Saved scenario to %v", editor.ScenarioPath)
+    editor.Message = "Saved " + editor.ScenarioPath
+}
+
+func (editor *Editor) loadScenario() {
+    scenario, err := maplib.LoadScenarioFile(editor.ScenarioPath)
+    if err != nil {
+        log.Printf("Unable to load scenario from %v: %v", editor.ScenarioPath, err)
+        editor.Message = "Load failed"
+        return
+    }
+
+    editor.ArcanusMap = scenario.ReconstructMap(data.PlaneArcanus, editor.Data, nil, nil)
+    editor.MyrrorMap = scenario.ReconstructMap(data.PlaneMyrror, editor.Data, nil, nil)
+    editor.Cities = scenario.Cities
+    editor.Starts = scenario.Starts
+
+    log.Printf("Loaded scenario from %v", editor.ScenarioPath)
+    editor.Message = "Loaded " + editor.ScenarioPath
+}
+
+// the terrain file of the current plane that goes with the scenario, such as scenario.png and scenario-myrror.png
+func (editor *Editor) terrainPath(extension string) string {
+    path := strings.TrimSuffix(editor.ScenarioPath, filepath.Ext(editor.ScenarioPath)) + extension
+    if editor.Plane == data.PlaneMyrror {
+        path = maplib.MyrrorTerrainPath(path)
+    }
+    return path
+}
+
+// write the terrain of the current plane as a png and as a text grid
+func (editor *Editor) exportTerrain() {
+    for _, extension := range []string{".png", ".txt"} {
+        path := editor.terrainPath(extension)
+        err := editor.getMap().SaveTerrainFile(path)
+        if err != nil {
+            log.Printf("Unable to export terrain to %v: %v", path, err)
+            editor.Message = "Export failed"
+            return
+        }
+        log.Printf("Exported terrain to %v", path)
+    }
+
+    editor.Message = "Exported " + editor.terrainPath(".png")
+}
+
+// replace the terrain of the given plane with the terrain in a png or text file. nodes in the file get guardians,
+// everything else on the plane is removed
+func (editor *Editor) importTerrain(path string, plane data.Plane) bool {
+    imported, err := maplib.LoadTerrainFile(path, editor.Data, plane)
+    if err != nil {
+        log.Printf("Unable to import terrain from %v: %v", path, err)
+        editor.Message = "Import failed"
+        return false
+    }
+
+    mapObject := makeEmptyMap(editor.Data, imported.Rows(), imported.Columns(), plane)
+    mapObject.Map = imported
+
+    for x := range imported.Columns() {
+        for y := range imported.Rows() {
+            ensureExtras(mapObject, x, y)
+            switch terrain.GetTile(imported.Terrain[x][y]).TerrainType() {
+                case terrain.SorceryNode: placeNode(mapObject, x, y, maplib.MagicNodeSorcery, plane)
+                case terrain.NatureNode: placeNode(mapObject, x, y, maplib.MagicNodeNature, plane)
+                case terrain.ChaosNode: placeNode(mapObject, x, y, maplib.MagicNodeChaos, plane)
+            }
+        }
+    }
+
+    if plane == data.PlaneArcanus {
+        editor.ArcanusMap = mapObject
+    } else {
+        editor.MyrrorMap = mapObject
+    }
+    editor.clearPlane(plane)
+
+    log.Printf("Imported terrain from %v", path)
+    editor.Message = "Imported " + path
+    return true
+}
+
+// import the terrain of the current plane from the files that exportTerrain writes
+func (editor *Editor) reimportTerrain() {
+    for _, extension := range []string{".png", ".txt"} {
+        path := editor.terrainPath(extension)
+        if _, err := os.Stat(path); err == nil {
+            editor.importTerrain(path, editor.Plane)
+            return
+        }
+    }
+
+    editor.Message = "No " + editor.terrainPath(".png")
+}